@@ -15,6 +15,7 @@ import (
 	"csd-pilote/backend/modules/platform/server"
 
 	// Import modules to register their GraphQL operations
+	_ "csd-pilote/backend/modules/pilot/activity"
 	_ "csd-pilote/backend/modules/pilot/clusters"
 	_ "csd-pilote/backend/modules/pilot/containers"
 	_ "csd-pilote/backend/modules/pilot/dashboard"
@@ -38,6 +39,7 @@ var Version = "1.0.0"
 func main() {
 	configPath := flag.String("config", "", "Path to configuration file")
 	flag.Parse()
+	config.AppVersion = Version
 
 	// Load configuration
 	cfg, err := config.Load(*configPath)
@@ -56,6 +58,11 @@ func main() {
 
 	logger.Info("Configuration loaded (log level: %s)", cfg.Logging.Level)
 
+	// Report missing or malformed essential keys up front
+	if err := cfg.Validate(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Register with csd-core
 	if cfg.CSDCore.ServiceToken != "" {
 		log.Printf("Registering service with csd-core at %s%s...", cfg.CSDCore.URL, cfg.CSDCore.GraphQLEndpoint)
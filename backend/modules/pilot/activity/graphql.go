@@ -0,0 +1,122 @@
+package activity
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"csd-pilote/backend/modules/platform/events"
+	"csd-pilote/backend/modules/platform/graphql"
+	"csd-pilote/backend/modules/platform/middleware"
+	"csd-pilote/backend/modules/platform/pagination"
+	"csd-pilote/backend/modules/platform/server"
+	"csd-pilote/backend/modules/platform/validation"
+)
+
+func init() {
+	service := NewService()
+
+	// Recording events needs the database, which exists only once the server starts
+	server.OnStart(func() {
+		service.bind()
+		service.Subscribe(events.GetEventBus())
+	})
+
+	graphql.RegisterQuery("recentEvents", "List activity feed events with filtering and pagination", "csd-pilote.dashboard.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListRecentEvents(ctx, w, variables, service)
+		})
+}
+
+func handleListRecentEvents(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceActivity)
+
+	filter, err := parseActivityEventFilter(graphql.GetFilter(variables))
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	items, count, err := service.List(ctx, tenantID, filter, variables["advancedFilter"], limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list recent events")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"recentEvents":      items,
+		"recentEventsCount": count,
+	})
+}
+
+func parseActivityEventFilter(f map[string]interface{}) (*ActivityEventFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	v := validation.NewValidator()
+	filter := &ActivityEventFilter{}
+
+	search, err := graphql.ParseFilterSearch(f)
+	if err != nil {
+		return nil, err
+	}
+	if search != "" {
+		filter.Search = &search
+	}
+	if types, ok := f["types"].([]interface{}); ok {
+		v.MaxItems("types", len(types), validation.MaxBulkIDs)
+		for _, t := range types {
+			if typeStr, ok := t.(string); ok && typeStr != "" {
+				v.MaxLength("types", typeStr, validation.MaxNameLength).SafeString("types", typeStr)
+				filter.Types = append(filter.Types, typeStr)
+			}
+		}
+	}
+	if resourceType, ok := f["resourceType"].(string); ok && resourceType != "" {
+		v.MaxLength("resourceType", resourceType, validation.MaxNameLength).SafeString("resourceType", resourceType)
+		filter.ResourceType = &resourceType
+	}
+	if resourceID, ok := f["resourceId"].(string); ok && resourceID != "" {
+		v.MaxLength("resourceId", resourceID, validation.MaxNameLength)
+		filter.ResourceID = &resourceID
+	}
+	if actorID, ok := f["actorId"].(string); ok && actorID != "" {
+		v.UUID("actorId", actorID)
+		if id, err := uuid.Parse(actorID); err == nil {
+			filter.ActorID = &id
+		}
+	}
+	filter.From = parseFilterTime(v, f, "from")
+	filter.To = parseFilterTime(v, f, "to")
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		v.Errors().Add("to", "to must be after from", "INVALID_RANGE")
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return filter, nil
+}
+
+// parseFilterTime parses an optional RFC 3339 timestamp from the filter
+func parseFilterTime(v *validation.Validator, f map[string]interface{}, key string) *time.Time {
+	value, ok := f[key].(string)
+	if !ok || value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.Errors().Add(key, key+" must be an RFC 3339 timestamp", "INVALID_FORMAT")
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,35 @@
+package activity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityEvent is a persisted domain event shown in the activity feed
+type ActivityEvent struct {
+	ID           uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID     uuid.UUID              `json:"tenantId" gorm:"type:uuid;not null;index:idx_activity_tenant_time"`
+	Type         string                 `json:"type" gorm:"not null"`
+	ResourceType string                 `json:"resourceType"`
+	ResourceID   string                 `json:"resourceId"`
+	ActorID      *uuid.UUID             `json:"actorId" gorm:"type:uuid"`
+	Payload      map[string]interface{} `json:"payload" gorm:"type:jsonb;serializer:json"`
+	Timestamp    time.Time              `json:"timestamp" gorm:"not null;index:idx_activity_tenant_time"`
+}
+
+// TableName returns the table name for GORM
+func (ActivityEvent) TableName() string {
+	return "activity_events"
+}
+
+// ActivityEventFilter represents filter options for listing activity events
+type ActivityEventFilter struct {
+	Search       *string    `json:"search"`
+	Types        []string   `json:"types"`
+	ResourceType *string    `json:"resourceType"`
+	ResourceID   *string    `json:"resourceId"`
+	ActorID      *uuid.UUID `json:"actorId"`
+	From         *time.Time `json:"from"`
+	To           *time.Time `json:"to"`
+}
@@ -0,0 +1,82 @@
+package activity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"csd-pilote/backend/modules/platform/database"
+	"csd-pilote/backend/modules/platform/filters"
+)
+
+// Repository handles database operations for activity events
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new activity repository
+func NewRepository() *Repository {
+	return &Repository{db: database.GetDB()}
+}
+
+// Create persists an activity event
+func (r *Repository) Create(event *ActivityEvent) error {
+	return r.db.Create(event).Error
+}
+
+// List retrieves activity events for a tenant, newest first, with optional filtering
+func (r *Repository) List(tenantID uuid.UUID, filter *ActivityEventFilter, advancedFilter interface{}, limit, offset int) ([]ActivityEvent, int64, error) {
+	var items []ActivityEvent
+	var count int64
+
+	query := r.db.Model(&ActivityEvent{}).Where("tenant_id = ?", tenantID)
+
+	if filter != nil {
+		if filter.Search != nil && *filter.Search != "" {
+			search := "%" + *filter.Search + "%"
+			query = query.Where("type ILIKE ? OR resource_id ILIKE ?", search, search)
+		}
+		if len(filter.Types) > 0 {
+			query = query.Where("type IN ?", filter.Types)
+		}
+		if filter.ResourceType != nil {
+			query = query.Where("resource_type = ?", *filter.ResourceType)
+		}
+		if filter.ResourceID != nil {
+			query = query.Where("resource_id = ?", *filter.ResourceID)
+		}
+		if filter.ActorID != nil {
+			query = query.Where("actor_id = ?", *filter.ActorID)
+		}
+		if filter.From != nil {
+			query = query.Where("timestamp >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			query = query.Where("timestamp < ?", *filter.To)
+		}
+	}
+
+	// Apply advanced filter
+	if advancedFilter != nil {
+		qb := filters.NewQueryBuilder(r.db).
+			WithStrictMode().
+			WithFieldMappings(map[string]string{
+				"type":         "type",
+				"resourceType": "resource_type",
+				"resourceId":   "resource_id",
+				"actorId":      "actor_id",
+				"timestamp":    "timestamp",
+			})
+		var err error
+		query, err = qb.ApplyFilterJSON(query, advancedFilter)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&items).Error
+	return items, count, err
+}
@@ -0,0 +1,66 @@
+package activity
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+
+	"csd-pilote/backend/modules/platform/events"
+	"csd-pilote/backend/modules/platform/pagination"
+)
+
+// Service handles business logic for the activity feed
+type Service struct {
+	repo *Repository
+}
+
+// NewService creates a new activity service
+func NewService() *Service {
+	return &Service{
+		repo: NewRepository(),
+	}
+}
+
+// bind points the service at the database, once it is connected
+func (s *Service) bind() {
+	s.repo = NewRepository()
+}
+
+// Subscribe persists every published domain event so it can be queried later
+func (s *Service) Subscribe(bus *events.EventBus) {
+	bus.SubscribeAll(func(ctx context.Context, event events.Event) {
+		if err := s.Record(event); err != nil {
+			log.Printf("[Activity] Failed to record event %s: %v", event.Type, err)
+		}
+	})
+}
+
+// Record stores a domain event in the activity feed
+func (s *Service) Record(event events.Event) error {
+	if event.TenantID == uuid.Nil {
+		return nil
+	}
+
+	id, err := uuid.Parse(event.ID)
+	if err != nil {
+		id = uuid.New()
+	}
+
+	return s.repo.Create(&ActivityEvent{
+		ID:           id,
+		TenantID:     event.TenantID,
+		Type:         string(event.Type),
+		ResourceType: event.ResourceType(),
+		ResourceID:   event.ResourceID,
+		ActorID:      event.ActorID,
+		Payload:      event.Payload,
+		Timestamp:    event.Timestamp,
+	})
+}
+
+// List retrieves activity events for a tenant
+func (s *Service) List(ctx context.Context, tenantID uuid.UUID, filter *ActivityEventFilter, advancedFilter interface{}, limit, offset int) ([]ActivityEvent, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceActivity, limit, offset)
+	return s.repo.List(tenantID, filter, advancedFilter, p.Limit, p.Offset)
+}
@@ -0,0 +1,131 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"csd-pilote/backend/modules/platform/database"
+	"csd-pilote/backend/modules/platform/events"
+)
+
+// memoryDriver is a database/sql driver keeping the rows inserted in activity_events in memory.
+// It understands the statements the repository issues: INSERT, SELECT count(*) and SELECT *,
+// both scoped by tenant_id
+type memoryDriver struct {
+	mu      sync.Mutex
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *memoryDriver) Open(string) (driver.Conn, error) { return &memoryConn{d: d}, nil }
+
+type memoryConn struct{ d *memoryDriver }
+
+func (c *memoryConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *memoryConn) Close() error                        { return nil }
+func (c *memoryConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *memoryConn) Commit() error                       { return nil }
+func (c *memoryConn) Rollback() error                     { return nil }
+
+func (c *memoryConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.HasPrefix(query, `INSERT INTO "activity_events"`) {
+		return nil, driver.ErrSkip
+	}
+	list := query[strings.Index(query, "(")+1 : strings.Index(query, ")")]
+	columns := strings.Split(strings.ReplaceAll(list, `"`, ""), ",")
+	row := make([]driver.Value, len(args))
+	for i, arg := range args {
+		row[i] = arg.Value
+	}
+
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.columns = columns
+	c.d.rows = append(c.d.rows, row)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *memoryConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	tenant := args[0].Value
+	var matched [][]driver.Value
+	for _, row := range c.d.rows {
+		for i, column := range c.d.columns {
+			if column == "tenant_id" && row[i] == tenant {
+				matched = append(matched, row)
+			}
+		}
+	}
+	if strings.HasPrefix(query, "SELECT count(*)") {
+		return &memoryRows{columns: []string{"count"}, rows: [][]driver.Value{{int64(len(matched))}}}, nil
+	}
+	return &memoryRows{columns: c.d.columns, rows: matched}, nil
+}
+
+type memoryRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *memoryRows) Columns() []string { return r.columns }
+func (r *memoryRows) Close() error      { return nil }
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestRecordedEventsAreListed(t *testing.T) {
+	sql.Register("activity-memory", &memoryDriver{})
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "activity-memory"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	previous := database.DB
+	database.DB = db
+	defer func() { database.DB = previous }()
+
+	// Like init(): the service exists before the database and is bound when the server starts
+	service := &Service{}
+	service.bind()
+	bus := events.GetEventBus()
+	service.Subscribe(bus)
+
+	tenantID := uuid.New()
+	bus.Publish(context.Background(), events.NewEvent(events.EventFirewallRuleCreated, tenantID, "rule-1", map[string]interface{}{"name": "ssh"}))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		items, count, err := service.List(context.Background(), tenantID, nil, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if count == 1 && len(items) == 1 {
+			got := items[0]
+			if got.TenantID != tenantID || got.Type != string(events.EventFirewallRuleCreated) || got.ResourceID != "rule-1" || got.Payload["name"] != "ssh" {
+				t.Errorf("listed event = %+v", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("recorded event was not listed: count = %d, items = %v", count, items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"csd-pilote/backend/modules/platform/graphql"
 	"csd-pilote/backend/modules/platform/graphql/crud"
 	"csd-pilote/backend/modules/platform/middleware"
+	"csd-pilote/backend/modules/platform/pagination"
 	"csd-pilote/backend/modules/platform/validation"
 )
 
@@ -85,7 +86,7 @@ func handleListClusters(ctx context.Context, w http.ResponseWriter, variables ma
 	}
 
 	// Use validated pagination with max limits
-	limit, offset := graphql.ParsePagination(variables)
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceClusters)
 
 	var filter *ClusterFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
@@ -293,7 +293,7 @@ func (s *Service) Get(ctx context.Context, tenantID, id uuid.UUID) (*Cluster, er
 
 // List retrieves all clusters for a tenant
 func (s *Service) List(ctx context.Context, tenantID uuid.UUID, filter *ClusterFilter, limit, offset int) ([]Cluster, int64, error) {
-	p := pagination.Normalize(limit, offset)
+	p := pagination.NormalizeFor(pagination.ResourceClusters, limit, offset)
 	return s.repo.List(tenantID, filter, p.Limit, p.Offset)
 }
 
@@ -2,17 +2,31 @@ package containers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"path"
+	"regexp"
+
+	"github.com/google/uuid"
 
 	csdcore "csd-pilote/backend/modules/platform/csd-core"
 	"csd-pilote/backend/modules/platform/graphql"
 	"csd-pilote/backend/modules/platform/middleware"
+	"csd-pilote/backend/modules/platform/pagination"
+	"csd-pilote/backend/modules/platform/server"
 	"csd-pilote/backend/modules/platform/validation"
 )
 
 func init() {
 	service := NewService()
 
+	// Background jobs need the database and the csd-core client, which exist only once the server starts
+	server.OnStart(func() {
+		service.bind()
+		go service.runInventoryJob()
+		go service.runProbeJob()
+	})
+
 	// Queries
 	graphql.RegisterQuery("containerEngines", "List all container engines", "csd-pilote.containers.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
@@ -44,11 +58,21 @@ func init() {
 			handleListVolumes(ctx, w, variables, service)
 		})
 
-	graphql.RegisterQuery("containerLogs", "Get container logs", "csd-pilote.containers.read",
+	graphql.RegisterQuery("containerStats", "Get the CPU, memory and network usage of a container", "csd-pilote.containers.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetContainerStats(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("containerLogs", "Get container logs with optional timestamps, stream selection (COMBINED/STDOUT/STDERR/SEPARATE) and structured JSON lines", "csd-pilote.containers.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleGetContainerLogs(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("failedContainers", "List containers found stopped or missing after running, within the last hours (default 24)", "csd-pilote.containers.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListFailedContainers(ctx, w, variables, service)
+		})
+
 	// Mutations
 	graphql.RegisterMutation("createContainerEngine", "Create a new container engine", "csd-pilote.containers.create",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
@@ -80,6 +104,11 @@ func init() {
 			handlePullImage(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("deployContainerStack", "Deploy a compose-like stack of containers on an engine", "csd-pilote.containers.manage",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeployContainerStack(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("bulkDeleteContainerEngines", "Delete multiple container engines", "csd-pilote.containers.delete",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleBulkDeleteContainerEngines(ctx, w, variables, service)
@@ -93,7 +122,7 @@ func handleListContainerEngines(ctx context.Context, w http.ResponseWriter, vari
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceContainerEngines)
 
 	var filter *ContainerEngineFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
@@ -182,7 +211,7 @@ func handleCreateContainerEngine(ctx context.Context, w http.ResponseWriter, var
 
 	input, err := parseContainerEngineInput(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -199,6 +228,7 @@ func handleCreateContainerEngine(ctx context.Context, w http.ResponseWriter, var
 		graphql.WriteError(w, err, "create container engine")
 		return
 	}
+	service.ProbeEngineAsync(token, engine)
 
 	// Audit log
 	csdcore.GetClient().LogAuditAsync(ctx, token, csdcore.AuditEntry{
@@ -240,7 +270,7 @@ func handleUpdateContainerEngine(ctx context.Context, w http.ResponseWriter, var
 
 	input, err := parseContainerEngineInput(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -249,6 +279,10 @@ func handleUpdateContainerEngine(ctx context.Context, w http.ResponseWriter, var
 		graphql.WriteError(w, err, "update container engine")
 		return
 	}
+	// A new host or agent resets the status, probe it again
+	if engine.Status == EngineStatusPending {
+		service.ProbeEngineAsync(token, engine)
+	}
 
 	// Audit log
 	csdcore.GetClient().LogAuditAsync(ctx, token, csdcore.AuditEntry{
@@ -322,7 +356,7 @@ func handleTestContainerEngineConnection(ctx context.Context, w http.ResponseWri
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
 	if err := service.TestConnection(ctx, token, tenantID, id, agentID); err != nil {
@@ -350,19 +384,66 @@ func handleListContainers(ctx context.Context, w http.ResponseWriter, variables
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
 	all := graphql.ParseBool(variables, "all", false)
 
-	containers, err := service.ListContainers(ctx, token, tenantID, engineID, agentID, all)
+	filter, limit, offset, err := parseEngineListArgs(variables, true)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	containers, count, err := service.ListContainers(ctx, token, tenantID, engineID, agentID, all, filter, limit, offset)
 	if err != nil {
 		graphql.WriteError(w, err, "list containers")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"containers": containers,
+		"containers":      containers,
+		"containersCount": count,
+	})
+}
+
+func handleListFailedContainers(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceContainerInventory)
+
+	// engineId is optional; all engines of the tenant by default
+	var engineID *uuid.UUID
+	if _, ok := variables["engineId"]; ok {
+		id, err := graphql.ParseUUID(variables, "engineId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		engineID = &id
+	}
+
+	hours := graphql.ParseInt(variables, "hours", 24)
+	v := validation.NewValidator()
+	v.Range("hours", hours, 1, 24*30)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	snapshots, count, err := service.ListFailedContainers(ctx, tenantID, engineID, hours, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list failed containers")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"failedContainers":      snapshots,
+		"failedContainersCount": count,
 	})
 }
 
@@ -381,17 +462,24 @@ func handleListImages(ctx context.Context, w http.ResponseWriter, variables map[
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
-	images, err := service.ListImages(ctx, token, tenantID, engineID, agentID)
+	filter, limit, offset, err := parseEngineListArgs(variables, false)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	images, count, err := service.ListImages(ctx, token, tenantID, engineID, agentID, filter, limit, offset)
 	if err != nil {
 		graphql.WriteError(w, err, "list images")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"containerImages": images,
+		"containerImages":      images,
+		"containerImagesCount": count,
 	})
 }
 
@@ -410,17 +498,24 @@ func handleListNetworks(ctx context.Context, w http.ResponseWriter, variables ma
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
-	networks, err := service.ListNetworks(ctx, token, tenantID, engineID, agentID)
+	filter, limit, offset, err := parseEngineListArgs(variables, false)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	networks, count, err := service.ListNetworks(ctx, token, tenantID, engineID, agentID, filter, limit, offset)
 	if err != nil {
 		graphql.WriteError(w, err, "list networks")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"containerNetworks": networks,
+		"containerNetworks":      networks,
+		"containerNetworksCount": count,
 	})
 }
 
@@ -439,17 +534,66 @@ func handleListVolumes(ctx context.Context, w http.ResponseWriter, variables map
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
-	volumes, err := service.ListVolumes(ctx, token, tenantID, engineID, agentID)
+	filter, limit, offset, err := parseEngineListArgs(variables, false)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	volumes, count, err := service.ListVolumes(ctx, token, tenantID, engineID, agentID, filter, limit, offset)
 	if err != nil {
 		graphql.WriteError(w, err, "list volumes")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"containerVolumes": volumes,
+		"containerVolumes":      volumes,
+		"containerVolumesCount": count,
+	})
+}
+
+func handleGetContainerStats(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	engineID, err := graphql.ParseUUID(variables, "engineId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// agentId is optional; defaults to the agent bound to the engine
+	agentID, _ := graphql.ParseUUID(variables, "agentId")
+
+	containerID, err := graphql.ParseStringRequired(variables, "containerId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	v := validation.NewValidator()
+	v.SafeString("containerId", containerID)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	stats, err := service.GetContainerStats(ctx, token, tenantID, engineID, agentID, containerID)
+	if err != nil {
+		graphql.WriteError(w, err, "get container stats")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"containerStats": stats,
 	})
 }
 
@@ -474,28 +618,37 @@ func handleGetContainerLogs(ctx context.Context, w http.ResponseWriter, variable
 		return
 	}
 
-	// Validate containerID format (safe string)
+	opts := ContainerLogOptions{
+		Tail:       graphql.ParseInt(variables, "tail", 100),
+		Timestamps: graphql.ParseBool(variables, "timestamps", false),
+		Stream:     LogStream(graphql.ParseString(variables, "stream")),
+		Format:     LogFormat(graphql.ParseString(variables, "format")),
+	}
+
+	// Validate containerID format (safe string) and log options
 	v := validation.NewValidator()
 	v.SafeString("containerId", containerID)
+	v.Range("tail", opts.Tail, 1, validation.MaxTailLines)
+	v.Enum("stream", string(opts.Stream), []string{string(LogStreamCombined), string(LogStreamStdout), string(LogStreamStderr), string(LogStreamSeparate)})
+	v.Enum("format", string(opts.Format), []string{string(LogFormatPlain), string(LogFormatJSON)})
 	if v.HasErrors() {
 		graphql.WriteValidationError(w, v.FirstError())
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
-	// Limit tail lines for security
-	tail := graphql.ParseIntWithMax(variables, "tail", 100, validation.MaxTailLines)
-
-	logs, err := service.GetContainerLogs(ctx, token, tenantID, engineID, agentID, containerID, tail)
+	logs, err := service.GetContainerLogs(ctx, token, tenantID, engineID, agentID, containerID, opts)
 	if err != nil {
 		graphql.WriteError(w, err, "get container logs")
 		return
 	}
 
+	// containerLogs keeps the plain output for existing clients
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"containerLogs": logs,
+		"containerLogs":       logs.Output,
+		"containerLogDetails": logs,
 	})
 }
 
@@ -540,7 +693,7 @@ func handleContainerAction(ctx context.Context, w http.ResponseWriter, variables
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
 	if err := service.ContainerAction(ctx, token, tenantID, engineID, agentID, containerID, action); err != nil {
@@ -582,7 +735,7 @@ func handlePullImage(ctx context.Context, w http.ResponseWriter, variables map[s
 		return
 	}
 
-	// agentId is optional
+	// agentId is optional; defaults to the agent bound to the engine
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
 	if err := service.PullImage(ctx, token, tenantID, engineID, agentID, imageName); err != nil {
@@ -595,6 +748,59 @@ func handlePullImage(ctx context.Context, w http.ResponseWriter, variables map[s
 	})
 }
 
+func handleDeployContainerStack(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	engineID, err := graphql.ParseUUID(variables, "engineId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// agentId is optional; defaults to the agent bound to the engine
+	agentID, _ := graphql.ParseUUID(variables, "agentId")
+
+	stackRaw, ok := variables["stack"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "stack is required")
+		return
+	}
+	spec, err := parseContainerStackSpec(stackRaw)
+	if err != nil {
+		graphql.WriteInputError(w, err)
+		return
+	}
+
+	deployment, err := service.DeployStack(ctx, token, tenantID, engineID, agentID, spec)
+	if err != nil {
+		graphql.WriteError(w, err, "deploy container stack")
+		return
+	}
+
+	// Audit log
+	csdcore.GetClient().LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "DEPLOY_CONTAINER_STACK",
+		ResourceType: "container_engine",
+		ResourceID:   engineID.String(),
+		Details: map[string]interface{}{
+			"stackName": spec.Name,
+			"agentId":   deployment.AgentID.String(),
+			"deployed":  deployment.Deployed,
+			"failed":    deployment.Failed,
+		},
+	})
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deployContainerStack": deployment,
+	})
+}
+
 func handleBulkDeleteContainerEngines(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
@@ -636,6 +842,43 @@ func handleBulkDeleteContainerEngines(ctx context.Context, w http.ResponseWriter
 // Helper Functions
 // ========================================
 
+// parseEngineListArgs parses the optional filter, limit and offset of an engine list query.
+// Engines do not paginate, so a missing limit returns every match up to maxEngineListResults.
+func parseEngineListArgs(variables map[string]interface{}, withState bool) (*EngineListFilter, int, int, error) {
+	limit := graphql.ParseInt(variables, "limit", 0)
+	offset := graphql.ParseInt(variables, "offset", 0)
+	v := validation.NewValidator()
+	v.Range("limit", limit, 0, maxEngineListResults)
+	if offset < 0 {
+		v.Errors().Add("offset", "offset must not be negative", "OUT_OF_RANGE")
+	}
+	if v.HasErrors() {
+		return nil, 0, 0, validation.NewValidationError(v.FirstError())
+	}
+
+	var filter *EngineListFilter
+	if f, ok := variables["filter"].(map[string]interface{}); ok {
+		filter = &EngineListFilter{}
+		if search, ok := f["search"].(string); ok {
+			if len(search) > validation.MaxSearchLength {
+				return nil, 0, 0, validation.NewValidationError("search term too long")
+			}
+			filter.Search = &search
+		}
+		if state, ok := f["state"].(string); ok && withState {
+			if err := graphql.ValidateEnum(state, graphql.ContainerStateValues, "state"); err != nil {
+				return nil, 0, 0, err
+			}
+			filter.State = &state
+		}
+	}
+
+	return filter, limit, offset, nil
+}
+
+// engineHostSchemes are the URL schemes accepted for a container engine host
+var engineHostSchemes = []string{"tcp", "unix", "ssh"}
+
 func parseContainerEngineInput(inputRaw map[string]interface{}) (*ContainerEngineInput, error) {
 	input := &ContainerEngineInput{}
 	v := validation.NewValidator()
@@ -655,16 +898,122 @@ func parseContainerEngineInput(inputRaw map[string]interface{}) (*ContainerEngin
 		input.EngineType = EngineType(engineType)
 	}
 	if host, ok := inputRaw["host"].(string); ok {
-		v.MaxLength("host", host, 1024).SafeString("host", host)
+		v.MaxLength("host", host, 1024).SafeString("host", host).URL("host", host, engineHostSchemes...)
 		input.Host = host
 	}
 	if artifactKey, ok := inputRaw["artifactKey"].(string); ok {
 		v.MaxLength("artifactKey", artifactKey, 255).SafeString("artifactKey", artifactKey)
 		input.ArtifactKey = artifactKey
 	}
+	if agentID, ok := inputRaw["agentId"].(string); ok && agentID != "" {
+		v.UUID("agentId", agentID)
+		input.AgentID = agentID
+	}
 
 	if v.HasErrors() {
 		return nil, v.Errors()
 	}
 	return input, nil
 }
+
+// envNameRegex matches the environment variable names accepted in a stack service
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseContainerStackSpec parses and validates a stack spec, reporting errors per field
+func parseContainerStackSpec(raw map[string]interface{}) (*ContainerStackSpec, error) {
+	v := validation.NewValidator()
+	spec := &ContainerStackSpec{}
+
+	spec.Name, _ = raw["name"].(string)
+	v.Required("name", spec.Name).KubernetesName("name", spec.Name)
+
+	servicesRaw, _ := raw["services"].([]interface{})
+	v.MinItems("services", len(servicesRaw), 1).MaxItems("services", len(servicesRaw), maxStackServices)
+	if len(servicesRaw) == 0 || len(servicesRaw) > maxStackServices {
+		return nil, v.Errors()
+	}
+
+	names := make(map[string]bool, len(servicesRaw))
+	for i, s := range servicesRaw {
+		serviceMap, ok := s.(map[string]interface{})
+		if !ok {
+			v.Errors().Add(fmt.Sprintf("services[%d]", i), "service must be an object", "INVALID_FORMAT")
+			continue
+		}
+		field := fmt.Sprintf("services[%d]", i)
+		svc := StackService{}
+
+		svc.Name, _ = serviceMap["name"].(string)
+		v.Required(field+".name", svc.Name).KubernetesName(field+".name", svc.Name)
+		if names[svc.Name] {
+			v.Errors().Add(field+".name", fmt.Sprintf("service %s is defined more than once", svc.Name), "DUPLICATE")
+		}
+		names[svc.Name] = true
+
+		svc.Image, _ = serviceMap["image"].(string)
+		v.Required(field+".image", svc.Image).DockerImageName(field+".image", svc.Image)
+
+		ports, _ := serviceMap["ports"].([]interface{})
+		v.MaxItems(field+".ports", len(ports), validation.MaxArrayLength)
+		for j, p := range ports {
+			portMap, _ := p.(map[string]interface{})
+			portField := fmt.Sprintf("%s.ports[%d]", field, j)
+			mapping := StackPortMapping{
+				HostPort:      graphql.ParseInt(portMap, "hostPort", 0),
+				ContainerPort: graphql.ParseInt(portMap, "containerPort", 0),
+			}
+			mapping.Protocol, _ = portMap["protocol"].(string)
+			v.Port(portField+".hostPort", mapping.HostPort).Port(portField+".containerPort", mapping.ContainerPort)
+			v.Enum(portField+".protocol", mapping.Protocol, []string{"tcp", "udp"})
+			svc.Ports = append(svc.Ports, mapping)
+		}
+
+		if envRaw, ok := serviceMap["env"].(map[string]interface{}); ok {
+			svc.Env = make(map[string]string, len(envRaw))
+			for key, value := range envRaw {
+				str, ok := value.(string)
+				if !envNameRegex.MatchString(key) || !ok {
+					v.Errors().Add(field+".env."+key, "env entries must be string values with a valid variable name", "INVALID_FORMAT")
+					continue
+				}
+				v.MaxLength(field+".env."+key, str, validation.MaxDescriptionLength)
+				svc.Env[key] = str
+			}
+		}
+
+		volumes, _ := serviceMap["volumes"].([]interface{})
+		v.MaxItems(field+".volumes", len(volumes), validation.MaxArrayLength)
+		for j, m := range volumes {
+			mountMap, _ := m.(map[string]interface{})
+			mountField := fmt.Sprintf("%s.volumes[%d]", field, j)
+			mount := StackVolumeMount{ReadOnly: graphql.ParseBool(mountMap, "readOnly", false)}
+			mount.Source, _ = mountMap["source"].(string)
+			mount.Target, _ = mountMap["target"].(string)
+			v.Required(mountField+".source", mount.Source).Required(mountField+".target", mount.Target)
+			if mount.Source != "" && !path.IsAbs(mount.Source) {
+				v.KubernetesName(mountField+".source", mount.Source)
+			}
+			if mount.Target != "" && !path.IsAbs(mount.Target) {
+				v.Errors().Add(mountField+".target", "target must be an absolute path", "INVALID_FORMAT")
+			}
+			v.SafeString(mountField+".source", mount.Source).SafeString(mountField+".target", mount.Target)
+			svc.Volumes = append(svc.Volumes, mount)
+		}
+
+		networks, _ := serviceMap["networks"].([]interface{})
+		v.MaxItems(field+".networks", len(networks), validation.MaxArrayLength)
+		for j, n := range networks {
+			network, _ := n.(string)
+			networkField := fmt.Sprintf("%s.networks[%d]", field, j)
+			v.Required(networkField, network).KubernetesName(networkField, network)
+			svc.Networks = append(svc.Networks, network)
+		}
+
+		spec.Services = append(spec.Services, svc)
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return spec, nil
+}
@@ -0,0 +1,38 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestParseContainerEngineInputHost(t *testing.T) {
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"unix:///var/run/docker.sock", true},
+		{"unix:///run/user/1000/podman/podman.sock", true},
+		{"tcp://10.0.0.5:2376", true},
+		{"tcp://docker.example.com:2375", true},
+		{"tcp://[2001:db8::1]:2375", true},
+		{"TCP://docker.example.com", true},
+		{"ssh://deploy@docker.example.com", true},
+		{"ssh://deploy@docker.example.com:2222", true},
+		{"http://docker.example.com:2375", false},
+		{"npipe:////./pipe/docker_engine", false},
+		{"docker.example.com:2375", false},
+		{"/var/run/docker.sock", false},
+		{"tcp://", false},
+		{"unix://", false},
+		{"tcp://docker.example.com:0", false},
+		{"tcp://docker.example.com:65536", false},
+		{"tcp://docker_host:2375", false},
+		{"tcp://-docker.example.com", false},
+		{"tcp://bad host:2375", false},
+	}
+	for _, tt := range tests {
+		_, err := parseContainerEngineInput(map[string]interface{}{"host": tt.host})
+		if (err == nil) != tt.valid {
+			t.Errorf("host %q: err = %v, want valid %v", tt.host, err, tt.valid)
+		}
+	}
+}
@@ -31,8 +31,9 @@ type ContainerEngine struct {
 	Name          string       `json:"name" gorm:"not null"`
 	Description   string       `json:"description"`
 	EngineType    EngineType   `json:"engineType" gorm:"not null;default:'DOCKER'"`
-	Host          string       `json:"host" gorm:"not null"` // unix:///var/run/docker.sock or tcp://host:port
-	ArtifactKey   string       `json:"artifactKey"`          // Reference to TLS certs artifact (optional)
+	Host          string       `json:"host" gorm:"not null"`     // unix:///var/run/docker.sock or tcp://host:port
+	ArtifactKey   string       `json:"artifactKey"`              // Reference to TLS certs artifact (optional)
+	AgentID       *uuid.UUID   `json:"agentId" gorm:"type:uuid"` // csd-core agent managing the engine
 	Status        EngineStatus `json:"status" gorm:"default:'PENDING';index:idx_engine_tenant_status"`
 	StatusMessage string       `json:"statusMessage"`
 	// Cached info from engine
@@ -62,6 +63,7 @@ type ContainerEngineInput struct {
 	EngineType  EngineType `json:"engineType"`
 	Host        string     `json:"host"`
 	ArtifactKey string     `json:"artifactKey"`
+	AgentID     string     `json:"agentId"` // Agent managing the engine (optional)
 }
 
 // ContainerEngineFilter represents filter options for listing container engines
@@ -71,6 +73,15 @@ type ContainerEngineFilter struct {
 	EngineType *EngineType   `json:"engineType"`
 }
 
+// EngineListFilter represents filter options for listing objects of an engine
+type EngineListFilter struct {
+	Search *string `json:"search"` // Name substring (repo tag for images)
+	State  *string `json:"state"`  // Containers only
+}
+
+// maxEngineListResults caps the objects returned by one engine list query
+const maxEngineListResults = 1000
+
 // Container represents a running or stopped container
 type Container struct {
 	ID         string            `json:"id"`
@@ -88,6 +99,33 @@ type Container struct {
 	SizeRootFs int64             `json:"sizeRootFs"`
 }
 
+// ContainerSnapshot is the last observed state of a container on an engine,
+// maintained by the inventory reconciler
+type ContainerSnapshot struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID      uuid.UUID  `json:"tenantId" gorm:"type:uuid;not null;index:idx_container_snapshot_tenant_failed"`
+	EngineID      uuid.UUID  `json:"engineId" gorm:"type:uuid;not null;uniqueIndex:idx_container_snapshot_engine_container"`
+	ContainerID   string     `json:"containerId" gorm:"not null;uniqueIndex:idx_container_snapshot_engine_container"`
+	Name          string     `json:"name"`
+	Image         string     `json:"image"`
+	State         string     `json:"state"` // Engine state at the last reconciliation (running, exited, dead...)
+	Status        string     `json:"status"`
+	Missing       bool       `json:"missing" gorm:"default:false"`                               // Not listed by the engine at the last reconciliation
+	FailedAt      *time.Time `json:"failedAt" gorm:"index:idx_container_snapshot_tenant_failed"` // When it was last found stopped or missing after running
+	FailureReason string     `json:"failureReason"`
+	LastSeenAt    *time.Time `json:"lastSeenAt"` // Last reconciliation the engine listed the container
+	CreatedAt     time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	// Relations
+	Engine *ContainerEngine `json:"engine,omitempty" gorm:"foreignKey:EngineID"`
+}
+
+// TableName returns the table name for GORM
+func (ContainerSnapshot) TableName() string {
+	return "container_snapshots"
+}
+
 // ContainerPort represents a container port mapping
 type ContainerPort struct {
 	IP          string `json:"ip"`
@@ -148,3 +186,113 @@ type Volume struct {
 	Labels     map[string]string `json:"labels"`
 	Scope      string            `json:"scope"`
 }
+
+// LogStream selects which container output streams are returned
+type LogStream string
+
+const (
+	LogStreamCombined LogStream = "COMBINED" // stdout and stderr interleaved
+	LogStreamStdout   LogStream = "STDOUT"
+	LogStreamStderr   LogStream = "STDERR"
+	LogStreamSeparate LogStream = "SEPARATE" // stdout and stderr returned apart
+)
+
+// LogFormat selects how container logs are returned
+type LogFormat string
+
+const (
+	LogFormatPlain LogFormat = "PLAIN" // raw engine output
+	LogFormatJSON  LogFormat = "JSON"  // structured lines
+)
+
+// ContainerLogOptions controls how container logs are fetched
+type ContainerLogOptions struct {
+	Tail       int       `json:"tail"`
+	Timestamps bool      `json:"timestamps"`
+	Stream     LogStream `json:"stream"`
+	Format     LogFormat `json:"format"`
+}
+
+// ContainerLogLine is a single log line parsed from the engine output
+type ContainerLogLine struct {
+	Timestamp *time.Time `json:"timestamp"`
+	Stream    string     `json:"stream"` // stdout, stderr, or empty when unknown
+	Message   string     `json:"message"`
+}
+
+// ContainerLogs holds container logs in the requested format
+type ContainerLogs struct {
+	Output string             `json:"output"`           // combined raw output
+	Stdout string             `json:"stdout,omitempty"` // SEPARATE only
+	Stderr string             `json:"stderr,omitempty"` // SEPARATE only
+	Lines  []ContainerLogLine `json:"lines,omitempty"`  // JSON format only
+}
+
+// ContainerStats is a point-in-time resource usage sample of a container
+type ContainerStats struct {
+	ContainerID   string    `json:"containerId"`
+	Available     bool      `json:"available"` // False when the container is not running; the values are then zero
+	CPUPercent    float64   `json:"cpuPercent"`
+	MemoryUsage   int64     `json:"memoryUsage"` // Bytes
+	MemoryLimit   int64     `json:"memoryLimit"` // Bytes
+	MemoryPercent float64   `json:"memoryPercent"`
+	NetworkRx     int64     `json:"networkRx"` // Bytes
+	NetworkTx     int64     `json:"networkTx"` // Bytes
+	SampledAt     time.Time `json:"sampledAt"`
+}
+
+// maxStackServices caps the services of one container stack
+const maxStackServices = 50
+
+// ContainerStackSpec is a compose-like description of containers deployed together on an engine
+type ContainerStackSpec struct {
+	Name     string         `json:"name"` // Prefixes the container names and labels them
+	Services []StackService `json:"services"`
+}
+
+// StackService is one container of a stack
+type StackService struct {
+	Name     string             `json:"name"`
+	Image    string             `json:"image"`
+	Ports    []StackPortMapping `json:"ports"`
+	Env      map[string]string  `json:"env"`
+	Volumes  []StackVolumeMount `json:"volumes"`
+	Networks []string           `json:"networks"` // Created on the engine when missing
+}
+
+// StackPortMapping publishes a container port on the host
+type StackPortMapping struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"` // tcp (default) or udp
+}
+
+// StackVolumeMount mounts a named volume or a host path into a container
+type StackVolumeMount struct {
+	Source   string `json:"source"` // Volume name or absolute host path
+	Target   string `json:"target"` // Absolute path in the container
+	ReadOnly bool   `json:"readOnly"`
+}
+
+// StackServiceResult is the outcome of deploying one service of a stack
+type StackServiceResult struct {
+	Service       string `json:"service"`
+	ContainerName string `json:"containerName"`
+	ContainerID   string `json:"containerId,omitempty"`
+	Success       bool   `json:"success"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ContainerStackDeployment is the outcome of deploying a stack; services are deployed
+// independently, so a stack can be partially deployed
+type ContainerStackDeployment struct {
+	StackName   string               `json:"stackName"`
+	EngineID    uuid.UUID            `json:"engineId"`
+	AgentID     uuid.UUID            `json:"agentId"`
+	Success     bool                 `json:"success"` // Every service was deployed
+	Deployed    int                  `json:"deployed"`
+	Failed      int                  `json:"failed"`
+	Services    []StackServiceResult `json:"services"`
+	StartedAt   time.Time            `json:"startedAt"`
+	CompletedAt time.Time            `json:"completedAt"`
+}
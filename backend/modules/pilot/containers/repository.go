@@ -2,6 +2,7 @@ package containers
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -73,9 +74,14 @@ func (r *Repository) Update(engine *ContainerEngine) error {
 	return r.db.Save(engine).Error
 }
 
-// Delete deletes a container engine
+// Delete deletes a container engine and its container inventory
 func (r *Repository) Delete(tenantID, id uuid.UUID) error {
-	return r.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&ContainerEngine{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND engine_id = ?", tenantID, id).Delete(&ContainerSnapshot{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&ContainerEngine{}).Error
+	})
 }
 
 // UpdateStatus updates the status of a container engine
@@ -111,9 +117,76 @@ func (r *Repository) CountByStatus(tenantID uuid.UUID, status EngineStatus) (int
 	return count, err
 }
 
-// BulkDelete deletes multiple container engines by IDs
+// BulkDelete deletes multiple container engines by IDs, with their container inventory
 func (r *Repository) BulkDelete(tenantID uuid.UUID, ids []uuid.UUID) (int64, error) {
-	result := r.db.Where("tenant_id = ? AND id IN ?", tenantID, ids).Delete(&ContainerEngine{})
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND engine_id IN ?", tenantID, ids).Delete(&ContainerSnapshot{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("tenant_id = ? AND id IN ?", tenantID, ids).Delete(&ContainerEngine{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// ========================================
+// Container Inventory
+// ========================================
+
+// ListInventoryEngines returns the engines of all tenants that have an agent bound
+func (r *Repository) ListInventoryEngines() ([]ContainerEngine, error) {
+	var engines []ContainerEngine
+	err := r.db.Where("agent_id IS NOT NULL").Order("tenant_id, name").Find(&engines).Error
+	return engines, err
+}
+
+// ListSnapshots returns the container inventory of an engine
+func (r *Repository) ListSnapshots(tenantID, engineID uuid.UUID) ([]ContainerSnapshot, error) {
+	var snapshots []ContainerSnapshot
+	err := r.db.Where("tenant_id = ? AND engine_id = ?", tenantID, engineID).Find(&snapshots).Error
+	return snapshots, err
+}
+
+// SaveSnapshots creates or updates container snapshots in a single transaction
+func (r *Repository) SaveSnapshots(snapshots []ContainerSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range snapshots {
+			if err := tx.Save(&snapshots[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListFailedSnapshots returns the containers of a tenant found stopped or missing since the given time,
+// most recent first, optionally restricted to one engine
+func (r *Repository) ListFailedSnapshots(tenantID uuid.UUID, engineID *uuid.UUID, since time.Time, limit, offset int) ([]ContainerSnapshot, int64, error) {
+	var snapshots []ContainerSnapshot
+	var count int64
+
+	query := r.db.Model(&ContainerSnapshot{}).Where("tenant_id = ? AND failed_at >= ?", tenantID, since)
+	if engineID != nil {
+		query = query.Where("engine_id = ?", *engineID)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Preload("Engine").Order("failed_at DESC").Limit(limit).Offset(offset).Find(&snapshots).Error; err != nil {
+		return nil, 0, err
+	}
+	return snapshots, count, nil
+}
+
+// DeleteMissingSnapshotsBefore removes containers that have not been listed by their engine since the cutoff
+func (r *Repository) DeleteMissingSnapshotsBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("missing = ? AND last_seen_at < ?", true, cutoff).Delete(&ContainerSnapshot{})
 	return result.RowsAffected, result.Error
 }
 
@@ -2,13 +2,21 @@ package containers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"csd-pilote/backend/modules/platform/config"
 	csdcore "csd-pilote/backend/modules/platform/csd-core"
 	"csd-pilote/backend/modules/platform/events"
+	"csd-pilote/backend/modules/platform/logger"
 	"csd-pilote/backend/modules/platform/pagination"
+	"csd-pilote/backend/modules/platform/validation"
 )
 
 // Service handles business logic for container engines
@@ -25,6 +33,12 @@ func NewService() *Service {
 	}
 }
 
+// bind points the service at the database and csd-core client, which are created after init()
+func (s *Service) bind() {
+	s.repo = NewRepository()
+	s.client = csdcore.GetClient()
+}
+
 // Create creates a new container engine
 func (s *Service) Create(ctx context.Context, tenantID, userID uuid.UUID, input *ContainerEngineInput) (*ContainerEngine, error) {
 	engineType := input.EngineType
@@ -32,6 +46,15 @@ func (s *Service) Create(ctx context.Context, tenantID, userID uuid.UUID, input
 		engineType = EngineTypeDocker
 	}
 
+	var agentID *uuid.UUID
+	if input.AgentID != "" {
+		id, err := uuid.Parse(input.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agentId: %w", err)
+		}
+		agentID = &id
+	}
+
 	engine := &ContainerEngine{
 		TenantID:    tenantID,
 		Name:        input.Name,
@@ -39,6 +62,7 @@ func (s *Service) Create(ctx context.Context, tenantID, userID uuid.UUID, input
 		EngineType:  engineType,
 		Host:        input.Host,
 		ArtifactKey: input.ArtifactKey,
+		AgentID:     agentID,
 		Status:      EngineStatusPending,
 		CreatedBy:   userID,
 	}
@@ -69,7 +93,7 @@ func (s *Service) Get(ctx context.Context, tenantID, id uuid.UUID) (*ContainerEn
 
 // List retrieves all container engines for a tenant
 func (s *Service) List(ctx context.Context, tenantID uuid.UUID, filter *ContainerEngineFilter, limit, offset int) ([]ContainerEngine, int64, error) {
-	p := pagination.Normalize(limit, offset)
+	p := pagination.NormalizeFor(pagination.ResourceContainerEngines, limit, offset)
 	return s.repo.List(tenantID, filter, p.Limit, p.Offset)
 }
 
@@ -96,6 +120,14 @@ func (s *Service) Update(ctx context.Context, tenantID, id uuid.UUID, input *Con
 	if input.ArtifactKey != "" {
 		engine.ArtifactKey = input.ArtifactKey
 	}
+	if input.AgentID != "" {
+		agentID, err := uuid.Parse(input.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agentId: %w", err)
+		}
+		engine.AgentID = &agentID
+		engine.Status = EngineStatusPending
+	}
 
 	if err := s.repo.Update(engine); err != nil {
 		return nil, fmt.Errorf("failed to update container engine: %w", err)
@@ -132,70 +164,1108 @@ func (s *Service) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 	return nil
 }
 
-// TestConnection tests the connection to a container engine using a playbook
+// validateEngineAgent checks the agent is online and supports the engine's runtime
+// ("docker" or "podman") before any task is dispatched to it.
+// An explicit agentID overrides the agent bound to the engine.
+func (s *Service) validateEngineAgent(ctx context.Context, token string, tenantID, engineID, agentID uuid.UUID) (*ContainerEngine, error) {
+	engine, err := s.repo.GetByID(tenantID, engineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if agentID == uuid.Nil {
+		if engine.AgentID == nil {
+			return nil, validation.NewValidationError(fmt.Sprintf("container engine %s has no agent bound; agentId is required", engine.Name))
+		}
+		agentID = *engine.AgentID
+	}
+
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, engineCapability(engine)); err != nil {
+		return nil, fmt.Errorf("agent unavailable for container engine %s: %w", engine.Name, err)
+	}
+
+	return engine, nil
+}
+
+// engineCapability returns the agent capability and task type for the engine's runtime
+func engineCapability(engine *ContainerEngine) string {
+	if engine.EngineType == EngineTypePodman {
+		return "podman"
+	}
+	return "docker"
+}
+
+// TestConnection tests the connection to a container engine and records the result on the engine
 func (s *Service) TestConnection(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID) error {
 	engine, err := s.repo.GetByID(tenantID, engineID)
 	if err != nil {
 		return err
 	}
 
-	// This would execute a docker playbook with info action via csd-core agent
-	// For now, we just update the status
-	s.repo.UpdateStatus(tenantID, engineID, EngineStatusConnected, "Connection successful")
+	return s.probeEngine(ctx, token, engine, agentID)
+}
+
+// engineProbeTimeout bounds a background connectivity probe of an engine
+const engineProbeTimeout = time.Minute
+
+// ProbeEngineAsync probes a newly created or reconfigured engine in the background, so the caller
+// returns with the engine still PENDING. Engines without an agent bound stay PENDING.
+func (s *Service) ProbeEngineAsync(token string, engine *ContainerEngine) {
+	if engine.AgentID == nil {
+		return
+	}
+	probed := *engine
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), engineProbeTimeout)
+		defer cancel()
+		if err := s.probeEngine(ctx, token, &probed, uuid.Nil); err != nil {
+			logger.Warn("[Containers] Engine %s is unreachable: %s", probed.Name, err.Error())
+		}
+	}()
+}
+
+// probeEngine checks the agent can reach the engine by running its info action.
+// The result is stored as the engine status, with the error as status message, and
+// an event is published when the engine becomes connected or unreachable.
+func (s *Service) probeEngine(ctx context.Context, token string, engine *ContainerEngine, agentID uuid.UUID) error {
+	_, err := s.validateEngineAgent(ctx, token, engine.TenantID, engine.ID, agentID)
+	if err == nil {
+		if agentID == uuid.Nil {
+			agentID = *engine.AgentID
+		}
+		_, err = s.runEngineTask(ctx, token, engine, agentID, "info", map[string]interface{}{}, 30)
+	}
+	if err != nil {
+		s.markEngineError(engine, err)
+		return err
+	}
 
-	_ = engine
+	s.repo.UpdateStatus(engine.TenantID, engine.ID, EngineStatusConnected, "Connection successful")
+	if engine.Status != EngineStatusConnected {
+		events.GetEventBus().PublishAsync(events.NewEvent(
+			events.EventContainerEngineConnected,
+			engine.TenantID,
+			engine.ID.String(),
+			map[string]interface{}{
+				"name":   engine.Name,
+				"status": EngineStatusConnected,
+			},
+		))
+	}
 	return nil
 }
 
-// ListContainers lists all containers on an engine
-func (s *Service) ListContainers(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, all bool) ([]Container, error) {
-	// This would execute a docker playbook with container_list action via csd-core agent
-	return []Container{}, nil
+// runProbeJob periodically probes every engine with an agent bound.
+// The interval is read from the configuration on each check, so the job idles until the config is loaded.
+func (s *Service) runProbeJob() {
+	defer logger.RecoverPanic("[Containers] Probe job")
+
+	ticker := time.NewTicker(inventoryCheckInterval)
+	defer ticker.Stop()
+
+	var lastRun time.Time
+	for range ticker.C {
+		cfg := config.GetConfig()
+		if cfg == nil || cfg.Limits.ContainerProbeMinutes <= 0 {
+			continue
+		}
+		if time.Since(lastRun) < time.Duration(cfg.Limits.ContainerProbeMinutes)*time.Minute {
+			continue
+		}
+		lastRun = time.Now()
+
+		func() {
+			defer logger.RecoverPanic("[Containers] Probe pass")
+			s.ProbeAllEngines(context.Background(), cfg.CSDCore.ServiceToken)
+		}()
+	}
+}
+
+// ProbeAllEngines probes every engine with an agent bound, one at a time
+func (s *Service) ProbeAllEngines(ctx context.Context, token string) {
+	engines, err := s.repo.ListInventoryEngines()
+	if err != nil {
+		logger.Error("[Containers] Failed to list engines to probe: %s", err.Error())
+		return
+	}
+
+	for i := range engines {
+		probeCtx, cancel := context.WithTimeout(ctx, engineProbeTimeout)
+		if err := s.probeEngine(probeCtx, token, &engines[i], uuid.Nil); err != nil {
+			logger.Warn("[Containers] Engine %s is unreachable: %s", engines[i].Name, err.Error())
+		}
+		cancel()
+	}
+}
+
+// ListContainers lists the containers on an engine matching filter, with the total count before paging
+func (s *Service) ListContainers(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, all bool, filter *EngineListFilter, limit, offset int) ([]Container, int, error) {
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if agentID == uuid.Nil {
+		agentID = *engine.AgentID
+	}
+
+	containers, err := s.fetchContainers(ctx, token, engine, agentID, all)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matched := make([]Container, 0, len(containers))
+	for _, c := range containers {
+		if filter != nil && filter.State != nil && !strings.EqualFold(c.State, *filter.State) {
+			continue
+		}
+		if !matchesSearch(filter, strings.TrimPrefix(c.Name, "/")) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+
+	start, end := pageBounds(len(matched), limit, offset)
+	return matched[start:end], len(matched), nil
+}
+
+// matchesSearch reports whether any of names contains the filter search term, case-insensitively
+func matchesSearch(filter *EngineListFilter, names ...string) bool {
+	if filter == nil || filter.Search == nil || *filter.Search == "" {
+		return true
+	}
+	search := strings.ToLower(*filter.Search)
+	for _, name := range names {
+		if strings.Contains(strings.ToLower(name), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// pageBounds returns the slice bounds of a page of total items;
+// a limit of 0 returns everything from offset, capped at maxEngineListResults
+func pageBounds(total, limit, offset int) (int, int) {
+	if limit <= 0 || limit > maxEngineListResults {
+		limit = maxEngineListResults
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+// fetchContainers runs the container_list action of the engine's runtime on an agent
+func (s *Service) fetchContainers(ctx context.Context, token string, engine *ContainerEngine, agentID uuid.UUID, all bool) ([]Container, error) {
+	capability := engineCapability(engine)
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: capability,
+			Name: fmt.Sprintf("%s-container_list", capability),
+			Config: map[string]interface{}{
+				"action": "container_list",
+				"host":   engine.Host,
+				"all":    all,
+			},
+		},
+		ArtifactKey: engine.ArtifactKey,
+		Wait:        true,
+		Timeout:     30,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	if execution.Status != "SUCCESS" {
+		return nil, fmt.Errorf("failed to list containers: %s", execution.Error)
+	}
+
+	return decodeContainers(execution.Output)
+}
+
+// decodeContainers converts the agent output, a JSON array or its decoded form, into containers
+func decodeContainers(output interface{}) ([]Container, error) {
+	var raw []byte
+	switch out := output.(type) {
+	case nil:
+		return []Container{}, nil
+	case string:
+		if strings.TrimSpace(out) == "" {
+			return []Container{}, nil
+		}
+		raw = []byte(out)
+	default:
+		encoded, err := json.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container list output: %w", err)
+		}
+		raw = encoded
+	}
+
+	containers := []Container{}
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		return nil, fmt.Errorf("invalid container list output: %w", err)
+	}
+	return containers, nil
 }
 
 // ContainerAction performs an action on a container (start, stop, restart, etc.)
 func (s *Service) ContainerAction(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, containerID string, action string) error {
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return err
+	}
+
 	// This would execute a docker playbook with container_start/stop/etc. action
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventContainerActionPerformed,
+		tenantID,
+		containerID,
+		map[string]interface{}{
+			"engineId":   engine.ID.String(),
+			"engineName": engine.Name,
+			"action":     action,
+		},
+	))
+
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "CONTAINER_" + strings.ToUpper(action),
+		ResourceType: "container",
+		ResourceID:   containerID,
+		Details: map[string]interface{}{
+			"engineId":   engine.ID.String(),
+			"engineName": engine.Name,
+			"action":     action,
+		},
+	})
+
 	return nil
 }
 
-// ListImages lists all images on an engine
-func (s *Service) ListImages(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID) ([]Image, error) {
+// ListImages lists the images on an engine matching filter, with the total count before paging
+func (s *Service) ListImages(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, filter *EngineListFilter, limit, offset int) ([]Image, int, error) {
+	if _, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID); err != nil {
+		return nil, 0, err
+	}
+
 	// This would execute a docker playbook with image_list action
-	return []Image{}, nil
+	images := []Image{}
+
+	matched := make([]Image, 0, len(images))
+	for _, img := range images {
+		if matchesSearch(filter, append([]string{img.ID}, img.RepoTags...)...) {
+			matched = append(matched, img)
+		}
+	}
+
+	start, end := pageBounds(len(matched), limit, offset)
+	return matched[start:end], len(matched), nil
 }
 
 // PullImage pulls an image
 func (s *Service) PullImage(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, imageName string) error {
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return err
+	}
+
 	// This would execute a docker playbook with image_pull action
+
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "PULL_IMAGE",
+		ResourceType: "container_engine",
+		ResourceID:   engine.ID.String(),
+		Details: map[string]interface{}{
+			"engineName": engine.Name,
+			"imageName":  imageName,
+		},
+	})
+
 	return nil
 }
 
-// ListNetworks lists all networks on an engine
-func (s *Service) ListNetworks(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID) ([]Network, error) {
+// ListNetworks lists the networks on an engine matching filter, with the total count before paging
+func (s *Service) ListNetworks(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, filter *EngineListFilter, limit, offset int) ([]Network, int, error) {
+	if _, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID); err != nil {
+		return nil, 0, err
+	}
+
 	// This would execute a docker playbook with network_list action
-	return []Network{}, nil
+	networks := []Network{}
+
+	matched := make([]Network, 0, len(networks))
+	for _, n := range networks {
+		if matchesSearch(filter, n.Name) {
+			matched = append(matched, n)
+		}
+	}
+
+	start, end := pageBounds(len(matched), limit, offset)
+	return matched[start:end], len(matched), nil
 }
 
-// ListVolumes lists all volumes on an engine
-func (s *Service) ListVolumes(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID) ([]Volume, error) {
+// ListVolumes lists the volumes on an engine matching filter, with the total count before paging
+func (s *Service) ListVolumes(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, filter *EngineListFilter, limit, offset int) ([]Volume, int, error) {
+	if _, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID); err != nil {
+		return nil, 0, err
+	}
+
 	// This would execute a docker playbook with volume_list action
-	return []Volume{}, nil
+	volumes := []Volume{}
+
+	matched := make([]Volume, 0, len(volumes))
+	for _, vol := range volumes {
+		if matchesSearch(filter, vol.Name) {
+			matched = append(matched, vol)
+		}
+	}
+
+	start, end := pageBounds(len(matched), limit, offset)
+	return matched[start:end], len(matched), nil
 }
 
 // GetContainerLogs gets logs from a container
-func (s *Service) GetContainerLogs(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, containerID string, tail int) (string, error) {
-	// This would execute a docker playbook with container_logs action
-	return "", nil
+func (s *Service) GetContainerLogs(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, containerID string, opts ContainerLogOptions) (*ContainerLogs, error) {
+	if opts.Tail < 1 || opts.Tail > validation.MaxTailLines {
+		return nil, validation.NewValidationError(fmt.Sprintf("tail must be between 1 and %d", validation.MaxTailLines))
+	}
+	if opts.Stream == "" {
+		opts.Stream = LogStreamCombined
+	}
+	if opts.Format == "" {
+		opts.Format = LogFormatPlain
+	}
+
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agentID == uuid.Nil {
+		agentID = *engine.AgentID
+	}
+
+	capability := engineCapability(engine)
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: capability,
+			Name: fmt.Sprintf("%s-container_logs", capability),
+			Config: map[string]interface{}{
+				"action":       "container_logs",
+				"host":         engine.Host,
+				"container_id": containerID,
+				"tail":         opts.Tail,
+				"timestamps":   opts.Timestamps,
+				"stdout":       opts.Stream != LogStreamStderr,
+				"stderr":       opts.Stream != LogStreamStdout,
+				// Streams are kept apart so lines can be tagged with their origin
+				"split_streams": opts.Stream == LogStreamSeparate || opts.Format == LogFormatJSON,
+			},
+		},
+		ArtifactKey: engine.ArtifactKey,
+		Wait:        true,
+		Timeout:     30,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+	if execution.Status != "SUCCESS" {
+		return nil, fmt.Errorf("failed to get container logs: %s", execution.Error)
+	}
+
+	return buildContainerLogs(execution.Output, opts), nil
+}
+
+// buildContainerLogs shapes the agent output according to the requested options.
+// The agent returns either the combined output as a string or a map with
+// separate "stdout" and "stderr" entries.
+func buildContainerLogs(output interface{}, opts ContainerLogOptions) *ContainerLogs {
+	var combined, stdout, stderr string
+	split := false
+	switch out := output.(type) {
+	case string:
+		combined = out
+	case map[string]interface{}:
+		stdout, _ = out["stdout"].(string)
+		stderr, _ = out["stderr"].(string)
+		combined, _ = out["output"].(string)
+		split = combined == ""
+	}
+
+	// Drop streams the agent returned despite not being requested
+	if opts.Stream == LogStreamStdout {
+		stderr = ""
+	}
+	if opts.Stream == LogStreamStderr {
+		stdout = ""
+	}
+
+	var lines []ContainerLogLine
+	if split {
+		lines = append(parseLogLines(stdout, "stdout", opts.Timestamps), parseLogLines(stderr, "stderr", opts.Timestamps)...)
+		if opts.Timestamps {
+			sort.SliceStable(lines, func(i, j int) bool {
+				a, b := lines[i].Timestamp, lines[j].Timestamp
+				return a != nil && b != nil && a.Before(*b)
+			})
+		}
+		combined = joinLogLines(lines, opts.Timestamps)
+	} else {
+		stream := ""
+		switch opts.Stream {
+		case LogStreamStdout:
+			stream = "stdout"
+		case LogStreamStderr:
+			stream = "stderr"
+		}
+		lines = parseLogLines(combined, stream, opts.Timestamps)
+	}
+
+	logs := &ContainerLogs{Output: combined}
+	if opts.Stream == LogStreamSeparate {
+		logs.Stdout = stdout
+		logs.Stderr = stderr
+	}
+	if opts.Format == LogFormatJSON {
+		logs.Lines = lines
+		if logs.Lines == nil {
+			logs.Lines = []ContainerLogLine{}
+		}
+	}
+	return logs
+}
+
+// parseLogLines splits engine output into lines, extracting the RFC3339 timestamp
+// prefix the engine adds when timestamps are enabled
+func parseLogLines(text, stream string, timestamps bool) []ContainerLogLine {
+	var lines []ContainerLogLine
+	for _, raw := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if raw == "" {
+			continue
+		}
+		line := ContainerLogLine{Stream: stream, Message: strings.TrimSuffix(raw, "\r")}
+		if timestamps {
+			if prefix, rest, found := strings.Cut(line.Message, " "); found {
+				if ts, err := time.Parse(time.RFC3339Nano, prefix); err == nil {
+					line.Timestamp = &ts
+					line.Message = rest
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// joinLogLines rebuilds plain output from parsed lines
+func joinLogLines(lines []ContainerLogLine, timestamps bool) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if timestamps && line.Timestamp != nil {
+			b.WriteString(line.Timestamp.Format(time.RFC3339Nano))
+			b.WriteByte(' ')
+		}
+		b.WriteString(line.Message)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// GetContainerStats samples the CPU, memory and network usage of a container, like `docker stats --no-stream`.
+// A container that is not running yields zero values with Available false rather than an error.
+func (s *Service) GetContainerStats(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, containerID string) (*ContainerStats, error) {
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agentID == uuid.Nil {
+		agentID = *engine.AgentID
+	}
+
+	stats := &ContainerStats{ContainerID: containerID, SampledAt: time.Now()}
+	output, err := s.runEngineTask(ctx, token, engine, agentID, "container_stats", map[string]interface{}{
+		"container_id": containerID,
+		"stream":       false,
+	}, 30)
+	if err != nil {
+		if isContainerNotRunning(err) {
+			return stats, nil
+		}
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+
+	sample, err := decodeStatsSample(output, engine.EngineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	if sample != nil {
+		applyStatsSample(stats, sample)
+	}
+	return stats, nil
+}
+
+// isContainerNotRunning reports whether an engine error means the container is stopped
+func isContainerNotRunning(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not running") || strings.Contains(msg, "state improper")
+}
+
+// statsSample holds the fields of one stats line as printed by the engine.
+// Docker prints `{{json .}}` with Go template names, Podman its own JSON names.
+type statsSample struct {
+	CPUPercent string
+	MemUsage   string // "used / limit"
+	MemPercent string
+	NetIO      string // "received / sent"
+}
+
+// decodeStatsSample extracts the sample from the agent output; it returns nil when the engine printed none
+func decodeStatsSample(output interface{}, engineType EngineType) (*statsSample, error) {
+	var raw []byte
+	switch out := output.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if strings.TrimSpace(out) == "" {
+			return nil, nil
+		}
+		raw = []byte(strings.TrimSpace(out))
+	default:
+		encoded, err := json.Marshal(out)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container stats output: %w", err)
+		}
+		raw = encoded
+	}
+
+	// Podman prints an array, Docker one object per line
+	var entries []map[string]interface{}
+	if len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("invalid container stats output: %w", err)
+		}
+	} else {
+		line := strings.SplitN(string(raw), "\n", 2)[0]
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("invalid container stats output: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	entry := entries[0]
+	field := func(docker, podman string) string {
+		key := docker
+		if engineType == EngineTypePodman {
+			key = podman
+		}
+		if value, ok := entry[key].(string); ok {
+			return value
+		}
+		// Engines of either type may be fronted by the other's CLI
+		if value, ok := entry[docker].(string); ok {
+			return value
+		}
+		value, _ := entry[podman].(string)
+		return value
+	}
+	return &statsSample{
+		CPUPercent: field("CPUPerc", "cpu_percent"),
+		MemUsage:   field("MemUsage", "mem_usage"),
+		MemPercent: field("MemPerc", "mem_percent"),
+		NetIO:      field("NetIO", "net_io"),
+	}, nil
+}
+
+// applyStatsSample fills stats from a sample. A stopped container prints "--" or zero values;
+// it is told apart by its memory limit, which is never zero while running.
+func applyStatsSample(stats *ContainerStats, sample *statsSample) {
+	cpu, ok := parsePercent(sample.CPUPercent)
+	if !ok {
+		return
+	}
+	usage, limit := parseSizePair(sample.MemUsage)
+	if limit == 0 {
+		return
+	}
+	stats.Available = true
+	stats.CPUPercent = cpu
+	stats.MemoryUsage, stats.MemoryLimit = usage, limit
+	stats.MemoryPercent, _ = parsePercent(sample.MemPercent)
+	stats.NetworkRx, stats.NetworkTx = parseSizePair(sample.NetIO)
+}
+
+// parsePercent parses a percentage such as "12.34%"
+func parsePercent(value string) (float64, bool) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	if value == "" || value == "--" {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return percent, true
+}
+
+// parseSizePair parses a "used / limit" or "received / sent" pair of sizes
+func parseSizePair(value string) (int64, int64) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	return parseSize(parts[0]), parseSize(parts[1])
+}
+
+// sizeUnits maps the size suffixes printed by Docker (binary memory, decimal network)
+// and Podman (decimal) to their multiplier
+var sizeUnits = map[string]float64{
+	"b":  1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+}
+
+// parseSize parses a human-readable size such as "10.5MiB" or "1.2kB" into bytes
+func parseSize(value string) int64 {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i <= 0 {
+		n, _ := strconv.ParseFloat(value, 64)
+		return int64(n)
+	}
+	n, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0
+	}
+	multiplier, ok := sizeUnits[strings.ToLower(strings.TrimSpace(value[i:]))]
+	if !ok {
+		return 0
+	}
+	return int64(n * multiplier)
 }
 
 // ExecContainer executes a command in a container
 // Note: For interactive exec, use WebSocket via csd-core terminal module
 func (s *Service) ExecContainer(ctx context.Context, token string, tenantID, engineID uuid.UUID, agentID uuid.UUID, containerID string, command []string) (string, error) {
+	if _, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID); err != nil {
+		return "", err
+	}
+
 	// This would execute a docker playbook with container_exec action
 	return "", nil
 }
 
+// ========================================
+// Container Stacks
+// ========================================
+
+// Labels set on the containers of a stack
+const (
+	stackLabel        = "csd-pilote.stack"
+	stackServiceLabel = "csd-pilote.stack.service"
+)
+
+// DeployStack creates the networks and containers of a stack on an engine.
+// Services are deployed in order and independently: a service that fails is reported
+// in the result without stopping the others.
+func (s *Service) DeployStack(ctx context.Context, token string, tenantID, engineID, agentID uuid.UUID, spec *ContainerStackSpec) (*ContainerStackDeployment, error) {
+	engine, err := s.validateEngineAgent(ctx, token, tenantID, engineID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agentID == uuid.Nil {
+		agentID = *engine.AgentID
+	}
+
+	deployment := &ContainerStackDeployment{
+		StackName: spec.Name,
+		EngineID:  engine.ID,
+		AgentID:   agentID,
+		Services:  make([]StackServiceResult, 0, len(spec.Services)),
+		StartedAt: time.Now(),
+	}
+
+	// Networks shared by several services are created once
+	networkErrors := make(map[string]error)
+	for _, network := range stackNetworks(spec) {
+		if _, err := s.runEngineTask(ctx, token, engine, agentID, "network_create", map[string]interface{}{
+			"name":     network,
+			"exist_ok": true,
+			"labels":   map[string]string{stackLabel: spec.Name},
+		}, 30); err != nil {
+			networkErrors[network] = err
+		}
+	}
+
+	for _, svc := range spec.Services {
+		result := StackServiceResult{
+			Service:       svc.Name,
+			ContainerName: fmt.Sprintf("%s-%s", spec.Name, svc.Name),
+		}
+		for _, network := range svc.Networks {
+			if err, failed := networkErrors[network]; failed {
+				result.Error = fmt.Sprintf("network %s: %v", network, err)
+				break
+			}
+		}
+		if result.Error == "" {
+			// Running a container may pull its image first
+			output, err := s.runEngineTask(ctx, token, engine, agentID, "container_run", stackContainerConfig(spec.Name, result.ContainerName, svc), 300)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.ContainerID = containerIDFromOutput(output)
+				result.Success = true
+			}
+		}
+
+		if result.Success {
+			deployment.Deployed++
+		} else {
+			deployment.Failed++
+			logger.Warn("[Containers] Stack %s: service %s failed on engine %s: %s", spec.Name, svc.Name, engine.Name, result.Error)
+		}
+		deployment.Services = append(deployment.Services, result)
+	}
+	deployment.Success = deployment.Failed == 0
+	deployment.CompletedAt = time.Now()
+
+	eventType := events.EventContainerStackDeployed
+	if !deployment.Success {
+		eventType = events.EventContainerStackFailed
+	}
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		eventType,
+		tenantID,
+		engine.ID.String(),
+		map[string]interface{}{
+			"engineName": engine.Name,
+			"stackName":  spec.Name,
+			"deployed":   deployment.Deployed,
+			"failed":     deployment.Failed,
+		},
+	))
+
+	return deployment, nil
+}
+
+// runEngineTask runs an action of the engine's runtime on an agent, waiting up to timeout seconds, and returns its output
+func (s *Service) runEngineTask(ctx context.Context, token string, engine *ContainerEngine, agentID uuid.UUID, action string, config map[string]interface{}, timeout int) (interface{}, error) {
+	capability := engineCapability(engine)
+	config["action"] = action
+	config["host"] = engine.Host
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type:   capability,
+			Name:   fmt.Sprintf("%s-%s", capability, action),
+			Config: config,
+		},
+		ArtifactKey: engine.ArtifactKey,
+		Wait:        true,
+		Timeout:     timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", action, err)
+	}
+	if execution.Status != "SUCCESS" {
+		return nil, fmt.Errorf("%s failed: %s", action, execution.Error)
+	}
+	return execution.Output, nil
+}
+
+// stackNetworks returns the distinct networks used by the services of a stack, in order of first use
+func stackNetworks(spec *ContainerStackSpec) []string {
+	seen := make(map[string]bool)
+	var networks []string
+	for _, svc := range spec.Services {
+		for _, network := range svc.Networks {
+			if !seen[network] {
+				seen[network] = true
+				networks = append(networks, network)
+			}
+		}
+	}
+	return networks
+}
+
+// stackContainerConfig builds the container_run task config of a stack service
+func stackContainerConfig(stackName, containerName string, svc StackService) map[string]interface{} {
+	ports := make([]map[string]interface{}, 0, len(svc.Ports))
+	for _, p := range svc.Ports {
+		protocol := p.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		ports = append(ports, map[string]interface{}{
+			"host_port":      p.HostPort,
+			"container_port": p.ContainerPort,
+			"protocol":       protocol,
+		})
+	}
+	volumes := make([]map[string]interface{}, 0, len(svc.Volumes))
+	for _, vol := range svc.Volumes {
+		volumes = append(volumes, map[string]interface{}{
+			"source":    vol.Source,
+			"target":    vol.Target,
+			"read_only": vol.ReadOnly,
+		})
+	}
+	env := svc.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"name":     containerName,
+		"image":    svc.Image,
+		"pull":     "missing",
+		"ports":    ports,
+		"env":      env,
+		"volumes":  volumes,
+		"networks": svc.Networks,
+		"labels": map[string]string{
+			stackLabel:        stackName,
+			stackServiceLabel: svc.Name,
+		},
+		"detach": true,
+	}
+}
+
+// containerIDFromOutput extracts the created container ID from the agent output,
+// either the bare ID or an object with an "id" entry
+func containerIDFromOutput(output interface{}) string {
+	switch out := output.(type) {
+	case string:
+		return strings.TrimSpace(out)
+	case map[string]interface{}:
+		if id, ok := out["id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// ========================================
+// Container Inventory
+// ========================================
+
+// inventoryCheckInterval is how often the inventory job checks whether a reconciliation is due
+const inventoryCheckInterval = time.Minute
+
+// failedContainerStates are the engine states of a container that stopped running
+var failedContainerStates = map[string]bool{"exited": true, "dead": true}
+
+// runInventoryJob periodically reconciles the container inventory of every engine with an agent bound.
+// The interval is read from the configuration on each check, so the job idles until the config is loaded.
+func (s *Service) runInventoryJob() {
+	defer logger.RecoverPanic("[Containers] Inventory job")
+
+	ticker := time.NewTicker(inventoryCheckInterval)
+	defer ticker.Stop()
+
+	var lastRun time.Time
+	for range ticker.C {
+		cfg := config.GetConfig()
+		if cfg == nil || cfg.Limits.ContainerInventoryMinutes <= 0 {
+			continue
+		}
+		if time.Since(lastRun) < time.Duration(cfg.Limits.ContainerInventoryMinutes)*time.Minute {
+			continue
+		}
+		lastRun = time.Now()
+		s.reconcileAndPruneInventories(cfg)
+	}
+}
+
+// reconcileAndPruneInventories runs one pass of the inventory job; a panic is logged and
+// the job carries on at the next tick
+func (s *Service) reconcileAndPruneInventories(cfg *config.Config) {
+	defer logger.RecoverPanic("[Containers] Inventory pass")
+
+	s.ReconcileAllInventories(context.Background(), cfg.CSDCore.ServiceToken)
+
+	if cfg.Limits.ContainerInventoryRetentionDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -cfg.Limits.ContainerInventoryRetentionDays)
+		if _, err := s.repo.DeleteMissingSnapshotsBefore(cutoff); err != nil {
+			logger.Error("[Containers] Failed to prune container inventory: %s", err.Error())
+		}
+	}
+}
+
+// ReconcileAllInventories reconciles the inventory of every engine with an agent bound.
+// Unreachable engines are logged and skipped so one failure does not stop the others.
+func (s *Service) ReconcileAllInventories(ctx context.Context, token string) {
+	engines, err := s.repo.ListInventoryEngines()
+	if err != nil {
+		logger.Error("[Containers] Failed to list engines for inventory: %s", err.Error())
+		return
+	}
+
+	for i := range engines {
+		if _, err := s.ReconcileInventory(ctx, token, &engines[i]); err != nil {
+			logger.Warn("[Containers] Inventory skipped for engine %s: %s", engines[i].Name, err.Error())
+		}
+	}
+}
+
+// ReconcileInventory lists the containers of an engine, updates its stored inventory and
+// publishes an event for every previously running container now stopped or missing.
+// Returns the containers found failed by this reconciliation.
+func (s *Service) ReconcileInventory(ctx context.Context, token string, engine *ContainerEngine) ([]ContainerSnapshot, error) {
+	if engine.AgentID == nil {
+		return nil, validation.NewValidationError(fmt.Sprintf("container engine %s has no agent bound", engine.Name))
+	}
+
+	if err := s.client.ValidateAgentCapability(ctx, token, *engine.AgentID, engineCapability(engine)); err != nil {
+		s.markEngineError(engine, err)
+		return nil, err
+	}
+	containers, err := s.fetchContainers(ctx, token, engine, *engine.AgentID, true)
+	if err != nil {
+		s.markEngineError(engine, err)
+		return nil, err
+	}
+
+	previous, err := s.repo.ListSnapshots(engine.TenantID, engine.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load container inventory: %w", err)
+	}
+	snapshots, failed := reconcileSnapshots(engine, previous, containers, time.Now())
+	if err := s.repo.SaveSnapshots(snapshots); err != nil {
+		return nil, fmt.Errorf("failed to store container inventory: %w", err)
+	}
+
+	s.repo.UpdateInfo(engine.TenantID, engine.ID, map[string]interface{}{
+		"container_count": len(containers),
+		"status":          EngineStatusConnected,
+		"status_message":  "Container inventory reconciled",
+	})
+	if engine.Status != EngineStatusConnected {
+		events.GetEventBus().PublishAsync(events.NewEvent(
+			events.EventContainerEngineConnected,
+			engine.TenantID,
+			engine.ID.String(),
+			map[string]interface{}{
+				"name":   engine.Name,
+				"status": EngineStatusConnected,
+			},
+		))
+	}
+
+	for _, snapshot := range failed {
+		eventType := events.EventContainerStopped
+		if snapshot.Missing {
+			eventType = events.EventContainerMissing
+		}
+		events.GetEventBus().PublishAsync(events.NewEvent(
+			eventType,
+			engine.TenantID,
+			snapshot.ContainerID,
+			map[string]interface{}{
+				"engineId":   engine.ID.String(),
+				"engineName": engine.Name,
+				"name":       snapshot.Name,
+				"image":      snapshot.Image,
+				"state":      snapshot.State,
+				"reason":     snapshot.FailureReason,
+			},
+		))
+	}
+
+	return failed, nil
+}
+
+// markEngineError records an engine as unreachable, publishing an event when it was not already in error
+func (s *Service) markEngineError(engine *ContainerEngine, err error) {
+	s.repo.UpdateStatus(engine.TenantID, engine.ID, EngineStatusError, err.Error())
+	if engine.Status == EngineStatusError {
+		return
+	}
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventContainerEngineError,
+		engine.TenantID,
+		engine.ID.String(),
+		map[string]interface{}{
+			"name":  engine.Name,
+			"error": err.Error(),
+		},
+	))
+}
+
+// reconcileSnapshots merges a container listing into the stored inventory of an engine.
+// It returns every snapshot to save and, among them, the containers that were running at the
+// previous reconciliation and are now exited, dead or no longer listed.
+func reconcileSnapshots(engine *ContainerEngine, previous []ContainerSnapshot, containers []Container, now time.Time) ([]ContainerSnapshot, []ContainerSnapshot) {
+	known := make(map[string]ContainerSnapshot, len(previous))
+	for _, snapshot := range previous {
+		known[snapshot.ContainerID] = snapshot
+	}
+
+	var snapshots, failed []ContainerSnapshot
+	listed := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		listed[container.ID] = true
+		snapshot, exists := known[container.ID]
+		wasRunning := exists && !snapshot.Missing && snapshot.State == "running"
+		if !exists {
+			snapshot = ContainerSnapshot{TenantID: engine.TenantID, EngineID: engine.ID, ContainerID: container.ID}
+		}
+
+		snapshot.Name = strings.TrimPrefix(container.Name, "/")
+		snapshot.Image = container.Image
+		snapshot.State = strings.ToLower(container.State)
+		snapshot.Status = container.Status
+		snapshot.Missing = false
+		snapshot.LastSeenAt = &now
+
+		if wasRunning && failedContainerStates[snapshot.State] {
+			snapshot.FailedAt = &now
+			snapshot.FailureReason = container.Status
+			if snapshot.FailureReason == "" {
+				snapshot.FailureReason = snapshot.State
+			}
+			failed = append(failed, snapshot)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	for _, snapshot := range previous {
+		if listed[snapshot.ContainerID] || snapshot.Missing {
+			continue
+		}
+		snapshot.Missing = true
+		if snapshot.State == "running" {
+			snapshot.FailedAt = &now
+			snapshot.FailureReason = "No longer listed by the engine"
+			failed = append(failed, snapshot)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, failed
+}
+
+// ListFailedContainers returns the containers found stopped or missing after running within the last hours,
+// optionally restricted to one engine
+func (s *Service) ListFailedContainers(ctx context.Context, tenantID uuid.UUID, engineID *uuid.UUID, hours, limit, offset int) ([]ContainerSnapshot, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceContainerInventory, limit, offset)
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	return s.repo.ListFailedSnapshots(tenantID, engineID, since, p.Limit, p.Offset)
+}
+
 // BulkDelete deletes multiple container engines by IDs
 func (s *Service) BulkDelete(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (int64, error) {
 	return s.repo.BulkDelete(tenantID, ids)
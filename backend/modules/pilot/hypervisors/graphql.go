@@ -7,6 +7,7 @@ import (
 	csdcore "csd-pilote/backend/modules/platform/csd-core"
 	"csd-pilote/backend/modules/platform/graphql"
 	"csd-pilote/backend/modules/platform/middleware"
+	"csd-pilote/backend/modules/platform/pagination"
 	"csd-pilote/backend/modules/platform/validation"
 )
 
@@ -78,7 +79,7 @@ func handleListHypervisors(ctx context.Context, w http.ResponseWriter, variables
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceHypervisors)
 
 	var filter *HypervisorFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
@@ -167,7 +168,7 @@ func handleCreateHypervisor(ctx context.Context, w http.ResponseWriter, variable
 
 	input, err := parseHypervisorInput(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -225,7 +226,7 @@ func handleUpdateHypervisor(ctx context.Context, w http.ResponseWriter, variable
 
 	input, err := parseHypervisorInput(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -307,7 +308,7 @@ func handleTestHypervisorConnection(ctx context.Context, w http.ResponseWriter,
 		return
 	}
 
-	// agentId is optional - parse but don't fail if invalid
+	// agentId is optional; defaults to the agent bound to the hypervisor
 	agentID, _ := graphql.ParseUUID(variables, "agentId")
 
 	if err := service.TestConnection(ctx, token, tenantID, id, agentID); err != nil {
@@ -522,6 +523,9 @@ func handleBulkDeleteHypervisors(ctx context.Context, w http.ResponseWriter, var
 // Helper Functions
 // ========================================
 
+// libvirtURISchemes are the libvirt drivers accepted in a hypervisor URI, with any transport (qemu+ssh)
+var libvirtURISchemes = []string{"qemu", "xen", "lxc"}
+
 func parseHypervisorInput(inputRaw map[string]interface{}) (*HypervisorInput, error) {
 	input := &HypervisorInput{}
 	v := validation.NewValidator()
@@ -539,7 +543,7 @@ func parseHypervisorInput(inputRaw map[string]interface{}) (*HypervisorInput, er
 		input.AgentID = agentId
 	}
 	if uri, ok := inputRaw["uri"].(string); ok {
-		v.MaxLength("uri", uri, 1024).SafeString("uri", uri)
+		v.MaxLength("uri", uri, 1024).SafeString("uri", uri).URL("uri", uri, libvirtURISchemes...)
 		input.URI = uri
 	}
 	if artifactKey, ok := inputRaw["artifactKey"].(string); ok {
@@ -0,0 +1,39 @@
+package hypervisors
+
+import (
+	"testing"
+)
+
+func TestParseHypervisorInputURI(t *testing.T) {
+	tests := []struct {
+		uri   string
+		valid bool
+	}{
+		{"qemu:///system", true},
+		{"qemu:///session", true},
+		{"qemu+ssh://root@kvm01.example.com/system", true},
+		{"qemu+ssh://root@kvm01.example.com:2222/system", true},
+		{"qemu+tls://10.0.0.10/system", true},
+		{"qemu+tcp://[2001:db8::10]:16509/system", true},
+		{"qemu+unix:///system?socket=/run/libvirt/libvirt-sock", true},
+		{"xen:///", true},
+		{"xen+ssh://root@xen01.example.com/", true},
+		{"lxc:///", true},
+		{"vbox:///session", false},
+		{"esx://esx01.example.com", false},
+		{"http://kvm01.example.com/system", false},
+		{"qemu", false},
+		{"qemu://", false},
+		{"/var/run/libvirt/libvirt-sock", false},
+		{"qemu+ssh://root@kvm_01/system", false},
+		{"qemu+ssh://root@kvm01.example.com:0/system", false},
+		{"qemu+ssh://root@kvm01.example.com:70000/system", false},
+		{"qemu+ssh://bad host/system", false},
+	}
+	for _, tt := range tests {
+		_, err := parseHypervisorInput(map[string]interface{}{"uri": tt.uri})
+		if (err == nil) != tt.valid {
+			t.Errorf("uri %q: err = %v, want valid %v", tt.uri, err, tt.valid)
+		}
+	}
+}
@@ -74,7 +74,7 @@ func (s *Service) Get(ctx context.Context, tenantID, id uuid.UUID) (*Hypervisor,
 
 // List retrieves all hypervisors for a tenant
 func (s *Service) List(ctx context.Context, tenantID uuid.UUID, filter *HypervisorFilter, limit, offset int) ([]Hypervisor, int64, error) {
-	p := pagination.Normalize(limit, offset)
+	p := pagination.NormalizeFor(pagination.ResourceHypervisors, limit, offset)
 	return s.repo.List(tenantID, filter, p.Limit, p.Offset)
 }
 
@@ -149,8 +149,13 @@ func (s *Service) TestConnection(ctx context.Context, token string, tenantID, hy
 		return err
 	}
 
+	// The bound agent is used unless the caller overrides it
+	if agentID == uuid.Nil {
+		agentID = hypervisor.AgentID
+	}
+
 	// Execute a libvirt playbook with node_info action to test connection
-	_, err = s.client.ExecuteLibvirtTask(ctx, token, hypervisor.AgentID, hypervisor.URI, hypervisor.ArtifactKey, "node-info", nil)
+	_, err = s.client.ExecuteLibvirtTask(ctx, token, agentID, hypervisor.URI, hypervisor.ArtifactKey, "node-info", nil)
 	if err != nil {
 		s.repo.UpdateStatus(tenantID, hypervisorID, HypervisorStatusDisconnected, err.Error())
 		return err
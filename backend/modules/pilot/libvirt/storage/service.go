@@ -26,13 +26,28 @@ func NewService() *Service {
 	}
 }
 
-// ListPools returns all storage pools for a hypervisor
-func (s *Service) ListPools(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, filter *StoragePoolFilter) ([]StoragePool, error) {
+// getReadyHypervisor loads a hypervisor and checks its agent is online and supports libvirt
+// before any task is dispatched, so an unreachable agent fails fast with a clear error
+func (s *Service) getReadyHypervisor(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID) (*hypervisors.Hypervisor, error) {
 	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
 	if err != nil {
 		return nil, fmt.Errorf("hypervisor not found: %w", err)
 	}
 
+	if err := s.coreClient.ValidateAgentCapability(ctx, token, hv.AgentID, "libvirt"); err != nil {
+		return nil, fmt.Errorf("agent unavailable for hypervisor %s: %w", hv.Name, err)
+	}
+
+	return hv, nil
+}
+
+// ListPools returns all storage pools for a hypervisor
+func (s *Service) ListPools(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, filter *StoragePoolFilter) ([]StoragePool, error) {
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
+	if err != nil {
+		return nil, err
+	}
+
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "list-storage-pools", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list storage pools: %w", err)
@@ -75,9 +90,9 @@ func (s *Service) ListPools(ctx context.Context, token string, tenantID, hypervi
 
 // GetPool returns a specific storage pool
 func (s *Service) GetPool(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, name string) (*StoragePool, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "get-storage-pool", map[string]interface{}{
@@ -106,9 +121,9 @@ func (s *Service) GetPool(ctx context.Context, token string, tenantID, hyperviso
 
 // StartPool starts a storage pool
 func (s *Service) StartPool(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, name string) (*StoragePool, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "start-storage-pool", map[string]interface{}{
@@ -127,9 +142,9 @@ func (s *Service) StartPool(ctx context.Context, token string, tenantID, hypervi
 
 // StopPool stops a storage pool
 func (s *Service) StopPool(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, name string) (*StoragePool, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "stop-storage-pool", map[string]interface{}{
@@ -148,9 +163,9 @@ func (s *Service) StopPool(ctx context.Context, token string, tenantID, hypervis
 
 // RefreshPool refreshes a storage pool
 func (s *Service) RefreshPool(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, name string) (*StoragePool, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "refresh-storage-pool", map[string]interface{}{
@@ -169,9 +184,9 @@ func (s *Service) RefreshPool(ctx context.Context, token string, tenantID, hyper
 
 // ListVolumes returns all volumes in a storage pool
 func (s *Service) ListVolumes(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, poolName string, filter *StorageVolumeFilter) ([]StorageVolume, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "list-storage-volumes", map[string]interface{}{
@@ -213,9 +228,9 @@ func (s *Service) ListVolumes(ctx context.Context, token string, tenantID, hyper
 
 // GetVolume returns a specific volume
 func (s *Service) GetVolume(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, poolName, volumeName string) (*StorageVolume, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "get-storage-volume", map[string]interface{}{
@@ -245,9 +260,9 @@ func (s *Service) GetVolume(ctx context.Context, token string, tenantID, hypervi
 
 // CreateVolume creates a new volume
 func (s *Service) CreateVolume(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, poolName string, input *CreateVolumeInput) (*StorageVolume, error) {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return nil, fmt.Errorf("hypervisor not found: %w", err)
+		return nil, err
 	}
 
 	format := input.Format
@@ -274,9 +289,9 @@ func (s *Service) CreateVolume(ctx context.Context, token string, tenantID, hype
 
 // DeleteVolume deletes a volume
 func (s *Service) DeleteVolume(ctx context.Context, token string, tenantID, hypervisorID uuid.UUID, poolName, volumeName string) error {
-	hv, err := s.hypervisorSvc.Get(ctx, tenantID, hypervisorID)
+	hv, err := s.getReadyHypervisor(ctx, token, tenantID, hypervisorID)
 	if err != nil {
-		return fmt.Errorf("hypervisor not found: %w", err)
+		return err
 	}
 
 	execution, err := s.coreClient.ExecuteLibvirtTask(ctx, token, hv.AgentID, hv.URI, hv.ArtifactKey, "delete-storage-volume", map[string]interface{}{
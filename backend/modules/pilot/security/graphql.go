@@ -2,22 +2,46 @@ package security
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	csdcore "csd-pilote/backend/modules/platform/csd-core"
 	"csd-pilote/backend/modules/platform/graphql"
 	"csd-pilote/backend/modules/platform/middleware"
+	"csd-pilote/backend/modules/platform/pagination"
+	"csd-pilote/backend/modules/platform/server"
 	"csd-pilote/backend/modules/platform/validation"
 )
 
 func init() {
 	service := NewService()
 
+	// Background jobs need the database and the csd-core client, which exist only once the server starts
+	server.OnStart(func() {
+		service.bind()
+
+		// Periodic counter snapshots for rule counter history
+		go service.runCounterSnapshotJob()
+
+		// Scheduled profile deployments
+		go service.runScheduleJob()
+
+		// Built-in templates visible to every tenant
+		go service.runTemplateSeeding()
+	})
+	server.OnStop(service.Shutdown)
+
 	// ========================================
 	// Firewall Rules Queries
 	// ========================================
 
-	graphql.RegisterQuery("securityRules", "List all firewall rules", "csd-pilote.security.rules.read",
+	graphql.RegisterQuery("securityRules", "List all firewall rules; after (\"\" for the first page) switches to cursor pagination", "csd-pilote.security.rules.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleListRules(ctx, w, variables, service)
 		})
@@ -27,11 +51,21 @@ func init() {
 			handleGetRule(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("securityRuleUsage", "List the profiles that include a firewall rule", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetRuleUsage(ctx, w, variables, service)
+		})
+
 	graphql.RegisterQuery("securityRulesCount", "Count firewall rules", "csd-pilote.security.rules.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleCountRules(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("searchRulesByMatch", "Find enabled rules whose address, port or protocol overlap the given traffic, with the profiles and agents they affect", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleSearchRulesByMatch(ctx, w, variables, service)
+		})
+
 	// ========================================
 	// Firewall Rules Mutations
 	// ========================================
@@ -41,12 +75,17 @@ func init() {
 			handleCreateRule(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("cloneSecurityRule", "Clone a firewall rule", "csd-pilote.security.rules.create",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCloneRule(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("updateSecurityRule", "Update a firewall rule", "csd-pilote.security.rules.update",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleUpdateRule(ctx, w, variables, service)
 		})
 
-	graphql.RegisterMutation("deleteSecurityRule", "Delete a firewall rule", "csd-pilote.security.rules.delete",
+	graphql.RegisterMutation("deleteSecurityRule", "Delete a firewall rule; force is required when profiles include it", "csd-pilote.security.rules.delete",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleDeleteRule(ctx, w, variables, service)
 		})
@@ -56,6 +95,21 @@ func init() {
 			handleBulkDeleteRules(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("bulkSetRulesEnabled", "Enable or disable multiple firewall rules", "csd-pilote.security.rules.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleBulkSetRulesEnabled(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("normalizeSecurityRulePriorities", "Renumber a profile's rule priorities in steps of 10", "csd-pilote.security.rules.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleNormalizeRulePriorities(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("bulkSetSecurityRulePriorities", "Set the priority of multiple firewall rules", "csd-pilote.security.rules.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleBulkSetRulePriorities(ctx, w, variables, service)
+		})
+
 	// ========================================
 	// Firewall Profiles Queries
 	// ========================================
@@ -84,11 +138,21 @@ func init() {
 			handleCreateProfile(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("cloneSecurityProfile", "Clone a firewall profile with copies of its rules", "csd-pilote.security.profiles.create",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCloneProfile(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("updateSecurityProfile", "Update a firewall profile", "csd-pilote.security.profiles.update",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleUpdateProfile(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("setSecurityProfileEnabled", "Enable or disable a firewall profile (disabled profiles are refused at deploy time unless forced)", "csd-pilote.security.profiles.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleSetProfileEnabled(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("deleteSecurityProfile", "Delete a firewall profile", "csd-pilote.security.profiles.delete",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleDeleteProfile(ctx, w, variables, service)
@@ -99,11 +163,41 @@ func init() {
 			handleAddRulesToProfile(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("reorderSecurityProfileRules", "Set the evaluation order of all rules in a profile", "csd-pilote.security.profiles.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleReorderProfileRules(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("removeRulesFromSecurityProfile", "Remove rules from a profile", "csd-pilote.security.profiles.update",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleRemoveRulesFromProfile(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("securityProfileBaseRulesPreview", "Preview the base rules generated by profile settings", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handlePreviewBaseRules(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("previewSecurityProfile", "Render the nftables config a profile would deploy, with rule counts per chain, without deploying it", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handlePreviewProfile(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityProfileRulesetEstimate", "Estimate the size of the ruleset generated for a profile", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleProfileRulesetEstimate(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityProfileLint", "Report risky patterns in the rules of a profile", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleLintProfile(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securitySimulatePacket", "Find the rule and verdict a profile applies to a packet", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleSimulatePacket(ctx, w, variables, service)
+		})
+
 	// ========================================
 	// Firewall Templates Queries
 	// ========================================
@@ -118,6 +212,11 @@ func init() {
 			handleGetTemplate(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("simulateTemplate", "Find the rule and verdict a template's rules apply to a packet, before creating a profile from it", "csd-pilote.security.templates.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleSimulateTemplate(ctx, w, variables, service)
+		})
+
 	graphql.RegisterQuery("securityTemplatesCount", "Count firewall templates", "csd-pilote.security.templates.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleCountTemplates(ctx, w, variables, service)
@@ -142,6 +241,11 @@ func init() {
 			handleDeleteTemplate(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("publishSecurityTemplate", "Share a tenant template with every tenant", "csd-pilote.security.templates.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handlePublishTemplate(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("applySecurityTemplate", "Apply a template to a profile", "csd-pilote.security.templates.update",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleApplyTemplate(ctx, w, variables, service)
@@ -151,7 +255,7 @@ func init() {
 	// Firewall Deployments Queries
 	// ========================================
 
-	graphql.RegisterQuery("securityDeployments", "List all firewall deployments", "csd-pilote.security.deploy",
+	graphql.RegisterQuery("securityDeployments", "List all firewall deployments; filter.excludeActions hides AUDIT/FLUSH records; after switches to cursor pagination", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleListDeployments(ctx, w, variables, service)
 		})
@@ -166,48 +270,283 @@ func init() {
 			handleCountDeployments(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("securityAgentStatus", "Get an agent's latest deployment, whether it is in sync with its profile and when it was last audited", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetAgentStatus(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityDeploymentsByConfigHash", "List the agents whose latest deployment applied the nftables config with the given fingerprint", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeploymentsByConfigHash(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityDeploymentStats", "Get average and median apply durations over the last 30 days, overall and per agent", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeploymentStats(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("profileDeploymentMatrix", "Get the latest deployment of a profile on every agent that ran it", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleProfileDeploymentMatrix(ctx, w, variables, service)
+		})
+
 	graphql.RegisterQuery("securityAgents", "List agents that support nftables", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleListSecurityAgents(ctx, w, variables, service)
 		})
 
+	graphql.RegisterQuery("securityAgentCounters", "Read the named nftables counters of an agent", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleSyncCounters(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityRuleCounters", "Read the per-rule hit counters of the rules last applied to an agent", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetRuleCounters(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityRuleCounterHistory", "Get the counter snapshots of a rule over a period (last 24 hours by default)", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetRuleCounterHistory(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityBackupContent", "Get the decoded content of an agent's firewall backup (latest when backupKey is omitted)", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetBackupContent(ctx, w, variables, service)
+		})
+
 	// ========================================
 	// Firewall Deployments Mutations
 	// ========================================
 
-	graphql.RegisterMutation("deploySecurityProfile", "Deploy a profile to an agent", "csd-pilote.security.deploy",
+	graphql.RegisterMutation("deploySecurityProfile", "Deploy a profile to an agent (by agentId or agentHostname)", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleDeployProfile(ctx, w, variables, service)
 		})
 
-	graphql.RegisterMutation("rollbackSecurityDeployment", "Rollback a deployment", "csd-pilote.security.deploy",
+	graphql.RegisterMutation("rollbackSecurityDeployment", "Rollback a deployment (dryRun previews the restored config and its diff against the live ruleset)", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleRollbackDeployment(ctx, w, variables, service)
 		})
 
+	graphql.RegisterMutation("retryDeployment", "Retry a failed deployment as a new attempt linked to the original", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleRetryDeployment(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("restoreSecurityDeployment", "Re-apply the rules snapshot of a past applied deployment as a new deployment", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleRestoreDeployment(ctx, w, variables, service)
+		})
+
 	graphql.RegisterMutation("auditSecurityDeployment", "Audit firewall state on an agent", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleAuditDeployment(ctx, w, variables, service)
 		})
 
-	graphql.RegisterMutation("flushSecurityRules", "Flush all firewall rules on an agent", "csd-pilote.security.deploy",
+	graphql.RegisterMutation("auditAllAgents", "Audit every online nftables agent and summarize drift", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleAuditAllAgents(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityEnforcementPauses", "List agents with drift enforcement paused and why", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListEnforcementPauses(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("pauseSecurityEnforcement", "Pause drift enforcement on an agent, optionally until a resume time", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handlePauseEnforcement(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("resumeSecurityEnforcement", "Resume drift enforcement on an agent", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleResumeEnforcement(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("securityComplianceReport", "Audit agents and score their live rulesets against a baseline profile", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleComplianceReport(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("diffSecurityProfile", "Diff the config a profile generates against the live ruleset of an agent", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDiffProfile(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("flushSecurityRules", "Flush the managed firewall tables on an agent (scope ALL with confirmFullFlush flushes everything)", "csd-pilote.security.deploy",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleFlushRules(ctx, w, variables, service)
 		})
 
+	// ========================================
+	// In-flight Operations
+	// ========================================
+
+	graphql.RegisterQuery("securityInFlightOperations", "List pending and running firewall operations", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListInFlightOperations(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("cancelSecurityOperation", "Cancel a pending or running firewall operation", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCancelOperation(ctx, w, variables, service)
+		})
+
+	// ========================================
+	// IP Sets
+	// ========================================
+
+	graphql.RegisterQuery("securityIPSets", "List all IP sets", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListIPSets(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityIPSet", "Get an IP set by ID", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetIPSet(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityIPSetUsage", "List the rules and profiles referencing an IP set", "csd-pilote.security.rules.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetIPSetUsage(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("createSecurityIPSet", "Create a new IP set", "csd-pilote.security.rules.create",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCreateIPSet(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("updateSecurityIPSet", "Update an IP set", "csd-pilote.security.rules.update",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleUpdateIPSet(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("deleteSecurityIPSet", "Delete an IP set", "csd-pilote.security.rules.delete",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeleteIPSet(ctx, w, variables, service)
+		})
+
+	// ========================================
+	// Agent Groups
+	// ========================================
+
+	graphql.RegisterQuery("securityAgentGroups", "List all agent groups", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListAgentGroups(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityAgentGroup", "Get an agent group by ID", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetAgentGroup(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("createSecurityAgentGroup", "Create a new agent group", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCreateAgentGroup(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("updateSecurityAgentGroup", "Update an agent group", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleUpdateAgentGroup(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("deleteSecurityAgentGroup", "Delete an agent group", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeleteAgentGroup(ctx, w, variables, service)
+		})
+
+	// ========================================
+	// Rolling Deployments
+	// ========================================
+
+	graphql.RegisterQuery("securityRollouts", "List rolling deployments", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListRollouts(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityRollout", "Get a rolling deployment by ID", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetRollout(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("deploySecurityProfileToGroup", "Deploy a profile to an agent group in verified batches", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeployProfileToGroup(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("deploySecurityProfileBulk", "Deploy a profile to several agents at once under a shared batch ID", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeployProfileBulk(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securityDeploymentBatch", "Summarize the deployments of a bulk deployment by status, with its failures", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetDeploymentBatch(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("resumeDeploymentBatch", "Resume an aborted rolling deployment for the agents it has not applied yet", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleResumeDeploymentBatch(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("cancelSecurityRollout", "Abort a pending or running rolling deployment after its current batch", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCancelRollout(ctx, w, variables, service)
+		})
+
+	// ========================================
+	// Deployment Schedules
+	// ========================================
+
+	graphql.RegisterQuery("securitySchedules", "List deployment schedules", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleListSchedules(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("securitySchedule", "Get a deployment schedule by ID", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleGetSchedule(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("createSecuritySchedule", "Create a schedule that re-applies a profile to an agent (cron expression, UTC)", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCreateSchedule(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("updateSecuritySchedule", "Update a deployment schedule", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleUpdateSchedule(ctx, w, variables, service)
+		})
+
+	graphql.RegisterMutation("deleteSecuritySchedule", "Delete a deployment schedule", "csd-pilote.security.deploy",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleDeleteSchedule(ctx, w, variables, service)
+		})
+
 	// ========================================
 	// Import/Export Mutations
 	// ========================================
 
-	graphql.RegisterQuery("exportSecurityProfile", "Export a profile with its rules", "csd-pilote.security.profiles.read",
+	graphql.RegisterQuery("exportSecurityProfile", "Export a profile with its rules, the IP sets they reference and optionally target agent groups (agentGroupIds)", "csd-pilote.security.profiles.read",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleExportProfile(ctx, w, variables, service)
 		})
 
-	graphql.RegisterMutation("importSecurityProfile", "Import a profile from JSON", "csd-pilote.security.profiles.create",
+	graphql.RegisterMutation("importSecurityProfile", "Import a profile from JSON, creating its bundled IP sets and agent groups", "csd-pilote.security.profiles.create",
 		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
 			handleImportProfile(ctx, w, variables, service)
 		})
+
+	graphql.RegisterQuery("validateSecurityProfileImport", "Validate a profile import without persisting it", "csd-pilote.security.profiles.create",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleValidateProfileImport(ctx, w, variables, service)
+		})
+
+	graphql.RegisterQuery("checkSecurityPolicy", "Validate a profile definition, generate its nftables config and optionally check it on an agent with nft -c, without persisting anything", "csd-pilote.security.profiles.read",
+		func(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}) {
+			handleCheckPolicy(ctx, w, variables, service)
+		})
 }
 
 // ========================================
@@ -221,7 +560,7 @@ func handleListRules(ctx context.Context, w http.ResponseWriter, variables map[s
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallRules)
 
 	var filter *FirewallRuleFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
@@ -246,7 +585,7 @@ func handleListRules(ctx context.Context, w http.ResponseWriter, variables map[s
 				graphql.WriteValidationError(w, err.Error())
 				return
 			}
-			p := RuleProtocol(protocol)
+			p := normalizeRuleProtocol(protocol)
 			filter.Protocol = &p
 		}
 		if action, ok := f["action"].(string); ok {
@@ -262,6 +601,21 @@ func handleListRules(ctx context.Context, w http.ResponseWriter, variables map[s
 		}
 	}
 
+	// A cursor ("" for the first page) switches to keyset pagination and takes precedence over offset
+	if after, ok := variables["after"].(string); ok {
+		rules, count, next, err := service.ListRulesAfter(ctx, tenantID, filter, after, limit)
+		if err != nil {
+			graphql.WriteError(w, err, "list security rules")
+			return
+		}
+		graphql.WriteSuccess(w, map[string]interface{}{
+			"securityRules":           rules,
+			"securityRulesCount":      count,
+			"securityRulesNextCursor": next,
+		})
+		return
+	}
+
 	rules, count, err := service.ListRules(ctx, tenantID, filter, limit, offset)
 	if err != nil {
 		graphql.WriteError(w, err, "list security rules")
@@ -298,6 +652,31 @@ func handleGetRule(ctx context.Context, w http.ResponseWriter, variables map[str
 	})
 }
 
+func handleGetRuleUsage(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	profiles, err := service.GetRuleUsage(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get security rule usage")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityRuleUsage":      profiles,
+		"securityRuleUsageCount": len(profiles),
+	})
+}
+
 func handleCountRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
@@ -339,7 +718,7 @@ func handleCreateRule(ctx context.Context, w http.ResponseWriter, variables map[
 
 	input, err := parseRuleInput(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -347,12 +726,16 @@ func handleCreateRule(ctx context.Context, w http.ResponseWriter, variables map[
 	v := validation.NewValidator()
 	v.Required("name", input.Name)
 	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
+		graphql.WriteValidationErrors(w, v.Errors())
 		return
 	}
 
 	rule, err := service.CreateRule(ctx, token, tenantID, user.UserID, input)
 	if err != nil {
+		// Semantic errors are reported per field
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
 		graphql.WriteError(w, err, "create security rule")
 		return
 	}
@@ -362,45 +745,41 @@ func handleCreateRule(ctx context.Context, w http.ResponseWriter, variables map[
 	})
 }
 
-func handleUpdateRule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleCloneRule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	token, _ := middleware.GetTokenFromContext(ctx)
-
-	id, err := graphql.ParseUUID(variables, "id")
-	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
-		return
-	}
-
-	inputRaw, ok := variables["input"].(map[string]interface{})
+	user, ok := middleware.GetUserFromContext(ctx)
 	if !ok {
-		graphql.WriteValidationError(w, "input is required")
+		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	input, err := parseRuleInput(inputRaw)
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	rule, err := service.UpdateRule(ctx, token, tenantID, id, input)
+	addToProfiles := graphql.ParseBool(variables, "addToProfiles", false)
+
+	rule, err := service.CloneRule(ctx, token, tenantID, user.UserID, id, addToProfiles)
 	if err != nil {
-		graphql.WriteError(w, err, "update security rule")
+		graphql.WriteError(w, err, "clone security rule")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"updateSecurityRule": rule,
+		"cloneSecurityRule": rule,
 	})
 }
 
-func handleDeleteRule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleUpdateRule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
@@ -415,14 +794,58 @@ func handleDeleteRule(ctx context.Context, w http.ResponseWriter, variables map[
 		return
 	}
 
-	if err := service.DeleteRule(ctx, token, tenantID, id); err != nil {
-		graphql.WriteError(w, err, "delete security rule")
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
 		return
 	}
 
-	graphql.WriteSuccess(w, map[string]interface{}{
-		"deleteSecurityRule": true,
-	})
+	input, err := parseRuleInput(inputRaw)
+	if err != nil {
+		graphql.WriteInputError(w, err)
+		return
+	}
+
+	rule, err := service.UpdateRule(ctx, token, tenantID, id, input)
+	if err != nil {
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
+		graphql.WriteError(w, err, "update security rule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"updateSecurityRule": rule,
+	})
+}
+
+func handleDeleteRule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// A rule included in profiles is only deleted when forced
+	force := graphql.ParseBool(variables, "force", false)
+
+	if err := service.DeleteRule(ctx, token, tenantID, id, force); err != nil {
+		graphql.WriteError(w, err, "delete security rule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deleteSecurityRule": true,
+	})
 }
 
 func handleBulkDeleteRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
@@ -449,6 +872,116 @@ func handleBulkDeleteRules(ctx context.Context, w http.ResponseWriter, variables
 	})
 }
 
+func handleBulkSetRulesEnabled(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	ids, err := graphql.ParseBulkUUIDs(variables, "ids")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	enabled, ok := variables["enabled"].(bool)
+	if !ok {
+		graphql.WriteValidationError(w, "enabled is required")
+		return
+	}
+
+	updated, err := service.BulkSetRulesEnabled(ctx, token, tenantID, ids, enabled)
+	if err != nil {
+		graphql.WriteError(w, err, "bulk set security rules enabled")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"bulkSetRulesEnabled": updated,
+	})
+}
+
+func handleNormalizeRulePriorities(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	rules, err := service.NormalizeRulePriorities(ctx, token, tenantID, profileID)
+	if err != nil {
+		graphql.WriteError(w, err, "normalize security rule priorities")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"normalizeSecurityRulePriorities": rules,
+	})
+}
+
+func handleBulkSetRulePriorities(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	entries, ok := variables["priorities"].([]interface{})
+	if !ok || len(entries) == 0 {
+		graphql.WriteValidationError(w, "priorities is required")
+		return
+	}
+
+	v := validation.NewValidator()
+	v.MaxItems("priorities", len(entries), validation.MaxBulkIDs)
+	priorities := make(map[uuid.UUID]int, len(entries))
+	for _, entry := range entries {
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok {
+			graphql.WriteValidationError(w, "priorities must be a list of { ruleId, priority }")
+			return
+		}
+		ruleID, err := graphql.ParseUUID(entryMap, "ruleId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		priority, ok := entryMap["priority"].(float64)
+		if !ok {
+			graphql.WriteValidationError(w, "priority is required")
+			return
+		}
+		v.Range("priority", int(priority), 0, 65535)
+		priorities[ruleID] = int(priority)
+	}
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	if err := service.BulkSetRulePriorities(ctx, token, tenantID, priorities); err != nil {
+		graphql.WriteError(w, err, "bulk set security rule priorities")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"bulkSetSecurityRulePriorities": len(priorities),
+	})
+}
+
 // ========================================
 // Firewall Profiles Handlers
 // ========================================
@@ -460,7 +993,7 @@ func handleListProfiles(ctx context.Context, w http.ResponseWriter, variables ma
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallProfiles)
 
 	var filter *FirewallProfileFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
@@ -557,7 +1090,7 @@ func handleCreateProfile(ctx context.Context, w http.ResponseWriter, variables m
 
 	input, err := parseProfileInputWithValidation(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
@@ -565,12 +1098,15 @@ func handleCreateProfile(ctx context.Context, w http.ResponseWriter, variables m
 	v := validation.NewValidator()
 	v.Required("name", input.Name)
 	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
+		graphql.WriteValidationErrors(w, v.Errors())
 		return
 	}
 
 	profile, err := service.CreateProfile(ctx, token, tenantID, user.UserID, input)
 	if err != nil {
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
 		graphql.WriteError(w, err, "create security profile")
 		return
 	}
@@ -580,6 +1116,46 @@ func handleCreateProfile(ctx context.Context, w http.ResponseWriter, variables m
 	})
 }
 
+func handleCloneProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	name := graphql.ParseString(variables, "name")
+	v := validation.NewValidator()
+	v.Required("name", name).MaxLength("name", name, validation.MaxNameLength).SafeString("name", name)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	profile, err := service.CloneProfile(ctx, token, tenantID, user.UserID, profileID, name)
+	if err != nil {
+		graphql.WriteError(w, err, "clone security profile")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"cloneSecurityProfile": profile,
+	})
+}
+
 func handleUpdateProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
@@ -603,12 +1179,15 @@ func handleUpdateProfile(ctx context.Context, w http.ResponseWriter, variables m
 
 	input, err := parseProfileInputWithValidation(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
 
 	profile, err := service.UpdateProfile(ctx, token, tenantID, id, input)
 	if err != nil {
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
 		graphql.WriteError(w, err, "update security profile")
 		return
 	}
@@ -618,6 +1197,38 @@ func handleUpdateProfile(ctx context.Context, w http.ResponseWriter, variables m
 	})
 }
 
+func handleSetProfileEnabled(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	enabled, ok := variables["enabled"].(bool)
+	if !ok {
+		graphql.WriteValidationError(w, "enabled is required")
+		return
+	}
+
+	profile, err := service.SetProfileEnabled(ctx, token, tenantID, id, enabled)
+	if err != nil {
+		graphql.WriteError(w, err, "set security profile enabled")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"setSecurityProfileEnabled": profile,
+	})
+}
+
 func handleDeleteProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
@@ -672,13 +1283,15 @@ func handleAddRulesToProfile(ctx context.Context, w http.ResponseWriter, variabl
 	})
 }
 
-func handleRemoveRulesFromProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleReorderProfileRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
+	token, _ := middleware.GetTokenFromContext(ctx)
+
 	profileID, err := graphql.ParseUUID(variables, "profileId")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
@@ -691,110 +1304,125 @@ func handleRemoveRulesFromProfile(ctx context.Context, w http.ResponseWriter, va
 		return
 	}
 
-	if err := service.RemoveRulesFromProfile(ctx, tenantID, profileID, ruleIDs); err != nil {
-		graphql.WriteError(w, err, "remove rules from profile")
+	if err := service.ReorderProfileRules(ctx, token, tenantID, profileID, ruleIDs); err != nil {
+		graphql.WriteError(w, err, "reorder profile rules")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"removeRulesFromSecurityProfile": true,
+		"reorderSecurityProfileRules": true,
 	})
 }
 
-// ========================================
-// Firewall Templates Handlers
-// ========================================
-
-func handleListTemplates(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleRemoveRulesFromProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
-
-	var filter *FirewallTemplateFilter
-	if f, ok := variables["filter"].(map[string]interface{}); ok {
-		filter = &FirewallTemplateFilter{}
-		if search, ok := f["search"].(string); ok {
-			if len(search) > validation.MaxSearchLength {
-				graphql.WriteValidationError(w, "search term too long")
-				return
-			}
-			filter.Search = &search
-		}
-		if category, ok := f["category"].(string); ok {
-			if err := graphql.ValidateEnum(category, graphql.TemplateCategoryValues, "category"); err != nil {
-				graphql.WriteValidationError(w, err.Error())
-				return
-			}
-			c := TemplateCategory(category)
-			filter.Category = &c
-		}
-		if isBuiltIn, ok := f["isBuiltIn"].(bool); ok {
-			filter.IsBuiltIn = &isBuiltIn
-		}
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
 	}
 
-	templates, count, err := service.ListTemplates(ctx, tenantID, filter, limit, offset)
+	ruleIDs, err := graphql.ParseBulkUUIDs(variables, "ruleIds")
 	if err != nil {
-		graphql.WriteError(w, err, "list security templates")
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := service.RemoveRulesFromProfile(ctx, tenantID, profileID, ruleIDs); err != nil {
+		graphql.WriteError(w, err, "remove rules from profile")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityTemplates":      templates,
-		"securityTemplatesCount": count,
+		"removeRulesFromSecurityProfile": true,
 	})
 }
 
-func handleGetTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleSearchRulesByMatch(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	id, err := graphql.ParseUUID(variables, "id")
-	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+	query := &RuleMatchQuery{
+		IP:           graphql.ParseString(variables, "ip"),
+		Port:         graphql.ParseInt(variables, "port", 0),
+		Protocol:     normalizeRuleProtocol(graphql.ParseString(variables, "protocol")),
+		SpecificOnly: graphql.ParseBool(variables, "specificOnly", false),
+	}
+
+	v := validation.NewValidator()
+	v.MaxLength("ip", query.IP, 64)
+	v.Enum("protocol", string(query.Protocol), []string{string(RuleProtocolTCP), string(RuleProtocolUDP), string(RuleProtocolICMP)})
+	if query.Port != 0 {
+		v.Range("port", query.Port, validation.MinPortNumber, validation.MaxPortNumber)
+	}
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+	if query.IP == "" && query.Port == 0 && query.Protocol == "" {
+		graphql.WriteValidationError(w, "at least one of ip, port or protocol is required")
 		return
 	}
 
-	template, err := service.GetTemplate(ctx, tenantID, id)
+	result, err := service.SearchRulesByMatch(ctx, tenantID, query)
 	if err != nil {
-		graphql.WriteError(w, err, "get security template")
+		graphql.WriteError(w, err, "search rules by match")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityTemplate": template,
+		"searchRulesByMatch": result,
 	})
 }
 
-func handleCountTemplates(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleSimulatePacket(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	count, err := service.CountTemplates(ctx, tenantID)
+	profileID, err := graphql.ParseUUID(variables, "profileId")
 	if err != nil {
-		graphql.WriteError(w, err, "count security templates")
+		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityTemplatesCount": count,
-	})
-}
-
-func handleCreateTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
-	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	packetRaw, ok := variables["packet"].(map[string]interface{})
 	if !ok {
-		graphql.WriteUnauthorized(w)
+		graphql.WriteValidationError(w, "packet is required")
+		return
+	}
+
+	packet, err := parseSimulatedPacket(packetRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	result, err := service.SimulatePacket(ctx, tenantID, profileID, packet)
+	if err != nil {
+		graphql.WriteError(w, err, "simulate packet")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securitySimulatePacket": result,
+	})
+}
+
+func handleSimulateTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
 		return
 	}
 
@@ -804,155 +1432,163 @@ func handleCreateTemplate(ctx context.Context, w http.ResponseWriter, variables
 		return
 	}
 
-	token, _ := middleware.GetTokenFromContext(ctx)
+	templateID, err := graphql.ParseUUID(variables, "templateId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
 
-	inputRaw, ok := variables["input"].(map[string]interface{})
+	packetRaw, ok := variables["packet"].(map[string]interface{})
 	if !ok {
-		graphql.WriteValidationError(w, "input is required")
+		graphql.WriteValidationError(w, "packet is required")
 		return
 	}
 
-	input, err := parseTemplateInputWithValidation(inputRaw)
+	packet, err := parseSimulatedPacket(packetRaw)
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	// Validate required fields
-	v := validation.NewValidator()
-	v.Required("name", input.Name)
-	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
-		return
+	// Optional profile settings (policies, base rules) in the createSecurityProfile input format
+	var settings *FirewallProfileInput
+	if profileRaw, ok := variables["profile"].(map[string]interface{}); ok {
+		settings, err = parseProfileInputWithValidation(profileRaw)
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
 	}
 
-	template, err := service.CreateTemplate(ctx, token, tenantID, user.UserID, input)
+	result, err := service.SimulateTemplate(ctx, tenantID, user.UserID, templateID, settings, packet)
 	if err != nil {
-		graphql.WriteError(w, err, "create security template")
+		graphql.WriteError(w, err, "simulate template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"createSecurityTemplate": template,
+		"simulateTemplate": result,
 	})
 }
 
-func handleUpdateTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
-	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
-	if !ok {
+func handlePreviewBaseRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	if _, ok := middleware.GetTenantIDFromContext(ctx); !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	token, _ := middleware.GetTokenFromContext(ctx)
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
 
-	id, err := graphql.ParseUUID(variables, "id")
+	input, err := parseProfileInputWithValidation(inputRaw)
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	inputRaw, ok := variables["input"].(map[string]interface{})
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityProfileBaseRulesPreview":         service.PreviewBaseRules(input),
+		"securityProfileBaseRulesPreviewEstimate": service.PreviewRulesetEstimate(input),
+	})
+}
+
+func handleProfileRulesetEstimate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
-		graphql.WriteValidationError(w, "input is required")
+		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	input, err := parseTemplateInputWithValidation(inputRaw)
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	template, err := service.UpdateTemplate(ctx, token, tenantID, id, input)
+	estimate, err := service.EstimateProfileRuleset(ctx, tenantID, id)
 	if err != nil {
-		graphql.WriteError(w, err, "update security template")
+		graphql.WriteError(w, err, "estimate profile ruleset")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"updateSecurityTemplate": template,
+		"securityProfileRulesetEstimate": estimate,
 	})
 }
 
-func handleDeleteTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handlePreviewProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	token, _ := middleware.GetTokenFromContext(ctx)
-
-	id, err := graphql.ParseUUID(variables, "id")
+	profileID, err := graphql.ParseUUID(variables, "profileId")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	if err := service.DeleteTemplate(ctx, token, tenantID, id); err != nil {
-		graphql.WriteError(w, err, "delete security template")
+	preview, err := service.PreviewProfile(ctx, tenantID, profileID)
+	if err != nil {
+		graphql.WriteError(w, err, "preview security profile")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"deleteSecurityTemplate": true,
+		"previewSecurityProfile": preview,
 	})
 }
 
-func handleApplyTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleLintProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	user, ok := middleware.GetUserFromContext(ctx)
-	if !ok {
-		graphql.WriteUnauthorized(w)
-		return
-	}
-
-	token, _ := middleware.GetTokenFromContext(ctx)
-
-	templateID, err := graphql.ParseUUID(variables, "templateId")
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	profileID, err := graphql.ParseUUID(variables, "profileId")
+	findings, err := service.LintProfile(ctx, tenantID, id)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
-		return
-	}
-
-	if err := service.ApplyTemplateToProfile(ctx, token, tenantID, user.UserID, templateID, profileID); err != nil {
-		graphql.WriteError(w, err, "apply security template")
+		graphql.WriteError(w, err, "lint profile")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"applySecurityTemplate": true,
+		"securityProfileLint": findings,
 	})
 }
 
 // ========================================
-// Firewall Deployments Handlers
+// Firewall Templates Handlers
 // ========================================
 
-func handleListDeployments(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleListTemplates(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	limit, offset := graphql.ParsePagination(variables)
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
 
-	var filter *FirewallDeploymentFilter
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallTemplates)
+
+	var filter *FirewallTemplateFilter
 	if f, ok := variables["filter"].(map[string]interface{}); ok {
-		filter = &FirewallDeploymentFilter{}
+		filter = &FirewallTemplateFilter{}
 		if search, ok := f["search"].(string); ok {
 			if len(search) > validation.MaxSearchLength {
 				graphql.WriteValidationError(w, "search term too long")
@@ -960,112 +1596,94 @@ func handleListDeployments(ctx context.Context, w http.ResponseWriter, variables
 			}
 			filter.Search = &search
 		}
-		if profileId, ok := f["profileId"].(string); ok {
-			v := validation.NewValidator()
-			v.UUID("profileId", profileId)
-			if v.HasErrors() {
-				graphql.WriteValidationError(w, v.FirstError())
-				return
-			}
-			filter.ProfileID = &profileId
-		}
-		if agentId, ok := f["agentId"].(string); ok {
-			v := validation.NewValidator()
-			v.UUID("agentId", agentId)
-			if v.HasErrors() {
-				graphql.WriteValidationError(w, v.FirstError())
-				return
-			}
-			filter.AgentID = &agentId
-		}
-		if action, ok := f["action"].(string); ok {
-			if err := graphql.ValidateEnum(action, graphql.DeploymentStatusValues, "action"); err != nil {
+		if category, ok := f["category"].(string); ok {
+			if err := graphql.ValidateEnum(category, graphql.TemplateCategoryValues, "category"); err != nil {
 				graphql.WriteValidationError(w, err.Error())
 				return
 			}
-			a := DeploymentAction(action)
-			filter.Action = &a
+			c := TemplateCategory(category)
+			filter.Category = &c
 		}
-		if status, ok := f["status"].(string); ok {
-			if err := graphql.ValidateEnum(status, graphql.DeploymentStatusValues, "status"); err != nil {
+		if isBuiltIn, ok := f["isBuiltIn"].(bool); ok {
+			filter.IsBuiltIn = &isBuiltIn
+		}
+		if visibility, ok := f["visibility"].(string); ok {
+			if err := graphql.ValidateEnum(visibility, []string{string(TemplateVisibilityPrivate), string(TemplateVisibilityTenant), string(TemplateVisibilityShared)}, "visibility"); err != nil {
 				graphql.WriteValidationError(w, err.Error())
 				return
 			}
-			s := DeploymentStatus(status)
-			filter.Status = &s
+			vis := TemplateVisibility(visibility)
+			filter.Visibility = &vis
 		}
 	}
 
-	deployments, count, err := service.ListDeployments(ctx, tenantID, filter, limit, offset)
+	templates, count, err := service.ListTemplates(ctx, tenantID, user.UserID, filter, limit, offset)
 	if err != nil {
-		graphql.WriteError(w, err, "list security deployments")
+		graphql.WriteError(w, err, "list security templates")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityDeployments":      deployments,
-		"securityDeploymentsCount": count,
+		"securityTemplates":      templates,
+		"securityTemplatesCount": count,
 	})
 }
 
-func handleGetDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleGetTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
 	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	deployment, err := service.GetDeployment(ctx, tenantID, id)
+	template, err := service.GetTemplate(ctx, tenantID, user.UserID, id)
 	if err != nil {
-		graphql.WriteError(w, err, "get security deployment")
+		graphql.WriteError(w, err, "get security template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityDeployment": deployment,
+		"securityTemplate": template,
 	})
 }
 
-func handleCountDeployments(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleCountTemplates(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	count, err := service.CountDeployments(ctx, tenantID)
-	if err != nil {
-		graphql.WriteError(w, err, "count security deployments")
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityDeploymentsCount": count,
-	})
-}
-
-func handleListSecurityAgents(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
-	token, _ := middleware.GetTokenFromContext(ctx)
-
-	client := csdcore.GetClient()
-	agents, err := client.ListAgentsByCapability(ctx, token, "nftables")
+	count, err := service.CountTemplates(ctx, tenantID, user.UserID)
 	if err != nil {
-		graphql.WriteError(w, err, "list security agents")
+		graphql.WriteError(w, err, "count security templates")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"securityAgents": agents,
+		"securityTemplatesCount": count,
 	})
 }
 
-func handleDeployProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleCreateTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
@@ -1080,52 +1698,41 @@ func handleDeployProfile(ctx context.Context, w http.ResponseWriter, variables m
 
 	token, _ := middleware.GetTokenFromContext(ctx)
 
-	profileIDStr, err := graphql.ParseStringRequired(variables, "profileId")
-	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
-		return
-	}
-	v := validation.NewValidator()
-	v.UUID("profileId", profileIDStr)
-	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
 		return
 	}
 
-	agentIDStr, err := graphql.ParseStringRequired(variables, "agentId")
+	input, err := parseTemplateInputWithValidation(inputRaw)
 	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+		graphql.WriteInputError(w, err)
 		return
 	}
-	v = validation.NewValidator()
-	v.UUID("agentId", agentIDStr)
+
+	// Validate required fields
+	v := validation.NewValidator()
+	v.Required("name", input.Name)
 	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
+		graphql.WriteValidationErrors(w, v.Errors())
 		return
 	}
 
-	// Check for dry-run mode
-	dryRun := graphql.ParseBool(variables, "dryRun", false)
-
-	input := &DeploymentInput{
-		ProfileID: profileIDStr,
-		AgentID:   agentIDStr,
-		Action:    DeploymentActionApply,
-		DryRun:    dryRun,
-	}
-
-	deployment, err := service.DeployProfile(ctx, token, tenantID, user.UserID, input)
+	template, err := service.CreateTemplate(ctx, token, tenantID, user.UserID, input)
 	if err != nil {
-		graphql.WriteError(w, err, "deploy security profile")
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
+		graphql.WriteError(w, err, "create security template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"deploySecurityProfile": deployment,
+		"createSecurityTemplate": template,
 	})
 }
 
-func handleRollbackDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleUpdateTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
@@ -1140,24 +1747,39 @@ func handleRollbackDeployment(ctx context.Context, w http.ResponseWriter, variab
 
 	token, _ := middleware.GetTokenFromContext(ctx)
 
-	deploymentID, err := graphql.ParseUUID(variables, "deploymentId")
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	rollback, err := service.RollbackDeployment(ctx, token, tenantID, user.UserID, deploymentID)
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseTemplateInputWithValidation(inputRaw)
 	if err != nil {
-		graphql.WriteError(w, err, "rollback security deployment")
+		graphql.WriteInputError(w, err)
+		return
+	}
+
+	template, err := service.UpdateTemplate(ctx, token, tenantID, user.UserID, id, input)
+	if err != nil {
+		if graphql.WriteFieldErrors(w, err) {
+			return
+		}
+		graphql.WriteError(w, err, "update security template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"rollbackSecurityDeployment": rollback,
+		"updateSecurityTemplate": template,
 	})
 }
 
-func handleAuditDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleDeleteTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
@@ -1172,24 +1794,23 @@ func handleAuditDeployment(ctx context.Context, w http.ResponseWriter, variables
 
 	token, _ := middleware.GetTokenFromContext(ctx)
 
-	agentID, err := graphql.ParseUUID(variables, "agentId")
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	audit, err := service.AuditDeployment(ctx, token, tenantID, user.UserID, agentID)
-	if err != nil {
-		graphql.WriteError(w, err, "audit security deployment")
+	if err := service.DeleteTemplate(ctx, token, tenantID, user.UserID, id); err != nil {
+		graphql.WriteError(w, err, "delete security template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"auditSecurityDeployment": audit,
+		"deleteSecurityTemplate": true,
 	})
 }
 
-func handleFlushRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handlePublishTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
@@ -1204,66 +1825,2153 @@ func handleFlushRules(ctx context.Context, w http.ResponseWriter, variables map[
 
 	token, _ := middleware.GetTokenFromContext(ctx)
 
-	agentID, err := graphql.ParseUUID(variables, "agentId")
+	id, err := graphql.ParseUUID(variables, "id")
 	if err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
 
-	flush, err := service.FlushRules(ctx, token, tenantID, user.UserID, agentID)
+	template, err := service.PublishTemplate(ctx, token, tenantID, user.UserID, id)
 	if err != nil {
-		graphql.WriteError(w, err, "flush security rules")
+		graphql.WriteError(w, err, "publish security template")
 		return
 	}
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"flushSecurityRules": flush,
+		"publishSecurityTemplate": template,
 	})
 }
 
-// ========================================
-// Import/Export Handlers
-// ========================================
+func handleApplyTemplate(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
 
-func handleExportProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	templateID, err := graphql.ParseUUID(variables, "templateId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	failures, err := service.ApplyTemplateToProfile(ctx, token, tenantID, user.UserID, templateID, profileID)
+	if err != nil {
+		graphql.WriteError(w, err, "apply security template")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"applySecurityTemplate":            true,
+		"applySecurityTemplateFailedRules": failures,
+	})
+}
+
+// ========================================
+// Firewall Deployments Handlers
+// ========================================
+
+func handleListDeployments(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallDeployments)
+
+	var filter *FirewallDeploymentFilter
+	if f, ok := variables["filter"].(map[string]interface{}); ok {
+		filter = &FirewallDeploymentFilter{}
+		if search, ok := f["search"].(string); ok {
+			if len(search) > validation.MaxSearchLength {
+				graphql.WriteValidationError(w, "search term too long")
+				return
+			}
+			filter.Search = &search
+		}
+		if profileId, ok := f["profileId"].(string); ok {
+			v := validation.NewValidator()
+			v.UUID("profileId", profileId)
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			filter.ProfileID = &profileId
+		}
+		if agentId, ok := f["agentId"].(string); ok {
+			v := validation.NewValidator()
+			v.UUID("agentId", agentId)
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			filter.AgentID = &agentId
+		}
+		if action, ok := f["action"].(string); ok {
+			v := validation.NewValidator()
+			v.Enum("action", action, []string{
+				string(DeploymentActionApply), string(DeploymentActionRollback),
+				string(DeploymentActionAudit), string(DeploymentActionFlush),
+			})
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			a := DeploymentAction(action)
+			filter.Action = &a
+		}
+		if excluded, ok := f["excludeActions"].([]interface{}); ok {
+			for _, item := range excluded {
+				action, _ := item.(string)
+				if action == "" {
+					graphql.WriteValidationError(w, "excludeActions must contain action names")
+					return
+				}
+				if err := graphql.ValidateEnum(action, []string{
+					string(DeploymentActionApply), string(DeploymentActionRollback),
+					string(DeploymentActionAudit), string(DeploymentActionFlush),
+				}, "excludeActions"); err != nil {
+					graphql.WriteValidationError(w, err.Error())
+					return
+				}
+				filter.ExcludeActions = append(filter.ExcludeActions, DeploymentAction(action))
+			}
+		}
+		if status, ok := f["status"].(string); ok {
+			v := validation.NewValidator()
+			v.Enum("status", status, []string{
+				string(DeploymentStatusPending), string(DeploymentStatusDeploying),
+				string(DeploymentStatusApplied), string(DeploymentStatusRolledBack),
+				string(DeploymentStatusError), string(DeploymentStatusUnknown),
+			})
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			s := DeploymentStatus(status)
+			filter.Status = &s
+		}
+		if rolloutId, ok := f["rolloutId"].(string); ok {
+			v := validation.NewValidator()
+			v.UUID("rolloutId", rolloutId)
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			filter.RolloutID = &rolloutId
+		}
+		if batchId, ok := f["batchId"].(string); ok {
+			v := validation.NewValidator()
+			v.UUID("batchId", batchId)
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			filter.BatchID = &batchId
+		}
+		if changeRef, ok := f["changeRef"].(string); ok && changeRef != "" {
+			v := validation.NewValidator()
+			v.MaxLength("changeRef", changeRef, maxChangeRefLength).SafeString("changeRef", changeRef)
+			if v.HasErrors() {
+				graphql.WriteValidationError(w, v.FirstError())
+				return
+			}
+			filter.ChangeRef = &changeRef
+		}
+	}
+
+	// A cursor ("" for the first page) switches to keyset pagination and takes precedence over offset
+	if after, ok := variables["after"].(string); ok {
+		deployments, count, next, err := service.ListDeploymentsAfter(ctx, tenantID, filter, after, limit)
+		if err != nil {
+			graphql.WriteError(w, err, "list security deployments")
+			return
+		}
+		graphql.WriteSuccess(w, map[string]interface{}{
+			"securityDeployments":           deployments,
+			"securityDeploymentsCount":      count,
+			"securityDeploymentsNextCursor": next,
+		})
+		return
+	}
+
+	deployments, count, err := service.ListDeployments(ctx, tenantID, filter, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list security deployments")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeployments":      deployments,
+		"securityDeploymentsCount": count,
+	})
+}
+
+func handleGetDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	deployment, err := service.GetDeployment(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get security deployment")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeployment": deployment,
+	})
+}
+
+func handleGetAgentStatus(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	status, err := service.GetAgentStatus(ctx, tenantID, agentID)
+	if err != nil {
+		graphql.WriteError(w, err, "get security agent status")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityAgentStatus": status,
+	})
+}
+
+func handleDeploymentsByConfigHash(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	hash, err := graphql.ParseStringRequired(variables, "configHash")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+	if !configHashRegex.MatchString(hash) {
+		graphql.WriteValidationError(w, "configHash must be a hex-encoded SHA-256")
+		return
+	}
+
+	deployments, err := service.GetDeploymentsByConfigHash(ctx, tenantID, strings.ToLower(hash))
+	if err != nil {
+		graphql.WriteError(w, err, "list security deployments by config hash")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeploymentsByConfigHash": deployments,
+	})
+}
+
+// configHashRegex matches a hex-encoded SHA-256 config fingerprint
+var configHashRegex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func handleCountDeployments(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	count, err := service.CountDeployments(ctx, tenantID)
+	if err != nil {
+		graphql.WriteError(w, err, "count security deployments")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeploymentsCount": count,
+	})
+}
+
+func handleDeploymentStats(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	stats, err := service.GetDeploymentStats(ctx, tenantID)
+	if err != nil {
+		graphql.WriteError(w, err, "get security deployment stats")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeploymentStats": stats,
+	})
+}
+
+func handleProfileDeploymentMatrix(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	matrix, err := service.ProfileDeploymentMatrix(ctx, tenantID, profileID)
+	if err != nil {
+		graphql.WriteError(w, err, "get profile deployment matrix")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"profileDeploymentMatrix": matrix,
+	})
+}
+
+func handleListSecurityAgents(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	client := csdcore.GetClient()
+	agents, err := client.ListAgentsByCapability(ctx, token, "nftables")
+	if err != nil {
+		graphql.WriteError(w, err, "list security agents")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityAgents": agents,
+	})
+}
+
+func handleGetBackupContent(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	backupKey := graphql.ParseString(variables, "backupKey")
+	v := validation.NewValidator()
+	v.MaxLength("backupKey", backupKey, 255).SafeString("backupKey", backupKey)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	content, err := service.GetBackupContent(ctx, token, tenantID, agentID, backupKey)
+	if err != nil {
+		graphql.WriteError(w, err, "get backup content")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityBackupContent": content,
+	})
+}
+
+func handleSyncCounters(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	counters, err := service.SyncCounters(ctx, token, tenantID, agentID)
+	if err != nil {
+		graphql.WriteError(w, err, "read agent counters")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityAgentCounters": counters,
+	})
+}
+
+func handleGetRuleCounters(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	counters, err := service.GetRuleCounters(ctx, token, tenantID, agentID)
+	if err != nil {
+		graphql.WriteError(w, err, "read rule counters")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityRuleCounters": counters,
+	})
+}
+
+func handleGetRuleCounterHistory(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	ruleID, err := graphql.ParseUUID(variables, "ruleId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	var agentID *uuid.UUID
+	if value, ok := variables["agentId"].(string); ok && value != "" {
+		id, err := graphql.ParseUUID(variables, "agentId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		agentID = &id
+	}
+
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	if value, ok := variables["to"].(string); ok && value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			graphql.WriteValidationError(w, "to must be an RFC 3339 timestamp")
+			return
+		}
+		from = to.Add(-24 * time.Hour)
+	}
+	if value, ok := variables["from"].(string); ok && value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			graphql.WriteValidationError(w, "from must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	history, err := service.GetRuleCounterHistory(ctx, tenantID, ruleID, agentID, from, to)
+	if err != nil {
+		graphql.WriteError(w, err, "get rule counter history")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityRuleCounterHistory": history,
+	})
+}
+
+func handleDeployProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileIDStr, err := graphql.ParseStringRequired(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+	v := validation.NewValidator()
+	v.UUID("profileId", profileIDStr)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	// Target agent by ID or, alternatively, by hostname
+	agentIDStr, _ := variables["agentId"].(string)
+	agentHostname, _ := variables["agentHostname"].(string)
+	v = validation.NewValidator()
+	switch {
+	case agentIDStr != "" && agentHostname != "":
+		v.Errors().Add("agentId", "agentId and agentHostname are mutually exclusive", "INVALID_COMBINATION")
+	case agentIDStr != "":
+		v.UUID("agentId", agentIDStr)
+	case agentHostname != "":
+		v.MaxLength("agentHostname", agentHostname, validation.MaxNameLength).SafeString("agentHostname", agentHostname)
+	default:
+		v.Errors().Add("agentId", "agentId or agentHostname is required", "REQUIRED")
+	}
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	// Check for dry-run mode
+	dryRun := graphql.ParseBool(variables, "dryRun", false)
+
+	changeRef := graphql.ParseString(variables, "changeRef")
+	v = validation.NewValidator()
+	v.MaxLength("changeRef", changeRef, maxChangeRefLength).SafeString("changeRef", changeRef)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	input := &DeploymentInput{
+		ProfileID:     profileIDStr,
+		AgentID:       agentIDStr,
+		AgentHostname: agentHostname,
+		Action:        DeploymentActionApply,
+		DryRun:        dryRun,
+		ChangeRef:     changeRef,
+		Force:         graphql.ParseBool(variables, "force", false),
+		Verify:        graphql.ParseBool(variables, "verify", false),
+	}
+
+	deployment, err := service.DeployProfile(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "deploy security profile")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deploySecurityProfile": deployment,
+	})
+}
+
+func handleRollbackDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	deploymentID, err := graphql.ParseUUID(variables, "deploymentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Dry-run previews the restored config and its diff against the live ruleset
+	if graphql.ParseBool(variables, "dryRun", false) {
+		preview, err := service.PreviewRollback(ctx, token, tenantID, user.UserID, deploymentID)
+		if err != nil {
+			graphql.WriteError(w, err, "preview security rollback")
+			return
+		}
+		graphql.WriteSuccess(w, map[string]interface{}{
+			"rollbackSecurityDeploymentPreview": preview,
+		})
+		return
+	}
+
+	rollback, err := service.RollbackDeployment(ctx, token, tenantID, user.UserID, deploymentID)
+	if err != nil {
+		graphql.WriteError(w, err, "rollback security deployment")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"rollbackSecurityDeployment": rollback,
+	})
+}
+
+func handleRetryDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	deploymentID, err := graphql.ParseUUID(variables, "deploymentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	deployment, err := service.RetryDeployment(ctx, token, tenantID, user.UserID, deploymentID)
+	if err != nil {
+		graphql.WriteError(w, err, "retry deployment")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"retryDeployment": deployment,
+	})
+}
+
+func handleRestoreDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	deploymentID, err := graphql.ParseUUID(variables, "deploymentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	deployment, err := service.RestoreDeployment(ctx, token, tenantID, user.UserID, deploymentID)
+	if err != nil {
+		graphql.WriteError(w, err, "restore security deployment")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"restoreSecurityDeployment": deployment,
+	})
+}
+
+func handleAuditDeployment(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	audit, err := service.AuditDeployment(ctx, token, tenantID, user.UserID, agentID)
+	if err != nil {
+		graphql.WriteError(w, err, "audit security deployment")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"auditSecurityDeployment": audit,
+	})
+}
+
+func handleAuditAllAgents(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	summary, err := service.AuditAllAgents(ctx, token, tenantID, user.UserID)
+	if err != nil {
+		graphql.WriteError(w, err, "audit all agents")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"auditAllAgents": summary,
+	})
+}
+
+func handleListEnforcementPauses(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	pauses, err := service.ListEnforcementPauses(ctx, tenantID)
+	if err != nil {
+		graphql.WriteError(w, err, "list enforcement pauses")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityEnforcementPauses": pauses,
+	})
+}
+
+func handlePauseEnforcement(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	reason := graphql.ParseString(variables, "reason")
+	v := validation.NewValidator()
+	v.MaxLength("reason", reason, validation.MaxDescriptionLength).SafeString("reason", reason)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	// resumeAt is optional; without it enforcement stays paused until resumed
+	var resumeAt *time.Time
+	if value, ok := variables["resumeAt"].(string); ok && value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			graphql.WriteValidationError(w, "resumeAt must be an RFC 3339 timestamp")
+			return
+		}
+		resumeAt = &t
+	}
+
+	pause, err := service.PauseEnforcement(ctx, token, tenantID, user.UserID, agentID, reason, resumeAt)
+	if err != nil {
+		graphql.WriteError(w, err, "pause enforcement")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"pauseSecurityEnforcement": pause,
+	})
+}
+
+func handleResumeEnforcement(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := service.ResumeEnforcement(ctx, token, tenantID, agentID); err != nil {
+		graphql.WriteError(w, err, "resume enforcement")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"resumeSecurityEnforcement": true,
+	})
+}
+
+func handleComplianceReport(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	baselineProfileID, err := graphql.ParseUUID(variables, "baselineProfileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	agentIDs, err := graphql.ParseBulkUUIDs(variables, "agentIds")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	report, err := service.ComplianceReport(ctx, token, tenantID, user.UserID, baselineProfileID, agentIDs)
+	if err != nil {
+		graphql.WriteError(w, err, "generate compliance report")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityComplianceReport": report,
+	})
+}
+
+func handleDiffProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	diff, err := service.DiffDeployment(ctx, token, tenantID, profileID, agentID)
+	if err != nil {
+		graphql.WriteError(w, err, "diff security profile")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"diffSecurityProfile": diff,
+	})
+}
+
+func handleFlushRules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	agentID, err := graphql.ParseUUID(variables, "agentId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	input := &FlushInput{
+		AgentID:          agentID,
+		Scope:            FlushScopeManaged,
+		ConfirmFullFlush: graphql.ParseBool(variables, "confirmFullFlush", false),
+	}
+	v := validation.NewValidator()
+	if scope, ok := variables["scope"].(string); ok && scope != "" {
+		v.Enum("scope", scope, []string{string(FlushScopeManaged), string(FlushScopeAll)})
+		input.Scope = FlushScope(scope)
+	}
+	if tableName, ok := variables["tableName"].(string); ok && tableName != "" {
+		v.MaxLength("tableName", tableName, validation.MaxNameLength)
+		if !nftTableNameRegex.MatchString(tableName) {
+			v.Errors().Add("tableName", "tableName must be a valid nftables identifier", "INVALID_FORMAT")
+		}
+		input.TableName = tableName
+	}
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	flush, err := service.FlushRules(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "flush security rules")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"flushSecurityRules": flush,
+	})
+}
+
+// ========================================
+// In-flight Operations Handlers
+// ========================================
+
+func handleListInFlightOperations(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	operations, err := service.ListInFlightOperations(ctx, tenantID)
+	if err != nil {
+		graphql.WriteError(w, err, "list in-flight security operations")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityInFlightOperations": operations,
+	})
+}
+
+func handleCancelOperation(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	deployment, err := service.CancelOperation(ctx, token, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "cancel security operation")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"cancelSecurityOperation": deployment,
+	})
+}
+
+// ========================================
+// IP Sets Handlers
+// ========================================
+
+func handleListIPSets(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallIPSets)
+
+	search, err := graphql.ParseFilterSearch(graphql.GetFilter(variables))
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	sets, count, err := service.ListIPSets(ctx, tenantID, search, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list IP sets")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityIPSets":      sets,
+		"securityIPSetsCount": count,
+	})
+}
+
+func handleGetIPSet(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	set, err := service.GetIPSet(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get IP set")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityIPSet": set,
+	})
+}
+
+func handleGetIPSetUsage(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	usage, err := service.GetIPSetUsage(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get IP set usage")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityIPSetUsage": usage,
+	})
+}
+
+func handleCreateIPSet(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseIPSetInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	set, err := service.CreateIPSet(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "create IP set")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"createSecurityIPSet": set,
+	})
+}
+
+func handleUpdateIPSet(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseIPSetInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	set, err := service.UpdateIPSet(ctx, token, tenantID, id, input)
+	if err != nil {
+		graphql.WriteError(w, err, "update IP set")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"updateSecurityIPSet": set,
+	})
+}
+
+func handleDeleteIPSet(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := service.DeleteIPSet(ctx, token, tenantID, id); err != nil {
+		graphql.WriteError(w, err, "delete IP set")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deleteSecurityIPSet": true,
+	})
+}
+
+// ========================================
+// Agent Groups Handlers
+// ========================================
+
+func handleListAgentGroups(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallAgentGroups)
+
+	search, err := graphql.ParseFilterSearch(graphql.GetFilter(variables))
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	groups, count, err := service.ListAgentGroups(ctx, tenantID, search, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list agent groups")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityAgentGroups":      groups,
+		"securityAgentGroupsCount": count,
+	})
+}
+
+func handleGetAgentGroup(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	group, err := service.GetAgentGroup(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get agent group")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityAgentGroup": group,
+	})
+}
+
+func handleCreateAgentGroup(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseAgentGroupInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	v := validation.NewValidator()
+	v.Required("name", input.Name)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	group, err := service.CreateAgentGroup(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "create agent group")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"createSecurityAgentGroup": group,
+	})
+}
+
+func handleUpdateAgentGroup(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseAgentGroupInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	group, err := service.UpdateAgentGroup(ctx, token, tenantID, id, input)
+	if err != nil {
+		graphql.WriteError(w, err, "update agent group")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"updateSecurityAgentGroup": group,
+	})
+}
+
+func handleDeleteAgentGroup(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := service.DeleteAgentGroup(ctx, token, tenantID, id); err != nil {
+		graphql.WriteError(w, err, "delete agent group")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deleteSecurityAgentGroup": true,
+	})
+}
+
+// ========================================
+// Rolling Deployments Handlers
+// ========================================
+
+func handleListRollouts(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallRollouts)
+
+	var profileID *uuid.UUID
+	if _, ok := variables["profileId"]; ok {
+		id, err := graphql.ParseUUID(variables, "profileId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		profileID = &id
+	}
+
+	rollouts, count, err := service.ListRollouts(ctx, tenantID, profileID, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list security rollouts")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityRollouts":      rollouts,
+		"securityRolloutsCount": count,
+	})
+}
+
+func handleGetRollout(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	rollout, err := service.GetRollout(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get security rollout")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityRollout": rollout,
+	})
+}
+
+func handleDeployProfileToGroup(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	groupID, err := graphql.ParseUUID(variables, "groupId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	batchSize := graphql.ParseInt(variables, "batchSize", 1)
+	pauseSeconds := graphql.ParseInt(variables, "pauseSeconds", 30)
+	changeRef := graphql.ParseString(variables, "changeRef")
+
+	v := validation.NewValidator()
+	v.Range("batchSize", batchSize, 1, validation.MaxBulkIDs)
+	v.Range("pauseSeconds", pauseSeconds, 0, 3600)
+	v.MaxLength("changeRef", changeRef, maxChangeRefLength).SafeString("changeRef", changeRef)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	input := &RolloutInput{
+		ProfileID:    profileID.String(),
+		GroupID:      groupID.String(),
+		BatchSize:    batchSize,
+		PauseSeconds: pauseSeconds,
+		ChangeRef:    changeRef,
+		Force:        graphql.ParseBool(variables, "force", false),
+	}
+
+	rollout, err := service.DeployProfileToGroup(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "deploy security profile to group")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deploySecurityProfileToGroup": rollout,
+	})
+}
+
+func handleDeployProfileBulk(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Every agent ID must be valid; dropping one silently would leave an agent undeployed
+	idsRaw, _ := variables["agentIds"].([]interface{})
+	changeRef := graphql.ParseString(variables, "changeRef")
+	v := validation.NewValidator()
+	v.MinItems("agentIds", len(idsRaw), 1).MaxItems("agentIds", len(idsRaw), maxBulkDeployAgents)
+	v.MaxLength("changeRef", changeRef, maxChangeRefLength).SafeString("changeRef", changeRef)
+	agentIDs := make([]uuid.UUID, 0, len(idsRaw))
+	for i, raw := range idsRaw {
+		idStr, _ := raw.(string)
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			v.Errors().Add(fmt.Sprintf("agentIds[%d]", i), "invalid agent ID", "INVALID_UUID")
+			continue
+		}
+		agentIDs = append(agentIDs, id)
+	}
+	if v.HasErrors() {
+		graphql.WriteValidationErrors(w, v.Errors())
+		return
+	}
+
+	input := &BulkDeploymentInput{
+		ProfileID: profileID.String(),
+		AgentIDs:  agentIDs,
+		ChangeRef: changeRef,
+		Force:     graphql.ParseBool(variables, "force", false),
+		Verify:    graphql.ParseBool(variables, "verify", false),
+	}
+
+	batch, err := service.DeployProfileToAgents(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "deploy security profile to agents")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deploySecurityProfileBulk": batch,
+	})
+}
+
+func handleGetDeploymentBatch(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	batchID, err := graphql.ParseUUID(variables, "batchId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	batch, err := service.GetDeploymentBatch(ctx, tenantID, batchID)
+	if err != nil {
+		graphql.WriteError(w, err, "get security deployment batch")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securityDeploymentBatch": batch,
+	})
+}
+
+func handleResumeDeploymentBatch(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	// A deployment batch is a rolling deployment; batchId is the rollout ID
+	batchID, err := graphql.ParseUUID(variables, "batchId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	rollout, err := service.ResumeRollout(ctx, token, tenantID, user.UserID, batchID)
+	if err != nil {
+		graphql.WriteError(w, err, "resume deployment batch")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"resumeDeploymentBatch": rollout,
+	})
+}
+
+func handleCancelRollout(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	rollout, err := service.CancelRollout(ctx, token, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "cancel security rollout")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"cancelSecurityRollout": rollout,
+	})
+}
+
+// ========================================
+// Deployment Schedules Handlers
+// ========================================
+
+func handleListSchedules(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	limit, offset := graphql.ParsePaginationFor(variables, pagination.ResourceFirewallSchedules)
+
+	var profileID *uuid.UUID
+	if _, ok := variables["profileId"]; ok {
+		id, err := graphql.ParseUUID(variables, "profileId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		profileID = &id
+	}
+
+	schedules, count, err := service.ListSchedules(ctx, tenantID, profileID, limit, offset)
+	if err != nil {
+		graphql.WriteError(w, err, "list security schedules")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securitySchedules":      schedules,
+		"securitySchedulesCount": count,
+	})
+}
+
+func handleGetSchedule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	schedule, err := service.GetSchedule(ctx, tenantID, id)
+	if err != nil {
+		graphql.WriteError(w, err, "get security schedule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"securitySchedule": schedule,
+	})
+}
+
+func handleCreateSchedule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseScheduleInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	v := validation.NewValidator()
+	v.Required("profileId", input.ProfileID)
+	v.Required("agentId", input.AgentID)
+	v.Required("cronExpr", input.CronExpr)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	schedule, err := service.CreateSchedule(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		graphql.WriteError(w, err, "create security schedule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"createSecuritySchedule": schedule,
+	})
+}
+
+func handleUpdateSchedule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	input, err := parseScheduleInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	schedule, err := service.UpdateSchedule(ctx, token, tenantID, id, input)
+	if err != nil {
+		graphql.WriteError(w, err, "update security schedule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"updateSecuritySchedule": schedule,
+	})
+}
+
+func handleDeleteSchedule(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	id, err := graphql.ParseUUID(variables, "id")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := service.DeleteSchedule(ctx, token, tenantID, id); err != nil {
+		graphql.WriteError(w, err, "delete security schedule")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"deleteSecuritySchedule": true,
+	})
+}
+
+// ========================================
+// Import/Export Handlers
+// ========================================
+
+func handleExportProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	// Recorded in the export; the email is more meaningful than the ID when available
+	exportedBy := ""
+	if user, ok := middleware.GetUserFromContext(ctx); ok {
+		exportedBy = user.Email
+		if exportedBy == "" {
+			exportedBy = user.UserID.String()
+		}
+	}
+
+	profileID, err := graphql.ParseUUID(variables, "profileId")
+	if err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Optional target groups to bundle; referenced IP sets are always included
+	var groupIDs []uuid.UUID
+	if rawIDs, ok := variables["agentGroupIds"].([]interface{}); ok {
+		for _, id := range rawIDs {
+			idStr, _ := id.(string)
+			groupID, err := uuid.Parse(idStr)
+			if err != nil {
+				graphql.WriteValidationError(w, "invalid agentGroupId: "+idStr)
+				return
+			}
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+
+	export, err := service.ExportProfile(ctx, token, tenantID, profileID, groupIDs, exportedBy)
+	if err != nil {
+		graphql.WriteError(w, err, "export security profile")
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"exportSecurityProfile": export,
+	})
+}
+
+func handleImportProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		graphql.WriteUnauthorized(w)
+		return
+	}
+
+	token, _ := middleware.GetTokenFromContext(ctx)
+
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
+		return
+	}
+
+	if err := migrateProfileImport(inputRaw); err != nil {
+		graphql.WriteValidationError(w, err.Error())
+		return
+	}
+	input, err := parseProfileImportInputWithValidation(inputRaw)
+	if err != nil {
+		graphql.WriteInputError(w, err)
+		return
+	}
+
+	// Validate required fields
+	v := validation.NewValidator()
+	v.Required("name", input.Name)
+	if v.HasErrors() {
+		graphql.WriteValidationError(w, v.FirstError())
+		return
+	}
+
+	profile, err := service.ImportProfile(ctx, token, tenantID, user.UserID, input)
+	if err != nil {
+		if !graphql.WriteFieldErrors(w, err) {
+			graphql.WriteError(w, err, "import security profile")
+		}
+		return
+	}
+
+	graphql.WriteSuccess(w, map[string]interface{}{
+		"importSecurityProfile": profile,
+	})
+}
+
+// profileImportFields lists the keys an import envelope may carry; the export metadata
+// (exportedAt, exportedBy, appVersion) is accepted so an export can be re-imported unchanged
+var profileImportFields = map[string]bool{
+	"schemaVersion": true, "name": true, "description": true, "rules": true,
+	"ipSets": true, "agentGroups": true, "exportedAt": true, "exportedBy": true, "appVersion": true,
+}
+
+// schemaVersionRegex matches an export format version such as "1.0"
+var schemaVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// validateProfileImportEnvelope checks the shape of an import before its content is parsed
+func validateProfileImportEnvelope(v *validation.Validator, inputRaw map[string]interface{}) {
+	errs := v.Errors()
+	keys := make([]string, 0, len(inputRaw))
+	for key := range inputRaw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !profileImportFields[key] {
+			errs.Add(key, fmt.Sprintf("unknown field %s", key), "UNKNOWN_FIELD")
+		}
+	}
+
+	for _, key := range []string{"schemaVersion", "name", "description", "exportedAt", "exportedBy", "appVersion"} {
+		if value, ok := inputRaw[key]; ok {
+			if _, ok := value.(string); !ok {
+				errs.Add(key, key+" must be a string", "INVALID_FORMAT")
+			}
+		}
+	}
+	if version, ok := inputRaw["schemaVersion"].(string); ok && !schemaVersionRegex.MatchString(version) {
+		errs.Add("schemaVersion", "schemaVersion must look like MAJOR.MINOR", "INVALID_FORMAT")
+	}
+
+	if _, ok := inputRaw["rules"]; !ok {
+		errs.Add("rules", "rules is required", "REQUIRED")
+	}
+	for _, key := range []string{"rules", "ipSets", "agentGroups"} {
+		if value, ok := inputRaw[key]; ok {
+			if _, ok := value.([]interface{}); !ok {
+				errs.Add(key, key+" must be a list", "INVALID_FORMAT")
+			}
+		}
+	}
+}
+
+func parseProfileImportInputWithValidation(inputRaw map[string]interface{}) (*ProfileImportInput, error) {
+	v := validation.NewValidator()
+	input := &ProfileImportInput{}
+	validateProfileImportEnvelope(v, inputRaw)
+
+	if version, ok := inputRaw["schemaVersion"].(string); ok {
+		input.SchemaVersion = version
+	}
+	if name, ok := inputRaw["name"].(string); ok {
+		v.MaxLength("name", name, validation.MaxNameLength).SafeString("name", name)
+		input.Name = name
+	}
+	if description, ok := inputRaw["description"].(string); ok {
+		v.MaxLength("description", description, validation.MaxDescriptionLength)
+		input.Description = description
+	}
+
+	if rules, ok := inputRaw["rules"].([]interface{}); ok {
+		// Limit number of rules that can be imported
+		v.MaxItems("rules", len(rules), validation.MaxBulkIDs)
+		input.Rules = make([]TemplateRuleDefinition, 0, len(rules))
+		// Every rule is checked so the caller sees all problems at once, each listed by index
+		for i, r := range rules {
+			field := fmt.Sprintf("rules[%d]", i)
+			ruleMap, ok := r.(map[string]interface{})
+			if !ok {
+				v.Errors().Add(field, "rule must be an object", "INVALID_FORMAT")
+				continue
+			}
+			rule, errs := parseImportRuleDefinition(ruleMap)
+			if rule.Chain == "" {
+				errs.Add("rules.chain", "chain is required", "REQUIRED")
+			}
+			if rule.Action == "" {
+				errs.Add("rules.action", "action is required", "REQUIRED")
+			}
+			for _, e := range errs.Errors {
+				v.Errors().Add(field+"."+strings.TrimPrefix(e.Field, "rules."), e.Message, e.Code)
+			}
+			input.Rules = append(input.Rules, rule)
+		}
+	}
+
+	if ipSets, ok := inputRaw["ipSets"].([]interface{}); ok {
+		v.MaxItems("ipSets", len(ipSets), validation.MaxBulkIDs)
+		for i, s := range ipSets {
+			setMap, ok := s.(map[string]interface{})
+			if !ok {
+				v.Errors().Add(fmt.Sprintf("ipSets[%d]", i), "IP set must be an object", "INVALID_FORMAT")
+				continue
+			}
+			set := IPSetExport{}
+			if name, ok := setMap["name"].(string); ok {
+				v.MaxLength("ipSets.name", name, validation.MaxNameLength)
+				set.Name = name
+			}
+			if description, ok := setMap["description"].(string); ok {
+				v.MaxLength("ipSets.description", description, validation.MaxDescriptionLength)
+				set.Description = description
+			}
+			if elements, ok := setMap["elements"].([]interface{}); ok {
+				v.MaxItems("ipSets.elements", len(elements), validation.MaxArrayLength)
+				for _, element := range elements {
+					if elementStr, ok := element.(string); ok {
+						set.Elements = append(set.Elements, elementStr)
+					}
+				}
+			}
+			input.IPSets = append(input.IPSets, set)
+		}
+	}
+
+	if agentGroups, ok := inputRaw["agentGroups"].([]interface{}); ok {
+		v.MaxItems("agentGroups", len(agentGroups), validation.MaxBulkIDs)
+		for i, g := range agentGroups {
+			groupMap, ok := g.(map[string]interface{})
+			if !ok {
+				v.Errors().Add(fmt.Sprintf("agentGroups[%d]", i), "agent group must be an object", "INVALID_FORMAT")
+				continue
+			}
+			group := AgentGroupExport{}
+			if name, ok := groupMap["name"].(string); ok {
+				v.MaxLength("agentGroups.name", name, validation.MaxNameLength).SafeString("agentGroups.name", name)
+				group.Name = name
+			}
+			if description, ok := groupMap["description"].(string); ok {
+				v.MaxLength("agentGroups.description", description, validation.MaxDescriptionLength)
+				group.Description = description
+			}
+			if hostnames, ok := groupMap["agentHostnames"].([]interface{}); ok {
+				v.MaxItems("agentGroups.agentHostnames", len(hostnames), validation.MaxBulkIDs)
+				for _, h := range hostnames {
+					if hostname, ok := h.(string); ok {
+						v.MaxLength("agentGroups.agentHostnames", hostname, validation.MaxNameLength).SafeString("agentGroups.agentHostnames", hostname)
+						group.AgentHostnames = append(group.AgentHostnames, hostname)
+					}
+				}
+			}
+			input.AgentGroups = append(input.AgentGroups, group)
+		}
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return input, nil
+}
+
+// parseImportRuleDefinition parses one imported rule, returning it along with its validation errors
+func parseImportRuleDefinition(ruleMap map[string]interface{}) (TemplateRuleDefinition, *validation.ValidationErrors) {
+	v := validation.NewValidator()
+	rule := TemplateRuleDefinition{}
+
+	if name, ok := ruleMap["name"].(string); ok {
+		v.MaxLength("rules.name", name, validation.MaxNameLength).SafeString("rules.name", name)
+		rule.Name = name
+	}
+	if description, ok := ruleMap["description"].(string); ok {
+		v.MaxLength("rules.description", description, validation.MaxDescriptionLength)
+		rule.Description = description
+	}
+	if chain, ok := ruleMap["chain"].(string); ok {
+		v.Enum("rules.chain", chain, graphql.RuleChainValues)
+		rule.Chain = RuleChain(chain)
+	}
+	if priority, ok := ruleMap["priority"].(float64); ok {
+		p := int(priority)
+		v.Range("rules.priority", p, 0, 65535)
+		rule.Priority = p
+	}
+	if protocol, ok := ruleMap["protocol"].(string); ok {
+		v.Enum("rules.protocol", protocol, graphql.RuleProtocolValues)
+		rule.Protocol = normalizeRuleProtocol(protocol)
+	}
+	if sourceIp, ok := ruleMap["sourceIp"].(string); ok {
+		if sourceIp != "" && sourceIp != "any" {
+			v.SafeString("rules.sourceIp", sourceIp)
+		}
+		rule.SourceIP = sourceIp
+	}
+	if sourcePort, ok := ruleMap["sourcePort"].(string); ok {
+		rule.SourcePort = parsePortField(v, "rules.sourcePort", sourcePort)
+	}
+	if destIp, ok := ruleMap["destIp"].(string); ok {
+		if destIp != "" && destIp != "any" {
+			v.SafeString("rules.destIp", destIp)
+		}
+		rule.DestIP = destIp
+	}
+	if destPort, ok := ruleMap["destPort"].(string); ok {
+		rule.DestPort = parsePortField(v, "rules.destPort", destPort)
+	}
+	parseRuleNegation(ruleMap, &rule)
+	parseRuleConnLimit(v, ruleMap, &rule)
+	if action, ok := ruleMap["action"].(string); ok {
+		v.Enum("rules.action", action, graphql.RuleActionValues)
+		rule.Action = RuleAction(action)
+	}
+	if comment, ok := ruleMap["comment"].(string); ok {
+		v.MaxLength("rules.comment", comment, 255).SafeString("rules.comment", comment)
+		rule.Comment = comment
+	}
+
+	return rule, v.Errors()
+}
+
+// parseRuleConnLimit reads the connection limit of a template or imported rule
+func parseRuleConnLimit(v *validation.Validator, ruleMap map[string]interface{}, rule *TemplateRuleDefinition) {
+	if count, ok := ruleMap["connLimitCount"].(float64); ok {
+		rule.ConnLimitCount = int(count)
+		v.Range("rules.connLimitCount", rule.ConnLimitCount, 0, 65535)
+	}
+	if mask, ok := ruleMap["connLimitMask"].(float64); ok {
+		rule.ConnLimitMask = int(mask)
+		v.Range("rules.connLimitMask", rule.ConnLimitMask, 0, 128)
+	}
+}
+
+// parseRuleNegation reads the match negation flags of a template or imported rule
+func parseRuleNegation(ruleMap map[string]interface{}, rule *TemplateRuleDefinition) {
+	rule.NegateProtocol, _ = ruleMap["negateProtocol"].(bool)
+	rule.NegateSourcePort, _ = ruleMap["negateSourcePort"].(bool)
+	rule.NegateDestPort, _ = ruleMap["negateDestPort"].(bool)
+}
+
+func handleValidateProfileImport(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	token, _ := middleware.GetTokenFromContext(ctx)
-
-	profileID, err := graphql.ParseUUID(variables, "profileId")
-	if err != nil {
-		graphql.WriteValidationError(w, err.Error())
+	inputRaw, ok := variables["input"].(map[string]interface{})
+	if !ok {
+		graphql.WriteValidationError(w, "input is required")
 		return
 	}
 
-	export, err := service.ExportProfile(ctx, token, tenantID, profileID)
-	if err != nil {
-		graphql.WriteError(w, err, "export security profile")
+	if err := migrateProfileImport(inputRaw); err != nil {
+		graphql.WriteValidationError(w, err.Error())
 		return
 	}
+	input, parseErrors := parseProfileImportForValidation(inputRaw)
+
+	result := service.ValidateProfileImport(ctx, tenantID, input)
+	result.Errors = append(parseErrors, result.Errors...)
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Index < result.Errors[j].Index })
+	result.Valid = len(result.Errors) == 0
 
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"exportSecurityProfile": export,
+		"validateSecurityProfileImport": result,
 	})
 }
 
-func handleImportProfile(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
+func handleCheckPolicy(ctx context.Context, w http.ResponseWriter, variables map[string]interface{}, service *Service) {
 	tenantID, ok := middleware.GetTenantIDFromContext(ctx)
 	if !ok {
 		graphql.WriteUnauthorized(w)
 		return
 	}
 
-	user, ok := middleware.GetUserFromContext(ctx)
-	if !ok {
-		graphql.WriteUnauthorized(w)
-		return
-	}
-
 	token, _ := middleware.GetTokenFromContext(ctx)
 
 	inputRaw, ok := variables["input"].(map[string]interface{})
@@ -1272,35 +3980,67 @@ func handleImportProfile(ctx context.Context, w http.ResponseWriter, variables m
 		return
 	}
 
-	input, err := parseProfileImportInputWithValidation(inputRaw)
-	if err != nil {
+	if err := migrateProfileImport(inputRaw); err != nil {
 		graphql.WriteValidationError(w, err.Error())
 		return
 	}
+	input, parseErrors := parseProfileImportForValidation(inputRaw)
 
-	// Validate required fields
-	v := validation.NewValidator()
-	v.Required("name", input.Name)
-	if v.HasErrors() {
-		graphql.WriteValidationError(w, v.FirstError())
-		return
+	// Optional profile settings in the createSecurityProfile input format
+	var profileInput *FirewallProfileInput
+	if profileRaw, ok := variables["profile"].(map[string]interface{}); ok {
+		var err error
+		profileInput, err = parseProfileInputWithValidation(profileRaw)
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
 	}
 
-	profile, err := service.ImportProfile(ctx, token, tenantID, user.UserID, input)
+	var agentID *uuid.UUID
+	if value, ok := variables["agentId"].(string); ok && value != "" {
+		id, err := graphql.ParseUUID(variables, "agentId")
+		if err != nil {
+			graphql.WriteValidationError(w, err.Error())
+			return
+		}
+		agentID = &id
+	}
+	// Definitions that do not parse are never sent to an agent
+	if len(parseErrors) > 0 {
+		agentID = nil
+	}
+
+	result, err := service.CheckPolicy(ctx, token, tenantID, input, profileInput, agentID)
 	if err != nil {
-		graphql.WriteError(w, err, "import security profile")
+		graphql.WriteError(w, err, "check security policy")
 		return
 	}
 
+	if len(parseErrors) > 0 {
+		result.Validation.Errors = append(parseErrors, result.Validation.Errors...)
+		result.Validation.Valid = false
+		result.Config = ""
+		result.Passed = false
+	}
+	sort.SliceStable(result.Validation.Errors, func(i, j int) bool {
+		return result.Validation.Errors[i].Index < result.Validation.Errors[j].Index
+	})
+
 	graphql.WriteSuccess(w, map[string]interface{}{
-		"importSecurityProfile": profile,
+		"checkSecurityPolicy": result,
 	})
 }
 
-func parseProfileImportInputWithValidation(inputRaw map[string]interface{}) (*ProfileImportInput, error) {
+// ========================================
+// Helper Functions
+// ========================================
+
+// parseProfileImportForValidation parses an import definition, collecting every problem instead of stopping at the first
+func parseProfileImportForValidation(inputRaw map[string]interface{}) (*ProfileImportInput, []ImportRuleError) {
+	var parseErrors []ImportRuleError
 	v := validation.NewValidator()
 	input := &ProfileImportInput{}
-
 	if name, ok := inputRaw["name"].(string); ok {
 		v.MaxLength("name", name, validation.MaxNameLength).SafeString("name", name)
 		input.Name = name
@@ -1309,91 +4049,27 @@ func parseProfileImportInputWithValidation(inputRaw map[string]interface{}) (*Pr
 		v.MaxLength("description", description, validation.MaxDescriptionLength)
 		input.Description = description
 	}
-
 	if rules, ok := inputRaw["rules"].([]interface{}); ok {
-		// Limit number of rules that can be imported
 		v.MaxItems("rules", len(rules), validation.MaxBulkIDs)
-		input.Rules = make([]TemplateRuleDefinition, 0, len(rules))
 		for i, r := range rules {
-			if ruleMap, ok := r.(map[string]interface{}); ok {
-				rule := TemplateRuleDefinition{}
-				if name, ok := ruleMap["name"].(string); ok {
-					v.MaxLength("rules.name", name, validation.MaxNameLength).SafeString("rules.name", name)
-					rule.Name = name
-				}
-				if description, ok := ruleMap["description"].(string); ok {
-					v.MaxLength("rules.description", description, validation.MaxDescriptionLength)
-					rule.Description = description
-				}
-				if chain, ok := ruleMap["chain"].(string); ok {
-					if err := graphql.ValidateEnum(chain, graphql.RuleChainValues, "rules.chain"); err != nil {
-						return nil, err
-					}
-					rule.Chain = RuleChain(chain)
-				}
-				if priority, ok := ruleMap["priority"].(float64); ok {
-					p := int(priority)
-					v.Range("rules.priority", p, 0, 65535)
-					rule.Priority = p
-				}
-				if protocol, ok := ruleMap["protocol"].(string); ok {
-					if err := graphql.ValidateEnum(protocol, graphql.RuleProtocolValues, "rules.protocol"); err != nil {
-						return nil, err
-					}
-					rule.Protocol = RuleProtocol(protocol)
-				}
-				if sourceIp, ok := ruleMap["sourceIp"].(string); ok {
-					if sourceIp != "" && sourceIp != "any" {
-						v.SafeString("rules.sourceIp", sourceIp)
-					}
-					rule.SourceIP = sourceIp
-				}
-				if sourcePort, ok := ruleMap["sourcePort"].(string); ok {
-					if sourcePort != "" {
-						v.PortRange("rules.sourcePort", sourcePort)
-					}
-					rule.SourcePort = sourcePort
-				}
-				if destIp, ok := ruleMap["destIp"].(string); ok {
-					if destIp != "" && destIp != "any" {
-						v.SafeString("rules.destIp", destIp)
-					}
-					rule.DestIP = destIp
-				}
-				if destPort, ok := ruleMap["destPort"].(string); ok {
-					if destPort != "" {
-						v.PortRange("rules.destPort", destPort)
-					}
-					rule.DestPort = destPort
-				}
-				if action, ok := ruleMap["action"].(string); ok {
-					if err := graphql.ValidateEnum(action, graphql.RuleActionValues, "rules.action"); err != nil {
-						return nil, err
-					}
-					rule.Action = RuleAction(action)
-				}
-				if comment, ok := ruleMap["comment"].(string); ok {
-					v.MaxLength("rules.comment", comment, 255).SafeString("rules.comment", comment)
-					rule.Comment = comment
-				}
-				input.Rules = append(input.Rules, rule)
-				// Stop early if we've hit too many errors
-				if i > 0 && v.HasErrors() {
-					break
-				}
+			ruleMap, ok := r.(map[string]interface{})
+			if !ok {
+				parseErrors = append(parseErrors, ImportRuleError{Index: i, Message: "rule must be an object", Code: "INVALID_FORMAT"})
+				continue
 			}
+			rule, errs := parseImportRuleDefinition(ruleMap)
+			for _, e := range errs.Errors {
+				parseErrors = append(parseErrors, ImportRuleError{Index: i, Name: rule.Name, Field: e.Field, Message: e.Message, Code: e.Code})
+			}
+			input.Rules = append(input.Rules, rule)
 		}
 	}
-
-	if v.HasErrors() {
-		return nil, v.Errors()
+	for _, e := range v.Errors().Errors {
+		parseErrors = append(parseErrors, ImportRuleError{Index: -1, Field: e.Field, Message: e.Message, Code: e.Code})
 	}
-	return input, nil
-}
 
-// ========================================
-// Helper Functions
-// ========================================
+	return input, parseErrors
+}
 
 func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error) {
 	input := &FirewallRuleInput{}
@@ -1422,7 +4098,7 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		if err := graphql.ValidateEnum(protocol, graphql.RuleProtocolValues, "protocol"); err != nil {
 			return nil, err
 		}
-		input.Protocol = RuleProtocol(protocol)
+		input.Protocol = normalizeRuleProtocol(protocol)
 	}
 	if sourceIp, ok := inputRaw["sourceIp"].(string); ok {
 		if sourceIp != "" && sourceIp != "any" {
@@ -1431,10 +4107,7 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		input.SourceIP = sourceIp
 	}
 	if sourcePort, ok := inputRaw["sourcePort"].(string); ok {
-		if sourcePort != "" {
-			v.PortRange("sourcePort", sourcePort)
-		}
-		input.SourcePort = sourcePort
+		input.SourcePort = parsePortField(v, "sourcePort", sourcePort)
 	}
 	if destIp, ok := inputRaw["destIp"].(string); ok {
 		if destIp != "" && destIp != "any" {
@@ -1443,10 +4116,7 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		input.DestIP = destIp
 	}
 	if destPort, ok := inputRaw["destPort"].(string); ok {
-		if destPort != "" {
-			v.PortRange("destPort", destPort)
-		}
-		input.DestPort = destPort
+		input.DestPort = parsePortField(v, "destPort", destPort)
 	}
 	if action, ok := inputRaw["action"].(string); ok {
 		if err := graphql.ValidateEnum(action, graphql.RuleActionValues, "action"); err != nil {
@@ -1468,6 +4138,30 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		v.MaxLength("ctState", ctState, 128).SafeString("ctState", ctState)
 		input.CTState = ctState
 	}
+	// ICMP matching; type names are checked against the rule's family in validateRuleSemantics
+	if icmpType, ok := inputRaw["icmpType"].(string); ok {
+		v.MaxLength("icmpType", icmpType, 255).SafeString("icmpType", icmpType)
+		input.ICMPType = icmpType
+	}
+	// Match negation
+	if negate, ok := inputRaw["negateProtocol"].(bool); ok {
+		input.NegateProtocol = &negate
+	}
+	if negate, ok := inputRaw["negateSourcePort"].(bool); ok {
+		input.NegateSourcePort = &negate
+	}
+	if negate, ok := inputRaw["negateDestPort"].(bool); ok {
+		input.NegateDestPort = &negate
+	}
+	// QoS matching
+	if dscp, ok := inputRaw["dscp"].(string); ok {
+		v.DSCP("dscp", dscp)
+		input.DSCP = dscp
+	}
+	if packetLength, ok := inputRaw["packetLength"].(string); ok {
+		v.PacketLength("packetLength", packetLength)
+		input.PacketLength = packetLength
+	}
 	// Rate limiting
 	if rateLimit, ok := inputRaw["rateLimit"].(string); ok {
 		v.MaxLength("rateLimit", rateLimit, 64).SafeString("rateLimit", rateLimit)
@@ -1482,6 +4176,17 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		v.MaxLength("limitOver", limitOver, 64).SafeString("limitOver", limitOver)
 		input.LimitOver = limitOver
 	}
+	// Connection limiting
+	if connLimitCount, ok := inputRaw["connLimitCount"].(float64); ok {
+		count := int(connLimitCount)
+		v.Range("connLimitCount", count, 0, 65535)
+		input.ConnLimitCount = count
+	}
+	if connLimitMask, ok := inputRaw["connLimitMask"].(float64); ok {
+		mask := int(connLimitMask)
+		v.Range("connLimitMask", mask, 0, 128)
+		input.ConnLimitMask = mask
+	}
 	// NAT options
 	if natToAddr, ok := inputRaw["natToAddr"].(string); ok {
 		v.MaxLength("natToAddr", natToAddr, 128).SafeString("natToAddr", natToAddr)
@@ -1493,6 +4198,15 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		}
 		input.NatToPort = natToPort
 	}
+	// Reject options; the with/type combination is checked against the allowlist in validateRuleSemantics
+	if rejectWith, ok := inputRaw["rejectWith"].(string); ok {
+		v.MaxLength("rejectWith", rejectWith, 16).SafeString("rejectWith", rejectWith)
+		input.RejectWith = strings.ToLower(rejectWith)
+	}
+	if rejectType, ok := inputRaw["rejectType"].(string); ok {
+		v.MaxLength("rejectType", rejectType, 32).SafeString("rejectType", rejectType)
+		input.RejectType = strings.ToLower(rejectType)
+	}
 	// Logging options
 	if logPrefix, ok := inputRaw["logPrefix"].(string); ok {
 		v.MaxLength("logPrefix", logPrefix, 64).SafeString("logPrefix", logPrefix)
@@ -1502,6 +4216,14 @@ func parseRuleInput(inputRaw map[string]interface{}) (*FirewallRuleInput, error)
 		v.MaxLength("logLevel", logLevel, 32).SafeString("logLevel", logLevel)
 		input.LogLevel = logLevel
 	}
+	// Named counter
+	if counterName, ok := inputRaw["counterName"].(string); ok {
+		v.MaxLength("counterName", counterName, 63).SafeString("counterName", counterName)
+		input.CounterName = counterName
+	}
+	if counter, ok := inputRaw["counter"].(bool); ok {
+		input.Counter = &counter
+	}
 	if ruleExpr, ok := inputRaw["ruleExpr"].(string); ok {
 		// Validate nftables expression for safety
 		v.NftablesExpression("ruleExpr", ruleExpr)
@@ -1584,6 +4306,77 @@ func parseProfileInputWithValidation(inputRaw map[string]interface{}) (*Firewall
 	if enableIpv6, ok := inputRaw["enableIpv6"].(bool); ok {
 		input.EnableIPv6 = &enableIpv6
 	}
+	if enableReversePathFilter, ok := inputRaw["enableReversePathFilter"].(bool); ok {
+		input.EnableReversePathFilter = &enableReversePathFilter
+	}
+	if logDroppedPackets, ok := inputRaw["logDroppedPackets"].(bool); ok {
+		input.LogDroppedPackets = &logDroppedPackets
+	}
+	if logDropPrefix, ok := inputRaw["logDropPrefix"].(string); ok {
+		v.MaxLength("logDropPrefix", logDropPrefix, 64).SafeString("logDropPrefix", logDropPrefix)
+		input.LogDropPrefix = logDropPrefix
+	}
+	// Chain hook priorities - integers or named nftables priorities
+	if inputPriority, ok := inputRaw["inputPriority"].(string); ok {
+		v.NftablesPriority("inputPriority", inputPriority)
+		input.InputPriority = inputPriority
+	}
+	if outputPriority, ok := inputRaw["outputPriority"].(string); ok {
+		v.NftablesPriority("outputPriority", outputPriority)
+		input.OutputPriority = outputPriority
+	}
+	if forwardPriority, ok := inputRaw["forwardPriority"].(string); ok {
+		v.NftablesPriority("forwardPriority", forwardPriority)
+		input.ForwardPriority = forwardPriority
+	}
+	if preroutingPriority, ok := inputRaw["preroutingPriority"].(string); ok {
+		v.NftablesPriority("preroutingPriority", preroutingPriority)
+		input.PreroutingPriority = preroutingPriority
+	}
+	if postroutingPriority, ok := inputRaw["postroutingPriority"].(string); ok {
+		v.NftablesPriority("postroutingPriority", postroutingPriority)
+		input.PostroutingPriority = postroutingPriority
+	}
+	// Management access
+	if enableManagementAccess, ok := inputRaw["enableManagementAccess"].(bool); ok {
+		input.EnableManagementAccess = &enableManagementAccess
+	}
+	if managementPort, ok := inputRaw["managementPort"].(string); ok {
+		input.ManagementPort = parsePortField(v, "managementPort", managementPort)
+		if strings.ContainsAny(input.ManagementPort, "-,") {
+			v.Errors().Add("managementPort", "managementPort must be a single port", "INVALID_PORT")
+		}
+	}
+	if managementSource, ok := inputRaw["managementSource"].(string); ok {
+		v.MaxLength("managementSource", managementSource, 128)
+		if name, isSet := ipSetReference(managementSource); isSet {
+			if !ipSetNameRegex.MatchString(name) {
+				v.Errors().Add("managementSource", "managementSource references an invalid IP set name", "INVALID_IP_SET_REFERENCE")
+			}
+		} else if strings.Contains(managementSource, "/") {
+			v.CIDR("managementSource", managementSource)
+		} else {
+			v.IP("managementSource", managementSource)
+		}
+		input.ManagementSource = &managementSource
+	}
+	if nftBinaryPath, ok := inputRaw["nftBinaryPath"].(string); ok {
+		v.MaxLength("nftBinaryPath", nftBinaryPath, 255)
+		if nftBinaryPath != "" && !nftBinaryPathRegex.MatchString(nftBinaryPath) {
+			v.Errors().Add("nftBinaryPath", "nftBinaryPath must be an absolute path", "INVALID_PATH")
+		}
+		input.NftBinaryPath = nftBinaryPath
+	}
+	if tableMode, ok := inputRaw["tableMode"].(string); ok {
+		v.Enum("tableMode", tableMode, []string{string(TableModeManaged), string(TableModeRuleset)})
+		input.TableMode = ProfileTableMode(tableMode)
+	}
+	if ruleOrderStrategy, ok := inputRaw["ruleOrderStrategy"].(string); ok {
+		v.Enum("ruleOrderStrategy", ruleOrderStrategy, []string{
+			string(RuleOrderByPriority), string(RuleOrderBySortOrder), string(RuleOrderByCreated),
+		})
+		input.RuleOrderStrategy = RuleOrderStrategy(ruleOrderStrategy)
+	}
 
 	if v.HasErrors() {
 		return nil, v.Errors()
@@ -1609,6 +4402,11 @@ func parseTemplateInputWithValidation(inputRaw map[string]interface{}) (*Firewal
 		}
 		input.Category = TemplateCategory(category)
 	}
+	// SHARED is only reachable through publishSecurityTemplate
+	if visibility, ok := inputRaw["visibility"].(string); ok {
+		v.Enum("visibility", visibility, []string{string(TemplateVisibilityPrivate), string(TemplateVisibilityTenant)})
+		input.Visibility = TemplateVisibility(visibility)
+	}
 
 	if rules, ok := inputRaw["rules"].([]interface{}); ok {
 		// Limit number of rules in a template
@@ -1640,7 +4438,7 @@ func parseTemplateInputWithValidation(inputRaw map[string]interface{}) (*Firewal
 					if err := graphql.ValidateEnum(protocol, graphql.RuleProtocolValues, "rules.protocol"); err != nil {
 						return nil, err
 					}
-					rule.Protocol = RuleProtocol(protocol)
+					rule.Protocol = normalizeRuleProtocol(protocol)
 				}
 				if sourceIp, ok := ruleMap["sourceIp"].(string); ok {
 					if sourceIp != "" && sourceIp != "any" {
@@ -1649,10 +4447,7 @@ func parseTemplateInputWithValidation(inputRaw map[string]interface{}) (*Firewal
 					rule.SourceIP = sourceIp
 				}
 				if sourcePort, ok := ruleMap["sourcePort"].(string); ok {
-					if sourcePort != "" {
-						v.PortRange("rules.sourcePort", sourcePort)
-					}
-					rule.SourcePort = sourcePort
+					rule.SourcePort = parsePortField(v, "rules.sourcePort", sourcePort)
 				}
 				if destIp, ok := ruleMap["destIp"].(string); ok {
 					if destIp != "" && destIp != "any" {
@@ -1661,11 +4456,10 @@ func parseTemplateInputWithValidation(inputRaw map[string]interface{}) (*Firewal
 					rule.DestIP = destIp
 				}
 				if destPort, ok := ruleMap["destPort"].(string); ok {
-					if destPort != "" {
-						v.PortRange("rules.destPort", destPort)
-					}
-					rule.DestPort = destPort
+					rule.DestPort = parsePortField(v, "rules.destPort", destPort)
 				}
+				parseRuleNegation(ruleMap, &rule)
+				parseRuleConnLimit(v, ruleMap, &rule)
 				if action, ok := ruleMap["action"].(string); ok {
 					if err := graphql.ValidateEnum(action, graphql.RuleActionValues, "rules.action"); err != nil {
 						return nil, err
@@ -1684,9 +4478,233 @@ func parseTemplateInputWithValidation(inputRaw map[string]interface{}) (*Firewal
 			}
 		}
 	}
+	if settingsRaw, ok := inputRaw["settings"].(map[string]interface{}); ok {
+		input.Settings = parseTemplateSettings(v, settingsRaw)
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return input, nil
+}
+
+// parseTemplateSettings parses the optional profile-level settings of a template
+func parseTemplateSettings(v *validation.Validator, settingsRaw map[string]interface{}) *TemplateSettings {
+	settings := &TemplateSettings{}
+
+	// Default policies
+	policyValues := []string{"accept", "drop", "reject", ""}
+	if inputPolicy, ok := settingsRaw["inputPolicy"].(string); ok {
+		v.Enum("settings.inputPolicy", inputPolicy, policyValues)
+		settings.InputPolicy = inputPolicy
+	}
+	if outputPolicy, ok := settingsRaw["outputPolicy"].(string); ok {
+		v.Enum("settings.outputPolicy", outputPolicy, policyValues)
+		settings.OutputPolicy = outputPolicy
+	}
+	if forwardPolicy, ok := settingsRaw["forwardPolicy"].(string); ok {
+		v.Enum("settings.forwardPolicy", forwardPolicy, policyValues)
+		settings.ForwardPolicy = forwardPolicy
+	}
+	// Features
+	if enableNat, ok := settingsRaw["enableNat"].(bool); ok {
+		settings.EnableNAT = &enableNat
+	}
+	if enableConntrack, ok := settingsRaw["enableConntrack"].(bool); ok {
+		settings.EnableConntrack = &enableConntrack
+	}
+	if allowLoopback, ok := settingsRaw["allowLoopback"].(bool); ok {
+		settings.AllowLoopback = &allowLoopback
+	}
+	if allowEstablished, ok := settingsRaw["allowEstablished"].(bool); ok {
+		settings.AllowEstablished = &allowEstablished
+	}
+	if allowIcmpPing, ok := settingsRaw["allowIcmpPing"].(bool); ok {
+		settings.AllowICMPPing = &allowIcmpPing
+	}
+	if enableIpv6, ok := settingsRaw["enableIpv6"].(bool); ok {
+		settings.EnableIPv6 = &enableIpv6
+	}
+	if enableReversePathFilter, ok := settingsRaw["enableReversePathFilter"].(bool); ok {
+		settings.EnableReversePathFilter = &enableReversePathFilter
+	}
+	if logDroppedPackets, ok := settingsRaw["logDroppedPackets"].(bool); ok {
+		settings.LogDroppedPackets = &logDroppedPackets
+	}
+	return settings
+}
+
+func parseIPSetInputWithValidation(inputRaw map[string]interface{}) (*FirewallIPSetInput, error) {
+	v := validation.NewValidator()
+	input := &FirewallIPSetInput{}
+
+	if name, ok := inputRaw["name"].(string); ok {
+		v.MaxLength("name", name, validation.MaxNameLength)
+		input.Name = name
+	}
+	if description, ok := inputRaw["description"].(string); ok {
+		v.MaxLength("description", description, validation.MaxDescriptionLength)
+		input.Description = description
+	}
+	if elements, ok := inputRaw["elements"].([]interface{}); ok {
+		v.MaxItems("elements", len(elements), validation.MaxArrayLength)
+		input.Elements = make([]string, 0, len(elements))
+		for _, element := range elements {
+			if elementStr, ok := element.(string); ok {
+				input.Elements = append(input.Elements, elementStr)
+			}
+		}
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return input, nil
+}
+
+func parseAgentGroupInputWithValidation(inputRaw map[string]interface{}) (*FirewallAgentGroupInput, error) {
+	v := validation.NewValidator()
+	input := &FirewallAgentGroupInput{}
+
+	if name, ok := inputRaw["name"].(string); ok {
+		v.MaxLength("name", name, validation.MaxNameLength).SafeString("name", name)
+		input.Name = name
+	}
+	if description, ok := inputRaw["description"].(string); ok {
+		v.MaxLength("description", description, validation.MaxDescriptionLength)
+		input.Description = description
+	}
+	if agentIds, ok := inputRaw["agentIds"].([]interface{}); ok {
+		v.MaxItems("agentIds", len(agentIds), validation.MaxBulkIDs)
+		input.AgentIDs = make([]string, 0, len(agentIds))
+		for _, id := range agentIds {
+			if idStr, ok := id.(string); ok {
+				v.UUID("agentIds", idStr)
+				input.AgentIDs = append(input.AgentIDs, idStr)
+			}
+		}
+	}
+	if hostnames, ok := inputRaw["agentHostnames"].([]interface{}); ok {
+		v.MaxItems("agentHostnames", len(hostnames), validation.MaxBulkIDs)
+		input.AgentHostnames = make([]string, 0, len(hostnames))
+		for _, h := range hostnames {
+			if hostname, ok := h.(string); ok {
+				v.MaxLength("agentHostnames", hostname, validation.MaxNameLength).SafeString("agentHostnames", hostname)
+				input.AgentHostnames = append(input.AgentHostnames, hostname)
+			}
+		}
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return input, nil
+}
+
+func parseScheduleInputWithValidation(inputRaw map[string]interface{}) (*FirewallScheduleInput, error) {
+	v := validation.NewValidator()
+	input := &FirewallScheduleInput{}
+
+	if profileID, ok := inputRaw["profileId"].(string); ok {
+		v.UUID("profileId", profileID)
+		input.ProfileID = profileID
+	}
+	if agentID, ok := inputRaw["agentId"].(string); ok {
+		v.UUID("agentId", agentID)
+		input.AgentID = agentID
+	}
+	if cronExpr, ok := inputRaw["cronExpr"].(string); ok {
+		cronExpr = strings.TrimSpace(cronExpr)
+		v.MaxLength("cronExpr", cronExpr, 100)
+		if cronExpr != "" {
+			if _, err := nextScheduleRun(cronExpr, time.Now()); err != nil {
+				v.Errors().Add("cronExpr", err.Error(), "INVALID_CRON")
+			}
+		}
+		input.CronExpr = cronExpr
+	}
+	if enabled, ok := inputRaw["enabled"].(bool); ok {
+		input.Enabled = &enabled
+	}
 
 	if v.HasErrors() {
 		return nil, v.Errors()
 	}
 	return input, nil
 }
+
+func parseSimulatedPacket(packetRaw map[string]interface{}) (*SimulatedPacket, error) {
+	v := validation.NewValidator()
+	packet := &SimulatedPacket{}
+
+	if chain, ok := packetRaw["chain"].(string); ok {
+		if err := graphql.ValidateEnum(chain, graphql.RuleChainValues, "chain"); err != nil {
+			return nil, err
+		}
+		packet.Chain = RuleChain(chain)
+	}
+	if protocol, ok := packetRaw["protocol"].(string); ok {
+		packet.Protocol = normalizeRuleProtocol(protocol)
+		v.Enum("protocol", string(packet.Protocol), []string{"TCP", "UDP", "ICMP", "ALL"})
+	}
+	if sourceIp, ok := packetRaw["sourceIp"].(string); ok {
+		v.IP("sourceIp", sourceIp)
+		packet.SourceIP = sourceIp
+	}
+	if destIp, ok := packetRaw["destIp"].(string); ok {
+		v.IP("destIp", destIp)
+		packet.DestIP = destIp
+	}
+	if sourcePort, ok := packetRaw["sourcePort"].(float64); ok {
+		v.Port("sourcePort", int(sourcePort))
+		packet.SourcePort = int(sourcePort)
+	}
+	if destPort, ok := packetRaw["destPort"].(float64); ok {
+		v.Port("destPort", int(destPort))
+		packet.DestPort = int(destPort)
+	}
+	if inInterface, ok := packetRaw["inInterface"].(string); ok {
+		v.MaxLength("inInterface", inInterface, 64).SafeString("inInterface", inInterface)
+		packet.InInterface = inInterface
+	}
+	if outInterface, ok := packetRaw["outInterface"].(string); ok {
+		v.MaxLength("outInterface", outInterface, 64).SafeString("outInterface", outInterface)
+		packet.OutInterface = outInterface
+	}
+	if ctState, ok := packetRaw["ctState"].(string); ok {
+		v.Enum("ctState", ctState, []string{"NEW", "ESTABLISHED", "RELATED", "INVALID"})
+		packet.CTState = ctState
+	}
+	if dscp, ok := packetRaw["dscp"].(string); ok {
+		v.DSCP("dscp", dscp)
+		packet.DSCP = dscp
+	}
+	if length, ok := packetRaw["length"].(float64); ok {
+		v.Range("length", int(length), 0, validation.MaxPortNumber)
+		packet.Length = int(length)
+	}
+	if icmpType, ok := packetRaw["icmpType"].(string); ok {
+		v.MaxLength("icmpType", icmpType, 64).SafeString("icmpType", icmpType)
+		packet.ICMPType = icmpType
+	}
+
+	if v.HasErrors() {
+		return nil, v.Errors()
+	}
+	return packet, nil
+}
+
+// parsePortField validates a port, port range, well-known service name (e.g. "ssh") or a
+// comma-separated list of them and returns it normalized to numeric form
+func parsePortField(v *validation.Validator, field, value string) string {
+	if value == "" {
+		return value
+	}
+	resolved, err := resolveServicePorts(value)
+	if err != nil {
+		v.Errors().Add(field, field+": "+err.Error(), "UNKNOWN_SERVICE")
+		return value
+	}
+	v.PortSet(field, resolved)
+	return resolved
+}
@@ -0,0 +1,21 @@
+package security
+
+import "testing"
+
+func TestParseProfileInputClearsManagementSource(t *testing.T) {
+	input, err := parseProfileInputWithValidation(map[string]interface{}{"name": "p", "managementSource": ""})
+	if err != nil {
+		t.Fatalf("parseProfileInputWithValidation: %v", err)
+	}
+	if input.ManagementSource == nil || *input.ManagementSource != "" {
+		t.Errorf("ManagementSource = %v, want an explicit empty source", input.ManagementSource)
+	}
+
+	input, err = parseProfileInputWithValidation(map[string]interface{}{"name": "p"})
+	if err != nil {
+		t.Fatalf("parseProfileInputWithValidation: %v", err)
+	}
+	if input.ManagementSource != nil {
+		t.Errorf("ManagementSource = %q, want it left unset", *input.ManagementSource)
+	}
+}
@@ -25,10 +25,11 @@ const (
 type RuleProtocol string
 
 const (
-	RuleProtocolTCP  RuleProtocol = "TCP"
-	RuleProtocolUDP  RuleProtocol = "UDP"
-	RuleProtocolICMP RuleProtocol = "ICMP"
-	RuleProtocolAll  RuleProtocol = "ALL"
+	RuleProtocolTCP    RuleProtocol = "TCP"
+	RuleProtocolUDP    RuleProtocol = "UDP"
+	RuleProtocolICMP   RuleProtocol = "ICMP"
+	RuleProtocolICMPv6 RuleProtocol = "ICMPV6"
+	RuleProtocolAll    RuleProtocol = "ALL"
 )
 
 // RuleAction represents the action to take
@@ -57,12 +58,12 @@ const (
 
 // FirewallRule represents an individual nftables rule
 type FirewallRule struct {
-	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TenantID    uuid.UUID    `json:"tenantId" gorm:"type:uuid;not null;index:idx_rule_tenant;index:idx_rule_tenant_chain_enabled"`
+	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_rule_tenant_priority_created,priority:4,sort:desc"`
+	TenantID    uuid.UUID    `json:"tenantId" gorm:"type:uuid;not null;index:idx_rule_tenant;index:idx_rule_tenant_chain_enabled;index:idx_rule_tenant_priority_created,priority:1"`
 	Name        string       `json:"name" gorm:"not null"`
 	Description string       `json:"description"`
 	Chain       RuleChain    `json:"chain" gorm:"not null;default:'INPUT';index:idx_rule_tenant_chain_enabled"`
-	Priority    int          `json:"priority" gorm:"default:0"`
+	Priority    int          `json:"priority" gorm:"default:0;index:idx_rule_tenant_priority_created,priority:2"`
 	Protocol    RuleProtocol `json:"protocol"`
 	SourceIP    string       `json:"sourceIp"`
 	SourcePort  string       `json:"sourcePort"`
@@ -77,6 +78,18 @@ type FirewallRule struct {
 	// Connection tracking
 	CTState string `json:"ctState"` // Connection tracking state (NEW,ESTABLISHED,RELATED,INVALID)
 
+	// ICMP matching
+	ICMPType string `json:"icmpType"` // Comma-separated ICMP or ICMPv6 type names (e.g. packet-too-big)
+
+	// Match negation
+	NegateProtocol   bool `json:"negateProtocol"`   // Match every protocol except Protocol
+	NegateSourcePort bool `json:"negateSourcePort"` // Match every source port except SourcePort
+	NegateDestPort   bool `json:"negateDestPort"`   // Match every destination port except DestPort
+
+	// QoS matching
+	DSCP         string `json:"dscp"`         // DSCP class name or value (ip dscp)
+	PacketLength string `json:"packetLength"` // Packet length or range (meta length)
+
 	// Rate limiting
 	RateLimit  string `json:"rateLimit"`  // e.g., "10/second", "100/minute"
 	RateBurst  int    `json:"rateBurst"`  // Burst limit
@@ -86,16 +99,32 @@ type FirewallRule struct {
 	NatToAddr string `json:"natToAddr"` // Target address for DNAT/SNAT
 	NatToPort string `json:"natToPort"` // Target port for DNAT/REDIRECT
 
+	// Reject options (for REJECT); a bare reject is generated when empty
+	RejectWith string `json:"rejectWith"` // icmp, icmpv6, icmpx or tcp
+	RejectType string `json:"rejectType"` // ICMP type (e.g. admin-prohibited) or reset for tcp
+
+	// Connection limiting (TCP only); the rule matches once the limit is exceeded
+	ConnLimitCount int `json:"connLimitCount"` // Maximum concurrent connections, 0 disables the limit
+	ConnLimitMask  int `json:"connLimitMask"`  // Source prefix length connections are grouped by, 0 counts them all together
+
 	// Logging options
 	LogPrefix string `json:"logPrefix"` // Prefix for log messages
 	LogLevel  string `json:"logLevel"`  // Log level (emerg, alert, crit, err, warn, notice, info, debug)
 
+	// Named counter incremented by the rule (counter name "<name>")
+	CounterName string `json:"counterName"`
+	// Per-rule hit counter named after the rule ID (see ruleCounterName), read by securityRuleCounters
+	Counter bool `json:"counter" gorm:"default:false"`
+
 	RuleExpr  string    `json:"ruleExpr"` // Raw nftables expression (advanced)
 	Comment   string    `json:"comment"`
 	Enabled   bool      `json:"enabled" gorm:"default:true;index:idx_rule_tenant_chain_enabled"`
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_rule_tenant_priority_created,priority:3,sort:desc"`
 	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 	CreatedBy uuid.UUID `json:"createdBy" gorm:"type:uuid"`
+
+	// Resolved fields (not persisted)
+	ProfileSortOrder int `json:"profileSortOrder" gorm:"-"` // Position in the profile the rule was loaded with
 }
 
 // TableName returns the table name for GORM
@@ -123,6 +152,18 @@ type FirewallRuleInput struct {
 	// Connection tracking
 	CTState string `json:"ctState"`
 
+	// ICMP matching
+	ICMPType string `json:"icmpType"`
+
+	// Match negation
+	NegateProtocol   *bool `json:"negateProtocol"`
+	NegateSourcePort *bool `json:"negateSourcePort"`
+	NegateDestPort   *bool `json:"negateDestPort"`
+
+	// QoS matching
+	DSCP         string `json:"dscp"`
+	PacketLength string `json:"packetLength"`
+
 	// Rate limiting
 	RateLimit string `json:"rateLimit"`
 	RateBurst int    `json:"rateBurst"`
@@ -132,10 +173,22 @@ type FirewallRuleInput struct {
 	NatToAddr string `json:"natToAddr"`
 	NatToPort string `json:"natToPort"`
 
+	// Reject options
+	RejectWith string `json:"rejectWith"`
+	RejectType string `json:"rejectType"`
+
+	// Connection limiting
+	ConnLimitCount int `json:"connLimitCount"`
+	ConnLimitMask  int `json:"connLimitMask"`
+
 	// Logging options
 	LogPrefix string `json:"logPrefix"`
 	LogLevel  string `json:"logLevel"`
 
+	// Named counter
+	CounterName string `json:"counterName"`
+	Counter     *bool  `json:"counter"`
+
 	RuleExpr string `json:"ruleExpr"`
 	Comment  string `json:"comment"`
 	Enabled  *bool  `json:"enabled"`
@@ -176,10 +229,43 @@ type FirewallProfile struct {
 	AllowICMPPing       bool `json:"allowIcmpPing" gorm:"default:true"`        // Allow ICMP ping
 	EnableIPv6          bool `json:"enableIpv6" gorm:"default:false"`          // Enable IPv6 support
 
+	// Anti-spoofing: drop input/forward packets failing the fib reverse-path check
+	EnableReversePathFilter bool `json:"enableReversePathFilter" gorm:"default:false"`
+
+	// Drop logging: DROP rules and drop policies go to a shared log_drop chain that logs, counts and drops
+	LogDroppedPackets bool   `json:"logDroppedPackets" gorm:"default:false"`
+	LogDropPrefix     string `json:"logDropPrefix"` // Log prefix of the log_drop chain, "pilote-drop: " when empty
+
+	// Chain hook priorities (integer or named nftables priority, e.g. "filter + 10")
+	InputPriority       string `json:"inputPriority" gorm:"default:'0'"`
+	OutputPriority      string `json:"outputPriority" gorm:"default:'0'"`
+	ForwardPriority     string `json:"forwardPriority" gorm:"default:'0'"`
+	PreroutingPriority  string `json:"preroutingPriority" gorm:"default:'dstnat'"`
+	PostroutingPriority string `json:"postroutingPriority" gorm:"default:'srcnat'"`
+
+	// Management access: accepted first in the input chain so policy changes cannot lock it out
+	EnableManagementAccess bool   `json:"enableManagementAccess" gorm:"default:false"`
+	ManagementPort         string `json:"managementPort" gorm:"default:'22'"` // TCP port
+	ManagementSource       string `json:"managementSource"`                   // IP, CIDR or @set; empty allows any source
+
+	// Path of the nft binary on the target hosts, used in the shebang and to apply the config
+	NftBinaryPath string `json:"nftBinaryPath" gorm:"default:'/usr/sbin/nft'"`
+
+	// How the generated config replaces existing state (existing profiles keep RULESET)
+	TableMode ProfileTableMode `json:"tableMode" gorm:"default:'RULESET'"`
+
+	// Order of the user rules within each generated chain
+	RuleOrderStrategy RuleOrderStrategy `json:"ruleOrderStrategy" gorm:"default:'BY_PRIORITY'"`
+
 	CreatedAt time.Time      `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
 	CreatedBy uuid.UUID      `json:"createdBy" gorm:"type:uuid"`
 	Rules     []FirewallRule `json:"rules,omitempty" gorm:"many2many:firewall_profile_rules"`
+
+	// Resolved fields (not persisted)
+	IPSets               []FirewallIPSet   `json:"-" gorm:"-"` // IP sets referenced by the rules, loaded before generation
+	LastDeploymentStatus *DeploymentStatus `json:"lastDeploymentStatus,omitempty" gorm:"-"` // Latest deployment across agents (list view)
+	LastDeployedAt       *time.Time        `json:"lastDeployedAt,omitempty" gorm:"-"`
 }
 
 // TableName returns the table name for GORM
@@ -219,6 +305,101 @@ type FirewallProfileInput struct {
 	AllowEstablished *bool `json:"allowEstablished"`
 	AllowICMPPing    *bool `json:"allowIcmpPing"`
 	EnableIPv6       *bool `json:"enableIpv6"`
+
+	EnableReversePathFilter *bool `json:"enableReversePathFilter"`
+
+	LogDroppedPackets *bool  `json:"logDroppedPackets"`
+	LogDropPrefix     string `json:"logDropPrefix"`
+
+	// Chain hook priorities
+	InputPriority       string `json:"inputPriority"`
+	OutputPriority      string `json:"outputPriority"`
+	ForwardPriority     string `json:"forwardPriority"`
+	PreroutingPriority  string `json:"preroutingPriority"`
+	PostroutingPriority string `json:"postroutingPriority"`
+
+	// Management access
+	EnableManagementAccess *bool   `json:"enableManagementAccess"`
+	ManagementPort         string  `json:"managementPort"`
+	ManagementSource       *string `json:"managementSource"` // "" clears the source on update
+
+	NftBinaryPath string           `json:"nftBinaryPath"`
+	TableMode     ProfileTableMode `json:"tableMode"`
+
+	RuleOrderStrategy RuleOrderStrategy `json:"ruleOrderStrategy"`
+}
+
+// RuleOrderStrategy determines the order user rules are evaluated in within a chain
+type RuleOrderStrategy string
+
+const (
+	// RuleOrderByPriority orders rules by priority, then by creation time
+	RuleOrderByPriority RuleOrderStrategy = "BY_PRIORITY"
+	// RuleOrderBySortOrder keeps the order the rules were added to the profile
+	RuleOrderBySortOrder RuleOrderStrategy = "BY_SORT_ORDER"
+	// RuleOrderByCreated orders rules by creation time, oldest first
+	RuleOrderByCreated RuleOrderStrategy = "BY_CREATED"
+)
+
+// ProfileTableMode determines which nftables state a deployment replaces
+type ProfileTableMode string
+
+const (
+	// TableModeManaged replaces only the pilote table, leaving docker, CNI and other tools' tables intact
+	TableModeManaged ProfileTableMode = "MANAGED"
+	// TableModeRuleset flushes the entire ruleset before loading the filter and nat tables
+	TableModeRuleset ProfileTableMode = "RULESET"
+)
+
+// BaseRule is a rule generated from a profile feature flag rather than a user rule
+type BaseRule struct {
+	Chain       RuleChain `json:"chain"`
+	Feature     string    `json:"feature"` // Profile flag that produces the rule (e.g. allowLoopback)
+	Description string    `json:"description"`
+	Expr        string    `json:"expr"`
+}
+
+// BaseRuleChain lists the base rules and policy generated for one chain
+type BaseRuleChain struct {
+	Chain  RuleChain  `json:"chain"`
+	Policy string     `json:"policy"`
+	Rules  []BaseRule `json:"rules"`
+}
+
+// RulesetEstimate reports the size of the nftables config generated for a profile
+type RulesetEstimate struct {
+	RulesByChain     map[RuleChain]int `json:"rulesByChain"` // Base and enabled user rules per chain
+	TotalRules       int               `json:"totalRules"`
+	StatementCount   int               `json:"statementCount"` // Emitted lines excluding comments and block delimiters
+	EstimatedBytes   int               `json:"estimatedBytes"`
+	ThresholdBytes   int               `json:"thresholdBytes"`
+	ExceedsThreshold bool              `json:"exceedsThreshold"`
+	Warning          string            `json:"warning,omitempty"`
+}
+
+// ProfilePreview is the nftables config a profile would deploy, rendered without touching any agent
+type ProfilePreview struct {
+	ProfileID    uuid.UUID         `json:"profileId"`
+	ProfileName  string            `json:"profileName"`
+	Config       string            `json:"config"`
+	RulesByChain map[RuleChain]int `json:"rulesByChain"` // Base and user rules emitted per chain
+	TotalRules   int               `json:"totalRules"`
+}
+
+// LintSeverity grades a lint finding
+type LintSeverity string
+
+const (
+	LintSeverityWarning LintSeverity = "WARNING"
+)
+
+// LintFinding is a risky pattern detected in a profile rule
+type LintFinding struct {
+	RuleID   uuid.UUID    `json:"ruleId"`
+	RuleName string       `json:"ruleName"`
+	Severity LintSeverity `json:"severity"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
 }
 
 // FirewallProfileFilter represents filter options for listing profiles
@@ -243,18 +424,44 @@ const (
 	TemplateCategoryCustom     TemplateCategory = "CUSTOM"
 )
 
+// TemplateVisibility controls who can see a tenant template
+type TemplateVisibility string
+
+const (
+	TemplateVisibilityPrivate TemplateVisibility = "PRIVATE" // Only its creator
+	TemplateVisibilityTenant  TemplateVisibility = "TENANT"  // Every user of the owning tenant
+	TemplateVisibilityShared  TemplateVisibility = "SHARED"  // Every tenant; only the owning tenant can modify it
+)
+
 // FirewallTemplate represents a reusable firewall template
 type FirewallTemplate struct {
-	ID          uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TenantID    uuid.UUID        `json:"tenantId" gorm:"type:uuid;not null;index"`
-	Name        string           `json:"name" gorm:"not null"`
-	Description string           `json:"description"`
-	Category    TemplateCategory `json:"category" gorm:"default:'CUSTOM'"`
-	IsBuiltIn   bool             `json:"isBuiltIn" gorm:"default:false"`
-	RulesJSON   string           `json:"rulesJson" gorm:"type:jsonb"` // JSON array of rule definitions
-	CreatedAt   time.Time        `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time        `json:"updatedAt" gorm:"autoUpdateTime"`
-	CreatedBy   uuid.UUID        `json:"createdBy" gorm:"type:uuid"`
+	ID           uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID     uuid.UUID          `json:"tenantId" gorm:"type:uuid;not null;index"`
+	Name         string             `json:"name" gorm:"not null"`
+	Description  string             `json:"description"`
+	Category     TemplateCategory   `json:"category" gorm:"default:'CUSTOM'"`
+	IsBuiltIn    bool               `json:"isBuiltIn" gorm:"default:false"`
+	Visibility   TemplateVisibility `json:"visibility" gorm:"default:'TENANT'"`
+	RulesJSON    string             `json:"rulesJson" gorm:"type:jsonb"`    // JSON array of rule definitions
+	SettingsJSON string             `json:"settingsJson" gorm:"type:jsonb"` // JSON TemplateSettings; empty leaves profile settings untouched
+	CreatedAt    time.Time          `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time          `json:"updatedAt" gorm:"autoUpdateTime"`
+	CreatedBy    uuid.UUID          `json:"createdBy" gorm:"type:uuid"`
+}
+
+// VisibleTo reports whether a user of a tenant can see the template.
+// It mirrors the visibleTemplates repository condition.
+func (t *FirewallTemplate) VisibleTo(tenantID, userID uuid.UUID) bool {
+	switch {
+	case t.IsBuiltIn, t.Visibility == TemplateVisibilityShared:
+		return true
+	case t.TenantID != tenantID:
+		return false
+	case t.Visibility == TemplateVisibilityPrivate:
+		return t.CreatedBy == userID
+	default:
+		return true
+	}
 }
 
 // TableName returns the table name for GORM
@@ -275,6 +482,11 @@ type TemplateRuleDefinition struct {
 	DestPort    string       `json:"destPort"`
 	Action      RuleAction   `json:"action"`
 
+	// Match negation
+	NegateProtocol   bool `json:"negateProtocol,omitempty"`
+	NegateSourcePort bool `json:"negateSourcePort,omitempty"`
+	NegateDestPort   bool `json:"negateDestPort,omitempty"`
+
 	// Interface matching
 	InInterface  string `json:"inInterface,omitempty"`
 	OutInterface string `json:"outInterface,omitempty"`
@@ -287,6 +499,10 @@ type TemplateRuleDefinition struct {
 	RateBurst int    `json:"rateBurst,omitempty"`
 	LimitOver string `json:"limitOver,omitempty"`
 
+	// Connection limiting
+	ConnLimitCount int `json:"connLimitCount,omitempty"`
+	ConnLimitMask  int `json:"connLimitMask,omitempty"`
+
 	// NAT options
 	NatToAddr string `json:"natToAddr,omitempty"`
 	NatToPort string `json:"natToPort,omitempty"`
@@ -298,19 +514,42 @@ type TemplateRuleDefinition struct {
 	Comment string `json:"comment"`
 }
 
+// TemplateSettings holds the profile-level settings a template applies.
+// Unset fields leave the target profile's value untouched.
+type TemplateSettings struct {
+	// Default policies
+	InputPolicy   string `json:"inputPolicy,omitempty"`
+	OutputPolicy  string `json:"outputPolicy,omitempty"`
+	ForwardPolicy string `json:"forwardPolicy,omitempty"`
+
+	// Features
+	EnableNAT        *bool `json:"enableNat,omitempty"`
+	EnableConntrack  *bool `json:"enableConntrack,omitempty"`
+	AllowLoopback    *bool `json:"allowLoopback,omitempty"`
+	AllowEstablished *bool `json:"allowEstablished,omitempty"`
+	AllowICMPPing    *bool `json:"allowIcmpPing,omitempty"`
+	EnableIPv6       *bool `json:"enableIpv6,omitempty"`
+
+	EnableReversePathFilter *bool `json:"enableReversePathFilter,omitempty"`
+	LogDroppedPackets       *bool `json:"logDroppedPackets,omitempty"`
+}
+
 // FirewallTemplateInput represents input for creating/updating a template
 type FirewallTemplateInput struct {
 	Name        string                   `json:"name"`
 	Description string                   `json:"description"`
 	Category    TemplateCategory         `json:"category"`
+	Visibility  TemplateVisibility       `json:"visibility"` // PRIVATE or TENANT; SHARED is set by publishing
 	Rules       []TemplateRuleDefinition `json:"rules"`
+	Settings    *TemplateSettings        `json:"settings"` // Optional profile-level settings
 }
 
 // FirewallTemplateFilter represents filter options for listing templates
 type FirewallTemplateFilter struct {
 	Search    *string           `json:"search"`
-	Category  *TemplateCategory `json:"category"`
-	IsBuiltIn *bool             `json:"isBuiltIn"`
+	Category   *TemplateCategory   `json:"category"`
+	IsBuiltIn  *bool               `json:"isBuiltIn"`
+	Visibility *TemplateVisibility `json:"visibility"`
 }
 
 // ========================================
@@ -326,6 +565,7 @@ const (
 	DeploymentStatusApplied    DeploymentStatus = "APPLIED"
 	DeploymentStatusRolledBack DeploymentStatus = "ROLLED_BACK"
 	DeploymentStatusError      DeploymentStatus = "ERROR"
+	DeploymentStatusUnknown    DeploymentStatus = "UNKNOWN" // Timed out or lost contact; may have applied
 )
 
 // DeploymentAction represents the type of deployment action
@@ -338,10 +578,15 @@ const (
 	DeploymentActionFlush    DeploymentAction = "FLUSH"
 )
 
+// IsMaintenance reports whether the action inspects or clears an agent rather than deploying a profile
+func (a DeploymentAction) IsMaintenance() bool {
+	return a == DeploymentActionAudit || a == DeploymentActionFlush
+}
+
 // FirewallDeployment tracks deployments of profiles to agents
 type FirewallDeployment struct {
-	ID            uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TenantID      uuid.UUID         `json:"tenantId" gorm:"type:uuid;not null;index:idx_deploy_tenant;index:idx_deploy_tenant_status;index:idx_deploy_tenant_agent"`
+	ID            uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_deploy_tenant_created,priority:3"`
+	TenantID      uuid.UUID         `json:"tenantId" gorm:"type:uuid;not null;index:idx_deploy_tenant;index:idx_deploy_tenant_status;index:idx_deploy_tenant_agent;index:idx_deploy_tenant_created,priority:1"`
 	ProfileID     *uuid.UUID        `json:"profileId" gorm:"type:uuid"` // Optional: null for audit/flush
 	AgentID       uuid.UUID         `json:"agentId" gorm:"type:uuid;not null;index:idx_deploy_tenant_agent"`
 	AgentName     string            `json:"agentName"`
@@ -353,8 +598,27 @@ type FirewallDeployment struct {
 	Output        string            `json:"output" gorm:"type:text"`         // Playbook output
 	StartedAt     *time.Time        `json:"startedAt"`
 	CompletedAt   *time.Time        `json:"completedAt"`
-	CreatedAt     time.Time         `json:"createdAt" gorm:"autoCreateTime"`
+	CreatedAt     time.Time         `json:"createdAt" gorm:"autoCreateTime;index:idx_deploy_tenant_created,priority:2"`
 	CreatedBy     uuid.UUID         `json:"createdBy" gorm:"type:uuid"`
+	RolloutID     *uuid.UUID        `json:"rolloutId,omitempty" gorm:"type:uuid"` // Set when part of a rolling deployment
+	BatchID       *uuid.UUID        `json:"batchId,omitempty" gorm:"type:uuid;index"` // Set when part of a bulk deployment
+	ChangeRef     string            `json:"changeRef" gorm:"index"`                // External change request / ticket ID
+	RetryOfID     *uuid.UUID        `json:"retryOfId,omitempty" gorm:"type:uuid"`  // Failed deployment this attempt retries
+	RestoredFrom  *uuid.UUID        `json:"restoredFrom,omitempty" gorm:"type:uuid"` // Deployment whose rules snapshot was re-applied
+	BackupKey     string            `json:"backupKey"`                             // firewall-backup artifact stored before applying
+	NftVersion    string            `json:"nftVersion"`                            // nft version detected on the agent before generating
+	ConfigHash    string            `json:"configHash" gorm:"index"`               // SHA-256 of the generated nftables config (see nftConfigHash)
+
+	// Read-back confirmation, requested with DeploymentInput.Verify
+	Verify          bool       `json:"verify"`                           // Read back the live ruleset after applying
+	Verified        bool       `json:"verified"`                         // Every deployed statement was found in the live ruleset
+	VerifiedRuleset string     `json:"verifiedRuleset" gorm:"type:text"` // Live ruleset read back after applying
+	VerifiedAt      *time.Time `json:"verifiedAt"`
+
+	// Resolved fields (not persisted)
+	SnapshotRules   []FirewallRule `json:"snapshotRules" gorm:"-"`   // RulesSnapshot decoded for clients
+	Maintenance     bool           `json:"maintenance" gorm:"-"`     // AUDIT or FLUSH record, not a profile deployment
+	DurationSeconds *int64         `json:"durationSeconds" gorm:"-"` // CompletedAt - StartedAt, nil until both are set
 
 	// Relations
 	Profile *FirewallProfile `json:"profile,omitempty" gorm:"foreignKey:ProfileID"`
@@ -365,28 +629,345 @@ func (FirewallDeployment) TableName() string {
 	return "firewall_deployments"
 }
 
+// deploymentStatsWindow is the period covered by DeploymentStats
+const deploymentStatsWindow = 30 * 24 * time.Hour
+
+// AgentDeploymentStats summarizes the apply durations of one agent
+type AgentDeploymentStats struct {
+	AgentID        uuid.UUID `json:"agentId"`
+	AgentName      string    `json:"agentName"`
+	Deployments    int       `json:"deployments"`
+	AverageSeconds float64   `json:"averageSeconds"`
+	MedianSeconds  float64   `json:"medianSeconds"`
+}
+
+// DeploymentStats summarizes how long successful APPLY deployments took over the stats window
+type DeploymentStats struct {
+	Since          time.Time              `json:"since"`
+	Deployments    int                    `json:"deployments"`
+	AverageSeconds float64                `json:"averageSeconds"`
+	MedianSeconds  float64                `json:"medianSeconds"`
+	Agents         []AgentDeploymentStats `json:"agents"` // Slowest average first
+}
+
 // DeploymentInput represents input for creating a deployment
 type DeploymentInput struct {
-	ProfileID string           `json:"profileId"` // Required for APPLY action
-	AgentID   string           `json:"agentId"`
-	Action    DeploymentAction `json:"action"`
-	DryRun    bool             `json:"dryRun"` // If true, only validate without applying
+	ProfileID     string           `json:"profileId"` // Required for APPLY action
+	AgentID       string           `json:"agentId"`
+	AgentHostname string           `json:"agentHostname"` // Alternative to AgentID, resolved via csd-core
+	Action        DeploymentAction `json:"action"`
+	DryRun        bool             `json:"dryRun"` // If true, only validate without applying
+	ChangeRef     string           `json:"changeRef"`
+	Force         bool             `json:"force"`  // Deploy even when the profile is disabled
+	Verify        bool             `json:"verify"` // Read back the live ruleset after applying
+}
+
+// BulkDeploymentInput represents input for deploying a profile to several agents at once
+type BulkDeploymentInput struct {
+	ProfileID string      `json:"profileId"`
+	AgentIDs  []uuid.UUID `json:"agentIds"`
+	ChangeRef string      `json:"changeRef"`
+	Force     bool        `json:"force"`  // Deploy even when the profile is disabled
+	Verify    bool        `json:"verify"` // Read back the live ruleset after applying
+}
+
+// DeploymentBatch groups the deployments of one bulk deployment
+type DeploymentBatch struct {
+	BatchID      uuid.UUID                `json:"batchId"`
+	Total        int                      `json:"total"`
+	StatusCounts map[DeploymentStatus]int `json:"statusCounts"`
+	Deployments  []FirewallDeployment     `json:"deployments,omitempty"` // Set when the batch is created
+	Failures     []FirewallDeployment     `json:"failures"`              // ERROR and UNKNOWN deployments
+}
+
+// RollbackPreview describes what a rollback would restore on an agent without applying it
+type RollbackPreview struct {
+	DeploymentID       uuid.UUID             `json:"deploymentId"`
+	AgentID            uuid.UUID             `json:"agentId"`
+	AgentName          string                `json:"agentName"`
+	TargetDeploymentID uuid.UUID             `json:"targetDeploymentId"` // Previous deployment the agent would revert to
+	BackupKey          string                `json:"backupKey"`
+	ProfileID          string                `json:"profileId"`
+	ProfileName        string                `json:"profileName"`
+	Config             string                `json:"config"`  // nftables config restored by the rollback
+	AuditID            *uuid.UUID            `json:"auditId"` // AUDIT deployment holding the live ruleset
+	Diff               []ComplianceDeviation `json:"diff"`    // MISSING: restored by the rollback, UNEXPECTED: removed from the live ruleset
+	Message            string                `json:"message"`
+}
+
+// ProfileAgentDeployment is the latest deployment of a profile on one agent
+type ProfileAgentDeployment struct {
+	AgentID       uuid.UUID        `json:"agentId"`
+	AgentName     string           `json:"agentName"`
+	DeploymentID  uuid.UUID        `json:"deploymentId"`
+	Status        DeploymentStatus `json:"status"`
+	StatusMessage string           `json:"statusMessage"`
+	DeployedAt    time.Time        `json:"deployedAt"`
+	CompletedAt   *time.Time       `json:"completedAt"`
+	Current       bool             `json:"current"` // The profile is still the last one applied on the agent
+}
+
+// AgentAuditStatus is the outcome of auditing one agent in a fleet audit
+type AgentAuditStatus string
+
+const (
+	AgentAuditInSync  AgentAuditStatus = "IN_SYNC"
+	AgentAuditDrifted AgentAuditStatus = "DRIFTED"
+	AgentAuditFailed  AgentAuditStatus = "FAILED"
+	AgentAuditPaused  AgentAuditStatus = "PAUSED" // Enforcement is paused, the agent was skipped
+)
+
+// AgentAuditResult describes the audit of a single agent
+type AgentAuditResult struct {
+	AgentID   uuid.UUID        `json:"agentId"`
+	AgentName string           `json:"agentName"`
+	AuditID   *uuid.UUID       `json:"auditId"` // AUDIT deployment record, nil if it could not be created
+	Status    AgentAuditStatus `json:"status"`
+	Message   string           `json:"message"`
+}
+
+// AgentSecurityStatus is the firewall health of one managed agent
+type AgentSecurityStatus struct {
+	AgentID          uuid.UUID           `json:"agentId"`
+	LatestDeployment *FirewallDeployment `json:"latestDeployment"` // Last APPLY, ROLLBACK or FLUSH; nil if never deployed
+	Status           DeploymentStatus    `json:"status"`
+	ProfileName      string              `json:"profileName"`
+	InSync           bool                `json:"inSync"`      // The applied rules still match the profile's current rules
+	LastAuditAt      *time.Time          `json:"lastAuditAt"` // Most recent AUDIT, nil if never audited
+}
+
+// FleetAuditSummary summarizes an audit of all nftables-capable agents
+type FleetAuditSummary struct {
+	Total   int                `json:"total"`
+	InSync  int                `json:"inSync"`
+	Drifted int                `json:"drifted"`
+	Failed  int                `json:"failed"`
+	Paused  int                `json:"paused"`
+	Results []AgentAuditResult `json:"results"`
+}
+
+// BackupContent is the decoded content of a firewall-backup artifact
+type BackupContent struct {
+	Key         string         `json:"key"`
+	AgentID     uuid.UUID      `json:"agentId"`
+	ProfileID   string         `json:"profileId"`
+	ProfileName string         `json:"profileName"`
+	Config      string         `json:"config"` // nftables config as deployed
+	Rules       []FirewallRule `json:"rules"`
+}
+
+// NamedCounter is a named nftables counter read from an agent
+type NamedCounter struct {
+	Name    string   `json:"name"`
+	Family  string   `json:"family"`
+	Table   string   `json:"table"`
+	Packets int64    `json:"packets"`
+	Bytes   int64    `json:"bytes"`
+	Rules   []string `json:"rules"` // Names of the rules incrementing the counter
+
+	RuleIDs []uuid.UUID `json:"-"` // IDs of the rules incrementing the counter
+}
+
+// RuleCounter is the hit count of one rule deployed on an agent.
+// Packets and Bytes are nil when the rule was deployed without a per-rule counter.
+type RuleCounter struct {
+	RuleID   uuid.UUID `json:"ruleId"`
+	RuleName string    `json:"ruleName"`
+	Chain    RuleChain `json:"chain"`
+	Packets  *int64    `json:"packets"`
+	Bytes    *int64    `json:"bytes"`
+}
+
+// FirewallCounterSnapshot is a point-in-time reading of a rule's named counter on an agent
+type FirewallCounterSnapshot struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID    uuid.UUID `json:"tenantId" gorm:"type:uuid;not null;index:idx_counter_snapshot_rule_time"`
+	RuleID      uuid.UUID `json:"ruleId" gorm:"type:uuid;not null;index:idx_counter_snapshot_rule_time"`
+	AgentID     uuid.UUID `json:"agentId" gorm:"type:uuid;not null"`
+	CounterName string    `json:"counterName"`
+	Packets     int64     `json:"packets"`
+	Bytes       int64     `json:"bytes"`
+	Timestamp   time.Time `json:"timestamp" gorm:"not null;index:idx_counter_snapshot_rule_time"`
+}
+
+// TableName returns the table name for GORM
+func (FirewallCounterSnapshot) TableName() string {
+	return "firewall_counter_snapshots"
+}
+
+// CounterSnapshotTarget is an agent whose counters are snapshotted by the counter-sync job
+type CounterSnapshotTarget struct {
+	TenantID uuid.UUID
+	AgentID  uuid.UUID
+}
+
+// ComplianceStatus is the outcome of comparing one agent against a baseline profile
+type ComplianceStatus string
+
+const (
+	ComplianceCompliant    ComplianceStatus = "COMPLIANT"
+	ComplianceNonCompliant ComplianceStatus = "NON_COMPLIANT"
+	ComplianceFailed       ComplianceStatus = "FAILED"
+)
+
+// ComplianceDeviationType classifies a difference between the baseline and a live ruleset
+type ComplianceDeviationType string
+
+const (
+	DeviationMissing    ComplianceDeviationType = "MISSING"    // In the baseline, absent from the agent
+	DeviationUnexpected ComplianceDeviationType = "UNEXPECTED" // On the agent, absent from the baseline
+)
+
+// ComplianceDeviation is a single statement that differs from the baseline
+type ComplianceDeviation struct {
+	Type      ComplianceDeviationType `json:"type"`
+	Statement string                  `json:"statement"`
+}
+
+// AgentComplianceResult describes how one agent's live ruleset deviates from the baseline
+type AgentComplianceResult struct {
+	AgentID    uuid.UUID             `json:"agentId"`
+	AgentName  string                `json:"agentName"`
+	AuditID    *uuid.UUID            `json:"auditId"` // AUDIT deployment record, nil if it could not be created
+	Status     ComplianceStatus      `json:"status"`
+	Score      float64               `json:"score"` // Percentage of matching statements (0-100)
+	Deviations []ComplianceDeviation `json:"deviations"`
+	Message    string                `json:"message"`
+}
+
+// ComplianceReport compares a set of agents against a baseline profile
+type ComplianceReport struct {
+	BaselineProfileID   uuid.UUID               `json:"baselineProfileId"`
+	BaselineProfileName string                  `json:"baselineProfileName"`
+	GeneratedAt         time.Time               `json:"generatedAt"`
+	AverageScore        float64                 `json:"averageScore"` // Over agents that were audited
+	Compliant           int                     `json:"compliant"`
+	NonCompliant        int                     `json:"nonCompliant"`
+	Failed              int                     `json:"failed"`
+	Results             []AgentComplianceResult `json:"results"`
+}
+
+// ProfileDiffLineType classifies a line of a profile-to-agent diff
+type ProfileDiffLineType string
+
+const (
+	DiffLineAdded     ProfileDiffLineType = "ADDED"     // On the agent, not generated by the profile
+	DiffLineRemoved   ProfileDiffLineType = "REMOVED"   // Generated by the profile, absent from the agent
+	DiffLineUnchanged ProfileDiffLineType = "UNCHANGED" // Present on both sides
+)
+
+// ProfileDiffLine is a single normalized statement of a profile-to-agent diff
+type ProfileDiffLine struct {
+	Type ProfileDiffLineType `json:"type"`
+	Line string              `json:"line"`
+}
+
+// ProfileDiff compares the config a profile generates with the live ruleset of an agent
+type ProfileDiff struct {
+	ProfileID   uuid.UUID         `json:"profileId"`
+	ProfileName string            `json:"profileName"`
+	AgentID     uuid.UUID         `json:"agentId"`
+	InSync      bool              `json:"inSync"`
+	Added       int               `json:"added"`
+	Removed     int               `json:"removed"`
+	Unchanged   int               `json:"unchanged"`
+	Lines       []ProfileDiffLine `json:"lines"` // In ruleset order
+}
+
+// FlushScope determines how much of the agent's ruleset a flush removes
+type FlushScope string
+
+const (
+	FlushScopeManaged FlushScope = "MANAGED" // Only the tables generated by pilote
+	FlushScopeAll     FlushScope = "ALL"     // The entire ruleset, including other tools' tables
+)
+
+// FlushInput represents input for flushing firewall rules on an agent
+type FlushInput struct {
+	AgentID          uuid.UUID  `json:"agentId"`
+	Scope            FlushScope `json:"scope"`            // Defaults to MANAGED
+	TableName        string     `json:"tableName"`        // Optional: single managed table to flush
+	ConfirmFullFlush bool       `json:"confirmFullFlush"` // Required for ALL scope
 }
 
 // ProfileExport represents an exported profile with its rules
 type ProfileExport struct {
-	Name        string                   `json:"name"`
-	Description string                   `json:"description"`
-	Rules       []TemplateRuleDefinition `json:"rules"`
-	ExportedAt  string                   `json:"exportedAt"`
-	ExportedBy  string                   `json:"exportedBy,omitempty"`
+	SchemaVersion string                   `json:"schemaVersion"` // Export format version (see profileExportSchemaVersion)
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description"`
+	Rules         []TemplateRuleDefinition `json:"rules"`
+	IPSets        []IPSetExport            `json:"ipSets"`      // IP sets referenced by the rules
+	AgentGroups   []AgentGroupExport       `json:"agentGroups"` // Target groups requested with the export
+	ExportedAt    string                   `json:"exportedAt"`
+	ExportedBy    string                   `json:"exportedBy,omitempty"`
+	AppVersion    string                   `json:"appVersion"` // csd-pilote version that wrote the export
 }
 
 // ProfileImportInput represents input for importing a profile
 type ProfileImportInput struct {
-	Name        string                   `json:"name,omitempty"` // Override name
-	Description string                   `json:"description,omitempty"`
-	Rules       []TemplateRuleDefinition `json:"rules"`
+	SchemaVersion string                   `json:"schemaVersion,omitempty"` // Export format version, when the payload carries one
+	Name          string                   `json:"name,omitempty"`          // Override name
+	Description   string                   `json:"description,omitempty"`
+	Rules         []TemplateRuleDefinition `json:"rules"`
+	IPSets        []IPSetExport            `json:"ipSets,omitempty"`      // Must contain every set the rules reference
+	AgentGroups   []AgentGroupExport       `json:"agentGroups,omitempty"` // Created unless a group with the same name exists
+}
+
+// IPSetExport is an IP set carried in a profile export
+type IPSetExport struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Elements    []string `json:"elements"`
+}
+
+// AgentGroupExport is an agent group carried in a profile export. Members are listed by
+// hostname in rollout order so the group can be rebuilt against another environment's agents.
+type AgentGroupExport struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	AgentHostnames []string `json:"agentHostnames"`
+}
+
+// ImportRuleError describes a validation problem found in an import
+type ImportRuleError struct {
+	Index   int    `json:"index"` // Position in the imported rules, -1 for profile-level problems
+	Name    string `json:"name"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// RuleCreationFailure reports a rule that could not be created during an import or template apply
+type RuleCreationFailure struct {
+	Index int    `json:"index"` // Position in the imported or template rules
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// ProfileImportValidation is the result of validating an import without persisting it
+type ProfileImportValidation struct {
+	Valid     bool              `json:"valid"`
+	RuleCount int               `json:"ruleCount"`
+	Chains    map[string]int    `json:"chains"`  // Rule count per chain
+	Actions   map[string]int    `json:"actions"` // Rule count per action
+	Errors    []ImportRuleError `json:"errors"`
+}
+
+// PolicyCheckResult is the outcome of validating and generating a profile definition without persisting it
+type PolicyCheckResult struct {
+	Passed     bool                     `json:"passed"`
+	Validation *ProfileImportValidation `json:"validation"`
+	Warnings   []LintFinding            `json:"warnings"`   // Lint findings; they do not fail the check
+	Config     string                   `json:"config"`     // Generated nftables config, empty when validation fails
+	AgentCheck *AgentConfigCheck        `json:"agentCheck"` // Set when an agent was designated
+}
+
+// AgentConfigCheck is the result of checking a generated config on an agent with "nft -c"
+type AgentConfigCheck struct {
+	AgentID uuid.UUID `json:"agentId"`
+	Passed  bool      `json:"passed"`
+	Output  string    `json:"output"`
+	Error   string    `json:"error,omitempty"`
 }
 
 // FirewallDeploymentFilter represents filter options for listing deployments
@@ -396,4 +977,278 @@ type FirewallDeploymentFilter struct {
 	AgentID   *string           `json:"agentId"`
 	Action    *DeploymentAction `json:"action"`
 	Status    *DeploymentStatus `json:"status"`
+	// Actions to leave out, e.g. AUDIT and FLUSH to list only profile deployments
+	ExcludeActions []DeploymentAction `json:"excludeActions"`
+	RolloutID *string           `json:"rolloutId"`
+	BatchID   *string           `json:"batchId"`
+	ChangeRef *string           `json:"changeRef"`
+}
+
+// ========================================
+// IP Sets
+// ========================================
+
+// FirewallIPSet is a tenant-wide named set of IPv4 addresses and networks.
+// Rules reference a set by name with "@name" in SourceIP or DestIP.
+type FirewallIPSet struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID    uuid.UUID `json:"tenantId" gorm:"type:uuid;not null;uniqueIndex:idx_ipset_tenant_name"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex:idx_ipset_tenant_name"`
+	Description string    `json:"description"`
+	Elements    []string  `json:"elements" gorm:"type:jsonb;serializer:json"` // IP addresses or CIDRs
+	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	CreatedBy   uuid.UUID `json:"createdBy" gorm:"type:uuid"`
+}
+
+// TableName returns the table name for GORM
+func (FirewallIPSet) TableName() string {
+	return "firewall_ip_sets"
+}
+
+// FirewallIPSetInput represents input for creating/updating an IP set
+type FirewallIPSetInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Elements    []string `json:"elements"`
+}
+
+// IPSetUsage lists the rules and profiles that reference an IP set
+type IPSetUsage struct {
+	IPSet    *FirewallIPSet    `json:"ipSet"`
+	Rules    []FirewallRule    `json:"rules"`
+	Profiles []FirewallProfile `json:"profiles"`
+}
+
+// ========================================
+// Agent Groups
+// ========================================
+
+// FirewallAgentGroup represents a named set of agents targeted together
+type FirewallAgentGroup struct {
+	ID          uuid.UUID                  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID    uuid.UUID                  `json:"tenantId" gorm:"type:uuid;not null;index"`
+	Name        string                     `json:"name" gorm:"not null"`
+	Description string                     `json:"description"`
+	CreatedAt   time.Time                  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time                  `json:"updatedAt" gorm:"autoUpdateTime"`
+	CreatedBy   uuid.UUID                  `json:"createdBy" gorm:"type:uuid"`
+	Members     []FirewallAgentGroupMember `json:"members,omitempty" gorm:"foreignKey:GroupID"`
+}
+
+// TableName returns the table name for GORM
+func (FirewallAgentGroup) TableName() string {
+	return "firewall_agent_groups"
+}
+
+// FirewallAgentGroupMember links an agent to a group
+type FirewallAgentGroupMember struct {
+	GroupID   uuid.UUID `json:"groupId" gorm:"type:uuid;primaryKey"`
+	AgentID   uuid.UUID `json:"agentId" gorm:"type:uuid;primaryKey"`
+	SortOrder int       `json:"sortOrder" gorm:"default:0"` // Rollout order within the group
+}
+
+// TableName returns the table name for GORM
+func (FirewallAgentGroupMember) TableName() string {
+	return "firewall_agent_group_members"
+}
+
+// FirewallAgentGroupInput represents input for creating/updating an agent group
+type FirewallAgentGroupInput struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	AgentIDs       []string `json:"agentIds"`       // Ordered: rollouts follow this order
+	AgentHostnames []string `json:"agentHostnames"` // Resolved via csd-core and appended after AgentIDs
+}
+
+// InFlightOperation is a pending or running deployment operation
+type InFlightOperation struct {
+	ID            uuid.UUID        `json:"id"`
+	Action        DeploymentAction `json:"action"`
+	Status        DeploymentStatus `json:"status"`
+	StatusMessage string           `json:"statusMessage"`
+	ProfileID     *uuid.UUID       `json:"profileId"`
+	AgentID       uuid.UUID        `json:"agentId"`
+	AgentName     string           `json:"agentName"`
+	RolloutID     *uuid.UUID       `json:"rolloutId,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	StartedAt     *time.Time       `json:"startedAt"`
+	AgeSeconds    int64            `json:"ageSeconds"`
+	Running       bool             `json:"running"` // A worker in this instance is executing it
+}
+
+// ========================================
+// Rolling Deployments
+// ========================================
+
+// RolloutStatus represents the status of a rolling deployment
+type RolloutStatus string
+
+const (
+	RolloutStatusPending   RolloutStatus = "PENDING"
+	RolloutStatusRunning   RolloutStatus = "RUNNING"
+	RolloutStatusCompleted RolloutStatus = "COMPLETED"
+	RolloutStatusAborted   RolloutStatus = "ABORTED"
+)
+
+// FirewallRollout tracks a batched deployment of a profile to an agent group
+type FirewallRollout struct {
+	ID              uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID        uuid.UUID     `json:"tenantId" gorm:"type:uuid;not null;index"`
+	ProfileID       uuid.UUID     `json:"profileId" gorm:"type:uuid;not null"`
+	GroupID         uuid.UUID     `json:"groupId" gorm:"type:uuid;not null"`
+	BatchSize       int           `json:"batchSize"`
+	PauseSeconds    int           `json:"pauseSeconds"`
+	TotalAgents     int           `json:"totalAgents"`
+	TotalBatches    int           `json:"totalBatches"`
+	CurrentBatch    int           `json:"currentBatch"`    // 1-based index of the batch in progress
+	CompletedAgents int           `json:"completedAgents"` // Agents applied and verified
+	Status          RolloutStatus `json:"status" gorm:"default:'PENDING'"`
+	StatusMessage   string        `json:"statusMessage"`
+	ChangeRef       string        `json:"changeRef"` // Copied to every deployment of the rollout
+	AgentIDs        []uuid.UUID   `json:"agentIds" gorm:"type:jsonb;serializer:json"` // Members when the rollout started
+	ResumeCount     int           `json:"resumeCount"`
+	StartedAt       *time.Time    `json:"startedAt"`
+	CompletedAt     *time.Time    `json:"completedAt"`
+	CreatedAt       time.Time     `json:"createdAt" gorm:"autoCreateTime"`
+	CreatedBy       uuid.UUID     `json:"createdBy" gorm:"type:uuid"`
+}
+
+// TableName returns the table name for GORM
+func (FirewallRollout) TableName() string {
+	return "firewall_rollouts"
+}
+
+// RolloutInput represents input for a rolling deployment to an agent group
+type RolloutInput struct {
+	ProfileID    string `json:"profileId"`
+	GroupID      string `json:"groupId"`
+	BatchSize    int    `json:"batchSize"`    // Agents deployed per batch
+	PauseSeconds int    `json:"pauseSeconds"` // Wait between successful batches
+	ChangeRef    string `json:"changeRef"`
+	Force        bool   `json:"force"` // Deploy even when the profile is disabled
+}
+
+// ========================================
+// Enforcement Pauses
+// ========================================
+
+// FirewallEnforcementPause suspends drift enforcement on an agent so that manual
+// changes on the host are left alone until it is resumed
+type FirewallEnforcementPause struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID  uuid.UUID  `json:"tenantId" gorm:"type:uuid;not null;uniqueIndex:idx_enforcement_pause_agent"`
+	AgentID   uuid.UUID  `json:"agentId" gorm:"type:uuid;not null;uniqueIndex:idx_enforcement_pause_agent"`
+	AgentName string     `json:"agentName"`
+	Reason    string     `json:"reason"`
+	ResumeAt  *time.Time `json:"resumeAt"` // Enforcement resumes automatically after this time, nil pauses until resumed
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	PausedBy  uuid.UUID  `json:"pausedBy" gorm:"type:uuid"`
+}
+
+// TableName returns the table name for GORM
+func (FirewallEnforcementPause) TableName() string {
+	return "firewall_enforcement_pauses"
+}
+
+// FirewallSchedule re-applies a profile to an agent on a cron schedule to correct drift
+type FirewallSchedule struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TenantID         uuid.UUID  `json:"tenantId" gorm:"type:uuid;not null;index"`
+	ProfileID        uuid.UUID  `json:"profileId" gorm:"type:uuid;not null;index"`
+	AgentID          uuid.UUID  `json:"agentId" gorm:"type:uuid;not null"`
+	AgentName        string     `json:"agentName"`
+	CronExpr         string     `json:"cronExpr" gorm:"not null"` // Standard 5-field expression or descriptor (@daily), evaluated in UTC
+	Enabled          bool       `json:"enabled" gorm:"not null;index:idx_schedule_due"`
+	LastRunAt        *time.Time `json:"lastRunAt"`
+	NextRunAt        *time.Time `json:"nextRunAt" gorm:"index:idx_schedule_due"` // Nil while disabled
+	LastDeploymentID *uuid.UUID `json:"lastDeploymentId" gorm:"type:uuid"`
+	LastError        string     `json:"lastError"` // Why the last run did not start a deployment
+	CreatedAt        time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	CreatedBy        uuid.UUID  `json:"createdBy" gorm:"type:uuid"` // Scheduled deployments are made on behalf of this user
+}
+
+// TableName returns the table name for GORM
+func (FirewallSchedule) TableName() string {
+	return "firewall_schedules"
+}
+
+// FirewallScheduleInput represents input for creating/updating a deployment schedule
+type FirewallScheduleInput struct {
+	ProfileID string `json:"profileId"`
+	AgentID   string `json:"agentId"`
+	CronExpr  string `json:"cronExpr"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// Active reports whether the pause is still in effect at the given time
+func (p *FirewallEnforcementPause) Active(now time.Time) bool {
+	return p.ResumeAt == nil || now.Before(*p.ResumeAt)
+}
+
+// ========================================
+// Packet Simulation
+// ========================================
+
+// SimulatedPacket describes a hypothetical packet to test against a profile
+type SimulatedPacket struct {
+	Chain        RuleChain    `json:"chain"` // Chain the packet traverses (default INPUT)
+	Protocol     RuleProtocol `json:"protocol"`
+	SourceIP     string       `json:"sourceIp"`
+	DestIP       string       `json:"destIp"`
+	SourcePort   int          `json:"sourcePort"`
+	DestPort     int          `json:"destPort"`
+	InInterface  string       `json:"inInterface"`
+	OutInterface string       `json:"outInterface"`
+	CTState      string       `json:"ctState"`  // Connection tracking state (default NEW)
+	ICMPType     string       `json:"icmpType"` // ICMP type for ICMP packets (default echo-request)
+	DSCP         string       `json:"dscp"`     // DSCP class name or value (default cs0)
+	Length       int          `json:"length"`   // Packet length in bytes
+}
+
+// RuleMatchQuery selects rules by the traffic they match, e.g. "who can reach 10.0.0.5 on 22"
+type RuleMatchQuery struct {
+	IP           string       `json:"ip"`           // IP address or CIDR, compared to source and destination
+	Port         int          `json:"port"`         // Compared to source and destination ports
+	Protocol     RuleProtocol `json:"protocol"`     // TCP, UDP or ICMP
+	SpecificOnly bool         `json:"specificOnly"` // Ignore rules that only match through an unset (any) field
+}
+
+// RuleMatchAgent is an agent where a profile containing a matching rule is currently applied
+type RuleMatchAgent struct {
+	AgentID   uuid.UUID `json:"agentId"`
+	AgentName string    `json:"agentName"`
+}
+
+// RuleMatchProfile is a profile containing a matching rule
+type RuleMatchProfile struct {
+	ProfileID   uuid.UUID        `json:"profileId"`
+	ProfileName string           `json:"profileName"`
+	Enabled     bool             `json:"enabled"`
+	Agents      []RuleMatchAgent `json:"agents"`
+}
+
+// RuleMatch is a rule overlapping a RuleMatchQuery
+type RuleMatch struct {
+	Rule      FirewallRule       `json:"rule"`
+	MatchedOn []string           `json:"matchedOn"` // Fields that overlap the query, e.g. destIp, destPort (any)
+	Profiles  []RuleMatchProfile `json:"profiles"`
+}
+
+// RuleMatchSearchResult lists the rules overlapping a query and the profiles and agents they reach
+type RuleMatchSearchResult struct {
+	Matches        []RuleMatch      `json:"matches"`
+	AffectedAgents []RuleMatchAgent `json:"affectedAgents"` // Distinct agents across all matches
+	SkippedRules   []string         `json:"skippedRules"`   // Raw-expression rules that cannot be evaluated
+}
+
+// PacketSimulationResult describes which rule decides a simulated packet's fate
+type PacketSimulationResult struct {
+	Verdict         string        `json:"verdict"`                   // accept, drop, reject, or the NAT action taken
+	MatchedRule     *FirewallRule `json:"matchedRule"`               // User rule that decided the verdict, if any
+	MatchedBaseRule string        `json:"matchedBaseRule,omitempty"` // Base rule that decided the verdict, if any
+	DecidedByPolicy bool          `json:"decidedByPolicy"`           // True when no rule matched and the chain policy applied
+	LoggedBy        []string      `json:"loggedBy"`                  // Non-terminal LOG rules matched on the way
+	SkippedRules    []string      `json:"skippedRules"`              // Raw-expression rules that cannot be evaluated
 }
@@ -2,12 +2,14 @@ package security
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"csd-pilote/backend/modules/platform/database"
 	"csd-pilote/backend/modules/platform/filters"
+	"csd-pilote/backend/modules/platform/pagination"
 )
 
 // Repository handles database operations for security entities
@@ -42,8 +44,54 @@ func (r *Repository) GetRuleByID(tenantID, id uuid.UUID) (*FirewallRule, error)
 // ListRules retrieves all rules for a tenant with optional filtering
 func (r *Repository) ListRules(tenantID uuid.UUID, filter *FirewallRuleFilter, limit, offset int) ([]FirewallRule, int64, error) {
 	var rules []FirewallRule
-	var count int64
 
+	query := r.ruleListQuery(tenantID, filter)
+	count, err := pagination.CurrentCountStrategy().Count(query, !filter.isEmpty())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order(ruleListOrder).Limit(limit).Offset(offset).Find(&rules).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return rules, count, nil
+}
+
+// ruleListOrder is the order of rule lists, in offset and cursor mode alike; id breaks ties
+const ruleListOrder = "priority ASC, created_at DESC, id DESC"
+
+// ListRulesAfter retrieves a keyset page of rules in ruleListOrder, starting after the cursor
+// (from the first rule when it is nil). The cursor must carry the priority of its rule.
+// The count covers every rule matching the filter
+func (r *Repository) ListRulesAfter(tenantID uuid.UUID, filter *FirewallRuleFilter, cursor *pagination.Cursor, limit int) ([]FirewallRule, int64, error) {
+	var rules []FirewallRule
+
+	query := r.ruleListQuery(tenantID, filter)
+	count, err := pagination.CurrentCountStrategy().Count(query, !filter.isEmpty())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// priority ascends while (created_at, id) descends, so no single row comparison fits
+	if cursor != nil && cursor.Priority != nil {
+		query = query.Where("(priority > ? OR (priority = ? AND (created_at, id) < (?, ?)))",
+			*cursor.Priority, *cursor.Priority, cursor.CreatedAt, cursor.ID)
+	}
+	if err := query.Order(ruleListOrder).Limit(limit).Find(&rules).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return rules, count, nil
+}
+
+// isEmpty reports whether the filter has no condition, so a list uses only the tenant scope
+func (f *FirewallRuleFilter) isEmpty() bool {
+	return f == nil || ((f.Search == nil || *f.Search == "") && f.Chain == nil && f.Protocol == nil && f.Action == nil && f.Enabled == nil)
+}
+
+// ruleListQuery returns the query of the rules of a tenant matching filter
+func (r *Repository) ruleListQuery(tenantID uuid.UUID, filter *FirewallRuleFilter) *gorm.DB {
 	query := r.db.Model(&FirewallRule{}).Where("tenant_id = ?", tenantID)
 
 	if filter != nil {
@@ -64,16 +112,7 @@ func (r *Repository) ListRules(tenantID uuid.UUID, filter *FirewallRuleFilter, l
 			query = query.Where("enabled = ?", *filter.Enabled)
 		}
 	}
-
-	if err := query.Count(&count).Error; err != nil {
-		return nil, 0, err
-	}
-
-	if err := query.Order("priority ASC, created_at DESC").Limit(limit).Offset(offset).Find(&rules).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return rules, count, nil
+	return query
 }
 
 // UpdateRule updates a firewall rule
@@ -88,6 +127,15 @@ func (r *Repository) DeleteRule(tenantID, id uuid.UUID) error {
 	return r.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&FirewallRule{}).Error
 }
 
+// GetProfilesUsingRule returns the profiles of a tenant that include a rule
+func (r *Repository) GetProfilesUsingRule(tenantID, ruleID uuid.UUID) ([]FirewallProfile, error) {
+	var profiles []FirewallProfile
+	err := r.db.Where("tenant_id = ? AND id IN (?)", tenantID,
+		r.db.Model(&FirewallProfileRule{}).Select("profile_id").Where("rule_id = ?", ruleID)).
+		Order("name").Find(&profiles).Error
+	return profiles, err
+}
+
 // BulkDeleteRules deletes multiple rules by IDs
 func (r *Repository) BulkDeleteRules(tenantID uuid.UUID, ids []uuid.UUID) (int64, error) {
 	var rowsAffected int64
@@ -109,6 +157,78 @@ func (r *Repository) BulkDeleteRules(tenantID uuid.UUID, ids []uuid.UUID) (int64
 	return rowsAffected, err
 }
 
+// BulkSetRulesEnabled enables or disables multiple rules by IDs and returns the IDs of the
+// rules of the tenant that were updated
+func (r *Repository) BulkSetRulesEnabled(tenantID uuid.UUID, ids []uuid.UUID, enabled bool) ([]uuid.UUID, error) {
+	var updated []uuid.UUID
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&FirewallRule{}).
+			Where("tenant_id = ? AND id IN ?", tenantID, ids).
+			Pluck("id", &updated).Error; err != nil {
+			return err
+		}
+		if len(updated) == 0 {
+			return nil
+		}
+		return tx.Model(&FirewallRule{}).
+			Where("tenant_id = ? AND id IN ?", tenantID, updated).
+			Update("enabled", enabled).Error
+	})
+
+	return updated, err
+}
+
+// NormalizeProfileRulePriorities renumbers a profile's rules in steps of step, keeping their
+// current evaluation order (priority, then creation time)
+func (r *Repository) NormalizeProfileRulePriorities(tenantID, profileID uuid.UUID, step int) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND id IN (?)", tenantID,
+			tx.Model(&FirewallProfileRule{}).Select("rule_id").Where("profile_id = ?", profileID)).
+			Order("priority ASC, created_at ASC, id ASC").
+			Find(&rules).Error; err != nil {
+			return err
+		}
+		for i := range rules {
+			rules[i].Priority = (i + 1) * step
+			if err := tx.Model(&FirewallRule{}).Where("id = ?", rules[i].ID).
+				Update("priority", rules[i].Priority).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// BulkSetRulePriorities sets the priority of several rules atomically (validates tenant ownership)
+func (r *Repository) BulkSetRulePriorities(tenantID uuid.UUID, priorities map[uuid.UUID]int) error {
+	ids := make([]uuid.UUID, 0, len(priorities))
+	for id := range priorities {
+		ids = append(ids, id)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&FirewallRule{}).Where("tenant_id = ? AND id IN ?", tenantID, ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return gorm.ErrRecordNotFound // Some rules don't belong to this tenant
+		}
+		for id, priority := range priorities {
+			if err := tx.Model(&FirewallRule{}).Where("id = ?", id).Update("priority", priority).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // CountRules returns the total count of rules for a tenant
 func (r *Repository) CountRules(tenantID uuid.UUID) (int64, error) {
 	var count int64
@@ -171,6 +291,44 @@ func (r *Repository) CreateProfile(profile *FirewallProfile) error {
 	return r.db.Create(profile).Error
 }
 
+// CloneProfile creates a copy of a profile with copies of its rules, linked in the given order.
+// Create replaces false flags by their column defaults (true), so they are written back afterwards.
+func (r *Repository) CloneProfile(profile *FirewallProfile, rules []FirewallRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		flags := map[string]interface{}{
+			"enabled":           profile.Enabled,
+			"enable_conntrack":  profile.EnableConntrack,
+			"allow_loopback":    profile.AllowLoopback,
+			"allow_established": profile.AllowEstablished,
+			"allow_icmp_ping":   profile.AllowICMPPing,
+		}
+		if err := tx.Omit("Rules").Create(profile).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(profile).UpdateColumns(flags).Error; err != nil {
+			return err
+		}
+
+		for i := range rules {
+			enabled := rules[i].Enabled
+			rules[i].ID = uuid.Nil
+			if err := tx.Create(&rules[i]).Error; err != nil {
+				return err
+			}
+			if !enabled {
+				if err := tx.Model(&rules[i]).UpdateColumn("enabled", false).Error; err != nil {
+					return err
+				}
+			}
+			link := FirewallProfileRule{ProfileID: profile.ID, RuleID: rules[i].ID, SortOrder: rules[i].ProfileSortOrder}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // GetProfileByID retrieves a profile by ID
 func (r *Repository) GetProfileByID(tenantID, id uuid.UUID) (*FirewallProfile, error) {
 	var profile FirewallProfile
@@ -190,13 +348,25 @@ func (r *Repository) GetProfileByIDWithRules(tenantID, id uuid.UUID) (*FirewallP
 	if err != nil {
 		return nil, err
 	}
+
+	// Positions of the rules in the profile, used by the BY_SORT_ORDER strategy
+	var links []FirewallProfileRule
+	if err := r.db.Where("profile_id = ?", id).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	sortOrders := make(map[uuid.UUID]int, len(links))
+	for _, link := range links {
+		sortOrders[link.RuleID] = link.SortOrder
+	}
+	for i := range profile.Rules {
+		profile.Rules[i].ProfileSortOrder = sortOrders[profile.Rules[i].ID]
+	}
 	return &profile, nil
 }
 
 // ListProfiles retrieves all profiles for a tenant with optional filtering
 func (r *Repository) ListProfiles(tenantID uuid.UUID, filter *FirewallProfileFilter, limit, offset int) ([]FirewallProfile, int64, error) {
 	var profiles []FirewallProfile
-	var count int64
 
 	query := r.db.Model(&FirewallProfile{}).Where("tenant_id = ?", tenantID)
 
@@ -213,7 +383,9 @@ func (r *Repository) ListProfiles(tenantID uuid.UUID, filter *FirewallProfileFil
 		}
 	}
 
-	if err := query.Count(&count).Error; err != nil {
+	filtered := filter != nil && ((filter.Search != nil && *filter.Search != "") || filter.IsDefault != nil || filter.Enabled != nil)
+	count, err := pagination.CurrentCountStrategy().Count(query, filtered)
+	if err != nil {
 		return nil, 0, err
 	}
 
@@ -221,9 +393,52 @@ func (r *Repository) ListProfiles(tenantID uuid.UUID, filter *FirewallProfileFil
 		return nil, 0, err
 	}
 
+	if err := r.attachLastDeployments(tenantID, profiles); err != nil {
+		return nil, 0, err
+	}
+
 	return profiles, count, nil
 }
 
+// attachLastDeployments fills the latest deployment status and time of each profile
+// with a single query across all agents
+func (r *Repository) attachLastDeployments(tenantID uuid.UUID, profiles []FirewallProfile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(profiles))
+	for i, p := range profiles {
+		ids[i] = p.ID
+	}
+
+	var latest []struct {
+		ProfileID uuid.UUID
+		Status    DeploymentStatus
+		CreatedAt time.Time
+	}
+	err := r.db.Model(&FirewallDeployment{}).
+		Select("DISTINCT ON (profile_id) profile_id, status, created_at").
+		Where("tenant_id = ? AND profile_id IN ?", tenantID, ids).
+		Order("profile_id, created_at DESC").
+		Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	byProfile := make(map[uuid.UUID]int, len(latest))
+	for i, l := range latest {
+		byProfile[l.ProfileID] = i
+	}
+	for i := range profiles {
+		if j, ok := byProfile[profiles[i].ID]; ok {
+			profiles[i].LastDeploymentStatus = &latest[j].Status
+			profiles[i].LastDeployedAt = &latest[j].CreatedAt
+		}
+	}
+	return nil
+}
+
 // UpdateProfile updates a firewall profile
 func (r *Repository) UpdateProfile(profile *FirewallProfile) error {
 	return r.db.Save(profile).Error
@@ -280,6 +495,78 @@ func (r *Repository) AddRulesToProfile(tenantID, profileID uuid.UUID, ruleIDs []
 	return nil
 }
 
+// ReorderProfileRules rewrites the sort order of a profile's rules to follow orderedRuleIDs and
+// switches the profile to the BY_SORT_ORDER strategy so the generator honors it
+func (r *Repository) ReorderProfileRules(profileID uuid.UUID, orderedRuleIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, ruleID := range orderedRuleIDs {
+			result := tx.Model(&FirewallProfileRule{}).
+				Where("profile_id = ? AND rule_id = ?", profileID, ruleID).
+				Update("sort_order", i)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return tx.Model(&FirewallProfile{}).Where("id = ?", profileID).
+			Update("rule_order_strategy", RuleOrderBySortOrder).Error
+	})
+}
+
+// ListEnabledRules retrieves all enabled rules of a tenant
+func (r *Repository) ListEnabledRules(tenantID uuid.UUID) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	err := r.db.Where("tenant_id = ? AND enabled = ?", tenantID, true).
+		Order("chain ASC, priority ASC, name ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetProfilesForRules returns the profiles containing each of the given rules
+func (r *Repository) GetProfilesForRules(tenantID uuid.UUID, ruleIDs []uuid.UUID) (map[uuid.UUID][]FirewallProfile, error) {
+	result := make(map[uuid.UUID][]FirewallProfile)
+	if len(ruleIDs) == 0 {
+		return result, nil
+	}
+
+	var links []FirewallProfileRule
+	if err := r.db.Where("rule_id IN ?", ruleIDs).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	profileIDs := make([]uuid.UUID, 0, len(links))
+	for _, link := range links {
+		profileIDs = append(profileIDs, link.ProfileID)
+	}
+
+	var profiles []FirewallProfile
+	if len(profileIDs) > 0 {
+		if err := r.db.Where("tenant_id = ? AND id IN ?", tenantID, profileIDs).Order("name ASC").Find(&profiles).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uuid.UUID]FirewallProfile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+	for _, link := range links {
+		if profile, ok := byID[link.ProfileID]; ok {
+			result[link.RuleID] = append(result[link.RuleID], profile)
+		}
+	}
+	return result, nil
+}
+
+// GetProfileIDsForRule returns the IDs of the profiles containing a rule
+func (r *Repository) GetProfileIDsForRule(ruleID uuid.UUID) ([]uuid.UUID, error) {
+	var profileIDs []uuid.UUID
+	err := r.db.Model(&FirewallProfileRule{}).
+		Where("rule_id = ?", ruleID).
+		Pluck("profile_id", &profileIDs).Error
+	return profileIDs, err
+}
+
 // RemoveRulesFromProfile removes rules from a profile
 func (r *Repository) RemoveRulesFromProfile(profileID uuid.UUID, ruleIDs []uuid.UUID) error {
 	return r.db.Where("profile_id = ? AND rule_id IN ?", profileID, ruleIDs).Delete(&FirewallProfileRule{}).Error
@@ -307,23 +594,32 @@ func (r *Repository) CreateTemplate(template *FirewallTemplate) error {
 	return r.db.Create(template).Error
 }
 
-// GetTemplateByID retrieves a template by ID
-func (r *Repository) GetTemplateByID(tenantID, id uuid.UUID) (*FirewallTemplate, error) {
+// visibleTemplates restricts a template query to the templates a user of a tenant can see:
+// built-ins, templates shared by any tenant, the tenant's TENANT templates and the user's PRIVATE ones.
+// FirewallTemplate.VisibleTo applies the same rules in memory.
+func visibleTemplates(tenantID, userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("is_built_in = true OR visibility = ? OR (tenant_id = ? AND (visibility <> ? OR created_by = ?))",
+			TemplateVisibilityShared, tenantID, TemplateVisibilityPrivate, userID)
+	}
+}
+
+// GetTemplateByID retrieves a template visible to a user of a tenant by ID
+func (r *Repository) GetTemplateByID(tenantID, userID, id uuid.UUID) (*FirewallTemplate, error) {
 	var template FirewallTemplate
-	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&template).Error
+	err := r.db.Scopes(visibleTemplates(tenantID, userID)).Where("id = ?", id).First(&template).Error
 	if err != nil {
 		return nil, err
 	}
 	return &template, nil
 }
 
-// ListTemplates retrieves all templates for a tenant with optional filtering
-func (r *Repository) ListTemplates(tenantID uuid.UUID, filter *FirewallTemplateFilter, limit, offset int) ([]FirewallTemplate, int64, error) {
+// ListTemplates retrieves the templates visible to a user of a tenant with optional filtering
+func (r *Repository) ListTemplates(tenantID, userID uuid.UUID, filter *FirewallTemplateFilter, limit, offset int) ([]FirewallTemplate, int64, error) {
 	var templates []FirewallTemplate
 	var count int64
 
-	// Include system-wide templates (tenant_id is null) and tenant-specific
-	query := r.db.Model(&FirewallTemplate{}).Where("tenant_id = ? OR is_built_in = true", tenantID)
+	query := r.db.Model(&FirewallTemplate{}).Scopes(visibleTemplates(tenantID, userID))
 
 	if filter != nil {
 		if filter.Search != nil && *filter.Search != "" {
@@ -336,6 +632,9 @@ func (r *Repository) ListTemplates(tenantID uuid.UUID, filter *FirewallTemplateF
 		if filter.IsBuiltIn != nil {
 			query = query.Where("is_built_in = ?", *filter.IsBuiltIn)
 		}
+		if filter.Visibility != nil {
+			query = query.Where("visibility = ?", *filter.Visibility)
+		}
 	}
 
 	if err := query.Count(&count).Error; err != nil {
@@ -354,16 +653,23 @@ func (r *Repository) UpdateTemplate(template *FirewallTemplate) error {
 	return r.db.Save(template).Error
 }
 
+// ListBuiltInTemplates returns every built-in template
+func (r *Repository) ListBuiltInTemplates() ([]FirewallTemplate, error) {
+	var templates []FirewallTemplate
+	err := r.db.Where("is_built_in = true").Order("created_at").Find(&templates).Error
+	return templates, err
+}
+
 // DeleteTemplate deletes a firewall template
 func (r *Repository) DeleteTemplate(tenantID, id uuid.UUID) error {
 	// Don't allow deleting built-in templates
 	return r.db.Where("tenant_id = ? AND id = ? AND is_built_in = false", tenantID, id).Delete(&FirewallTemplate{}).Error
 }
 
-// CountTemplates returns the total count of templates for a tenant
-func (r *Repository) CountTemplates(tenantID uuid.UUID) (int64, error) {
+// CountTemplates returns the total count of templates visible to a user of a tenant
+func (r *Repository) CountTemplates(tenantID, userID uuid.UUID) (int64, error) {
 	var count int64
-	err := r.db.Model(&FirewallTemplate{}).Where("tenant_id = ? OR is_built_in = true", tenantID).Count(&count).Error
+	err := r.db.Model(&FirewallTemplate{}).Scopes(visibleTemplates(tenantID, userID)).Count(&count).Error
 	return count, err
 }
 
@@ -377,15 +683,40 @@ func (r *Repository) GetTemplateRules(template *FirewallTemplate) ([]TemplateRul
 	return rules, err
 }
 
+// GetTemplateSettings parses the profile settings of a template; nil when the template has none
+func (r *Repository) GetTemplateSettings(template *FirewallTemplate) (*TemplateSettings, error) {
+	if template.SettingsJSON == "" || template.SettingsJSON == "null" {
+		return nil, nil
+	}
+	var settings TemplateSettings
+	if err := json.Unmarshal([]byte(template.SettingsJSON), &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
 // ========================================
 // Firewall Deployments
 // ========================================
 
 // CreateDeployment creates a new firewall deployment
 func (r *Repository) CreateDeployment(deployment *FirewallDeployment) error {
+	deployment.Maintenance = deployment.Action.IsMaintenance()
 	return r.db.Create(deployment).Error
 }
 
+// ListBatchDeployments returns the deployments of a bulk deployment
+func (r *Repository) ListBatchDeployments(tenantID, batchID uuid.UUID) ([]FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	if err := r.db.Where("tenant_id = ? AND batch_id = ?", tenantID, batchID).Order("agent_name").Find(&deployments).Error; err != nil {
+		return nil, err
+	}
+	for i := range deployments {
+		resolveDeployment(&deployments[i])
+	}
+	return deployments, nil
+}
+
 // GetDeploymentByID retrieves a deployment by ID
 func (r *Repository) GetDeploymentByID(tenantID, id uuid.UUID) (*FirewallDeployment, error) {
 	var deployment FirewallDeployment
@@ -393,14 +724,62 @@ func (r *Repository) GetDeploymentByID(tenantID, id uuid.UUID) (*FirewallDeploym
 	if err != nil {
 		return nil, err
 	}
+	resolveDeployment(&deployment)
 	return &deployment, nil
 }
 
 // ListDeployments retrieves all deployments for a tenant with optional filtering
 func (r *Repository) ListDeployments(tenantID uuid.UUID, filter *FirewallDeploymentFilter, limit, offset int) ([]FirewallDeployment, int64, error) {
 	var deployments []FirewallDeployment
-	var count int64
 
+	query := r.deploymentListQuery(tenantID, filter)
+	count, err := pagination.CurrentCountStrategy().Count(query, !filter.isEmpty())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Profile").Order("created_at DESC").Limit(limit).Offset(offset).Find(&deployments).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range deployments {
+		resolveDeployment(&deployments[i])
+	}
+
+	return deployments, count, nil
+}
+
+// ListDeploymentsAfter retrieves a keyset page of deployments ordered newest first, starting after
+// the cursor (from the newest deployment when it is nil). The count covers every matching deployment
+func (r *Repository) ListDeploymentsAfter(tenantID uuid.UUID, filter *FirewallDeploymentFilter, cursor *pagination.Cursor, limit int) ([]FirewallDeployment, int64, error) {
+	var deployments []FirewallDeployment
+
+	query := r.deploymentListQuery(tenantID, filter)
+	count, err := pagination.CurrentCountStrategy().Count(query, !filter.isEmpty())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+	if err := query.Preload("Profile").Order("created_at DESC, id DESC").Limit(limit).Find(&deployments).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range deployments {
+		resolveDeployment(&deployments[i])
+	}
+
+	return deployments, count, nil
+}
+
+// isEmpty reports whether the filter has no condition, so a list uses only the tenant scope
+func (f *FirewallDeploymentFilter) isEmpty() bool {
+	return f == nil || ((f.Search == nil || *f.Search == "") && f.ProfileID == nil && f.AgentID == nil && f.Action == nil &&
+		len(f.ExcludeActions) == 0 && f.Status == nil && f.RolloutID == nil && f.BatchID == nil && f.ChangeRef == nil)
+}
+
+// deploymentListQuery returns the query of the deployments of a tenant matching filter
+func (r *Repository) deploymentListQuery(tenantID uuid.UUID, filter *FirewallDeploymentFilter) *gorm.DB {
 	query := r.db.Model(&FirewallDeployment{}).Where("tenant_id = ?", tenantID)
 
 	if filter != nil {
@@ -421,20 +800,27 @@ func (r *Repository) ListDeployments(tenantID uuid.UUID, filter *FirewallDeploym
 		if filter.Action != nil {
 			query = query.Where("action = ?", *filter.Action)
 		}
+		if len(filter.ExcludeActions) > 0 {
+			query = query.Where("action NOT IN ?", filter.ExcludeActions)
+		}
 		if filter.Status != nil {
 			query = query.Where("status = ?", *filter.Status)
 		}
+		if filter.RolloutID != nil {
+			if rolloutID, err := uuid.Parse(*filter.RolloutID); err == nil {
+				query = query.Where("rollout_id = ?", rolloutID)
+			}
+		}
+		if filter.BatchID != nil {
+			if batchID, err := uuid.Parse(*filter.BatchID); err == nil {
+				query = query.Where("batch_id = ?", batchID)
+			}
+		}
+		if filter.ChangeRef != nil {
+			query = query.Where("change_ref = ?", *filter.ChangeRef)
+		}
 	}
-
-	if err := query.Count(&count).Error; err != nil {
-		return nil, 0, err
-	}
-
-	if err := query.Preload("Profile").Order("created_at DESC").Limit(limit).Offset(offset).Find(&deployments).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return deployments, count, nil
+	return query
 }
 
 // UpdateDeployment updates a deployment
@@ -442,6 +828,51 @@ func (r *Repository) UpdateDeployment(deployment *FirewallDeployment) error {
 	return r.db.Save(deployment).Error
 }
 
+// GetLatestProfileDeploymentPerAgent retrieves the most recent APPLY deployment of a profile on each agent
+func (r *Repository) GetLatestProfileDeploymentPerAgent(tenantID, profileID uuid.UUID) ([]FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	err := r.db.Model(&FirewallDeployment{}).
+		Select("DISTINCT ON (agent_id) id, agent_id, agent_name, status, status_message, created_at, completed_at").
+		Where("tenant_id = ? AND profile_id = ? AND action = ?", tenantID, profileID, DeploymentActionApply).
+		Order("agent_id, created_at DESC").
+		Find(&deployments).Error
+	return deployments, err
+}
+
+// ListTimedApplies retrieves the successful APPLY deployments created since a time that have both
+// a start and a completion time
+func (r *Repository) ListTimedApplies(tenantID uuid.UUID, since time.Time) ([]FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	err := r.db.Model(&FirewallDeployment{}).
+		Select("id, agent_id, agent_name, started_at, completed_at").
+		Where("tenant_id = ? AND action = ? AND status = ? AND created_at >= ?", tenantID, DeploymentActionApply, DeploymentStatusApplied, since).
+		Where("started_at IS NOT NULL AND completed_at IS NOT NULL").
+		Find(&deployments).Error
+	return deployments, err
+}
+
+// GetAppliedProfilePerAgent maps each agent to the profile of its most recent applied APPLY deployment
+func (r *Repository) GetAppliedProfilePerAgent(tenantID uuid.UUID, agentIDs []uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	var latest []struct {
+		AgentID   uuid.UUID
+		ProfileID uuid.UUID
+	}
+	err := r.db.Model(&FirewallDeployment{}).
+		Select("DISTINCT ON (agent_id) agent_id, profile_id").
+		Where("tenant_id = ? AND agent_id IN ? AND action = ? AND status = ?", tenantID, agentIDs, DeploymentActionApply, DeploymentStatusApplied).
+		Order("agent_id, created_at DESC").
+		Scan(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[uuid.UUID]uuid.UUID, len(latest))
+	for _, l := range latest {
+		profiles[l.AgentID] = l.ProfileID
+	}
+	return profiles, nil
+}
+
 // UpdateDeploymentStatus updates the status of a deployment
 func (r *Repository) UpdateDeploymentStatus(id uuid.UUID, status DeploymentStatus, message, output string) error {
 	updates := map[string]interface{}{
@@ -454,12 +885,69 @@ func (r *Repository) UpdateDeploymentStatus(id uuid.UUID, status DeploymentStatu
 	if status == DeploymentStatusDeploying {
 		updates["started_at"] = gorm.Expr("NOW()")
 	}
-	if status == DeploymentStatusApplied || status == DeploymentStatusError || status == DeploymentStatusRolledBack {
+	if status == DeploymentStatusApplied || status == DeploymentStatusError || status == DeploymentStatusUnknown ||
+		status == DeploymentStatusRolledBack {
 		updates["completed_at"] = gorm.Expr("NOW()")
 	}
 	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// SetDeploymentVerification records the outcome of reading back the live ruleset after a deployment
+func (r *Repository) SetDeploymentVerification(id uuid.UUID, verified bool, ruleset, message string) error {
+	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"verified":         verified,
+		"verified_ruleset": ruleset,
+		"verified_at":      gorm.Expr("NOW()"),
+		"status_message":   message,
+	}).Error
+}
+
+// SetDeploymentNftVersion records the nft version detected on the agent of a deployment
+func (r *Repository) SetDeploymentNftVersion(id uuid.UUID, version string) error {
+	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Update("nft_version", version).Error
+}
+
+// SetDeploymentConfigHash records the fingerprint of the nftables config a deployment generated
+func (r *Repository) SetDeploymentConfigHash(id uuid.UUID, hash string) error {
+	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Update("config_hash", hash).Error
+}
+
+// GetDeploymentsByConfigHash retrieves, for every agent whose latest ruleset change is a successful
+// deployment of the given config fingerprint, that deployment
+func (r *Repository) GetDeploymentsByConfigHash(tenantID uuid.UUID, hash string) ([]FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	latest := r.db.Model(&FirewallDeployment{}).
+		Select("DISTINCT ON (agent_id) id").
+		Where("tenant_id = ? AND action <> ?", tenantID, DeploymentActionAudit).
+		Order("agent_id, created_at DESC")
+	err := r.db.Preload("Profile").
+		Where("id IN (?) AND config_hash = ? AND status = ?", latest, hash, DeploymentStatusApplied).
+		Order("agent_name").
+		Find(&deployments).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments {
+		resolveDeployment(&deployments[i])
+	}
+	return deployments, nil
+}
+
+// ListInFlightDeployments returns pending and running deployments, oldest first
+func (r *Repository) ListInFlightDeployments(tenantID uuid.UUID) ([]FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	err := r.db.Where("tenant_id = ? AND status IN ?", tenantID,
+		[]DeploymentStatus{DeploymentStatusPending, DeploymentStatusDeploying}).
+		Order("created_at ASC").
+		Find(&deployments).Error
+	return deployments, err
+}
+
+// UpdateDeploymentOutput replaces the output captured so far for a running deployment
+func (r *Repository) UpdateDeploymentOutput(id uuid.UUID, output string) error {
+	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Update("output", output).Error
+}
+
 // CountDeployments returns the total count of deployments for a tenant
 func (r *Repository) CountDeployments(tenantID uuid.UUID) (int64, error) {
 	var count int64
@@ -474,11 +962,63 @@ func (r *Repository) CountDeploymentsByStatus(tenantID uuid.UUID, status Deploym
 	return count, err
 }
 
-// GetLatestDeploymentForAgent retrieves the most recent deployment for an agent
+// GetLatestDeploymentForAgent retrieves the most recent deployment that changed an agent's
+// ruleset; audits only read it and are skipped. Returns nil when the agent has none.
 func (r *Repository) GetLatestDeploymentForAgent(tenantID, agentID uuid.UUID) (*FirewallDeployment, error) {
-	var deployment FirewallDeployment
+	var deployments []FirewallDeployment
 	err := r.db.Preload("Profile").
-		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).
+		Where("tenant_id = ? AND agent_id = ? AND action <> ?", tenantID, agentID, DeploymentActionAudit).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&deployments).Error
+	if err != nil || len(deployments) == 0 {
+		return nil, err
+	}
+	resolveDeployment(&deployments[0])
+	return &deployments[0], nil
+}
+
+// GetLatestAuditForAgent retrieves the most recent AUDIT deployment of an agent, or nil when it was never audited
+func (r *Repository) GetLatestAuditForAgent(tenantID, agentID uuid.UUID) (*FirewallDeployment, error) {
+	var deployments []FirewallDeployment
+	err := r.db.Where("tenant_id = ? AND agent_id = ? AND action = ?", tenantID, agentID, DeploymentActionAudit).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&deployments).Error
+	if err != nil || len(deployments) == 0 {
+		return nil, err
+	}
+	return &deployments[0], nil
+}
+
+// GetLatestApplyForAgent retrieves the most recent APPLY deployment for an agent
+func (r *Repository) GetLatestApplyForAgent(tenantID, agentID uuid.UUID) (*FirewallDeployment, error) {
+	var deployment FirewallDeployment
+	err := r.db.Where("tenant_id = ? AND agent_id = ? AND action = ?", tenantID, agentID, DeploymentActionApply).
+		Order("created_at DESC").
+		First(&deployment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// GetLatestBackupForAgent retrieves the most recent deployment that stored a backup for an agent
+func (r *Repository) GetLatestBackupForAgent(tenantID, agentID uuid.UUID) (*FirewallDeployment, error) {
+	var deployment FirewallDeployment
+	err := r.db.Where("tenant_id = ? AND agent_id = ? AND backup_key <> ''", tenantID, agentID).
+		Order("created_at DESC").
+		First(&deployment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// GetPreviousBackupForAgent retrieves the latest applied deployment with a backup created before a point in time
+func (r *Repository) GetPreviousBackupForAgent(tenantID, agentID uuid.UUID, before time.Time) (*FirewallDeployment, error) {
+	var deployment FirewallDeployment
+	err := r.db.Where("tenant_id = ? AND agent_id = ? AND backup_key <> '' AND status = ? AND created_at < ?", tenantID, agentID, DeploymentStatusApplied, before).
 		Order("created_at DESC").
 		First(&deployment).Error
 	if err != nil {
@@ -486,3 +1026,474 @@ func (r *Repository) GetLatestDeploymentForAgent(tenantID, agentID uuid.UUID) (*
 	}
 	return &deployment, nil
 }
+
+// SetDeploymentBackupKey records the backup artifact stored for a deployment
+func (r *Repository) SetDeploymentBackupKey(id uuid.UUID, key string) error {
+	return r.db.Model(&FirewallDeployment{}).Where("id = ?", id).Update("backup_key", key).Error
+}
+
+// resolveDeployment fills the non-persisted fields derived from stored columns
+func resolveDeployment(deployment *FirewallDeployment) {
+	deployment.Maintenance = deployment.Action.IsMaintenance()
+	deployment.DurationSeconds = nil
+	if deployment.StartedAt != nil && deployment.CompletedAt != nil {
+		seconds := int64(deployment.CompletedAt.Sub(*deployment.StartedAt).Seconds())
+		deployment.DurationSeconds = &seconds
+	}
+	deployment.SnapshotRules = []FirewallRule{}
+	if deployment.RulesSnapshot != "" {
+		// A corrupt snapshot leaves the list empty; the raw column is still returned
+		json.Unmarshal([]byte(deployment.RulesSnapshot), &deployment.SnapshotRules)
+	}
+}
+
+// ========================================
+// IP Sets
+// ========================================
+
+// CreateIPSet creates a new IP set
+func (r *Repository) CreateIPSet(set *FirewallIPSet) error {
+	return r.db.Create(set).Error
+}
+
+// GetIPSetByID retrieves an IP set by ID
+func (r *Repository) GetIPSetByID(tenantID, id uuid.UUID) (*FirewallIPSet, error) {
+	var set FirewallIPSet
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&set).Error
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// GetIPSetsByNames retrieves the IP sets of a tenant with the given names
+func (r *Repository) GetIPSetsByNames(tenantID uuid.UUID, names []string) ([]FirewallIPSet, error) {
+	var sets []FirewallIPSet
+	if len(names) == 0 {
+		return sets, nil
+	}
+	err := r.db.Where("tenant_id = ? AND name IN ?", tenantID, names).Order("name ASC").Find(&sets).Error
+	return sets, err
+}
+
+// ListIPSets retrieves all IP sets for a tenant
+func (r *Repository) ListIPSets(tenantID uuid.UUID, search string, limit, offset int) ([]FirewallIPSet, int64, error) {
+	var sets []FirewallIPSet
+	var count int64
+
+	query := r.db.Model(&FirewallIPSet{}).Where("tenant_id = ?", tenantID)
+	if search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("name ASC").Limit(limit).Offset(offset).Find(&sets).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return sets, count, nil
+}
+
+// UpdateIPSet updates an IP set
+func (r *Repository) UpdateIPSet(set *FirewallIPSet) error {
+	return r.db.Save(set).Error
+}
+
+// DeleteIPSet deletes an IP set
+func (r *Repository) DeleteIPSet(tenantID, id uuid.UUID) error {
+	result := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&FirewallIPSet{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetRulesReferencingIPSet retrieves the rules that match against an IP set
+func (r *Repository) GetRulesReferencingIPSet(tenantID uuid.UUID, name string) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	ref := ipSetReferencePrefix + name
+	err := r.db.Where("tenant_id = ? AND (source_ip = ? OR dest_ip = ?)", tenantID, ref, ref).
+		Order("name ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetRulesWithCounters retrieves the rules that increment a named counter
+func (r *Repository) GetRulesWithCounters(tenantID uuid.UUID) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	err := r.db.Where("tenant_id = ? AND counter_name <> ''", tenantID).
+		Order("name ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// ListCounterSnapshotTargets retrieves the agents running an applied profile in tenants with counted rules
+func (r *Repository) ListCounterSnapshotTargets() ([]CounterSnapshotTarget, error) {
+	var targets []CounterSnapshotTarget
+	err := r.db.Model(&FirewallDeployment{}).
+		Select("DISTINCT tenant_id, agent_id").
+		Where("action = ? AND status = ?", DeploymentActionApply, DeploymentStatusApplied).
+		Where("tenant_id IN (?)", r.db.Model(&FirewallRule{}).Select("tenant_id").Where("counter_name <> ''")).
+		Scan(&targets).Error
+	return targets, err
+}
+
+// CreateCounterSnapshots stores a batch of counter snapshots
+func (r *Repository) CreateCounterSnapshots(snapshots []FirewallCounterSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(snapshots, 500).Error
+}
+
+// GetRuleCounterHistory retrieves the counter snapshots of a rule within a period, oldest first
+func (r *Repository) GetRuleCounterHistory(tenantID, ruleID uuid.UUID, agentID *uuid.UUID, from, to time.Time, limit int) ([]FirewallCounterSnapshot, error) {
+	var snapshots []FirewallCounterSnapshot
+	query := r.db.Where("tenant_id = ? AND rule_id = ? AND timestamp >= ? AND timestamp <= ?", tenantID, ruleID, from, to)
+	if agentID != nil {
+		query = query.Where("agent_id = ?", *agentID)
+	}
+	err := query.Order("timestamp ASC").Limit(limit).Find(&snapshots).Error
+	return snapshots, err
+}
+
+// DeleteCounterSnapshotsBefore removes counter snapshots older than the cutoff
+func (r *Repository) DeleteCounterSnapshotsBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("timestamp < ?", cutoff).Delete(&FirewallCounterSnapshot{})
+	return result.RowsAffected, result.Error
+}
+
+// GetProfilesReferencingIPSet retrieves the profiles containing a rule that matches against an IP set
+func (r *Repository) GetProfilesReferencingIPSet(tenantID uuid.UUID, name string) ([]FirewallProfile, error) {
+	var profiles []FirewallProfile
+	ref := ipSetReferencePrefix + name
+	err := r.db.Where("tenant_id = ? AND id IN (?)", tenantID,
+		r.db.Table("firewall_profile_rules").
+			Select("firewall_profile_rules.profile_id").
+			Joins("JOIN firewall_rules ON firewall_rules.id = firewall_profile_rules.rule_id").
+			Where("firewall_rules.tenant_id = ? AND (firewall_rules.source_ip = ? OR firewall_rules.dest_ip = ?)", tenantID, ref, ref)).
+		Order("name ASC").
+		Find(&profiles).Error
+	return profiles, err
+}
+
+// ========================================
+// Agent Groups
+// ========================================
+
+// CreateAgentGroup creates a new agent group with its members
+func (r *Repository) CreateAgentGroup(group *FirewallAgentGroup) error {
+	return r.db.Create(group).Error
+}
+
+// GetAgentGroupByID retrieves an agent group with its members in rollout order
+func (r *Repository) GetAgentGroupByID(tenantID, id uuid.UUID) (*FirewallAgentGroup, error) {
+	var group FirewallAgentGroup
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Where("tenant_id = ? AND id = ?", tenantID, id).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListAgentGroups retrieves all agent groups for a tenant
+func (r *Repository) ListAgentGroups(tenantID uuid.UUID, search string, limit, offset int) ([]FirewallAgentGroup, int64, error) {
+	var groups []FirewallAgentGroup
+	var count int64
+
+	query := r.db.Model(&FirewallAgentGroup{}).Where("tenant_id = ?", tenantID)
+	if search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Order("name ASC").Limit(limit).Offset(offset).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return groups, count, nil
+}
+
+// GetAgentGroupsByNames retrieves the agent groups of a tenant with the given names
+func (r *Repository) GetAgentGroupsByNames(tenantID uuid.UUID, names []string) ([]FirewallAgentGroup, error) {
+	var groups []FirewallAgentGroup
+	if len(names) == 0 {
+		return groups, nil
+	}
+	err := r.db.Where("tenant_id = ? AND name IN ?", tenantID, names).Order("created_at ASC").Find(&groups).Error
+	return groups, err
+}
+
+// UpdateAgentGroup updates an agent group's attributes and, when agentIDs is not nil,
+// replaces its members, in one transaction
+func (r *Repository) UpdateAgentGroup(group *FirewallAgentGroup, agentIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Members").Save(group).Error; err != nil {
+			return err
+		}
+		if agentIDs == nil {
+			return nil
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&FirewallAgentGroupMember{}).Error; err != nil {
+			return err
+		}
+		for i, agentID := range agentIDs {
+			member := FirewallAgentGroupMember{GroupID: group.ID, AgentID: agentID, SortOrder: i}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteAgentGroup deletes an agent group and its members
+func (r *Repository) DeleteAgentGroup(tenantID, id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&FirewallAgentGroup{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("group_id = ?", id).Delete(&FirewallAgentGroupMember{}).Error
+	})
+}
+
+// ========================================
+// Rolling Deployments
+// ========================================
+
+// CreateRollout creates a new rollout record
+func (r *Repository) CreateRollout(rollout *FirewallRollout) error {
+	return r.db.Create(rollout).Error
+}
+
+// GetRolloutByID retrieves a rollout by ID
+func (r *Repository) GetRolloutByID(tenantID, id uuid.UUID) (*FirewallRollout, error) {
+	var rollout FirewallRollout
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&rollout).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rollout, nil
+}
+
+// ListRollouts retrieves rollouts for a tenant, optionally for a single profile
+func (r *Repository) ListRollouts(tenantID uuid.UUID, profileID *uuid.UUID, limit, offset int) ([]FirewallRollout, int64, error) {
+	var rollouts []FirewallRollout
+	var count int64
+
+	query := r.db.Model(&FirewallRollout{}).Where("tenant_id = ?", tenantID)
+	if profileID != nil {
+		query = query.Where("profile_id = ?", *profileID)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&rollouts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return rollouts, count, nil
+}
+
+// UpdateRollout saves all fields of a rollout
+func (r *Repository) UpdateRollout(rollout *FirewallRollout) error {
+	return r.db.Save(rollout).Error
+}
+
+// GetAppliedRolloutAgents retrieves the agents a rollout has successfully applied its profile to
+func (r *Repository) GetAppliedRolloutAgents(tenantID, rolloutID uuid.UUID) ([]uuid.UUID, error) {
+	var agentIDs []uuid.UUID
+	err := r.db.Model(&FirewallDeployment{}).
+		Where("tenant_id = ? AND rollout_id = ? AND action = ? AND status = ?", tenantID, rolloutID, DeploymentActionApply, DeploymentStatusApplied).
+		Distinct().
+		Pluck("agent_id", &agentIDs).Error
+	return agentIDs, err
+}
+
+// UpdateRolloutProgress records batch progress of a rollout
+func (r *Repository) UpdateRolloutProgress(id uuid.UUID, status RolloutStatus, currentBatch, completedAgents int, message string) error {
+	updates := map[string]interface{}{
+		"status":           status,
+		"current_batch":    currentBatch,
+		"completed_agents": completedAgents,
+		"status_message":   message,
+	}
+	if status == RolloutStatusRunning && currentBatch == 1 {
+		updates["started_at"] = gorm.Expr("NOW()")
+	}
+	if status == RolloutStatusCompleted || status == RolloutStatusAborted {
+		updates["completed_at"] = gorm.Expr("NOW()")
+	}
+	return r.db.Model(&FirewallRollout{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// ========================================
+// Enforcement Pause Operations
+// ========================================
+
+// SaveEnforcementPause creates or replaces the enforcement pause of an agent
+func (r *Repository) SaveEnforcementPause(pause *FirewallEnforcementPause) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND agent_id = ?", pause.TenantID, pause.AgentID).
+			Delete(&FirewallEnforcementPause{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(pause).Error
+	})
+}
+
+// GetEnforcementPause retrieves the enforcement pause of an agent
+func (r *Repository) GetEnforcementPause(tenantID, agentID uuid.UUID) (*FirewallEnforcementPause, error) {
+	var pause FirewallEnforcementPause
+	err := r.db.Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).First(&pause).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+// ListActiveEnforcementPauses retrieves pauses of a tenant that have not reached their resume time
+func (r *Repository) ListActiveEnforcementPauses(tenantID uuid.UUID, now time.Time) ([]FirewallEnforcementPause, error) {
+	var pauses []FirewallEnforcementPause
+	err := r.db.Where("tenant_id = ? AND (resume_at IS NULL OR resume_at > ?)", tenantID, now).
+		Order("created_at DESC").
+		Find(&pauses).Error
+	return pauses, err
+}
+
+// DeleteEnforcementPause removes the enforcement pause of an agent
+func (r *Repository) DeleteEnforcementPause(tenantID, agentID uuid.UUID) (int64, error) {
+	result := r.db.Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).Delete(&FirewallEnforcementPause{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpiredEnforcementPauses removes pauses of a tenant whose resume time has passed
+func (r *Repository) DeleteExpiredEnforcementPauses(tenantID uuid.UUID, now time.Time) error {
+	return r.db.Where("tenant_id = ? AND resume_at IS NOT NULL AND resume_at <= ?", tenantID, now).
+		Delete(&FirewallEnforcementPause{}).Error
+}
+
+// ========================================
+// Deployment Schedules
+// ========================================
+
+// CreateSchedule creates a new deployment schedule
+func (r *Repository) CreateSchedule(schedule *FirewallSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+// GetScheduleByID retrieves a deployment schedule by ID
+func (r *Repository) GetScheduleByID(tenantID, id uuid.UUID) (*FirewallSchedule, error) {
+	var schedule FirewallSchedule
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules retrieves deployment schedules for a tenant, optionally for a single profile
+func (r *Repository) ListSchedules(tenantID uuid.UUID, profileID *uuid.UUID, limit, offset int) ([]FirewallSchedule, int64, error) {
+	var schedules []FirewallSchedule
+	var count int64
+
+	query := r.db.Model(&FirewallSchedule{}).Where("tenant_id = ?", tenantID)
+	if profileID != nil {
+		query = query.Where("profile_id = ?", *profileID)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&schedules).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return schedules, count, nil
+}
+
+// UpdateSchedule saves all fields of a deployment schedule
+func (r *Repository) UpdateSchedule(schedule *FirewallSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+// DeleteSchedule deletes a deployment schedule
+func (r *Repository) DeleteSchedule(tenantID, id uuid.UUID) error {
+	result := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&FirewallSchedule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDueSchedules retrieves the enabled schedules of all tenants whose next run time has passed
+func (r *Repository) ListDueSchedules(now time.Time) ([]FirewallSchedule, error) {
+	var schedules []FirewallSchedule
+	err := r.db.Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// ClaimSchedule marks a due schedule as run and moves it to its next run time. The claim holds a
+// transaction-scoped advisory lock on the schedule and only succeeds while next_run_at still has
+// the value the caller listed, so a run is claimed by exactly one backend replica.
+func (r *Repository) ClaimSchedule(schedule *FirewallSchedule, now, nextRunAt time.Time) (bool, error) {
+	claimed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", "firewall_schedule:"+schedule.ID.String()).
+			Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		result := tx.Model(&FirewallSchedule{}).
+			Where("id = ? AND enabled = ? AND next_run_at = ?", schedule.ID, true, schedule.NextRunAt).
+			Updates(map[string]interface{}{
+				"last_run_at": now,
+				"next_run_at": nextRunAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		claimed = result.RowsAffected == 1
+		return nil
+	})
+	return claimed, err
+}
+
+// SetScheduleResult records the deployment started by the last run of a schedule, or why none was
+func (r *Repository) SetScheduleResult(id uuid.UUID, deploymentID *uuid.UUID, lastError string) error {
+	return r.db.Model(&FirewallSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_deployment_id": deploymentID,
+		"last_error":         lastError,
+	}).Error
+}
@@ -2,33 +2,63 @@ package security
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
 
 	"csd-pilote/backend/modules/platform/config"
 	csdcore "csd-pilote/backend/modules/platform/csd-core"
 	"csd-pilote/backend/modules/platform/events"
+	"csd-pilote/backend/modules/platform/logger"
 	"csd-pilote/backend/modules/platform/pagination"
+	"csd-pilote/backend/modules/platform/validation"
 )
 
 // Service handles business logic for firewall security
 type Service struct {
 	repo   *Repository
 	client *csdcore.Client
+
+	// lifetime is the parent context of all background operations
+	lifetime   context.Context
+	shutdown   context.CancelFunc
+	opsMu      sync.Mutex
+	operations map[uuid.UUID]context.CancelFunc
 }
 
 // NewService creates a new security service
 func NewService() *Service {
+	lifetime, shutdown := context.WithCancel(context.Background())
 	return &Service{
-		repo:   NewRepository(),
-		client: csdcore.GetClient(),
+		repo:       NewRepository(),
+		client:     csdcore.GetClient(),
+		lifetime:   lifetime,
+		shutdown:   shutdown,
+		operations: make(map[uuid.UUID]context.CancelFunc),
 	}
 }
 
+// bind points the service at the database and csd-core client, which are created after init()
+func (s *Service) bind() {
+	s.repo = NewRepository()
+	s.client = csdcore.GetClient()
+}
+
 // ========================================
 // Firewall Rules
 // ========================================
@@ -55,17 +85,38 @@ func (s *Service) CreateRule(ctx context.Context, token string, tenantID, userID
 		InInterface:  input.InInterface,
 		OutInterface: input.OutInterface,
 		CTState:      input.CTState,
+		ICMPType:     input.ICMPType,
+		DSCP:         input.DSCP,
+		PacketLength: input.PacketLength,
 		RateLimit:    input.RateLimit,
 		RateBurst:    input.RateBurst,
 		LimitOver:    input.LimitOver,
 		NatToAddr:    input.NatToAddr,
 		NatToPort:    input.NatToPort,
+		RejectWith:   input.RejectWith,
+		RejectType:   input.RejectType,
 		LogPrefix:    input.LogPrefix,
 		LogLevel:     input.LogLevel,
+		CounterName:  input.CounterName,
 		RuleExpr:     input.RuleExpr,
 		Comment:      input.Comment,
 		Enabled:      enabled,
 		CreatedBy:    userID,
+
+		ConnLimitCount: input.ConnLimitCount,
+		ConnLimitMask:  input.ConnLimitMask,
+	}
+	if input.NegateProtocol != nil {
+		rule.NegateProtocol = *input.NegateProtocol
+	}
+	if input.NegateSourcePort != nil {
+		rule.NegateSourcePort = *input.NegateSourcePort
+	}
+	if input.NegateDestPort != nil {
+		rule.NegateDestPort = *input.NegateDestPort
+	}
+	if input.Counter != nil {
+		rule.Counter = *input.Counter
 	}
 
 	// Set defaults
@@ -76,6 +127,13 @@ func (s *Service) CreateRule(ctx context.Context, token string, tenantID, userID
 		rule.Action = RuleActionAccept
 	}
 
+	if err := normalizeRuleRateLimit(rule); err != nil {
+		return nil, err
+	}
+	if err := validateRuleSemantics(rule); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.CreateRule(rule); err != nil {
 		return nil, fmt.Errorf("failed to create rule: %w", err)
 	}
@@ -89,7 +147,7 @@ func (s *Service) CreateRule(ctx context.Context, token string, tenantID, userID
 			"chain":  rule.Chain,
 			"action": rule.Action,
 		},
-	))
+	).WithActor(userID))
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
@@ -115,16 +173,120 @@ func (s *Service) GetRule(ctx context.Context, tenantID, id uuid.UUID) (*Firewal
 
 // ListRules retrieves all rules for a tenant
 func (s *Service) ListRules(ctx context.Context, tenantID uuid.UUID, filter *FirewallRuleFilter, limit, offset int) ([]FirewallRule, int64, error) {
-	p := pagination.Normalize(limit, offset)
+	p := pagination.NormalizeFor(pagination.ResourceFirewallRules, limit, offset)
 	return s.repo.ListRules(tenantID, filter, p.Limit, p.Offset)
 }
 
+// ListRulesAfter retrieves the page of rules after an opaque cursor ("" for the first page), in the
+// offset-mode order (priority, then newest first), with the cursor of the next page, empty on the last one
+func (s *Service) ListRulesAfter(ctx context.Context, tenantID uuid.UUID, filter *FirewallRuleFilter, after string, limit int) ([]FirewallRule, int64, string, error) {
+	cursor, err := decodeAfterCursor(after, true)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	p := pagination.NormalizeFor(pagination.ResourceFirewallRules, limit, 0)
+	rules, count, err := s.repo.ListRulesAfter(tenantID, filter, cursor, p.Limit)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	next := ""
+	if len(rules) == p.Limit {
+		last := rules[len(rules)-1]
+		next = pagination.EncodePriorityCursor(last.Priority, last.CreatedAt, last.ID)
+	}
+	return rules, count, next, nil
+}
+
+// decodeAfterCursor decodes the "after" argument of a list query, nil when it is empty.
+// withPriority tells whether the list is ordered by priority first, so its cursors carry one
+func decodeAfterCursor(after string, withPriority bool) (*pagination.Cursor, error) {
+	if after == "" {
+		return nil, nil
+	}
+	cursor, err := pagination.DecodeCursor(after)
+	if err != nil || (cursor.Priority != nil) != withPriority {
+		return nil, validation.NewValidationError("after is not a valid cursor")
+	}
+	return &cursor, nil
+}
+
+// CloneRule copies a rule under a new ID with a "- copy" name suffix and, when
+// addToProfiles is set, adds the copy to every profile containing the original
+func (s *Service) CloneRule(ctx context.Context, token string, tenantID, userID, id uuid.UUID, addToProfiles bool) (*FirewallRule, error) {
+	original, err := s.repo.GetRuleByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *original
+	clone.ID = uuid.Nil
+	clone.CreatedBy = userID
+	clone.CreatedAt = time.Time{}
+	clone.UpdatedAt = time.Time{}
+	clone.Name = cloneName(original.Name)
+
+	if err := s.repo.CreateRule(&clone); err != nil {
+		return nil, fmt.Errorf("failed to clone rule: %w", err)
+	}
+
+	var profileIDs []uuid.UUID
+	if addToProfiles {
+		profileIDs, err = s.repo.GetProfileIDsForRule(original.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load profiles for rule: %w", err)
+		}
+		for _, profileID := range profileIDs {
+			if err := s.repo.AddRulesToProfile(tenantID, profileID, []uuid.UUID{clone.ID}); err != nil {
+				return nil, fmt.Errorf("failed to add cloned rule to profile: %w", err)
+			}
+		}
+	}
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRuleCreated,
+		tenantID,
+		clone.ID.String(),
+		map[string]interface{}{
+			"name":       clone.Name,
+			"chain":      clone.Chain,
+			"action":     clone.Action,
+			"clonedFrom": original.ID.String(),
+		},
+	).WithActor(userID))
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rule.cloned",
+		ResourceType: "firewall_rule",
+		ResourceID:   clone.ID.String(),
+		Details: map[string]interface{}{
+			"name":         clone.Name,
+			"clonedFrom":   original.ID.String(),
+			"profileCount": len(profileIDs),
+		},
+	})
+
+	return &clone, nil
+}
+
+// cloneName appends the copy suffix, trimming the original so the result fits the name limit
+func cloneName(name string) string {
+	const suffix = " - copy"
+	runes := []rune(name)
+	if max := validation.MaxNameLength - len(suffix); len(runes) > max {
+		runes = runes[:max]
+	}
+	return string(runes) + suffix
+}
+
 // UpdateRule updates a firewall rule
 func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallRuleInput) (*FirewallRule, error) {
 	rule, err := s.repo.GetRuleByID(tenantID, id)
 	if err != nil {
 		return nil, err
 	}
+	before := *rule
 
 	if input.Name != "" {
 		rule.Name = input.Name
@@ -167,6 +329,23 @@ func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uui
 	if input.CTState != "" {
 		rule.CTState = input.CTState
 	}
+	// Match negation
+	if input.NegateProtocol != nil {
+		rule.NegateProtocol = *input.NegateProtocol
+	}
+	if input.NegateSourcePort != nil {
+		rule.NegateSourcePort = *input.NegateSourcePort
+	}
+	if input.NegateDestPort != nil {
+		rule.NegateDestPort = *input.NegateDestPort
+	}
+	// QoS matching
+	if input.DSCP != "" {
+		rule.DSCP = input.DSCP
+	}
+	if input.PacketLength != "" {
+		rule.PacketLength = input.PacketLength
+	}
 	// Rate limiting
 	if input.RateLimit != "" {
 		rule.RateLimit = input.RateLimit
@@ -184,6 +363,24 @@ func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uui
 	if input.NatToPort != "" {
 		rule.NatToPort = input.NatToPort
 	}
+	// Reject options
+	if input.RejectWith != "" {
+		rule.RejectWith = input.RejectWith
+	}
+	if input.RejectType != "" {
+		rule.RejectType = input.RejectType
+	}
+	// ICMP matching
+	if input.ICMPType != "" {
+		rule.ICMPType = input.ICMPType
+	}
+	// Connection limiting
+	if input.ConnLimitCount != 0 {
+		rule.ConnLimitCount = input.ConnLimitCount
+	}
+	if input.ConnLimitMask != 0 {
+		rule.ConnLimitMask = input.ConnLimitMask
+	}
 	// Logging options
 	if input.LogPrefix != "" {
 		rule.LogPrefix = input.LogPrefix
@@ -191,6 +388,12 @@ func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uui
 	if input.LogLevel != "" {
 		rule.LogLevel = input.LogLevel
 	}
+	if input.CounterName != "" {
+		rule.CounterName = input.CounterName
+	}
+	if input.Counter != nil {
+		rule.Counter = *input.Counter
+	}
 	if input.RuleExpr != "" {
 		rule.RuleExpr = input.RuleExpr
 	}
@@ -201,6 +404,13 @@ func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uui
 		rule.Enabled = *input.Enabled
 	}
 
+	if err := normalizeRuleRateLimit(rule); err != nil {
+		return nil, err
+	}
+	if err := validateRuleSemantics(rule); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.UpdateRule(rule); err != nil {
 		return nil, fmt.Errorf("failed to update rule: %w", err)
 	}
@@ -227,14 +437,50 @@ func (s *Service) UpdateRule(ctx context.Context, token string, tenantID, id uui
 			"action":   rule.Action,
 			"protocol": rule.Protocol,
 			"enabled":  rule.Enabled,
+			"changes":  changedFields(&before, rule),
 		},
 	})
 
 	return rule, nil
 }
 
+// changedFields compares two values of the same struct type and describes each
+// scalar field that differs as "old → new", keyed by its JSON name
+func changedFields(before, after interface{}) map[string]string {
+	bv := reflect.Indirect(reflect.ValueOf(before))
+	av := reflect.Indirect(reflect.ValueOf(after))
+
+	changes := make(map[string]string)
+	for i := 0; i < bv.NumField(); i++ {
+		field := bv.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		default:
+			continue // IDs, timestamps and associations are not user-editable
+		}
+
+		oldValue, newValue := bv.Field(i).Interface(), av.Field(i).Interface()
+		if oldValue != newValue {
+			changes[name] = auditValue(oldValue) + " → " + auditValue(newValue)
+		}
+	}
+	return changes
+}
+
+// auditValue formats a field value for change descriptions
+func auditValue(value interface{}) string {
+	if s := fmt.Sprint(value); s != "" {
+		return s
+	}
+	return "(empty)"
+}
+
 // DeleteRule deletes a firewall rule
-func (s *Service) DeleteRule(ctx context.Context, token string, tenantID, id uuid.UUID) error {
+func (s *Service) DeleteRule(ctx context.Context, token string, tenantID, id uuid.UUID, force bool) error {
 	// Get rule name for audit log
 	rule, _ := s.repo.GetRuleByID(tenantID, id)
 	ruleName := ""
@@ -242,6 +488,19 @@ func (s *Service) DeleteRule(ctx context.Context, token string, tenantID, id uui
 		ruleName = rule.Name
 	}
 
+	profiles, err := s.repo.GetProfilesUsingRule(tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to check rule usage: %w", err)
+	}
+	if len(profiles) > 0 && !force {
+		names := make([]string, len(profiles))
+		for i := range profiles {
+			names[i] = profiles[i].Name
+		}
+		return validation.NewConflictError(fmt.Sprintf("rule is used by %d profile(s): %s; pass force to remove it from them and delete it",
+			len(profiles), strings.Join(names, ", ")))
+	}
+
 	if err := s.repo.DeleteRule(tenantID, id); err != nil {
 		return err
 	}
@@ -259,438 +518,831 @@ func (s *Service) DeleteRule(ctx context.Context, token string, tenantID, id uui
 		ResourceType: "firewall_rule",
 		ResourceID:   id.String(),
 		Details: map[string]interface{}{
-			"name": ruleName,
+			"name":            ruleName,
+			"removedProfiles": len(profiles),
 		},
 	})
 
 	return nil
 }
 
+// GetRuleUsage returns the profiles that include a rule
+func (s *Service) GetRuleUsage(ctx context.Context, tenantID, id uuid.UUID) ([]FirewallProfile, error) {
+	if _, err := s.repo.GetRuleByID(tenantID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetProfilesUsingRule(tenantID, id)
+}
+
 // BulkDeleteRules deletes multiple rules by IDs
 func (s *Service) BulkDeleteRules(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (int64, error) {
 	return s.repo.BulkDeleteRules(tenantID, ids)
 }
 
-// CountRules returns the total count of rules
-func (s *Service) CountRules(ctx context.Context, tenantID uuid.UUID) (int64, error) {
-	return s.repo.CountRules(tenantID)
-}
-
-// ========================================
-// Firewall Profiles
-// ========================================
-
-// CreateProfile creates a new firewall profile
-func (s *Service) CreateProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallProfileInput) (*FirewallProfile, error) {
-	enabled := true
-	isDefault := false
-	if input.Enabled != nil {
-		enabled = *input.Enabled
+// BulkSetRulesEnabled enables or disables multiple rules by IDs and returns the number updated.
+// A single aggregate event lists the updated rules instead of one event per rule.
+func (s *Service) BulkSetRulesEnabled(ctx context.Context, token string, tenantID uuid.UUID, ids []uuid.UUID, enabled bool) (int64, error) {
+	updated, err := s.repo.BulkSetRulesEnabled(tenantID, ids, enabled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update rules: %w", err)
 	}
-	if input.IsDefault != nil {
-		isDefault = *input.IsDefault
+	if len(updated) == 0 {
+		return 0, nil
 	}
 
-	// Default feature settings
-	enableNAT := false
-	enableConntrack := true
-	allowLoopback := true
-	allowEstablished := true
-	allowICMPPing := true
-	enableIPv6 := false
-	if input.EnableNAT != nil {
-		enableNAT = *input.EnableNAT
-	}
-	if input.EnableConntrack != nil {
-		enableConntrack = *input.EnableConntrack
-	}
-	if input.AllowLoopback != nil {
-		allowLoopback = *input.AllowLoopback
-	}
-	if input.AllowEstablished != nil {
-		allowEstablished = *input.AllowEstablished
-	}
-	if input.AllowICMPPing != nil {
-		allowICMPPing = *input.AllowICMPPing
-	}
-	if input.EnableIPv6 != nil {
-		enableIPv6 = *input.EnableIPv6
+	ruleIDs := make([]string, len(updated))
+	for i, id := range updated {
+		ruleIDs[i] = id.String()
 	}
 
-	// Default policies
-	inputPolicy := "drop"
-	outputPolicy := "accept"
-	forwardPolicy := "drop"
-	if input.InputPolicy != "" {
-		inputPolicy = input.InputPolicy
-	}
-	if input.OutputPolicy != "" {
-		outputPolicy = input.OutputPolicy
-	}
-	if input.ForwardPolicy != "" {
-		forwardPolicy = input.ForwardPolicy
-	}
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRuleUpdated,
+		tenantID,
+		"",
+		map[string]interface{}{
+			"ruleIds": ruleIDs,
+			"enabled": enabled,
+			"count":   len(ruleIDs),
+		},
+	))
 
-	profile := &FirewallProfile{
-		TenantID:         tenantID,
-		Name:             input.Name,
-		Description:      input.Description,
-		IsDefault:        isDefault,
-		Enabled:          enabled,
-		InputPolicy:      inputPolicy,
-		OutputPolicy:     outputPolicy,
-		ForwardPolicy:    forwardPolicy,
-		EnableNAT:        enableNAT,
-		EnableConntrack:  enableConntrack,
-		AllowLoopback:    allowLoopback,
-		AllowEstablished: allowEstablished,
-		AllowICMPPing:    allowICMPPing,
-		EnableIPv6:       enableIPv6,
-		CreatedBy:        userID,
+	action := "firewall.rule.bulk_disabled"
+	if enabled {
+		action = "firewall.rule.bulk_enabled"
 	}
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       action,
+		ResourceType: "firewall_rule",
+		Details: map[string]interface{}{
+			"ruleIds": ruleIDs,
+			"count":   len(ruleIDs),
+		},
+	})
 
-	if err := s.repo.CreateProfile(profile); err != nil {
-		return nil, fmt.Errorf("failed to create profile: %w", err)
-	}
+	return int64(len(updated)), nil
+}
 
-	// Add rules if provided
-	if len(input.RuleIDs) > 0 {
-		ruleIDs := make([]uuid.UUID, 0, len(input.RuleIDs))
-		for _, idStr := range input.RuleIDs {
-			if id, err := uuid.Parse(idStr); err == nil {
-				ruleIDs = append(ruleIDs, id)
-			}
-		}
-		if len(ruleIDs) > 0 {
-			s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs)
-		}
+// rulePriorityStep is the gap left between rules when priorities are normalized
+const rulePriorityStep = 10
+
+// NormalizeRulePriorities renumbers a profile's rules 10, 20, 30... in their current order
+func (s *Service) NormalizeRulePriorities(ctx context.Context, token string, tenantID, profileID uuid.UUID) ([]FirewallRule, error) {
+	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
+		return nil, err
 	}
 
-	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallProfileCreated,
-		tenantID,
-		profile.ID.String(),
-		map[string]interface{}{
-			"name":      profile.Name,
-			"isDefault": profile.IsDefault,
-		},
-	))
+	rules, err := s.repo.NormalizeProfileRulePriorities(tenantID, profileID, rulePriorityStep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize rule priorities: %w", err)
+	}
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.profile.created",
+		Action:       "firewall.rule.priorities_normalized",
 		ResourceType: "firewall_profile",
-		ResourceID:   profile.ID.String(),
+		ResourceID:   profileID.String(),
 		Details: map[string]interface{}{
-			"name":      profile.Name,
-			"isDefault": profile.IsDefault,
-			"enabled":   profile.Enabled,
-			"ruleCount": len(input.RuleIDs),
+			"ruleCount": len(rules),
+			"step":      rulePriorityStep,
 		},
 	})
 
-	return profile, nil
+	return rules, nil
 }
 
-// GetProfile retrieves a profile by ID
-func (s *Service) GetProfile(ctx context.Context, tenantID, id uuid.UUID) (*FirewallProfile, error) {
-	return s.repo.GetProfileByID(tenantID, id)
+// BulkSetRulePriorities sets explicit priorities for several rules in one transaction
+func (s *Service) BulkSetRulePriorities(ctx context.Context, token string, tenantID uuid.UUID, priorities map[uuid.UUID]int) error {
+	if err := s.repo.BulkSetRulePriorities(tenantID, priorities); err != nil {
+		return fmt.Errorf("failed to set rule priorities: %w", err)
+	}
+
+	details := make(map[string]interface{}, len(priorities))
+	for id, priority := range priorities {
+		details[id.String()] = priority
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rule.priorities_updated",
+		ResourceType: "firewall_rule",
+		Details: map[string]interface{}{
+			"priorities": details,
+		},
+	})
+
+	return nil
 }
 
-// GetProfileWithRules retrieves a profile with its rules
-func (s *Service) GetProfileWithRules(ctx context.Context, tenantID, id uuid.UUID) (*FirewallProfile, error) {
-	return s.repo.GetProfileByIDWithRules(tenantID, id)
+// CountRules returns the total count of rules
+func (s *Service) CountRules(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	return s.repo.CountRules(tenantID)
 }
 
-// ListProfiles retrieves all profiles for a tenant
-func (s *Service) ListProfiles(ctx context.Context, tenantID uuid.UUID, filter *FirewallProfileFilter, limit, offset int) ([]FirewallProfile, int64, error) {
-	p := pagination.Normalize(limit, offset)
-	return s.repo.ListProfiles(tenantID, filter, p.Limit, p.Offset)
+// rateLimitRegex matches nftables rate limits: "N/unit" for packets or "N [k|m]bytes/unit" for bytes
+var rateLimitRegex = regexp.MustCompile(`^(\d+)\s*(bytes|kbytes|mbytes)?\s*/\s*(second|minute|hour|day)$`)
+
+// normalizeRateLimit validates a rate limit and returns it in canonical nftables form
+// (lowercase, "10/second" or "10 kbytes/second")
+func normalizeRateLimit(value string) (string, error) {
+	m := rateLimitRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if m == nil {
+		return "", fmt.Errorf("invalid rate limit %q: use N/unit or N bytes|kbytes|mbytes/unit with unit second, minute, hour or day (e.g. 10/second, 100/minute, 512 kbytes/second)", value)
+	}
+	rate, err := strconv.Atoi(m[1])
+	if err != nil || rate <= 0 {
+		return "", fmt.Errorf("invalid rate limit %q: the rate must be a positive number", value)
+	}
+	if m[2] != "" {
+		return fmt.Sprintf("%d %s/%s", rate, m[2], m[3]), nil
+	}
+	return fmt.Sprintf("%d/%s", rate, m[3]), nil
 }
 
-// UpdateProfile updates a firewall profile
-func (s *Service) UpdateProfile(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallProfileInput) (*FirewallProfile, error) {
-	profile, err := s.repo.GetProfileByID(tenantID, id)
+// normalizeRuleRateLimit rewrites a rule's rate limit in canonical form, rejecting invalid values
+func normalizeRuleRateLimit(rule *FirewallRule) error {
+	if rule.RateLimit == "" {
+		return nil
+	}
+	normalized, err := normalizeRateLimit(rule.RateLimit)
 	if err != nil {
-		return nil, err
+		errs := &validation.ValidationErrors{}
+		errs.Add("rateLimit", err.Error(), "INVALID_RATE_LIMIT")
+		return errs
 	}
+	rule.RateLimit = normalized
+	return nil
+}
 
-	if input.Name != "" {
-		profile.Name = input.Name
+// validateRuleSemantics rejects field combinations that would generate invalid nftables syntax
+func validateRuleSemantics(rule *FirewallRule) error {
+	v := validation.NewValidator()
+	errs := v.Errors()
+
+	// A raw expression replaces every generated match, so only the expression itself is checked
+	if rule.RuleExpr != "" {
+		v.NftablesExpression("ruleExpr", rule.RuleExpr)
+	} else {
+		validateRuleMatches(v, rule)
 	}
-	if input.Description != "" {
-		profile.Description = input.Description
+
+	if rule.CTState != "" {
+		for _, state := range strings.Split(rule.CTState, ",") {
+			switch ConnTrackState(strings.ToUpper(strings.TrimSpace(state))) {
+			case CTStateNew, CTStateEstablished, CTStateRelated, CTStateInvalid:
+			default:
+				errs.Add("ctState", fmt.Sprintf("ctState %q must be NEW, ESTABLISHED, RELATED or INVALID", strings.TrimSpace(state)), "INVALID_CT_STATE")
+			}
+		}
 	}
-	if input.IsDefault != nil {
-		profile.IsDefault = *input.IsDefault
+
+	// NAT targets only mean something to the NAT verdicts that use them
+	if rule.NatToAddr != "" {
+		if rule.Action != RuleActionSnat && rule.Action != RuleActionDnat {
+			errs.Add("natToAddr", "natToAddr is only valid for SNAT and DNAT actions", "INVALID_NAT_TARGET")
+		} else {
+			v.IP("natToAddr", rule.NatToAddr)
+		}
+	} else if rule.Action == RuleActionSnat || rule.Action == RuleActionDnat {
+		errs.Add("natToAddr", fmt.Sprintf("%s requires natToAddr", rule.Action), "REQUIRED")
 	}
-	if input.Enabled != nil {
-		profile.Enabled = *input.Enabled
+	if rule.NatToPort != "" {
+		if rule.Action != RuleActionDnat && rule.Action != RuleActionRedirect {
+			errs.Add("natToPort", "natToPort is only valid for DNAT and REDIRECT actions", "INVALID_NAT_TARGET")
+		} else {
+			v.PortRange("natToPort", rule.NatToPort)
+		}
+		// dnat to addr:port needs a transport protocol match
+		if rule.Action == RuleActionDnat && rule.RuleExpr == "" && rule.Protocol != RuleProtocolTCP && rule.Protocol != RuleProtocolUDP {
+			errs.Add("protocol", "DNAT to a port requires protocol TCP or UDP", "INVALID_NAT_PROTOCOL")
+		}
 	}
-	// Default policies
-	if input.InputPolicy != "" {
-		profile.InputPolicy = input.InputPolicy
+
+	if rule.RejectWith != "" || rule.RejectType != "" {
+		validateRejectOptions(errs, rule)
 	}
-	if input.OutputPolicy != "" {
-		profile.OutputPolicy = input.OutputPolicy
+	if rule.ConnLimitCount != 0 || rule.ConnLimitMask != 0 {
+		validateConnLimit(errs, rule)
 	}
-	if input.ForwardPolicy != "" {
-		profile.ForwardPolicy = input.ForwardPolicy
+	if rule.ICMPType != "" {
+		validateICMPType(errs, rule)
 	}
-	// Features
+
+	// Counter objects follow the same naming rules as sets
+	if rule.CounterName != "" {
+		if !ipSetNameRegex.MatchString(rule.CounterName) {
+			errs.Add("counterName", "counterName must start with a letter and contain only letters, digits and underscores", "INVALID_COUNTER_NAME")
+		}
+		if rule.RuleExpr != "" {
+			errs.Add("counterName", "counterName cannot be combined with ruleExpr", "INVALID_COUNTER_NAME")
+		}
+	}
+	if rule.Counter {
+		if rule.CounterName != "" {
+			errs.Add("counter", "counter cannot be combined with counterName; the rule already increments a named counter", "INVALID_COUNTER")
+		}
+		if rule.RuleExpr != "" {
+			errs.Add("counter", "counter cannot be combined with ruleExpr", "INVALID_COUNTER")
+		}
+	}
+
+	if rule.Action == RuleActionRedirect {
+		// redirect is a NAT statement; only the prerouting NAT chain is generated
+		if rule.Chain != RuleChainPrerouting {
+			errs.Add("chain", "REDIRECT is only valid in the PREROUTING chain", "INVALID_REDIRECT_CHAIN")
+		}
+		// redirect needs a transport protocol match, for its target port and to keep other protocols out
+		if rule.RuleExpr == "" && ((rule.Protocol != RuleProtocolTCP && rule.Protocol != RuleProtocolUDP) || rule.NegateProtocol) {
+			errs.Add("protocol", "REDIRECT requires protocol TCP or UDP", "INVALID_REDIRECT_PROTOCOL")
+		}
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// rejectTypes lists the types nftables accepts for each reject with option
+var rejectTypes = map[string][]string{
+	"icmp":   {"host-unreachable", "net-unreachable", "prot-unreachable", "port-unreachable", "net-prohibited", "host-prohibited", "admin-prohibited"},
+	"icmpv6": {"no-route", "admin-prohibited", "addr-unreachable", "port-unreachable", "policy-fail", "reject-route"},
+	"icmpx":  {"port-unreachable", "admin-prohibited", "no-route", "host-unreachable"},
+	"tcp":    {"reset"},
+}
+
+// validateRejectOptions checks the reject with/type combination of a rule against rejectTypes
+func validateRejectOptions(errs *validation.ValidationErrors, rule *FirewallRule) {
+	if rule.Action != RuleActionReject {
+		errs.Add("rejectWith", "rejectWith and rejectType are only valid for the REJECT action", "INVALID_REJECT")
+		return
+	}
+	types, ok := rejectTypes[rule.RejectWith]
+	if !ok {
+		errs.Add("rejectWith", "rejectWith must be icmp, icmpv6, icmpx or tcp", "INVALID_REJECT")
+		return
+	}
+	if rule.RejectType == "" {
+		errs.Add("rejectType", fmt.Sprintf("rejectWith %s requires rejectType", rule.RejectWith), "REQUIRED")
+		return
+	}
+	valid := false
+	for _, t := range types {
+		if t == rule.RejectType {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		errs.Add("rejectType", fmt.Sprintf("rejectType for %s must be one of: %s", rule.RejectWith, strings.Join(types, ", ")), "INVALID_REJECT")
+	}
+	// A TCP reset can only answer TCP packets
+	if rule.RejectWith == "tcp" && rule.RuleExpr == "" && (rule.Protocol != RuleProtocolTCP || rule.NegateProtocol) {
+		errs.Add("protocol", "reject with tcp reset requires protocol TCP", "INVALID_REJECT_PROTOCOL")
+	}
+}
+
+// validateConnLimit checks the connection limit of a rule; conntrack only counts
+// connections of a stateful protocol, so the limit requires a TCP match
+func validateConnLimit(errs *validation.ValidationErrors, rule *FirewallRule) {
+	if rule.ConnLimitCount <= 0 {
+		errs.Add("connLimitCount", "connLimitCount must be positive when connLimitMask is set", "INVALID_CONN_LIMIT")
+	}
+	maxMask := 32
+	if ruleAddressFamily(*rule) == "ip6" {
+		maxMask = 128
+	}
+	if rule.ConnLimitMask < 0 || rule.ConnLimitMask > maxMask {
+		errs.Add("connLimitMask", fmt.Sprintf("connLimitMask must be between 0 and %d", maxMask), "INVALID_CONN_LIMIT")
+	}
+	if rule.RuleExpr != "" {
+		errs.Add("connLimitCount", "connLimitCount cannot be combined with ruleExpr", "INVALID_CONN_LIMIT")
+	} else if rule.Protocol != RuleProtocolTCP || rule.NegateProtocol {
+		errs.Add("protocol", "connection limits require protocol TCP", "INVALID_CONN_LIMIT_PROTOCOL")
+	}
+}
+
+// icmpTypes lists the type names nft accepts in "icmp type"
+var icmpTypes = []string{
+	"echo-reply", "destination-unreachable", "source-quench", "redirect", "echo-request",
+	"router-advertisement", "router-solicitation", "time-exceeded", "parameter-problem",
+	"timestamp-request", "timestamp-reply", "info-request", "info-reply",
+	"address-mask-request", "address-mask-reply",
+}
+
+// icmpv6Types lists the type names nft accepts in "icmpv6 type"
+var icmpv6Types = []string{
+	"destination-unreachable", "packet-too-big", "time-exceeded", "parameter-problem",
+	"echo-request", "echo-reply", "mld-listener-query", "mld-listener-report",
+	"mld-listener-done", "mld-listener-reduction", "nd-router-solicit", "nd-router-advert",
+	"nd-neighbor-solicit", "nd-neighbor-advert", "nd-redirect", "router-renumbering",
+	"ind-neighbor-solicit", "ind-neighbor-advert", "mld2-listener-report",
+}
+
+// splitICMPTypes splits a comma-separated ICMP type list into trimmed, lower-case names
+func splitICMPTypes(value string) []string {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// validateICMPType checks a rule's ICMP types against the list of its family
+func validateICMPType(errs *validation.ValidationErrors, rule *FirewallRule) {
+	if rule.RuleExpr != "" {
+		errs.Add("icmpType", "icmpType cannot be combined with ruleExpr", "INVALID_ICMP_TYPE")
+		return
+	}
+	if (rule.Protocol != RuleProtocolICMP && rule.Protocol != RuleProtocolICMPv6) || rule.NegateProtocol {
+		errs.Add("protocol", "icmpType requires protocol ICMP or ICMPV6", "INVALID_ICMP_PROTOCOL")
+		return
+	}
+
+	known, keyword := icmpTypes, "icmp"
+	if ruleAddressFamily(*rule) == "ip6" {
+		known, keyword = icmpv6Types, "icmpv6"
+	}
+	types := splitICMPTypes(rule.ICMPType)
+	if len(types) == 0 {
+		errs.Add("icmpType", "icmpType must name at least one type", "INVALID_ICMP_TYPE")
+	}
+	for _, t := range types {
+		valid := false
+		for _, k := range known {
+			if t == k {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs.Add("icmpType", fmt.Sprintf("%q is not an %s type; expected one of: %s", t, keyword, strings.Join(known, ", ")), "INVALID_ICMP_TYPE")
+		}
+	}
+}
+
+// normalizeRuleProtocol returns the canonical upper-case form of a protocol received from the API
+// or a stored definition; "any" is an alias of ALL
+func normalizeRuleProtocol(protocol string) RuleProtocol {
+	normalized := RuleProtocol(strings.ToUpper(strings.TrimSpace(protocol)))
+	if normalized == "ANY" {
+		return RuleProtocolAll
+	}
+	return normalized
+}
+
+// protocolHasPorts reports whether port matches can be generated for a rule protocol
+func protocolHasPorts(protocol RuleProtocol) bool {
+	switch protocol {
+	case "", RuleProtocolAll, RuleProtocolTCP, RuleProtocolUDP:
+		return true
+	}
+	return false
+}
+
+// validateRuleMatches checks the generated matches of a rule: ports, protocol, negations and addresses
+func validateRuleMatches(v *validation.Validator, rule *FirewallRule) {
+	errs := v.Errors()
+
+	// ALL with ports is generated as meta l4proto { tcp, udp }; no other protocol has ports
+	if (rule.SourcePort != "" || rule.DestPort != "") && !protocolHasPorts(rule.Protocol) {
+		errs.Add("protocol", "ports require protocol TCP, UDP or ALL", "INVALID_PORT_PROTOCOL")
+	}
+	v.PortSet("sourcePort", rule.SourcePort)
+	v.PortSet("destPort", rule.DestPort)
+
+	// Negation needs a value to negate; a negated protocol cannot carry port matches,
+	// which would imply that same protocol
+	if rule.NegateProtocol {
+		if rule.Protocol == "" || rule.Protocol == RuleProtocolAll {
+			errs.Add("negateProtocol", "negateProtocol requires protocol TCP, UDP or ICMP", "INVALID_NEGATION")
+		} else if rule.SourcePort != "" || rule.DestPort != "" {
+			errs.Add("negateProtocol", "negateProtocol cannot be combined with port matches", "INVALID_NEGATION")
+		}
+	}
+	if rule.NegateSourcePort && rule.SourcePort == "" {
+		errs.Add("negateSourcePort", "negateSourcePort requires sourcePort", "INVALID_NEGATION")
+	}
+	if rule.NegateDestPort && rule.DestPort == "" {
+		errs.Add("negateDestPort", "negateDestPort requires destPort", "INVALID_NEGATION")
+	}
+
+	validateRuleAddress(v, "sourceIp", rule.SourceIP)
+	validateRuleAddress(v, "destIp", rule.DestIP)
+
+	// Both addresses are matched in the same family
+	if rule.SourceIP != "" && rule.SourceIP != "any" && rule.DestIP != "" && rule.DestIP != "any" &&
+		isIPv6Address(rule.SourceIP) != isIPv6Address(rule.DestIP) {
+		v.Errors().Add("destIp", "sourceIp and destIp must both be IPv4 or both be IPv6", "MIXED_ADDRESS_FAMILY")
+	}
+}
+
+// validateRuleAddress checks a rule address: an IP, a CIDR or a reference to a named IP set
+func validateRuleAddress(v *validation.Validator, field, addr string) {
+	if addr == "" || addr == "any" {
+		return
+	}
+	if name, ok := ipSetReference(addr); ok {
+		if !ipSetNameRegex.MatchString(name) {
+			v.Errors().Add(field, field+" references an invalid IP set name", "INVALID_IP_SET_REFERENCE")
+		}
+		return
+	}
+	if strings.Contains(addr, "/") {
+		v.CIDR(field, addr)
+	} else {
+		v.IP(field, addr)
+	}
+}
+
+// ========================================
+// Firewall Profiles
+// ========================================
+
+// newProfileFromInput builds an unsaved profile from input, applying default settings
+func newProfileFromInput(input *FirewallProfileInput) *FirewallProfile {
+	enabled := true
+	isDefault := false
+	if input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+	if input.IsDefault != nil {
+		isDefault = *input.IsDefault
+	}
+
+	// Default feature settings
+	enableNAT := false
+	enableConntrack := true
+	allowLoopback := true
+	allowEstablished := true
+	allowICMPPing := true
+	enableIPv6 := false
 	if input.EnableNAT != nil {
-		profile.EnableNAT = *input.EnableNAT
+		enableNAT = *input.EnableNAT
 	}
 	if input.EnableConntrack != nil {
-		profile.EnableConntrack = *input.EnableConntrack
+		enableConntrack = *input.EnableConntrack
 	}
 	if input.AllowLoopback != nil {
-		profile.AllowLoopback = *input.AllowLoopback
+		allowLoopback = *input.AllowLoopback
 	}
 	if input.AllowEstablished != nil {
-		profile.AllowEstablished = *input.AllowEstablished
+		allowEstablished = *input.AllowEstablished
 	}
 	if input.AllowICMPPing != nil {
-		profile.AllowICMPPing = *input.AllowICMPPing
+		allowICMPPing = *input.AllowICMPPing
 	}
 	if input.EnableIPv6 != nil {
-		profile.EnableIPv6 = *input.EnableIPv6
+		enableIPv6 = *input.EnableIPv6
 	}
 
-	if err := s.repo.UpdateProfile(profile); err != nil {
-		return nil, fmt.Errorf("failed to update profile: %w", err)
+	// Default policies
+	inputPolicy := "drop"
+	outputPolicy := "accept"
+	forwardPolicy := "drop"
+	if input.InputPolicy != "" {
+		inputPolicy = input.InputPolicy
+	}
+	if input.OutputPolicy != "" {
+		outputPolicy = input.OutputPolicy
+	}
+	if input.ForwardPolicy != "" {
+		forwardPolicy = input.ForwardPolicy
 	}
 
-	// Update rules if provided
-	if input.RuleIDs != nil {
+	enableReversePathFilter := false
+	if input.EnableReversePathFilter != nil {
+		enableReversePathFilter = *input.EnableReversePathFilter
+	}
+
+	logDroppedPackets := false
+	if input.LogDroppedPackets != nil {
+		logDroppedPackets = *input.LogDroppedPackets
+	}
+
+	enableManagementAccess := false
+	if input.EnableManagementAccess != nil {
+		enableManagementAccess = *input.EnableManagementAccess
+	}
+	managementPort := defaultManagementPort
+	if input.ManagementPort != "" {
+		managementPort = input.ManagementPort
+	}
+	managementSource := ""
+	if input.ManagementSource != nil {
+		managementSource = *input.ManagementSource
+	}
+	nftBinaryPath := defaultNftBinaryPath
+	if input.NftBinaryPath != "" {
+		nftBinaryPath = input.NftBinaryPath
+	}
+	tableMode := TableModeManaged
+	if input.TableMode != "" {
+		tableMode = input.TableMode
+	}
+	ruleOrderStrategy := RuleOrderByPriority
+	if input.RuleOrderStrategy != "" {
+		ruleOrderStrategy = input.RuleOrderStrategy
+	}
+
+	return &FirewallProfile{
+		Name:                input.Name,
+		Description:         input.Description,
+		IsDefault:           isDefault,
+		Enabled:             enabled,
+		InputPolicy:         inputPolicy,
+		OutputPolicy:        outputPolicy,
+		ForwardPolicy:       forwardPolicy,
+		EnableNAT:           enableNAT,
+		EnableConntrack:     enableConntrack,
+		AllowLoopback:       allowLoopback,
+		AllowEstablished:    allowEstablished,
+		AllowICMPPing:       allowICMPPing,
+		EnableIPv6:          enableIPv6,
+
+		EnableReversePathFilter: enableReversePathFilter,
+
+		LogDroppedPackets: logDroppedPackets,
+		LogDropPrefix:     input.LogDropPrefix,
+
+		InputPriority:       chainPriorityOrDefault(input.InputPriority, defaultFilterPriority),
+		OutputPriority:      chainPriorityOrDefault(input.OutputPriority, defaultFilterPriority),
+		ForwardPriority:     chainPriorityOrDefault(input.ForwardPriority, defaultFilterPriority),
+		PreroutingPriority:  chainPriorityOrDefault(input.PreroutingPriority, defaultPreroutingPriority),
+		PostroutingPriority: chainPriorityOrDefault(input.PostroutingPriority, defaultPostroutingPriority),
+
+		EnableManagementAccess: enableManagementAccess,
+		ManagementPort:         managementPort,
+		ManagementSource:       managementSource,
+
+		NftBinaryPath: nftBinaryPath,
+		TableMode:     tableMode,
+
+		RuleOrderStrategy: ruleOrderStrategy,
+	}
+}
+
+// CreateProfile creates a new firewall profile
+func (s *Service) CreateProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallProfileInput) (*FirewallProfile, error) {
+	profile := newProfileFromInput(input)
+	profile.TenantID = tenantID
+	profile.CreatedBy = userID
+
+	if err := s.repo.CreateProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+
+	// Add rules if provided
+	if len(input.RuleIDs) > 0 {
 		ruleIDs := make([]uuid.UUID, 0, len(input.RuleIDs))
 		for _, idStr := range input.RuleIDs {
 			if id, err := uuid.Parse(idStr); err == nil {
 				ruleIDs = append(ruleIDs, id)
 			}
 		}
-		s.repo.SetProfileRules(tenantID, profile.ID, ruleIDs)
+		if len(ruleIDs) > 0 {
+			s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs)
+		}
 	}
 
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallProfileUpdated,
+		events.EventFirewallProfileCreated,
 		tenantID,
 		profile.ID.String(),
 		map[string]interface{}{
 			"name":      profile.Name,
 			"isDefault": profile.IsDefault,
 		},
-	))
+	).WithActor(userID))
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.profile.updated",
+		Action:       "firewall.profile.created",
 		ResourceType: "firewall_profile",
 		ResourceID:   profile.ID.String(),
 		Details: map[string]interface{}{
 			"name":      profile.Name,
 			"isDefault": profile.IsDefault,
 			"enabled":   profile.Enabled,
+			"ruleCount": len(input.RuleIDs),
 		},
 	})
 
 	return profile, nil
 }
 
-// DeleteProfile deletes a firewall profile
-func (s *Service) DeleteProfile(ctx context.Context, token string, tenantID, id uuid.UUID) error {
-	// Get profile name for audit log
-	profile, _ := s.repo.GetProfileByID(tenantID, id)
-	profileName := ""
-	if profile != nil {
-		profileName = profile.Name
-	}
-
-	if err := s.repo.DeleteProfile(tenantID, id); err != nil {
-		return err
-	}
-
-	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallProfileDeleted,
-		tenantID,
-		id.String(),
-		nil,
-	))
-
-	// Audit logging
-	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.profile.deleted",
-		ResourceType: "firewall_profile",
-		ResourceID:   id.String(),
-		Details: map[string]interface{}{
-			"name": profileName,
-		},
-	})
-
-	return nil
-}
-
-// AddRulesToProfile adds rules to a profile
-func (s *Service) AddRulesToProfile(ctx context.Context, tenantID, profileID uuid.UUID, ruleIDs []uuid.UUID) error {
-	// Verify profile exists and belongs to tenant
-	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
-		return err
-	}
-	// AddRulesToProfile validates that rules also belong to this tenant
-	return s.repo.AddRulesToProfile(tenantID, profileID, ruleIDs)
+// GetProfile retrieves a profile by ID
+func (s *Service) GetProfile(ctx context.Context, tenantID, id uuid.UUID) (*FirewallProfile, error) {
+	return s.repo.GetProfileByID(tenantID, id)
 }
 
-// RemoveRulesFromProfile removes rules from a profile
-func (s *Service) RemoveRulesFromProfile(ctx context.Context, tenantID, profileID uuid.UUID, ruleIDs []uuid.UUID) error {
-	// Verify profile exists
-	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
-		return err
-	}
-	return s.repo.RemoveRulesFromProfile(profileID, ruleIDs)
+// GetProfileWithRules retrieves a profile with its rules
+func (s *Service) GetProfileWithRules(ctx context.Context, tenantID, id uuid.UUID) (*FirewallProfile, error) {
+	return s.repo.GetProfileByIDWithRules(tenantID, id)
 }
 
-// CountProfiles returns the total count of profiles
-func (s *Service) CountProfiles(ctx context.Context, tenantID uuid.UUID) (int64, error) {
-	return s.repo.CountProfiles(tenantID)
+// ListProfiles retrieves all profiles for a tenant
+func (s *Service) ListProfiles(ctx context.Context, tenantID uuid.UUID, filter *FirewallProfileFilter, limit, offset int) ([]FirewallProfile, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallProfiles, limit, offset)
+	return s.repo.ListProfiles(tenantID, filter, p.Limit, p.Offset)
 }
 
-// ========================================
-// Firewall Templates
-// ========================================
-
-// CreateTemplate creates a new firewall template
-func (s *Service) CreateTemplate(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallTemplateInput) (*FirewallTemplate, error) {
-	rulesJSON, err := json.Marshal(input.Rules)
+// UpdateProfile updates a firewall profile
+func (s *Service) UpdateProfile(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallProfileInput) (*FirewallProfile, error) {
+	profile, err := s.repo.GetProfileByID(tenantID, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize rules: %w", err)
+		return nil, err
 	}
+	before := *profile
 
-	template := &FirewallTemplate{
-		TenantID:    tenantID,
-		Name:        input.Name,
-		Description: input.Description,
-		Category:    input.Category,
-		IsBuiltIn:   false,
-		RulesJSON:   string(rulesJSON),
-		CreatedBy:   userID,
+	if input.Name != "" {
+		profile.Name = input.Name
+	}
+	if input.Description != "" {
+		profile.Description = input.Description
+	}
+	if input.IsDefault != nil {
+		profile.IsDefault = *input.IsDefault
+	}
+	if input.Enabled != nil {
+		profile.Enabled = *input.Enabled
+	}
+	// Default policies
+	if input.InputPolicy != "" {
+		profile.InputPolicy = input.InputPolicy
+	}
+	if input.OutputPolicy != "" {
+		profile.OutputPolicy = input.OutputPolicy
+	}
+	if input.ForwardPolicy != "" {
+		profile.ForwardPolicy = input.ForwardPolicy
+	}
+	// Features
+	if input.EnableNAT != nil {
+		profile.EnableNAT = *input.EnableNAT
+	}
+	if input.EnableConntrack != nil {
+		profile.EnableConntrack = *input.EnableConntrack
+	}
+	if input.AllowLoopback != nil {
+		profile.AllowLoopback = *input.AllowLoopback
+	}
+	if input.AllowEstablished != nil {
+		profile.AllowEstablished = *input.AllowEstablished
+	}
+	if input.AllowICMPPing != nil {
+		profile.AllowICMPPing = *input.AllowICMPPing
+	}
+	if input.EnableIPv6 != nil {
+		profile.EnableIPv6 = *input.EnableIPv6
+	}
+	if input.EnableReversePathFilter != nil {
+		profile.EnableReversePathFilter = *input.EnableReversePathFilter
+	}
+	if input.LogDroppedPackets != nil {
+		profile.LogDroppedPackets = *input.LogDroppedPackets
+	}
+	if input.LogDropPrefix != "" {
+		profile.LogDropPrefix = input.LogDropPrefix
+	}
+	if input.InputPriority != "" {
+		profile.InputPriority = input.InputPriority
+	}
+	if input.OutputPriority != "" {
+		profile.OutputPriority = input.OutputPriority
+	}
+	if input.ForwardPriority != "" {
+		profile.ForwardPriority = input.ForwardPriority
+	}
+	if input.PreroutingPriority != "" {
+		profile.PreroutingPriority = input.PreroutingPriority
+	}
+	if input.PostroutingPriority != "" {
+		profile.PostroutingPriority = input.PostroutingPriority
+	}
+	// Management access
+	if input.EnableManagementAccess != nil {
+		profile.EnableManagementAccess = *input.EnableManagementAccess
+	}
+	if input.ManagementPort != "" {
+		profile.ManagementPort = input.ManagementPort
+	}
+	if input.ManagementSource != nil {
+		profile.ManagementSource = *input.ManagementSource
+	}
+	if input.NftBinaryPath != "" {
+		profile.NftBinaryPath = input.NftBinaryPath
+	}
+	if input.TableMode != "" {
+		profile.TableMode = input.TableMode
+	}
+	if input.RuleOrderStrategy != "" {
+		profile.RuleOrderStrategy = input.RuleOrderStrategy
 	}
 
-	if template.Category == "" {
-		template.Category = TemplateCategoryCustom
+	if err := s.repo.UpdateProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
-	if err := s.repo.CreateTemplate(template); err != nil {
-		return nil, fmt.Errorf("failed to create template: %w", err)
+	// Update rules if provided
+	if input.RuleIDs != nil {
+		ruleIDs := make([]uuid.UUID, 0, len(input.RuleIDs))
+		for _, idStr := range input.RuleIDs {
+			if id, err := uuid.Parse(idStr); err == nil {
+				ruleIDs = append(ruleIDs, id)
+			}
+		}
+		s.repo.SetProfileRules(tenantID, profile.ID, ruleIDs)
+	}
+
+	changes := changedFields(&before, profile)
+	if input.RuleIDs != nil {
+		changes["ruleIds"] = fmt.Sprintf("replaced with %d rules", len(input.RuleIDs))
 	}
 
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallTemplateCreated,
+		events.EventFirewallProfileUpdated,
 		tenantID,
-		template.ID.String(),
+		profile.ID.String(),
 		map[string]interface{}{
-			"name":     template.Name,
-			"category": template.Category,
+			"name":      profile.Name,
+			"isDefault": profile.IsDefault,
 		},
 	))
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.template.created",
-		ResourceType: "firewall_template",
-		ResourceID:   template.ID.String(),
+		Action:       "firewall.profile.updated",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
 		Details: map[string]interface{}{
-			"name":      template.Name,
-			"category":  template.Category,
-			"ruleCount": len(input.Rules),
+			"name":      profile.Name,
+			"isDefault": profile.IsDefault,
+			"enabled":   profile.Enabled,
+			"changes":   changes,
 		},
 	})
 
-	return template, nil
-}
-
-// GetTemplate retrieves a template by ID
-func (s *Service) GetTemplate(ctx context.Context, tenantID, id uuid.UUID) (*FirewallTemplate, error) {
-	return s.repo.GetTemplateByID(tenantID, id)
-}
-
-// ListTemplates retrieves all templates for a tenant
-func (s *Service) ListTemplates(ctx context.Context, tenantID uuid.UUID, filter *FirewallTemplateFilter, limit, offset int) ([]FirewallTemplate, int64, error) {
-	p := pagination.Normalize(limit, offset)
-	return s.repo.ListTemplates(tenantID, filter, p.Limit, p.Offset)
+	return profile, nil
 }
 
-// UpdateTemplate updates a firewall template
-func (s *Service) UpdateTemplate(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallTemplateInput) (*FirewallTemplate, error) {
-	template, err := s.repo.GetTemplateByID(tenantID, id)
+// SetProfileEnabled enables or disables a profile. Disabled profiles are refused at deploy time unless forced.
+func (s *Service) SetProfileEnabled(ctx context.Context, token string, tenantID, id uuid.UUID, enabled bool) (*FirewallProfile, error) {
+	profile, err := s.repo.GetProfileByID(tenantID, id)
 	if err != nil {
 		return nil, err
 	}
-
-	// Don't allow updating built-in templates
-	if template.IsBuiltIn {
-		return nil, fmt.Errorf("cannot update built-in template")
-	}
-
-	if input.Name != "" {
-		template.Name = input.Name
-	}
-	if input.Description != "" {
-		template.Description = input.Description
-	}
-	if input.Category != "" {
-		template.Category = input.Category
-	}
-	if input.Rules != nil {
-		rulesJSON, err := json.Marshal(input.Rules)
-		if err != nil {
-			return nil, fmt.Errorf("failed to serialize rules: %w", err)
-		}
-		template.RulesJSON = string(rulesJSON)
+	if profile.Enabled == enabled {
+		return profile, nil
 	}
 
-	if err := s.repo.UpdateTemplate(template); err != nil {
-		return nil, fmt.Errorf("failed to update template: %w", err)
+	profile.Enabled = enabled
+	if err := s.repo.UpdateProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallTemplateUpdated,
+		events.EventFirewallProfileUpdated,
 		tenantID,
-		template.ID.String(),
+		profile.ID.String(),
 		map[string]interface{}{
-			"name":     template.Name,
-			"category": template.Category,
+			"name":    profile.Name,
+			"enabled": profile.Enabled,
 		},
 	))
 
-	// Audit logging
+	action := "firewall.profile.disabled"
+	if enabled {
+		action = "firewall.profile.enabled"
+	}
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.template.updated",
-		ResourceType: "firewall_template",
-		ResourceID:   template.ID.String(),
+		Action:       action,
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
 		Details: map[string]interface{}{
-			"name":     template.Name,
-			"category": template.Category,
+			"name": profile.Name,
 		},
 	})
 
-	return template, nil
+	return profile, nil
 }
 
-// DeleteTemplate deletes a firewall template
-func (s *Service) DeleteTemplate(ctx context.Context, token string, tenantID, id uuid.UUID) error {
-	// Get template name for audit log
-	template, _ := s.repo.GetTemplateByID(tenantID, id)
-	templateName := ""
-	if template != nil {
-		templateName = template.Name
+// DeleteProfile deletes a firewall profile
+func (s *Service) DeleteProfile(ctx context.Context, token string, tenantID, id uuid.UUID) error {
+	// Get profile name for audit log
+	profile, _ := s.repo.GetProfileByID(tenantID, id)
+	profileName := ""
+	if profile != nil {
+		profileName = profile.Name
 	}
 
-	if err := s.repo.DeleteTemplate(tenantID, id); err != nil {
+	if err := s.repo.DeleteProfile(tenantID, id); err != nil {
 		return err
 	}
 
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallTemplateDeleted,
+		events.EventFirewallProfileDeleted,
 		tenantID,
 		id.String(),
 		nil,
@@ -698,455 +1350,2079 @@ func (s *Service) DeleteTemplate(ctx context.Context, token string, tenantID, id
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.template.deleted",
-		ResourceType: "firewall_template",
+		Action:       "firewall.profile.deleted",
+		ResourceType: "firewall_profile",
 		ResourceID:   id.String(),
 		Details: map[string]interface{}{
-			"name": templateName,
+			"name": profileName,
 		},
 	})
 
 	return nil
 }
 
-// ApplyTemplateToProfile applies a template's rules to a profile
-func (s *Service) ApplyTemplateToProfile(ctx context.Context, token string, tenantID, userID, templateID, profileID uuid.UUID) error {
-	template, err := s.repo.GetTemplateByID(tenantID, templateID)
+// CloneProfile copies a profile under a new name with its settings and a new copy of each
+// of its rules, keeping their order. The clone is never the default profile.
+func (s *Service) CloneProfile(ctx context.Context, token string, tenantID, userID, profileID uuid.UUID, name string) (*FirewallProfile, error) {
+	source, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
 	if err != nil {
-		return fmt.Errorf("template not found: %w", err)
+		return nil, err
 	}
 
-	profile, err := s.repo.GetProfileByID(tenantID, profileID)
-	if err != nil {
-		return fmt.Errorf("profile not found: %w", err)
+	clone := *source
+	clone.ID = uuid.Nil
+	clone.Name = name
+	clone.IsDefault = false
+	clone.CreatedBy = userID
+	clone.CreatedAt = time.Time{}
+	clone.UpdatedAt = time.Time{}
+	clone.Rules = nil
+	clone.LastDeploymentStatus = nil
+	clone.LastDeployedAt = nil
+
+	rules := make([]FirewallRule, len(source.Rules))
+	for i, rule := range source.Rules {
+		rule.TenantID = tenantID
+		rule.CreatedBy = userID
+		rule.CreatedAt = time.Time{}
+		rule.UpdatedAt = time.Time{}
+		rules[i] = rule
 	}
 
-	// Parse template rules
-	rules, err := s.repo.GetTemplateRules(template)
-	if err != nil {
-		return fmt.Errorf("failed to parse template rules: %w", err)
+	if err := s.repo.CloneProfile(&clone, rules); err != nil {
+		return nil, fmt.Errorf("failed to clone profile: %w", err)
 	}
+	clone.Rules = rules
 
-	// Create rules from template and add to profile
-	ruleIDs := make([]uuid.UUID, 0, len(rules))
-	for _, ruleDef := range rules {
-		rule := &FirewallRule{
-			TenantID:    tenantID,
-			Name:        ruleDef.Name,
-			Description: ruleDef.Description,
-			Chain:       ruleDef.Chain,
-			Priority:    ruleDef.Priority,
-			Protocol:    ruleDef.Protocol,
-			SourceIP:    ruleDef.SourceIP,
-			SourcePort:  ruleDef.SourcePort,
-			DestIP:      ruleDef.DestIP,
-			DestPort:    ruleDef.DestPort,
-			Action:      ruleDef.Action,
-			Comment:     ruleDef.Comment,
-			Enabled:     true,
-			CreatedBy:   userID,
-		}
-		if err := s.repo.CreateRule(rule); err != nil {
-			continue // Skip failed rules
-		}
-		ruleIDs = append(ruleIDs, rule.ID)
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallProfileCreated,
+		tenantID,
+		clone.ID.String(),
+		map[string]interface{}{
+			"name":       clone.Name,
+			"isDefault":  false,
+			"clonedFrom": source.ID.String(),
+		},
+	).WithActor(userID))
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.profile.cloned",
+		ResourceType: "firewall_profile",
+		ResourceID:   clone.ID.String(),
+		Details: map[string]interface{}{
+			"name":       clone.Name,
+			"clonedFrom": source.ID.String(),
+			"sourceName": source.Name,
+			"ruleCount":  len(rules),
+		},
+	})
+
+	return &clone, nil
+}
+
+// AddRulesToProfile adds rules to a profile
+func (s *Service) AddRulesToProfile(ctx context.Context, tenantID, profileID uuid.UUID, ruleIDs []uuid.UUID) error {
+	// Verify profile exists and belongs to tenant
+	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
+		return err
 	}
+	// AddRulesToProfile validates that rules also belong to this tenant
+	return s.repo.AddRulesToProfile(tenantID, profileID, ruleIDs)
+}
 
-	// Add rules to profile (tenantID for validation)
-	if len(ruleIDs) > 0 {
-		if err := s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs); err != nil {
-			return fmt.Errorf("failed to add rules to profile: %w", err)
+// RemoveRulesFromProfile removes rules from a profile
+func (s *Service) RemoveRulesFromProfile(ctx context.Context, tenantID, profileID uuid.UUID, ruleIDs []uuid.UUID) error {
+	// Verify profile exists
+	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
+		return err
+	}
+	return s.repo.RemoveRulesFromProfile(profileID, ruleIDs)
+}
+
+// ReorderProfileRules sets the evaluation order of a profile's rules. ruleIDs must list every rule
+// of the profile exactly once; the profile switches to the BY_SORT_ORDER strategy.
+func (s *Service) ReorderProfileRules(ctx context.Context, token string, tenantID, profileID uuid.UUID, ruleIDs []uuid.UUID) error {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[uuid.UUID]bool, len(profile.Rules))
+	for _, rule := range profile.Rules {
+		current[rule.ID] = true
+	}
+	seen := make(map[uuid.UUID]bool, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		if seen[ruleID] {
+			return validation.NewValidationError(fmt.Sprintf("rule %s is listed more than once", ruleID))
 		}
+		if !current[ruleID] {
+			return validation.NewValidationError(fmt.Sprintf("rule %s does not belong to the profile", ruleID))
+		}
+		seen[ruleID] = true
+	}
+	if len(ruleIDs) != len(profile.Rules) {
+		return validation.NewValidationError(fmt.Sprintf("ruleIds must list all %d rules of the profile, got %d", len(profile.Rules), len(ruleIDs)))
 	}
 
+	if err := s.repo.ReorderProfileRules(profileID, ruleIDs); err != nil {
+		return fmt.Errorf("failed to reorder rules: %w", err)
+	}
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallProfileUpdated,
+		tenantID,
+		profile.ID.String(),
+		map[string]interface{}{
+			"name":              profile.Name,
+			"ruleOrderStrategy": RuleOrderBySortOrder,
+		},
+	))
+
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.template.applied",
+		Action:       "firewall.profile.rules_reordered",
 		ResourceType: "firewall_profile",
 		ResourceID:   profile.ID.String(),
 		Details: map[string]interface{}{
-			"templateId":   template.ID.String(),
-			"templateName": template.Name,
-			"profileName":  profile.Name,
-			"rulesCreated": len(ruleIDs),
+			"name":             profile.Name,
+			"rules":            len(ruleIDs),
+			"previousStrategy": profile.RuleOrderStrategy,
 		},
 	})
 
 	return nil
 }
 
-// CountTemplates returns the total count of templates
-func (s *Service) CountTemplates(ctx context.Context, tenantID uuid.UUID) (int64, error) {
-	return s.repo.CountTemplates(tenantID)
+// CountProfiles returns the total count of profiles
+func (s *Service) CountProfiles(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	return s.repo.CountProfiles(tenantID)
 }
 
 // ========================================
-// Firewall Deployments
+// Firewall Templates
 // ========================================
 
-// DeployProfile deploys a profile to an agent using nftables_apply playbook
-func (s *Service) DeployProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *DeploymentInput) (*FirewallDeployment, error) {
-	profileID, err := uuid.Parse(input.ProfileID)
+// CreateTemplate creates a new firewall template
+func (s *Service) CreateTemplate(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallTemplateInput) (*FirewallTemplate, error) {
+	rulesJSON, err := json.Marshal(input.Rules)
 	if err != nil {
-		return nil, fmt.Errorf("invalid profileId: %w", err)
+		return nil, fmt.Errorf("failed to serialize rules: %w", err)
 	}
-
-	agentID, err := uuid.Parse(input.AgentID)
+	settingsJSON, err := marshalTemplateSettings(input.Settings)
 	if err != nil {
-		return nil, fmt.Errorf("invalid agentId: %w", err)
+		return nil, err
 	}
 
-	// Validate agent capability (nftables)
-	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
-		return nil, fmt.Errorf("agent capability validation failed: %w", err)
+	template := &FirewallTemplate{
+		TenantID:     tenantID,
+		Name:         input.Name,
+		Description:  input.Description,
+		Category:     input.Category,
+		IsBuiltIn:    false,
+		Visibility:   input.Visibility,
+		RulesJSON:    string(rulesJSON),
+		SettingsJSON: settingsJSON,
+		CreatedBy:    userID,
 	}
 
-	// Get profile with rules
-	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
-	if err != nil {
-		return nil, fmt.Errorf("profile not found: %w", err)
+	if template.Category == "" {
+		template.Category = TemplateCategoryCustom
+	}
+	if template.Visibility == "" {
+		template.Visibility = TemplateVisibilityTenant
 	}
 
-	// Get agent name from csd-core
-	agentName := "Unknown"
-	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
-		agentName = agent.Name
-	}
-
-	// Create snapshot of rules
-	rulesSnapshot, _ := json.Marshal(profile.Rules)
-
-	// Determine status based on dry-run mode
-	status := DeploymentStatusPending
-	if input.DryRun {
-		status = DeploymentStatusApplied // Dry-run is instant validation
-	}
-
-	deployment := &FirewallDeployment{
-		TenantID:      tenantID,
-		ProfileID:     &profileID,
-		AgentID:       agentID,
-		AgentName:     agentName,
-		Action:        DeploymentActionApply,
-		Status:        status,
-		RulesSnapshot: string(rulesSnapshot),
-		CreatedBy:     userID,
+	if err := s.repo.CreateTemplate(template); err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
 	}
 
-	if err := s.repo.CreateDeployment(deployment); err != nil {
-		return nil, fmt.Errorf("failed to create deployment: %w", err)
-	}
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallTemplateCreated,
+		tenantID,
+		template.ID.String(),
+		map[string]interface{}{
+			"name":     template.Name,
+			"category": template.Category,
+		},
+	))
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.deployment.initiated",
-		ResourceType: "firewall_deployment",
-		ResourceID:   deployment.ID.String(),
+		Action:       "firewall.template.created",
+		ResourceType: "firewall_template",
+		ResourceID:   template.ID.String(),
 		Details: map[string]interface{}{
-			"profileId":   profile.ID.String(),
-			"profileName": profile.Name,
-			"agentId":     agentID.String(),
-			"agentName":   agentName,
-			"dryRun":      input.DryRun,
-			"ruleCount":   len(profile.Rules),
+			"name":      template.Name,
+			"category":  template.Category,
+			"ruleCount": len(input.Rules),
 		},
 	})
 
-	// For dry-run mode, just validate and return
-	if input.DryRun {
-		nftConfig := s.generateNftablesConfig(profile.Rules)
-		s.repo.UpdateDeploymentStatus(deployment.ID, DeploymentStatusApplied,
-			"Dry-run validation successful. Configuration is valid.",
-			nftConfig)
-		return deployment, nil
-	}
+	return template, nil
+}
 
-	// Start async deployment
-	go s.runDeployment(deployment.ID, tenantID, token, profile, agentID)
+// GetTemplate retrieves a template visible to the user by ID
+func (s *Service) GetTemplate(ctx context.Context, tenantID, userID, id uuid.UUID) (*FirewallTemplate, error) {
+	return s.repo.GetTemplateByID(tenantID, userID, id)
+}
 
-	return deployment, nil
+// ListTemplates retrieves the templates visible to the user
+func (s *Service) ListTemplates(ctx context.Context, tenantID, userID uuid.UUID, filter *FirewallTemplateFilter, limit, offset int) ([]FirewallTemplate, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallTemplates, limit, offset)
+	return s.repo.ListTemplates(tenantID, userID, filter, p.Limit, p.Offset)
 }
 
-// runDeployment executes the deployment in background
-func (s *Service) runDeployment(deploymentID, tenantID uuid.UUID, token string, profile *FirewallProfile, agentID uuid.UUID) {
-	// Use timeout to prevent goroutine leaks
-	timeout := 5 * time.Minute
-	if cfg := config.GetConfig(); cfg != nil && cfg.Limits.FirewallDeploymentTimeout > 0 {
-		timeout = time.Duration(cfg.Limits.FirewallDeploymentTimeout) * time.Minute
+// getOwnedTemplate retrieves a visible template that the tenant may modify (not built-in, not shared by another tenant)
+func (s *Service) getOwnedTemplate(tenantID, userID, id uuid.UUID) (*FirewallTemplate, error) {
+	template, err := s.repo.GetTemplateByID(tenantID, userID, id)
+	if err != nil {
+		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	if template.IsBuiltIn {
+		return nil, validation.NewValidationError("cannot modify built-in template")
+	}
+	if template.TenantID != tenantID {
+		return nil, validation.NewValidationError("cannot modify a template shared by another tenant")
+	}
+	return template, nil
+}
 
-	s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying, "Applying firewall rules...", "")
+// UpdateTemplate updates a firewall template
+func (s *Service) UpdateTemplate(ctx context.Context, token string, tenantID, userID, id uuid.UUID, input *FirewallTemplateInput) (*FirewallTemplate, error) {
+	template, err := s.getOwnedTemplate(tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" {
+		template.Name = input.Name
+	}
+	if input.Description != "" {
+		template.Description = input.Description
+	}
+	if input.Category != "" {
+		template.Category = input.Category
+	}
+	if input.Visibility != "" {
+		template.Visibility = input.Visibility
+	}
+	if input.Rules != nil {
+		rulesJSON, err := json.Marshal(input.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize rules: %w", err)
+		}
+		template.RulesJSON = string(rulesJSON)
+	}
+	if input.Settings != nil {
+		settingsJSON, err := marshalTemplateSettings(input.Settings)
+		if err != nil {
+			return nil, err
+		}
+		template.SettingsJSON = settingsJSON
+	}
+
+	if err := s.repo.UpdateTemplate(template); err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
 
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallDeployStarted,
+		events.EventFirewallTemplateUpdated,
 		tenantID,
-		deploymentID.String(),
+		template.ID.String(),
 		map[string]interface{}{
-			"profileId": profile.ID.String(),
-			"agentId":   agentID.String(),
+			"name":     template.Name,
+			"category": template.Category,
 		},
 	))
 
-	// Generate nftables configuration from profile (includes ct state, loopback, NAT)
-	nftConfig := s.generateNftablesConfigForProfile(profile)
-
-	// Store backup of current configuration via csd-core Artifacts
-	backupKey := fmt.Sprintf("firewall-backup-%s-%s", agentID.String(), time.Now().Format("20060102-150405"))
-	backupData := map[string]interface{}{
-		"profile_id":   profile.ID.String(),
-		"profile_name": profile.Name,
-		"rules":        profile.Rules,
-		"config":       nftConfig,
-	}
-	backupJSON, _ := json.Marshal(backupData)
-	if err := s.client.CreateArtifact(ctx, token, tenantID, backupKey, "firewall-backup", string(backupJSON)); err != nil {
-		// Log but don't fail - backup is best effort
-		s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying,
-			fmt.Sprintf("Backup creation failed (continuing): %s", err.Error()), "")
-	}
-
-	// Execute nftables task via csd-core using config_content
-	// This deploys the complete nftables configuration file
-	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
-		AgentID: agentID,
-		Task: csdcore.TaskInput{
-			Type: "nftables",
-			Name: fmt.Sprintf("deploy-profile-%s", profile.Name),
-			Config: map[string]interface{}{
-				"config_content": nftConfig,
-			},
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.template.updated",
+		ResourceType: "firewall_template",
+		ResourceID:   template.ID.String(),
+		Details: map[string]interface{}{
+			"name":     template.Name,
+			"category": template.Category,
 		},
-		Wait:    true,
-		Timeout: 120,
 	})
-	if err != nil {
-		s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusError, "Failed to execute task: "+err.Error(), "")
-		events.GetEventBus().PublishAsync(events.NewEvent(
-			events.EventFirewallDeployFailed,
-			tenantID,
-			deploymentID.String(),
-			map[string]interface{}{"error": err.Error()},
-		))
-
-		// Audit log for failure
-		s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-			Action:       "firewall.deployment.failed",
-			ResourceType: "firewall_deployment",
-			ResourceID:   deploymentID.String(),
-			Details: map[string]interface{}{
-				"profileId": profile.ID.String(),
-				"agentId":   agentID.String(),
-				"error":     err.Error(),
-			},
-		})
-		return
-	}
 
-	if execution.Status != "SUCCESS" {
-		output := ""
-		if execution.Output != nil {
-			if str, ok := execution.Output.(string); ok {
-				output = str
-			}
-		}
-		s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusError, "Task failed: "+execution.Error, output)
-		events.GetEventBus().PublishAsync(events.NewEvent(
-			events.EventFirewallDeployFailed,
-			tenantID,
-			deploymentID.String(),
-			map[string]interface{}{"error": execution.Error},
-		))
+	return template, nil
+}
 
-		// Audit log for failure
-		s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-			Action:       "firewall.deployment.failed",
-			ResourceType: "firewall_deployment",
-			ResourceID:   deploymentID.String(),
-			Details: map[string]interface{}{
-				"profileId": profile.ID.String(),
-				"agentId":   agentID.String(),
-				"error":     execution.Error,
-			},
-		})
-		return
+// DeleteTemplate deletes a firewall template
+func (s *Service) DeleteTemplate(ctx context.Context, token string, tenantID, userID, id uuid.UUID) error {
+	template, err := s.getOwnedTemplate(tenantID, userID, id)
+	if err != nil {
+		return err
 	}
+	templateName := template.Name
 
-	output := ""
-	if execution.Output != nil {
-		if str, ok := execution.Output.(string); ok {
-			output = str
-		}
+	if err := s.repo.DeleteTemplate(tenantID, id); err != nil {
+		return err
 	}
-	s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusApplied, "Firewall rules applied successfully", output)
+
 	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallDeployCompleted,
+		events.EventFirewallTemplateDeleted,
 		tenantID,
-		deploymentID.String(),
-		map[string]interface{}{
-			"profileId": profile.ID.String(),
-			"agentId":   agentID.String(),
-		},
+		id.String(),
+		nil,
 	))
 
-	// Audit log for success
+	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.deployment.completed",
-		ResourceType: "firewall_deployment",
-		ResourceID:   deploymentID.String(),
+		Action:       "firewall.template.deleted",
+		ResourceType: "firewall_template",
+		ResourceID:   id.String(),
 		Details: map[string]interface{}{
-			"profileId": profile.ID.String(),
-			"agentId":   agentID.String(),
-			"backupKey": backupKey,
+			"name": templateName,
 		},
 	})
+
+	return nil
 }
 
-// generateNftablesConfigForProfile generates complete nftables configuration from a profile
-func (s *Service) generateNftablesConfigForProfile(profile *FirewallProfile) string {
-	var config strings.Builder
-	// Pre-allocate reasonable capacity (reduces reallocations)
-	config.Grow(4096)
+// marshalTemplateSettings serializes template settings; nil settings are stored empty
+func marshalTemplateSettings(settings *TemplateSettings) (string, error) {
+	if settings == nil {
+		return "", nil
+	}
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize settings: %w", err)
+	}
+	return string(settingsJSON), nil
+}
 
-	config.WriteString("#!/usr/sbin/nft -f\n\n")
-	config.WriteString("# Generated by CSD-Pilote Security Module\n")
-	fmt.Fprintf(&config, "# Profile: %s\n", profile.Name)
-	fmt.Fprintf(&config, "# Generated at: %s\n\n", time.Now().Format(time.RFC3339))
-	config.WriteString("flush ruleset\n\n")
+// applyTemplateSettings copies the settings a template sets onto a profile
+func applyTemplateSettings(profile *FirewallProfile, settings *TemplateSettings) {
+	// Default policies
+	if settings.InputPolicy != "" {
+		profile.InputPolicy = settings.InputPolicy
+	}
+	if settings.OutputPolicy != "" {
+		profile.OutputPolicy = settings.OutputPolicy
+	}
+	if settings.ForwardPolicy != "" {
+		profile.ForwardPolicy = settings.ForwardPolicy
+	}
+	// Features
+	if settings.EnableNAT != nil {
+		profile.EnableNAT = *settings.EnableNAT
+	}
+	if settings.EnableConntrack != nil {
+		profile.EnableConntrack = *settings.EnableConntrack
+	}
+	if settings.AllowLoopback != nil {
+		profile.AllowLoopback = *settings.AllowLoopback
+	}
+	if settings.AllowEstablished != nil {
+		profile.AllowEstablished = *settings.AllowEstablished
+	}
+	if settings.AllowICMPPing != nil {
+		profile.AllowICMPPing = *settings.AllowICMPPing
+	}
+	if settings.EnableIPv6 != nil {
+		profile.EnableIPv6 = *settings.EnableIPv6
+	}
+	if settings.EnableReversePathFilter != nil {
+		profile.EnableReversePathFilter = *settings.EnableReversePathFilter
+	}
+	if settings.LogDroppedPackets != nil {
+		profile.LogDroppedPackets = *settings.LogDroppedPackets
+	}
+}
 
-	// Determine family (inet = IPv4+IPv6, ip = IPv4 only)
-	family := "inet"
-	if !profile.EnableIPv6 {
-		family = "ip"
+// ApplyTemplateToProfile applies a template's rules to a profile, along with its profile settings when it has any.
+// Templates without settings leave the profile settings untouched.
+// Returns the rules that could not be created after retries.
+func (s *Service) ApplyTemplateToProfile(ctx context.Context, token string, tenantID, userID, templateID, profileID uuid.UUID) ([]RuleCreationFailure, error) {
+	template, err := s.repo.GetTemplateByID(tenantID, userID, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
 	}
 
-	// Filter table
-	fmt.Fprintf(&config, "table %s filter {\n", family)
+	profile, err := s.repo.GetProfileByID(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
 
-	// Group rules by chain
-	chainRules := make(map[RuleChain][]FirewallRule)
-	for _, rule := range profile.Rules {
-		if rule.Enabled {
-			chainRules[rule.Chain] = append(chainRules[rule.Chain], rule)
+	// Parse template rules
+	rules, err := s.repo.GetTemplateRules(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template rules: %w", err)
+	}
+	settings, err := s.repo.GetTemplateSettings(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template settings: %w", err)
+	}
+
+	// Apply profile settings first so a failure leaves no orphan rules
+	settingsChanges := map[string]string{}
+	if settings != nil {
+		before := *profile
+		applyTemplateSettings(profile, settings)
+		settingsChanges = changedFields(&before, profile)
+		if len(settingsChanges) > 0 {
+			if err := s.repo.UpdateProfile(profile); err != nil {
+				return nil, fmt.Errorf("failed to apply template settings: %w", err)
+			}
 		}
 	}
 
-	// Generate filter chains
-	chains := []struct {
-		name       RuleChain
-		nftName    string
-		hookType   string
-		policyFunc func() string
-	}{
-		{RuleChainInput, "input", "input", func() string { return profile.InputPolicy }},
-		{RuleChainOutput, "output", "output", func() string { return profile.OutputPolicy }},
-		{RuleChainForward, "forward", "forward", func() string { return profile.ForwardPolicy }},
+	// Create rules from template and add to profile
+	ruleIDs, failures := s.createRulesFromDefinitions(tenantID, userID, rules)
+
+	// Add rules to profile (tenantID for validation)
+	if len(ruleIDs) > 0 {
+		if err := s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs); err != nil {
+			return nil, fmt.Errorf("failed to add rules to profile: %w", err)
+		}
 	}
 
-	for _, chain := range chains {
-		policy := chain.policyFunc()
-		if policy == "" {
-			policy = "drop"
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.template.applied",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
+		Details: map[string]interface{}{
+			"templateId":   template.ID.String(),
+			"templateName": template.Name,
+			"profileName":  profile.Name,
+			"rulesCreated": len(ruleIDs),
+			"rulesFailed":  len(failures),
+			"changes":      settingsChanges,
+		},
+	})
+
+	return failures, nil
+}
+
+// PublishTemplate shares a tenant template with every tenant. Other tenants can list and apply it but not modify it.
+func (s *Service) PublishTemplate(ctx context.Context, token string, tenantID, userID, id uuid.UUID) (*FirewallTemplate, error) {
+	template, err := s.getOwnedTemplate(tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Visibility == TemplateVisibilityShared {
+		return template, nil
+	}
+
+	template.Visibility = TemplateVisibilityShared
+	if err := s.repo.UpdateTemplate(template); err != nil {
+		return nil, fmt.Errorf("failed to publish template: %w", err)
+	}
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallTemplateUpdated,
+		tenantID,
+		template.ID.String(),
+		map[string]interface{}{
+			"name":       template.Name,
+			"visibility": template.Visibility,
+		},
+	))
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.template.published",
+		ResourceType: "firewall_template",
+		ResourceID:   template.ID.String(),
+		Details: map[string]interface{}{
+			"name": template.Name,
+		},
+	})
+
+	return template, nil
+}
+
+// CountTemplates returns the total count of templates visible to the user
+func (s *Service) CountTemplates(ctx context.Context, tenantID, userID uuid.UUID) (int64, error) {
+	return s.repo.CountTemplates(tenantID, userID)
+}
+
+// builtInTemplateNetwork is the source network of the built-in templates restricting access;
+// tenants copy the template rules and narrow it to their admin or application subnet
+const builtInTemplateNetwork = "10.0.0.0/8"
+
+// builtInTemplates is the curated set of templates every tenant sees out of the box
+var builtInTemplates = []struct {
+	Name        string
+	Description string
+	Category    TemplateCategory
+	Rules       []TemplateRuleDefinition
+	Settings    *TemplateSettings
+}{
+	{
+		Name:        "Web Server",
+		Description: "Allow HTTP and HTTPS from anywhere",
+		Category:    TemplateCategoryWebServer,
+		Rules: []TemplateRuleDefinition{
+			{Name: "Allow HTTP/HTTPS", Chain: RuleChainInput, Priority: 100, Protocol: RuleProtocolTCP, DestPort: "80,443", Action: RuleActionAccept, Comment: "Web traffic"},
+		},
+		Settings: &TemplateSettings{
+			InputPolicy: "drop", OutputPolicy: "accept", ForwardPolicy: "drop",
+			AllowLoopback: boolPtr(true), AllowEstablished: boolPtr(true), AllowICMPPing: boolPtr(true),
+		},
+	},
+	{
+		Name:        "Bastion Host",
+		Description: "Allow SSH from the admin network only",
+		Category:    TemplateCategoryBastion,
+		Rules: []TemplateRuleDefinition{
+			{Name: "Limit SSH connections per source", Chain: RuleChainInput, Priority: 90, Protocol: RuleProtocolTCP, DestPort: "22", ConnLimitCount: 5, ConnLimitMask: 32, Action: RuleActionDrop, Comment: "Slows down brute-force attempts"},
+			{Name: "Allow SSH from admins", Chain: RuleChainInput, Priority: 100, Protocol: RuleProtocolTCP, SourceIP: builtInTemplateNetwork, DestPort: "22", Action: RuleActionAccept, Comment: "Narrow to the admin network"},
+		},
+		Settings: &TemplateSettings{
+			InputPolicy: "drop", OutputPolicy: "accept", ForwardPolicy: "drop",
+			AllowLoopback: boolPtr(true), AllowEstablished: boolPtr(true), AllowICMPPing: boolPtr(true),
+			EnableReversePathFilter: boolPtr(true), LogDroppedPackets: boolPtr(true),
+		},
+	},
+	{
+		Name:        "Database Server",
+		Description: "Allow PostgreSQL and MySQL from the application network",
+		Category:    TemplateCategoryDatabase,
+		Rules: []TemplateRuleDefinition{
+			{Name: "Allow PostgreSQL", Chain: RuleChainInput, Priority: 100, Protocol: RuleProtocolTCP, SourceIP: builtInTemplateNetwork, DestPort: "5432", Action: RuleActionAccept, Comment: "Narrow to the application network"},
+			{Name: "Allow MySQL", Chain: RuleChainInput, Priority: 110, Protocol: RuleProtocolTCP, SourceIP: builtInTemplateNetwork, DestPort: "3306", Action: RuleActionAccept, Comment: "Narrow to the application network"},
+		},
+		Settings: &TemplateSettings{
+			InputPolicy: "drop", OutputPolicy: "accept", ForwardPolicy: "drop",
+			AllowLoopback: boolPtr(true), AllowEstablished: boolPtr(true), AllowICMPPing: boolPtr(false),
+		},
+	},
+}
+
+// boolPtr returns a pointer to b, for optional settings literals
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// templateSeedInterval is how often seeding is retried until it succeeds
+const templateSeedInterval = time.Minute
+
+// runTemplateSeeding seeds the built-in templates right away, then retries until a pass succeeds
+func (s *Service) runTemplateSeeding() {
+	if s.seedTemplatesOnce() {
+		return
+	}
+
+	ticker := time.NewTicker(templateSeedInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.seedTemplatesOnce() {
+			return
 		}
-		fmt.Fprintf(&config, "    chain %s {\n", chain.nftName)
-		fmt.Fprintf(&config, "        type filter hook %s priority 0; policy %s;\n\n", chain.hookType, policy)
+	}
+}
 
-		// Add base rules based on profile settings
-		if chain.name == RuleChainInput {
-			// Loopback rule
-			if profile.AllowLoopback {
-				config.WriteString("        # Allow loopback traffic\n")
-				config.WriteString("        iif lo accept\n\n")
-			}
+// seedTemplatesOnce runs one seeding pass and reports whether it succeeded; a panic is logged
+// and counts as a failed pass
+func (s *Service) seedTemplatesOnce() (ok bool) {
+	defer logger.RecoverPanic("[Security] Template seeding")
 
-			// Connection tracking
-			if profile.AllowEstablished {
-				config.WriteString("        # Allow established and related connections\n")
-				config.WriteString("        ct state established,related accept\n")
-				config.WriteString("        ct state invalid drop\n\n")
-			}
+	if config.GetConfig() == nil {
+		return false
+	}
+	if err := s.SeedBuiltInTemplates(context.Background()); err != nil {
+		logger.Error("[Security] Failed to seed built-in templates: %s", err.Error())
+		return false
+	}
+	return true
+}
 
-			// ICMP ping
-			if profile.AllowICMPPing {
-				config.WriteString("        # Allow ICMP ping\n")
-				if family == "inet" {
-					config.WriteString("        ip protocol icmp icmp type echo-request accept\n")
-					config.WriteString("        ip6 nexthdr icmpv6 icmpv6 type echo-request accept\n\n")
-				} else {
-					config.WriteString("        ip protocol icmp icmp type echo-request accept\n\n")
-				}
-			}
+// SeedBuiltInTemplates creates the built-in templates, or refreshes them when they exist.
+// Built-ins belong to no tenant and are matched by name.
+func (s *Service) SeedBuiltInTemplates(ctx context.Context) error {
+	existing, err := s.repo.ListBuiltInTemplates()
+	if err != nil {
+		return fmt.Errorf("failed to list built-in templates: %w", err)
+	}
+	byName := make(map[string]*FirewallTemplate, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	for _, builtIn := range builtInTemplates {
+		rulesJSON, err := json.Marshal(builtIn.Rules)
+		if err != nil {
+			return fmt.Errorf("failed to serialize rules of %s: %w", builtIn.Name, err)
+		}
+		settingsJSON, err := marshalTemplateSettings(builtIn.Settings)
+		if err != nil {
+			return fmt.Errorf("failed to serialize settings of %s: %w", builtIn.Name, err)
 		}
 
-		if chain.name == RuleChainOutput {
-			// Loopback output
-			if profile.AllowLoopback {
-				config.WriteString("        # Allow loopback traffic\n")
-				config.WriteString("        oif lo accept\n\n")
+		template := byName[builtIn.Name]
+		if template == nil {
+			template = &FirewallTemplate{
+				TenantID:   uuid.Nil,
+				Name:       builtIn.Name,
+				IsBuiltIn:  true,
+				Visibility: TemplateVisibilityTenant,
 			}
+		} else if template.Description == builtIn.Description && template.Category == builtIn.Category &&
+			template.RulesJSON == string(rulesJSON) && template.SettingsJSON == settingsJSON {
+			continue
+		}
+		template.Description = builtIn.Description
+		template.Category = builtIn.Category
+		template.RulesJSON = string(rulesJSON)
+		template.SettingsJSON = settingsJSON
 
-			// Connection tracking for output
-			if profile.AllowEstablished {
-				config.WriteString("        # Allow established and related connections\n")
-				config.WriteString("        ct state established,related accept\n\n")
-			}
+		if template.ID == uuid.Nil {
+			err = s.repo.CreateTemplate(template)
+		} else {
+			err = s.repo.UpdateTemplate(template)
 		}
+		if err != nil {
+			return fmt.Errorf("failed to seed built-in template %s: %w", builtIn.Name, err)
+		}
+	}
+	return nil
+}
 
-		if chain.name == RuleChainForward {
-			// Connection tracking for forward
-			if profile.AllowEstablished {
-				config.WriteString("        # Allow established and related connections\n")
-				config.WriteString("        ct state established,related accept\n")
-				config.WriteString("        ct state invalid drop\n\n")
-			}
+// ========================================
+// Firewall Deployments
+// ========================================
+
+// maxChangeRefLength bounds the external change request reference stored on deployments
+const maxChangeRefLength = 128
+
+// DeployProfile deploys a profile to an agent using nftables_apply playbook
+func (s *Service) DeployProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *DeploymentInput) (*FirewallDeployment, error) {
+	profileID, err := uuid.Parse(input.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profileId: %w", err)
+	}
+
+	var agentID uuid.UUID
+	if input.AgentID == "" && input.AgentHostname != "" {
+		ids, err := s.resolveAgentHostnames(ctx, token, []string{input.AgentHostname})
+		if err != nil {
+			return nil, err
+		}
+		agentID = ids[0]
+	} else {
+		agentID, err = uuid.Parse(input.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agentId: %w", err)
 		}
+	}
 
-		// Add user-defined rules
-		for _, rule := range chainRules[chain.name] {
-			config.WriteString("        ")
-			config.WriteString(s.ruleToNft(rule))
-			config.WriteByte('\n')
+	// Validate agent capability (nftables)
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent capability validation failed: %w", err)
+	}
+
+	// Get profile with rules
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+
+	if err := checkProfileEnabled(profile, input.Force); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	deployment := s.newApplyDeployment(ctx, token, tenantID, userID, profile, agentID)
+	deployment.ChangeRef = input.ChangeRef
+	deployment.Verify = input.Verify && !input.DryRun
+	agentName := deployment.AgentName
+
+	if err := s.repo.CreateDeployment(deployment); err != nil {
+		return nil, fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.deployment.initiated",
+		ResourceType: "firewall_deployment",
+		ResourceID:   deployment.ID.String(),
+		Details: map[string]interface{}{
+			"profileId":   profile.ID.String(),
+			"profileName": profile.Name,
+			"agentId":     agentID.String(),
+			"agentName":   agentName,
+			"dryRun":      input.DryRun,
+			"ruleCount":   len(profile.Rules),
+			"changeRef":   input.ChangeRef,
+			"forced":      input.Force && !profile.Enabled,
+			"verify":      deployment.Verify,
+		},
+	})
+
+	// For dry-run mode, validate on the agent and return
+	if input.DryRun {
+		deployment.Status, deployment.StatusMessage = s.validateDryRun(ctx, token, deployment.ID, agentID, profile)
+		return deployment, nil
+	}
+
+	// Start async deployment
+	go s.runDeployment(deployment.ID, tenantID, token, profile, agentID, deployment.Verify)
+
+	return deployment, nil
+}
+
+// nftValidateCapability is advertised by agents whose nftables task honours the validate_only flag
+const nftValidateCapability = "nftables-validate"
+
+// errNftValidateUnsupported is returned by checkNftConfig for agents without nftValidateCapability
+var errNftValidateUnsupported = errors.New("the agent does not support nft validation")
+
+// checkNftConfig checks a generated config on an agent with "nft -c" without applying it. The config
+// is only sent to agents advertising nftValidateCapability: an older agent ignores validate_only and
+// would apply it.
+func (s *Service) checkNftConfig(ctx context.Context, token string, agentID uuid.UUID, profile *FirewallProfile, nftConfig string) (*csdcore.TaskExecution, error) {
+	agent, err := s.client.GetAgent(ctx, token, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent: %w", err)
+	}
+	if agent == nil {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	if !agent.HasCapability(nftValidateCapability) {
+		return nil, errNftValidateUnsupported
+	}
+
+	return s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: fmt.Sprintf("validate-profile-%s", profile.Name),
+			Config: map[string]interface{}{
+				"config_content": nftConfig,
+				"nft_binary":     nftBinaryPath(profile),
+				"validate_only":  true,
+				"check_command":  nftBinaryPath(profile) + " -c -f",
+			},
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+}
+
+// validateDryRun generates the config of a dry-run deployment and checks it on the agent with "nft -c",
+// recording APPLIED when it passes and ERROR with the nft error text otherwise. Agents without
+// validation support fall back to server-side generation only.
+func (s *Service) validateDryRun(ctx context.Context, token string, deploymentID, agentID uuid.UUID, profile *FirewallProfile) (DeploymentStatus, string) {
+	nftConfig := s.generateNftablesConfigForProfile(profile)
+	s.repo.SetDeploymentConfigHash(deploymentID, nftConfigHash(nftConfig))
+
+	status, message := DeploymentStatusApplied, "Dry-run validation successful. Configuration is valid."
+	execution, err := s.checkNftConfig(ctx, token, agentID, profile, nftConfig)
+	switch {
+	case errors.Is(err, errNftValidateUnsupported):
+		message = "Dry-run generation successful. The agent does not support nft validation; the configuration was not checked against its kernel."
+	case err != nil:
+		status, message = DeploymentStatusError, "Dry-run validation could not run: "+err.Error()
+	case execution.Status != "SUCCESS":
+		status, message = DeploymentStatusError, "nft validation failed: "+nftErrorText(taskOutputString(execution), execution.Error)
+	}
+	s.repo.UpdateDeploymentStatus(deploymentID, status, message, nftConfig)
+	return status, message
+}
+
+// nftErrorText extracts the "Error:" lines nft prints for a rejected config, falling back to the task error
+func nftErrorText(output, taskError string) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "Error:") {
+			lines = append(lines, strings.TrimSpace(line))
 		}
+	}
+	if len(lines) == 0 {
+		if taskError != "" {
+			return taskError
+		}
+		return strings.TrimSpace(output)
+	}
+	return strings.Join(lines, "; ")
+}
 
-		config.WriteString("    }\n\n")
+// maxBulkDeployAgents caps the agents of one bulk deployment
+const maxBulkDeployAgents = 500
+
+// bulkDeployConcurrency bounds how many deployments of a bulk deployment run at the same time
+const bulkDeployConcurrency = 10
+
+// DeployProfileToAgents deploys a profile to several agents, creating one deployment per agent
+// under a shared batch ID. Agents that are unknown, offline or lack the nftables capability get
+// a failed deployment so the batch reports them. The others are deployed in the background,
+// bulkDeployConcurrency at a time.
+func (s *Service) DeployProfileToAgents(ctx context.Context, token string, tenantID, userID uuid.UUID, input *BulkDeploymentInput) (*DeploymentBatch, error) {
+	profileID, err := uuid.Parse(input.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profileId: %w", err)
+	}
+	if len(input.AgentIDs) == 0 || len(input.AgentIDs) > maxBulkDeployAgents {
+		return nil, validation.NewValidationError(fmt.Sprintf("agentIds must list between 1 and %d agents", maxBulkDeployAgents))
 	}
 
-	config.WriteString("}\n\n")
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := checkProfileEnabled(profile, input.Force); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
 
-	// NAT table (if enabled)
-	if profile.EnableNAT {
-		fmt.Fprintf(&config, "table %s nat {\n", family)
+	// One agent listing instead of a lookup per agent
+	agents, err := s.client.ListAgents(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	agentsByID := make(map[uuid.UUID]csdcore.Agent, len(agents))
+	for _, agent := range agents {
+		agentsByID[agent.ID] = agent
+	}
 
-		// Prerouting chain (for DNAT)
-		config.WriteString("    chain prerouting {\n")
-		config.WriteString("        type nat hook prerouting priority dstnat;\n\n")
-		for _, rule := range chainRules[RuleChainPrerouting] {
-			config.WriteString("        ")
-			config.WriteString(s.ruleToNft(rule))
-			config.WriteByte('\n')
+	batchID := uuid.New()
+	batch := &DeploymentBatch{
+		BatchID:      batchID,
+		StatusCounts: make(map[DeploymentStatus]int),
+		Deployments:  make([]FirewallDeployment, 0, len(input.AgentIDs)),
+		Failures:     []FirewallDeployment{},
+	}
+	var pending []FirewallDeployment
+	seen := make(map[uuid.UUID]bool, len(input.AgentIDs))
+	for _, agentID := range input.AgentIDs {
+		if seen[agentID] {
+			continue
 		}
-		config.WriteString("    }\n\n")
+		seen[agentID] = true
 
-		// Postrouting chain (for SNAT/MASQUERADE)
-		config.WriteString("    chain postrouting {\n")
-		config.WriteString("        type nat hook postrouting priority srcnat;\n\n")
-		for _, rule := range chainRules[RuleChainPostrouting] {
-			config.WriteString("        ")
-			config.WriteString(s.ruleToNft(rule))
-			config.WriteByte('\n')
+		agent, known := agentsByID[agentID]
+		agentName := "Unknown"
+		if known {
+			agentName = agent.Name
+		}
+		deployment := buildApplyDeployment(tenantID, userID, profile, agentID, agentName)
+		deployment.BatchID = &batchID
+		deployment.ChangeRef = input.ChangeRef
+		deployment.Verify = input.Verify
+
+		switch {
+		case !known:
+			deployment.Status, deployment.StatusMessage = DeploymentStatusError, "Agent not found"
+		case agent.Status != "ONLINE":
+			deployment.Status, deployment.StatusMessage = DeploymentStatusError, fmt.Sprintf("Agent is not online (status: %s)", agent.Status)
+		case !agent.HasCapability("nftables"):
+			deployment.Status, deployment.StatusMessage = DeploymentStatusError, "Agent does not support the nftables capability"
 		}
-		config.WriteString("    }\n")
 
-		config.WriteString("}\n")
+		if err := s.repo.CreateDeployment(deployment); err != nil {
+			return nil, fmt.Errorf("failed to create deployment for agent %s: %w", agentName, err)
+		}
+		if deployment.Status == DeploymentStatusPending {
+			pending = append(pending, *deployment)
+		} else {
+			batch.Failures = append(batch.Failures, *deployment)
+		}
+		batch.StatusCounts[deployment.Status]++
+		batch.Deployments = append(batch.Deployments, *deployment)
 	}
+	batch.Total = len(batch.Deployments)
 
-	return config.String()
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.deployment.bulk_initiated",
+		ResourceType: "firewall_deployment",
+		ResourceID:   batchID.String(),
+		Details: map[string]interface{}{
+			"profileId":   profile.ID.String(),
+			"profileName": profile.Name,
+			"agentCount":  batch.Total,
+			"rejected":    len(batch.Failures),
+			"changeRef":   input.ChangeRef,
+			"forced":      input.Force && !profile.Enabled,
+			"verify":      input.Verify,
+		},
+	})
+
+	go s.runBulkDeployments(tenantID, token, profile, pending)
+
+	return batch, nil
 }
 
-// generateNftablesConfig generates nftables configuration from rules (legacy, for dry-run)
-func (s *Service) generateNftablesConfig(rules []FirewallRule) string {
-	// Create a temporary profile with default settings
+// runBulkDeployments runs the deployments of a bulk deployment through a bounded worker pool
+func (s *Service) runBulkDeployments(tenantID uuid.UUID, token string, profile *FirewallProfile, deployments []FirewallDeployment) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkDeployConcurrency)
+	for _, deployment := range deployments {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(deployment FirewallDeployment) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.runDeployment(deployment.ID, tenantID, token, profile, deployment.AgentID, deployment.Verify)
+		}(deployment)
+	}
+	wg.Wait()
+}
+
+// GetDeploymentBatch summarizes the deployments of a bulk deployment by status, with its failures
+func (s *Service) GetDeploymentBatch(ctx context.Context, tenantID, batchID uuid.UUID) (*DeploymentBatch, error) {
+	deployments, err := s.repo.ListBatchDeployments(tenantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments) == 0 {
+		return nil, validation.NewNotFoundError("deployment batch")
+	}
+
+	batch := &DeploymentBatch{
+		BatchID:      batchID,
+		Total:        len(deployments),
+		StatusCounts: make(map[DeploymentStatus]int),
+		Failures:     []FirewallDeployment{},
+	}
+	for _, deployment := range deployments {
+		batch.StatusCounts[deployment.Status]++
+		if deployment.Status == DeploymentStatusError || deployment.Status == DeploymentStatusUnknown {
+			batch.Failures = append(batch.Failures, deployment)
+		}
+	}
+	return batch, nil
+}
+
+// checkProfileEnabled refuses to deploy a disabled profile unless the caller forces it.
+// A disabled profile is never rendered as an empty or permissive ruleset; it is simply not pushed.
+func checkProfileEnabled(profile *FirewallProfile, force bool) error {
+	if !profile.Enabled && !force {
+		return validation.NewValidationError(fmt.Sprintf("profile %q is disabled; enable it or deploy with force", profile.Name))
+	}
+	return nil
+}
+
+// validateProfileForDeploy checks that a profile's rules can be rendered for its settings
+func validateProfileForDeploy(profile *FirewallProfile) error {
+	if profile.EnableManagementAccess && isIPv6Address(profile.ManagementSource) && !profile.EnableIPv6 {
+		return validation.NewValidationError("managementSource is an IPv6 address, which requires IPv6 to be enabled on the profile")
+	}
+	for _, rule := range profile.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		if rule.Action == RuleActionRedirect && !profile.EnableNAT {
+			return validation.NewValidationError(fmt.Sprintf("rule %q uses REDIRECT, which requires NAT to be enabled on the profile", rule.Name))
+		}
+		// ip6 matches are only valid in the inet table generated with IPv6 enabled
+		if rule.RuleExpr == "" && ruleAddressFamily(rule) == "ip6" && !profile.EnableIPv6 {
+			return validation.NewValidationError(fmt.Sprintf("rule %q matches IPv6 addresses, which requires IPv6 to be enabled on the profile", rule.Name))
+		}
+	}
+	return nil
+}
+
+// newApplyDeployment builds an unsaved APPLY deployment record with agent name and rules snapshot
+func (s *Service) newApplyDeployment(ctx context.Context, token string, tenantID, userID uuid.UUID, profile *FirewallProfile, agentID uuid.UUID) *FirewallDeployment {
+	// Get agent name from csd-core
+	agentName := "Unknown"
+	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
+		agentName = agent.Name
+	}
+
+	return buildApplyDeployment(tenantID, userID, profile, agentID, agentName)
+}
+
+// buildApplyDeployment builds an unsaved APPLY deployment record for a known agent name
+func buildApplyDeployment(tenantID, userID uuid.UUID, profile *FirewallProfile, agentID uuid.UUID, agentName string) *FirewallDeployment {
+	// Create snapshot of rules
+	rulesSnapshot, _ := json.Marshal(profile.Rules)
+
+	profileID := profile.ID
+	return &FirewallDeployment{
+		TenantID:      tenantID,
+		ProfileID:     &profileID,
+		AgentID:       agentID,
+		AgentName:     agentName,
+		Action:        DeploymentActionApply,
+		Status:        DeploymentStatusPending,
+		RulesSnapshot: string(rulesSnapshot),
+		SnapshotRules: profile.Rules,
+		CreatedBy:     userID,
+	}
+}
+
+// GetBackupContent returns the decoded content of a firewall-backup artifact for an agent.
+// Without a key, the backup stored by the agent's most recent deployment is returned.
+func (s *Service) GetBackupContent(ctx context.Context, token string, tenantID, agentID uuid.UUID, key string) (*BackupContent, error) {
+	if key == "" {
+		deployment, err := s.repo.GetLatestBackupForAgent(tenantID, agentID)
+		if err != nil {
+			return nil, fmt.Errorf("no backup found for agent: %w", err)
+		}
+		key = deployment.BackupKey
+	}
+
+	// Only backups taken for this agent can be read through it
+	if !strings.HasPrefix(key, fmt.Sprintf("firewall-backup-%s-", agentID)) {
+		return nil, validation.NewValidationError("backupKey does not belong to this agent")
+	}
+
+	// Backups are JSON, so a plaintext backup is recognised and never base64 decoded
+	raw, err := s.client.GetArtifactContent(ctx, token, key, json.Valid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var data struct {
+		ProfileID   string         `json:"profile_id"`
+		ProfileName string         `json:"profile_name"`
+		Rules       []FirewallRule `json:"rules"`
+		Config      string         `json:"config"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+
+	content := &BackupContent{
+		Key:         key,
+		AgentID:     agentID,
+		ProfileID:   data.ProfileID,
+		ProfileName: data.ProfileName,
+		Config:      data.Config,
+		Rules:       data.Rules,
+	}
+	if content.Rules == nil {
+		content.Rules = []FirewallRule{}
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.backup.downloaded",
+		ResourceType: "firewall_backup",
+		ResourceID:   key,
+		Details: map[string]interface{}{
+			"agentId":   agentID.String(),
+			"profileId": data.ProfileID,
+		},
+	})
+
+	return content, nil
+}
+
+// runDeployment executes the deployment in background.
+// When verify is set, the live ruleset is read back after a successful apply and stored on the deployment.
+func (s *Service) runDeployment(deploymentID, tenantID uuid.UUID, token string, profile *FirewallProfile, agentID uuid.UUID, verify bool) {
+	// Use timeout to prevent goroutine leaks
+	timeout := 5 * time.Minute
+	if cfg := config.GetConfig(); cfg != nil && cfg.Limits.FirewallDeploymentTimeout > 0 {
+		timeout = time.Duration(cfg.Limits.FirewallDeploymentTimeout) * time.Minute
+	}
+	ctx, cancel := s.startOperation(deploymentID, timeout)
+	defer cancel()
+
+	s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying, "Applying firewall rules...", "")
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallDeployStarted,
+		tenantID,
+		deploymentID.String(),
+		map[string]interface{}{
+			"profileId": profile.ID.String(),
+			"agentId":   agentID.String(),
+		},
+	))
+
+	// Refuse syntax the agent's nft cannot parse; a failed detection does not block the deployment
+	if version, err := s.detectNftVersion(ctx, token, agentID, profile); err != nil {
+		logger.Warn("[Security] nft version detection failed on agent %s: %s", agentID.String(), err.Error())
+	} else {
+		s.repo.SetDeploymentNftVersion(deploymentID, version.String())
+		if err := checkNftCompatibility(profile, version); err != nil {
+			s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusError, err.Error(), "")
+			events.GetEventBus().PublishAsync(events.NewEvent(
+				events.EventFirewallDeployFailed,
+				tenantID,
+				deploymentID.String(),
+				map[string]interface{}{"error": err.Error(), "status": DeploymentStatusError},
+			))
+
+			s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+				Action:       "firewall.deployment.failed",
+				ResourceType: "firewall_deployment",
+				ResourceID:   deploymentID.String(),
+				Details: map[string]interface{}{
+					"profileId":  profile.ID.String(),
+					"agentId":    agentID.String(),
+					"error":      err.Error(),
+					"nftVersion": version.String(),
+				},
+			})
+			return
+		}
+	}
+
+	// Generate nftables configuration from profile (includes ct state, loopback, NAT)
+	nftConfig := s.generateNftablesConfigForProfile(profile)
+	s.repo.SetDeploymentConfigHash(deploymentID, nftConfigHash(nftConfig))
+
+	// Store backup of current configuration via csd-core Artifacts
+	backupKey := fmt.Sprintf("firewall-backup-%s-%s", agentID.String(), time.Now().Format("20060102-150405"))
+	backupData := map[string]interface{}{
+		"profile_id":   profile.ID.String(),
+		"profile_name": profile.Name,
+		"rules":        profile.Rules,
+		"config":       nftConfig,
+	}
+	backupJSON, _ := json.Marshal(backupData)
+	if err := s.client.CreateArtifact(ctx, token, tenantID, backupKey, "firewall-backup", string(backupJSON)); err != nil {
+		// Log but don't fail - backup is best effort
+		s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying,
+			fmt.Sprintf("Backup creation failed (continuing): %s", err.Error()), "")
+	} else {
+		s.repo.SetDeploymentBackupKey(deploymentID, backupKey)
+	}
+
+	// Execute nftables task via csd-core using config_content
+	// This deploys the complete nftables configuration file; output is
+	// streamed into the deployment record while the task runs, and the
+	// task is retried while the agent is unreachable
+	execution, attempts, err := s.executeDeploymentTask(ctx, token, deploymentID, agentID, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: fmt.Sprintf("deploy-profile-%s", profile.Name),
+			Config: map[string]interface{}{
+				"config_content": nftConfig,
+				"nft_binary":     nftBinaryPath(profile),
+				"apply_command":  nftBinaryPath(profile) + " -f",
+			},
+		},
+		Timeout: 120,
+	})
+	if err != nil {
+		s.repo.UpdateDeploymentStatus(deploymentID, classifyTaskFailure(err.Error()), withAttempts("Failed to execute task: "+err.Error(), attempts), "")
+		events.GetEventBus().PublishAsync(events.NewEvent(
+			events.EventFirewallDeployFailed,
+			tenantID,
+			deploymentID.String(),
+			map[string]interface{}{"error": err.Error(), "status": classifyTaskFailure(err.Error())},
+		))
+
+		// Audit log for failure
+		s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+			Action:       "firewall.deployment.failed",
+			ResourceType: "firewall_deployment",
+			ResourceID:   deploymentID.String(),
+			Details: map[string]interface{}{
+				"profileId": profile.ID.String(),
+				"agentId":   agentID.String(),
+				"error":     err.Error(),
+				"attempts":  attempts,
+			},
+		})
+		return
+	}
+
+	if execution.Status != "SUCCESS" {
+		output := taskOutputString(execution)
+		s.repo.UpdateDeploymentStatus(deploymentID, classifyTaskFailure(execution.Error), withAttempts("Task failed: "+execution.Error, attempts), output)
+		events.GetEventBus().PublishAsync(events.NewEvent(
+			events.EventFirewallDeployFailed,
+			tenantID,
+			deploymentID.String(),
+			map[string]interface{}{"error": execution.Error, "status": classifyTaskFailure(execution.Error)},
+		))
+
+		// Audit log for failure
+		s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+			Action:       "firewall.deployment.failed",
+			ResourceType: "firewall_deployment",
+			ResourceID:   deploymentID.String(),
+			Details: map[string]interface{}{
+				"profileId": profile.ID.String(),
+				"agentId":   agentID.String(),
+				"error":     execution.Error,
+				"attempts":  attempts,
+			},
+		})
+		return
+	}
+
+	output := taskOutputString(execution)
+	s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusApplied, withAttempts("Firewall rules applied successfully", attempts), output)
+
+	verified := false
+	if verify {
+		verified = s.verifyDeployment(ctx, token, deploymentID, agentID, nftConfig)
+	}
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallDeployCompleted,
+		tenantID,
+		deploymentID.String(),
+		map[string]interface{}{
+			"profileId": profile.ID.String(),
+			"agentId":   agentID.String(),
+		},
+	))
+
+	// Audit log for success
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.deployment.completed",
+		ResourceType: "firewall_deployment",
+		ResourceID:   deploymentID.String(),
+		Details: map[string]interface{}{
+			"profileId": profile.ID.String(),
+			"agentId":   agentID.String(),
+			"backupKey": backupKey,
+			"verify":    verify,
+			"verified":  verified,
+		},
+	})
+}
+
+// verifyDeployment reads back the live ruleset of an agent after a deployment and checks
+// that every statement of the deployed config is present. The deployment stays APPLIED
+// either way; the outcome is recorded in its verified flag and status message.
+func (s *Service) verifyDeployment(ctx context.Context, token string, deploymentID, agentID uuid.UUID, nftConfig string) bool {
+	live, err := s.readLiveRuleset(ctx, token, agentID)
+	if err != nil {
+		s.repo.SetDeploymentVerification(deploymentID, false, "",
+			"Firewall rules applied; reading back the live ruleset failed: "+err.Error())
+		return false
+	}
+
+	deviations, _ := diffStatements(rulesetStatements(nftConfig), rulesetStatements(live))
+	missing := 0
+	for _, deviation := range deviations {
+		if deviation.Type == DeviationMissing {
+			missing++
+		}
+	}
+
+	switch {
+	case !hasManagedFilterTable(live):
+		s.repo.SetDeploymentVerification(deploymentID, false, live,
+			"Firewall rules applied but the managed filter table is missing from the live ruleset")
+		return false
+	case missing > 0:
+		s.repo.SetDeploymentVerification(deploymentID, false, live,
+			fmt.Sprintf("Firewall rules applied but %d statement(s) are missing from the live ruleset", missing))
+		return false
+	}
+	s.repo.SetDeploymentVerification(deploymentID, true, live, "Firewall rules applied and verified in the live ruleset")
+	return true
+}
+
+// ============================================================================
+// nftables Version Detection
+// ============================================================================
+
+// nftVersion is an nft release as reported by "nft --version"
+type nftVersion struct {
+	Major, Minor, Patch int
+}
+
+// String returns the version in dotted form
+func (v nftVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is the same release as min or newer
+func (v nftVersion) AtLeast(min nftVersion) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+// nftVersionRegex matches the release in "nftables v1.0.6 (Lester Gooch #5)"
+var nftVersionRegex = regexp.MustCompile(`v(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseNftVersion extracts the release from nft --version output
+func parseNftVersion(output string) (nftVersion, error) {
+	match := nftVersionRegex.FindStringSubmatch(output)
+	if match == nil {
+		return nftVersion{}, fmt.Errorf("unrecognized nft version output: %q", strings.TrimSpace(output))
+	}
+	version := nftVersion{}
+	version.Major, _ = strconv.Atoi(match[1])
+	version.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		version.Patch, _ = strconv.Atoi(match[3])
+	}
+	return version, nil
+}
+
+// nftFeature is generated syntax that older nft releases fail to parse
+type nftFeature struct {
+	Name       string
+	MinVersion nftVersion
+	Used       func(profile *FirewallProfile) bool
+}
+
+// nftFeatures lists the syntax the generator emits that needs more than a baseline nft
+var nftFeatures = []nftFeature{
+	{"interval sets", nftVersion{0, 6, 0}, func(profile *FirewallProfile) bool {
+		return len(profile.IPSets) > 0
+	}},
+	{"named counters", nftVersion{0, 7, 0}, func(profile *FirewallProfile) bool {
+		for _, rule := range profile.Rules {
+			if rule.Enabled && ruleCounterName(rule) != "" {
+				return true
+			}
+		}
+		return false
+	}},
+	{"fib reverse-path filter", nftVersion{0, 8, 0}, reversePathFilterEnabled},
+	{"NAT in the inet family", nftVersion{0, 9, 1}, func(profile *FirewallProfile) bool {
+		return profile.EnableNAT && profileFamily(profile) == "inet"
+	}},
+	{"connection limits", nftVersion{0, 9, 0}, func(profile *FirewallProfile) bool {
+		for _, rule := range profile.Rules {
+			if rule.Enabled && rule.RuleExpr == "" && rule.ConnLimitCount > 0 {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// checkNftCompatibility returns an error naming the first feature of the profile the given nft release cannot parse
+func checkNftCompatibility(profile *FirewallProfile, version nftVersion) error {
+	for _, feature := range nftFeatures {
+		if feature.Used(profile) && !version.AtLeast(feature.MinVersion) {
+			return fmt.Errorf("profile uses %s, which requires nft >= %s (agent has %s)",
+				feature.Name, feature.MinVersion.String(), version.String())
+		}
+	}
+	return nil
+}
+
+// detectNftVersion runs a lightweight version task with the profile's nft binary on an agent
+func (s *Service) detectNftVersion(ctx context.Context, token string, agentID uuid.UUID, profile *FirewallProfile) (nftVersion, error) {
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-version",
+			Config: map[string]interface{}{
+				"action":     "version",
+				"nft_binary": nftBinaryPath(profile),
+			},
+		},
+		Wait:    true,
+		Timeout: 30,
+	})
+	if err != nil {
+		return nftVersion{}, err
+	}
+	if execution.Status != "SUCCESS" {
+		return nftVersion{}, fmt.Errorf("version task failed: %s", execution.Error)
+	}
+	return parseNftVersion(taskOutputString(execution))
+}
+
+// taskPollInterval is how often a running task is polled for new output
+const taskPollInterval = 2 * time.Second
+
+// executeTaskWithProgress starts a task without waiting and polls it until it finishes,
+// copying its output into the deployment record whenever new output is available
+func (s *Service) executeTaskWithProgress(ctx context.Context, token string, deploymentID uuid.UUID, input *csdcore.ExecuteTaskInput) (*csdcore.TaskExecution, error) {
+	input.Wait = false
+	execution, err := s.client.ExecuteTask(ctx, token, input)
+	if err != nil {
+		return nil, err
+	}
+	if execution.IsTerminal() {
+		return execution, nil
+	}
+
+	lastOutput := ""
+	return s.client.WaitForTask(ctx, token, execution.ID, taskPollInterval, func(current *csdcore.TaskExecution) {
+		if output := taskOutputString(current); output != lastOutput {
+			lastOutput = output
+			s.repo.UpdateDeploymentOutput(deploymentID, output)
+		}
+	})
+}
+
+// maxDeployRetryBackoff caps the delay between two deployment attempts
+const maxDeployRetryBackoff = 2 * time.Minute
+
+// nftSyntaxErrorMarkers identify failures where nft rejected the ruleset itself; retrying cannot help
+var nftSyntaxErrorMarkers = []string{
+	"syntax error", "could not process rule",
+}
+
+// deployRetrySettings returns how many times a deployment task is retried and the initial backoff
+func deployRetrySettings() (int, time.Duration) {
+	retries, backoff := 3, 10*time.Second
+	if cfg := config.GetConfig(); cfg != nil {
+		retries = cfg.Firewall.DeployRetries
+		if cfg.Firewall.DeployRetryBackoffSeconds > 0 {
+			backoff = time.Duration(cfg.Firewall.DeployRetryBackoffSeconds) * time.Second
+		}
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	return retries, backoff
+}
+
+// executeDeploymentTask runs a deployment task, retrying with exponential backoff when it
+// fails because the agent went offline or could not be reached. Before each retry the agent
+// must be back online; nft syntax errors are never retried. It returns the number of attempts made
+func (s *Service) executeDeploymentTask(ctx context.Context, token string, deploymentID, agentID uuid.UUID, input *csdcore.ExecuteTaskInput) (*csdcore.TaskExecution, int, error) {
+	retries, backoff := deployRetrySettings()
+
+	var execution *csdcore.TaskExecution
+	var err error
+	attempt := 1
+	for {
+		execution, err = s.executeTaskWithProgress(ctx, token, deploymentID, input)
+		failure := ""
+		switch {
+		case err != nil:
+			failure = err.Error()
+		case execution.Status != "SUCCESS":
+			failure = execution.Error
+		default:
+			return execution, attempt, nil
+		}
+
+		if !s.shouldRetryDeployment(ctx, token, agentID, err, failure) {
+			return execution, attempt, err
+		}
+
+		// Wait for the agent to come back, counting every check it is still offline as an attempt
+		for {
+			if attempt > retries {
+				return execution, attempt, err
+			}
+			delay := backoff << (attempt - 1)
+			if delay <= 0 || delay > maxDeployRetryBackoff {
+				delay = maxDeployRetryBackoff
+			}
+			s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying,
+				fmt.Sprintf("Attempt %d/%d failed: %s; retrying in %s", attempt, retries+1, failure, delay), "")
+
+			select {
+			case <-ctx.Done():
+				return execution, attempt, err
+			case <-time.After(delay):
+			}
+			attempt++
+
+			agent, agentErr := s.client.GetAgent(ctx, token, agentID)
+			if agentErr == nil && agent != nil && agent.Status == "ONLINE" {
+				break
+			}
+			// A deleted agent or a rejected token will not recover by waiting
+			if kind := csdcore.KindOf(agentErr); agent == nil && agentErr == nil || kind == csdcore.ErrorKindNotFound || kind == csdcore.ErrorKindUnauthorized {
+				return execution, attempt, err
+			}
+			if agentErr != nil {
+				failure = "agent lookup failed: " + agentErr.Error()
+			} else {
+				failure = fmt.Sprintf("agent is %s", agent.Status)
+			}
+		}
+
+		s.repo.UpdateDeploymentStatus(deploymentID, DeploymentStatusDeploying,
+			fmt.Sprintf("Applying firewall rules (attempt %d/%d)...", attempt, retries+1), "")
+	}
+}
+
+// shouldRetryDeployment reports whether a failed deployment task is worth retrying: the failure
+// must not come from nft rejecting the ruleset, and must be a connectivity problem or the agent
+// must have gone offline. Typed csd-core errors are trusted over message matching
+func (s *Service) shouldRetryDeployment(ctx context.Context, token string, agentID uuid.UUID, taskErr error, failure string) bool {
+	if ctx.Err() != nil || isNftSyntaxError(failure) {
+		return false
+	}
+	if kind := csdcore.KindOf(taskErr); kind != "" {
+		return kind == csdcore.ErrorKindTransient
+	}
+	if classifyTaskFailure(failure) == DeploymentStatusUnknown {
+		return true
+	}
+	agent, err := s.client.GetAgent(ctx, token, agentID)
+	return err == nil && agent != nil && agent.Status != "ONLINE"
+}
+
+// isNftSyntaxError reports whether a task failure comes from nft refusing the ruleset
+func isNftSyntaxError(message string) bool {
+	lower := strings.ToLower(message)
+	for _, marker := range nftSyntaxErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withAttempts appends the attempt count to a deployment status message when the task was retried
+func withAttempts(message string, attempts int) string {
+	if attempts <= 1 {
+		return message
+	}
+	return fmt.Sprintf("%s (after %d attempts)", message, attempts)
+}
+
+// transientFailureMarkers identify failures where the agent may still have applied the change
+var transientFailureMarkers = []string{
+	"timeout", "timed out", "deadline exceeded", "context canceled",
+	"connection refused", "connection reset", "no such host", "eof", "unavailable",
+}
+
+// classifyTaskFailure maps a failed change to UNKNOWN when it was lost to a timeout or
+// connectivity problem (the agent may have applied it and should be re-audited),
+// and to ERROR when the task definitely failed
+func classifyTaskFailure(message string) DeploymentStatus {
+	lower := strings.ToLower(message)
+	for _, marker := range transientFailureMarkers {
+		if strings.Contains(lower, marker) {
+			return DeploymentStatusUnknown
+		}
+	}
+	return DeploymentStatusError
+}
+
+// taskOutputString returns a task's output as text, encoding structured output as JSON
+func taskOutputString(execution *csdcore.TaskExecution) string {
+	if execution == nil || execution.Output == nil {
+		return ""
+	}
+	if str, ok := execution.Output.(string); ok {
+		return str
+	}
+	data, err := json.Marshal(execution.Output)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// defaultNftBinaryPath is where most distributions install nft
+const defaultNftBinaryPath = "/usr/sbin/nft"
+
+// nftBinaryPathRegex accepts absolute paths without shell metacharacters
+var nftBinaryPathRegex = regexp.MustCompile(`^/[A-Za-z0-9._/-]+$`)
+
+// nftBinaryPath returns the profile's nft binary path, or the default when unset
+func nftBinaryPath(profile *FirewallProfile) string {
+	if profile.NftBinaryPath == "" {
+		return defaultNftBinaryPath
+	}
+	return profile.NftBinaryPath
+}
+
+// generateNftablesConfigForProfile generates complete nftables configuration from a profile
+func (s *Service) generateNftablesConfigForProfile(profile *FirewallProfile) string {
+	var config strings.Builder
+	// Pre-allocate reasonable capacity (reduces reallocations)
+	config.Grow(4096)
+
+	fmt.Fprintf(&config, "#!%s -f\n\n", nftBinaryPath(profile))
+	config.WriteString("# Generated by CSD-Pilote Security Module\n")
+	fmt.Fprintf(&config, "# Profile: %s\n", profile.Name)
+	fmt.Fprintf(&config, "# Generated at: %s\n\n", time.Now().Format(time.RFC3339))
+
+	family := profileFamily(profile)
+
+	// Managed mode replaces only the pilote table: declaring it first lets the
+	// delete succeed on hosts where it does not exist yet
+	managed := profile.TableMode == TableModeManaged
+	filterTable := "filter"
+	if managed {
+		filterTable = managedTableName
+		fmt.Fprintf(&config, "table %s %s\n", family, managedTableName)
+		fmt.Fprintf(&config, "delete table %s %s\n\n", family, managedTableName)
+	} else {
+		config.WriteString("flush ruleset\n\n")
+	}
+
+	// Filter table (in managed mode it also holds the NAT chains)
+	fmt.Fprintf(&config, "table %s %s {\n", family, filterTable)
+	writeIPSets(&config, profile.IPSets)
+	if managed {
+		writeCounters(&config, profile.Rules, RuleChainInput, RuleChainOutput, RuleChainForward, RuleChainPrerouting, RuleChainPostrouting)
+	} else {
+		writeCounters(&config, profile.Rules, RuleChainInput, RuleChainOutput, RuleChainForward)
+	}
+
+	// Dropped packets are logged once in a shared chain instead of inlining log + drop on every rule;
+	// it is declared ahead of the base chains that jump to it
+	logDrop := profile.LogDroppedPackets
+	if logDrop {
+		writeLogDropChain(&config, profile)
+	}
+
+	// Group rules by chain, in the profile's evaluation order
+	chainRules := make(map[RuleChain][]FirewallRule)
+	for _, rule := range orderedRules(profile) {
+		if rule.Enabled {
+			chainRules[rule.Chain] = append(chainRules[rule.Chain], rule)
+		}
+	}
+
+	// Generate filter chains
+	chains := []struct {
+		name       RuleChain
+		nftName    string
+		hookType   string
+		priority   string
+		policyFunc func() string
+	}{
+		{RuleChainInput, "input", "input", profile.InputPriority, func() string { return profile.InputPolicy }},
+		{RuleChainOutput, "output", "output", profile.OutputPriority, func() string { return profile.OutputPolicy }},
+		{RuleChainForward, "forward", "forward", profile.ForwardPriority, func() string { return profile.ForwardPolicy }},
+	}
+
+	for _, chain := range chains {
+		policy := chain.policyFunc()
+		if policy == "" {
+			policy = "drop"
+		}
+		priority := chainPriorityOrDefault(chain.priority, defaultFilterPriority)
+		fmt.Fprintf(&config, "    chain %s {\n", chain.nftName)
+		fmt.Fprintf(&config, "        type filter hook %s priority %s; policy %s;\n\n", chain.hookType, priority, policy)
+
+		// Add base rules based on profile settings
+		baseRules := baseRulesForChain(profile, chain.name)
+		for i, base := range baseRules {
+			if i == 0 || baseRules[i-1].Feature != base.Feature {
+				fmt.Fprintf(&config, "        # %s\n", base.Description)
+			}
+			fmt.Fprintf(&config, "        %s\n", base.Expr)
+			if i == len(baseRules)-1 || baseRules[i+1].Feature != base.Feature {
+				config.WriteByte('\n')
+			}
+		}
+
+		// Add user-defined rules
+		for _, rule := range chainRules[chain.name] {
+			config.WriteString("        ")
+			config.WriteString(s.filterRuleToNft(rule, logDrop))
+			config.WriteByte('\n')
+		}
+
+		if logDrop && policy == "drop" {
+			if len(chainRules[chain.name]) > 0 {
+				config.WriteByte('\n')
+			}
+			config.WriteString("        # Log packets dropped by the chain policy\n")
+			fmt.Fprintf(&config, "        goto %s\n", logDropChain)
+		}
+
+		config.WriteString("    }\n\n")
+	}
+
+	if !managed {
+		config.WriteString("}\n\n")
+	}
+
+	// NAT table (if enabled)
+	if profile.EnableNAT {
+		if !managed {
+			fmt.Fprintf(&config, "table %s nat {\n", family)
+			writeIPSets(&config, profile.IPSets)
+			writeCounters(&config, profile.Rules, RuleChainPrerouting, RuleChainPostrouting)
+		}
+
+		// Prerouting chain (for DNAT)
+		config.WriteString("    chain prerouting {\n")
+		fmt.Fprintf(&config, "        type nat hook prerouting priority %s;\n\n",
+			chainPriorityOrDefault(profile.PreroutingPriority, defaultPreroutingPriority))
+		for _, rule := range chainRules[RuleChainPrerouting] {
+			config.WriteString("        ")
+			config.WriteString(s.ruleToNft(rule))
+			config.WriteByte('\n')
+		}
+		config.WriteString("    }\n\n")
+
+		// Postrouting chain (for SNAT/MASQUERADE)
+		config.WriteString("    chain postrouting {\n")
+		fmt.Fprintf(&config, "        type nat hook postrouting priority %s;\n\n",
+			chainPriorityOrDefault(profile.PostroutingPriority, defaultPostroutingPriority))
+		for _, rule := range chainRules[RuleChainPostrouting] {
+			config.WriteString("        ")
+			config.WriteString(s.ruleToNft(rule))
+			config.WriteByte('\n')
+		}
+		config.WriteString("    }\n")
+
+		if !managed {
+			config.WriteString("}\n")
+		}
+	}
+
+	if managed {
+		config.WriteString("}\n")
+	}
+
+	return config.String()
+}
+
+// baseRulesForChain returns the rules a profile's feature flags generate ahead of user rules in a chain
+func baseRulesForChain(profile *FirewallProfile, chain RuleChain) []BaseRule {
+	var rules []BaseRule
+	add := func(feature, description, expr string) {
+		rules = append(rules, BaseRule{Chain: chain, Feature: feature, Description: description, Expr: expr})
+	}
+
+	switch chain {
+	case RuleChainInput:
+		if profile.EnableManagementAccess {
+			add("enableManagementAccess", "Allow management access", managementAccessExpr(profile))
+		}
+		if reversePathFilterEnabled(profile) {
+			add("enableReversePathFilter", "Drop spoofed packets failing the reverse-path check", reversePathFilterExpr)
+		}
+		if profile.AllowLoopback {
+			add("allowLoopback", "Allow loopback traffic", "iif lo accept")
+		}
+		if profile.AllowEstablished {
+			add("allowEstablished", "Allow established and related connections", "ct state established,related accept")
+			add("allowEstablished", "Allow established and related connections", "ct state invalid drop")
+		}
+		if profile.AllowICMPPing {
+			add("allowIcmpPing", "Allow ICMP ping", "ip protocol icmp icmp type echo-request accept")
+			if profile.EnableIPv6 {
+				add("allowIcmpPing", "Allow ICMP ping", "ip6 nexthdr icmpv6 icmpv6 type echo-request accept")
+			}
+		}
+	case RuleChainOutput:
+		if profile.AllowLoopback {
+			add("allowLoopback", "Allow loopback traffic", "oif lo accept")
+		}
+		if profile.AllowEstablished {
+			add("allowEstablished", "Allow established and related connections", "ct state established,related accept")
+		}
+	case RuleChainForward:
+		if reversePathFilterEnabled(profile) {
+			add("enableReversePathFilter", "Drop spoofed packets failing the reverse-path check", reversePathFilterExpr)
+		}
+		if profile.AllowEstablished {
+			add("allowEstablished", "Allow established and related connections", "ct state established,related accept")
+			add("allowEstablished", "Allow established and related connections", "ct state invalid drop")
+		}
+	}
+	return rules
+}
+
+// reversePathFilterExpr drops packets whose source address is not routed back through the input interface
+const reversePathFilterExpr = "fib saddr . iif oif missing drop"
+
+// fibFamilies are the table families supporting the fib expression
+var fibFamilies = map[string]bool{"ip": true, "ip6": true, "inet": true}
+
+// orderedRules returns the profile's rules in evaluation order according to its rule order strategy;
+// rules with equal keys keep their loaded order
+func orderedRules(profile *FirewallProfile) []FirewallRule {
+	rules := make([]FirewallRule, len(profile.Rules))
+	copy(rules, profile.Rules)
+
+	var less func(a, b *FirewallRule) bool
+	switch profile.RuleOrderStrategy {
+	case RuleOrderBySortOrder:
+		less = func(a, b *FirewallRule) bool { return a.ProfileSortOrder < b.ProfileSortOrder }
+	case RuleOrderByCreated:
+		less = func(a, b *FirewallRule) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	default:
+		less = func(a, b *FirewallRule) bool {
+			if a.Priority != b.Priority {
+				return a.Priority < b.Priority
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return less(&rules[i], &rules[j]) })
+	return rules
+}
+
+// profileFamily returns the table family generated for a profile (inet = IPv4+IPv6, ip = IPv4 only)
+func profileFamily(profile *FirewallProfile) string {
+	if profile.EnableIPv6 {
+		return "inet"
+	}
+	return "ip"
+}
+
+// reversePathFilterEnabled reports whether the reverse-path filter is requested and supported by the profile's family
+func reversePathFilterEnabled(profile *FirewallProfile) bool {
+	return profile.EnableReversePathFilter && fibFamilies[profileFamily(profile)]
+}
+
+// defaultManagementPort is the management access port when none is configured (SSH)
+const defaultManagementPort = "22"
+
+// managementAccessExpr returns the accept rule for the profile's management port and source
+func managementAccessExpr(profile *FirewallProfile) string {
+	port := profile.ManagementPort
+	if port == "" {
+		port = defaultManagementPort
+	}
+	if profile.ManagementSource == "" {
+		return fmt.Sprintf("tcp dport %s accept", port)
+	}
+	family := "ip"
+	if isIPv6Address(profile.ManagementSource) {
+		family = "ip6"
+	}
+	return fmt.Sprintf("%s saddr %s tcp dport %s accept", family, profile.ManagementSource, port)
+}
+
+// PreviewBaseRules returns the base rules an unsaved profile input would generate, per chain
+func (s *Service) PreviewBaseRules(input *FirewallProfileInput) []BaseRuleChain {
+	profile := newProfileFromInput(input)
+
+	chains := []RuleChain{RuleChainInput, RuleChainOutput, RuleChainForward}
+	if profile.EnableNAT {
+		chains = append(chains, RuleChainPrerouting, RuleChainPostrouting)
+	}
+
+	result := make([]BaseRuleChain, 0, len(chains))
+	for _, chain := range chains {
+		rules := baseRulesForChain(profile, chain)
+		if rules == nil {
+			rules = []BaseRule{}
+		}
+		result = append(result, BaseRuleChain{
+			Chain:  chain,
+			Policy: chainPolicy(profile, chain),
+			Rules:  rules,
+		})
+	}
+	return result
+}
+
+// defaultRulesetWarnBytes is used when no ruleset size threshold is configured
+const defaultRulesetWarnBytes = 64 * 1024
+
+// PreviewRulesetEstimate estimates the ruleset size an unsaved profile input would generate
+func (s *Service) PreviewRulesetEstimate(input *FirewallProfileInput) *RulesetEstimate {
+	return s.estimateRuleset(newProfileFromInput(input))
+}
+
+// EstimateProfileRuleset estimates the size of the ruleset generated for a saved profile
+func (s *Service) EstimateProfileRuleset(ctx context.Context, tenantID, profileID uuid.UUID) (*RulesetEstimate, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+	return s.estimateRuleset(profile), nil
+}
+
+// PreviewProfile renders the nftables config a saved profile would deploy, base rules included.
+// Nothing is recorded and no task is sent to an agent.
+func (s *Service) PreviewProfile(ctx context.Context, tenantID, profileID uuid.UUID) (*ProfilePreview, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	// Same preparation as a deployment so the preview matches what the agent receives
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	chains := []RuleChain{RuleChainInput, RuleChainOutput, RuleChainForward}
+	if profile.EnableNAT {
+		chains = append(chains, RuleChainPrerouting, RuleChainPostrouting)
+	}
+
+	preview := &ProfilePreview{
+		ProfileID:    profile.ID,
+		ProfileName:  profile.Name,
+		Config:       s.generateNftablesConfigForProfile(profile),
+		RulesByChain: make(map[RuleChain]int, len(chains)),
+	}
+	for _, chain := range chains {
+		preview.RulesByChain[chain] = len(baseRulesForChain(profile, chain))
+	}
+	for _, rule := range profile.Rules {
+		// User rules in NAT chains are only emitted when NAT is enabled
+		if _, emitted := preview.RulesByChain[rule.Chain]; rule.Enabled && emitted {
+			preview.RulesByChain[rule.Chain]++
+		}
+	}
+	for _, count := range preview.RulesByChain {
+		preview.TotalRules += count
+	}
+	return preview, nil
+}
+
+// estimateRuleset runs the generator on a profile and measures its output
+func (s *Service) estimateRuleset(profile *FirewallProfile) *RulesetEstimate {
+	nftConfig := s.generateNftablesConfigForProfile(profile)
+
+	chains := []RuleChain{RuleChainInput, RuleChainOutput, RuleChainForward}
+	if profile.EnableNAT {
+		chains = append(chains, RuleChainPrerouting, RuleChainPostrouting)
+	}
+
+	estimate := &RulesetEstimate{
+		RulesByChain:   make(map[RuleChain]int, len(chains)),
+		EstimatedBytes: len(nftConfig),
+		ThresholdBytes: defaultRulesetWarnBytes,
+	}
+	for _, chain := range chains {
+		estimate.RulesByChain[chain] = len(baseRulesForChain(profile, chain))
+	}
+	for _, rule := range profile.Rules {
+		if rule.Enabled {
+			estimate.RulesByChain[rule.Chain]++
+		}
+	}
+	for _, count := range estimate.RulesByChain {
+		estimate.TotalRules += count
+	}
+
+	for _, line := range strings.Split(nftConfig, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "}" || strings.HasPrefix(line, "#") || strings.HasSuffix(line, "{") {
+			continue
+		}
+		estimate.StatementCount++
+	}
+
+	if cfg := config.GetConfig(); cfg != nil && cfg.Limits.FirewallRulesetWarnBytes > 0 {
+		estimate.ThresholdBytes = cfg.Limits.FirewallRulesetWarnBytes
+	}
+	if estimate.EstimatedBytes > estimate.ThresholdBytes {
+		estimate.ExceedsThreshold = true
+		estimate.Warning = fmt.Sprintf("generated ruleset is %d bytes, above the %d byte threshold; consider consolidating rules with IP sets or port ranges",
+			estimate.EstimatedBytes, estimate.ThresholdBytes)
+	}
+	return estimate
+}
+
+// LintProfile reports risky patterns in the enabled rules of a profile
+func (s *Service) LintProfile(ctx context.Context, tenantID, profileID uuid.UUID) ([]LintFinding, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+
+	findings := []LintFinding{}
+	for _, rule := range profile.Rules {
+		if rule.Enabled {
+			findings = append(findings, lintRule(rule)...)
+		}
+	}
+	return findings, nil
+}
+
+// lintRule checks a single rule for risky patterns
+func lintRule(rule FirewallRule) []LintFinding {
+	var findings []LintFinding
+	if acceptsAllNewConnections(rule) {
+		findings = append(findings, LintFinding{
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Severity: LintSeverityWarning,
+			Code:     "UNRESTRICTED_NEW_ACCEPT",
+			Message:  fmt.Sprintf("rule accepts every new connection in the %s chain; add a protocol, port, address or interface match", strings.ToLower(string(rule.Chain))),
+		})
+	}
+	return findings
+}
+
+// acceptsAllNewConnections reports whether a rule accepts ct state new on an inbound chain without
+// any protocol, port, address or interface constraint. Rate-limited and raw expression rules are skipped.
+func acceptsAllNewConnections(rule FirewallRule) bool {
+	if rule.Action != RuleActionAccept || rule.RuleExpr != "" || rule.RateLimit != "" {
+		return false
+	}
+	if rule.Chain != RuleChainInput && rule.Chain != RuleChainForward {
+		return false
+	}
+	if rule.CTState == "" || !ctStateMatches(rule.CTState, string(CTStateNew)) {
+		return false
+	}
+	if rule.Protocol != "" && rule.Protocol != RuleProtocolAll {
+		return false
+	}
+	return rule.SourcePort == "" && rule.DestPort == "" &&
+		rule.SourceIP == "" && rule.DestIP == "" &&
+		rule.InInterface == "" && rule.OutInterface == ""
+}
+
+// wellKnownServices maps service names accepted in port fields to their port numbers
+var wellKnownServices = map[string]int{
+	"ftp-data": 20, "ftp": 21, "ssh": 22, "telnet": 23, "smtp": 25, "dns": 53, "domain": 53,
+	"dhcp": 67, "tftp": 69, "http": 80, "kerberos": 88, "pop3": 110, "ntp": 123, "imap": 143,
+	"snmp": 161, "ldap": 389, "https": 443, "smb": 445, "syslog": 514, "submission": 587,
+	"ldaps": 636, "imaps": 993, "pop3s": 995, "mssql": 1433, "oracle": 1521, "nfs": 2049,
+	"mysql": 3306, "rdp": 3389, "postgresql": 5432, "postgres": 5432, "vnc": 5900,
+	"redis": 6379, "kubernetes": 6443, "http-alt": 8080, "https-alt": 8443,
+	"elasticsearch": 9200, "mongodb": 27017,
+}
+
+// resolveServicePorts converts well-known service names to their port numbers. The value may be
+// a comma-separated list (optionally in braces); numeric ports and ranges are kept unchanged and
+// the result is the normalized list "80,443,8000-8080".
+func resolveServicePorts(value string) (string, error) {
+	elements := splitSetElements(value)
+	resolved := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if port, ok := wellKnownServices[strings.ToLower(element)]; ok {
+			resolved = append(resolved, strconv.Itoa(port))
+			continue
+		}
+		for _, part := range strings.Split(element, "-") {
+			if _, err := strconv.Atoi(strings.TrimSpace(part)); err != nil {
+				return "", fmt.Errorf("unknown service %q (use a port number, a range like 8000-8080, or a known service name such as ssh, http, https)", element)
+			}
+		}
+		resolved = append(resolved, strings.ReplaceAll(element, " ", ""))
+	}
+	return strings.Join(resolved, ","), nil
+}
+
+// nftPortSpec renders a port list as an nft anonymous set ("{ 80, 443 }"); a single port or
+// range stays bare
+func nftPortSpec(spec string) string {
+	elements := splitSetElements(spec)
+	if len(elements) == 1 {
+		return elements[0]
+	}
+	return "{ " + strings.Join(elements, ", ") + " }"
+}
+
+// Default chain hook priorities
+const (
+	defaultFilterPriority      = "0"
+	defaultPreroutingPriority  = "dstnat"
+	defaultPostroutingPriority = "srcnat"
+)
+
+// chainPriorityOrDefault returns the configured priority, or the default when unset
+func chainPriorityOrDefault(priority, def string) string {
+	if strings.TrimSpace(priority) == "" {
+		return def
+	}
+	return strings.TrimSpace(priority)
+}
+
+// generateNftablesConfig generates nftables configuration from rules (legacy, for dry-run)
+func (s *Service) generateNftablesConfig(rules []FirewallRule) string {
+	// Create a temporary profile with default settings
 	profile := &FirewallProfile{
 		Name:             "Dry-Run Profile",
 		InputPolicy:      "drop",
@@ -1159,496 +3435,4236 @@ func (s *Service) generateNftablesConfig(rules []FirewallRule) string {
 		EnableIPv6:       false,
 		Rules:            rules,
 	}
-	return s.generateNftablesConfigForProfile(profile)
+	return s.generateNftablesConfigForProfile(profile)
+}
+
+// ruleToNft converts a FirewallRule to nftables syntax
+func (s *Service) ruleToNft(rule FirewallRule) string {
+	return s.ruleToNftWithVerdict(rule, s.actionToNft(rule))
+}
+
+// filterRuleToNft converts a filter chain rule, sending DROP verdicts to the log_drop chain when enabled
+func (s *Service) filterRuleToNft(rule FirewallRule, logDrop bool) string {
+	if logDrop && rule.Action == RuleActionDrop {
+		return s.ruleToNftWithVerdict(rule, "goto "+logDropChain)
+	}
+	return s.ruleToNft(rule)
+}
+
+// ruleToNftWithVerdict converts a rule's matches to nftables syntax followed by the given verdict.
+// Raw expressions are used as is.
+func (s *Service) ruleToNftWithVerdict(rule FirewallRule, verdict string) string {
+	// If raw expression is provided, use it directly
+	if rule.RuleExpr != "" {
+		return fmt.Sprintf("%s # %s", rule.RuleExpr, rule.Name)
+	}
+
+	var parts []string
+
+	// Interface matching
+	if rule.InInterface != "" {
+		parts = append(parts, fmt.Sprintf("iif %s", rule.InInterface))
+	}
+	if rule.OutInterface != "" {
+		parts = append(parts, fmt.Sprintf("oif %s", rule.OutInterface))
+	}
+
+	// Connection tracking state
+	if rule.CTState != "" {
+		parts = append(parts, fmt.Sprintf("ct state %s", strings.ToLower(rule.CTState)))
+	}
+
+	// IPv6 addresses switch the address, protocol and DSCP matches to the ip6 family
+	family := ruleAddressFamily(rule)
+
+	// Protocol (ip protocol only exists for IPv4; the IPv6 next header is matched with meta l4proto)
+	if rule.Protocol != "" && rule.Protocol != RuleProtocolAll {
+		proto := strings.ToLower(string(rule.Protocol))
+		if family == "ip6" {
+			if rule.Protocol == RuleProtocolICMP || rule.Protocol == RuleProtocolICMPv6 {
+				proto = "ipv6-icmp"
+			}
+			parts = append(parts, fmt.Sprintf("meta l4proto %s%s", negationOp(rule.NegateProtocol), proto))
+		} else {
+			parts = append(parts, fmt.Sprintf("ip protocol %s%s", negationOp(rule.NegateProtocol), proto))
+		}
+	}
+
+	// Source IP
+	if rule.SourceIP != "" {
+		parts = append(parts, fmt.Sprintf("%s saddr %s", family, rule.SourceIP))
+	}
+
+	// Destination IP
+	if rule.DestIP != "" {
+		parts = append(parts, fmt.Sprintf("%s daddr %s", family, rule.DestIP))
+	}
+
+	// QoS matching
+	if rule.DSCP != "" {
+		parts = append(parts, fmt.Sprintf("%s dscp %s", family, strings.ToLower(rule.DSCP)))
+	}
+	if rule.PacketLength != "" {
+		parts = append(parts, fmt.Sprintf("meta length %s", rule.PacketLength))
+	}
+
+	// Ports (require TCP or UDP). Without an explicit transport protocol the
+	// transport header match needs an l4proto context to be valid.
+	portProto := strings.ToLower(string(rule.Protocol))
+	if portProto != "tcp" && portProto != "udp" {
+		if rule.SourcePort != "" || rule.DestPort != "" {
+			parts = append(parts, "meta l4proto { tcp, udp }")
+		}
+		portProto = "th"
+	}
+
+	// Source port
+	if rule.SourcePort != "" {
+		parts = append(parts, fmt.Sprintf("%s sport %s%s", portProto, negationOp(rule.NegateSourcePort), nftPortSpec(rule.SourcePort)))
+	}
+
+	// Destination port
+	if rule.DestPort != "" {
+		parts = append(parts, fmt.Sprintf("%s dport %s%s", portProto, negationOp(rule.NegateDestPort), nftPortSpec(rule.DestPort)))
+	}
+
+	// ICMP types, matched with icmpv6 in the ip6 family
+	if types := splitICMPTypes(rule.ICMPType); len(types) > 0 {
+		keyword := "icmp"
+		if family == "ip6" {
+			keyword = "icmpv6"
+		}
+		if len(types) == 1 {
+			parts = append(parts, fmt.Sprintf("%s type %s", keyword, types[0]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s type { %s }", keyword, strings.Join(types, ", ")))
+		}
+	}
+
+	// Connection limit
+	if rule.ConnLimitCount > 0 {
+		parts = append(parts, connLimitExpr(rule, family))
+	}
+
+	// Rate limiting
+	if rule.RateLimit != "" {
+		limitExpr := fmt.Sprintf("limit rate %s", rule.RateLimit)
+		if rule.RateBurst > 0 {
+			// Byte rates take a byte burst
+			burstUnit := "packets"
+			if strings.Contains(rule.RateLimit, "bytes") {
+				burstUnit = "bytes"
+			}
+			limitExpr += fmt.Sprintf(" burst %d %s", rule.RateBurst, burstUnit)
+		}
+		parts = append(parts, limitExpr)
+	}
+
+	// Named counter
+	if name := ruleCounterName(rule); name != "" {
+		parts = append(parts, fmt.Sprintf("counter name \"%s\"", name))
+	}
+
+	// Action
+	parts = append(parts, verdict)
+
+	// Comment
+	if rule.Comment != "" {
+		// Escape quotes in comment
+		comment := strings.ReplaceAll(rule.Comment, "\"", "\\\"")
+		parts = append(parts, fmt.Sprintf("comment \"%s\"", comment))
+	}
+
+	return fmt.Sprintf("%s # %s", joinParts(parts), rule.Name)
+}
+
+// connLimitExpr renders a rule's connection limit. Without a mask every connection the rule
+// matches counts against one limit; with a mask a meter keeps one count per source network.
+func connLimitExpr(rule FirewallRule, family string) string {
+	if rule.ConnLimitMask == 0 {
+		return fmt.Sprintf("ct count over %d", rule.ConnLimitCount)
+	}
+
+	nfproto, bits := "ipv4", 32
+	if family == "ip6" {
+		nfproto, bits = "ipv6", 128
+	}
+	key := family + " saddr"
+	if rule.ConnLimitMask < bits {
+		key += " and " + net.IP(net.CIDRMask(rule.ConnLimitMask, bits)).String()
+	}
+	return fmt.Sprintf("meta nfproto %s meter %s { %s ct count over %d }", nfproto, connLimitMeterName(rule), key, rule.ConnLimitCount)
+}
+
+// connLimitMeterName returns a meter name unique to the rule within the generated table
+func connLimitMeterName(rule FirewallRule) string {
+	return fmt.Sprintf("connlimit_%08x", crc32.ChecksumIEEE([]byte(rule.ID.String()+rule.Name)))
+}
+
+// isIPv6Address reports whether a rule address is an IPv6 address or network
+func isIPv6Address(addr string) bool {
+	if _, network, err := net.ParseCIDR(addr); err == nil {
+		return network.IP.To4() == nil
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
+
+// ruleAddressFamily returns the nftables family of a rule's matches: "ip6" when it carries
+// IPv6 addresses or matches ICMPv6, "ip" otherwise (IP sets hold IPv4 addresses)
+func ruleAddressFamily(rule FirewallRule) string {
+	if isIPv6Address(rule.SourceIP) || isIPv6Address(rule.DestIP) || rule.Protocol == RuleProtocolICMPv6 {
+		return "ip6"
+	}
+	return "ip"
+}
+
+// negationOp returns the nftables inequality operator for a negated match
+func negationOp(negate bool) string {
+	if negate {
+		return "!= "
+	}
+	return ""
+}
+
+// actionToNft converts a rule action to nftables syntax
+func (s *Service) actionToNft(rule FirewallRule) string {
+	switch rule.Action {
+	case RuleActionAccept:
+		return "accept"
+	case RuleActionDrop:
+		return "drop"
+	case RuleActionReject:
+		switch {
+		case rule.RejectWith == "tcp":
+			return "reject with tcp " + rule.RejectType
+		case rule.RejectWith != "":
+			return fmt.Sprintf("reject with %s type %s", rule.RejectWith, rule.RejectType)
+		}
+		return "reject"
+	case RuleActionLog:
+		logExpr := "log"
+		if rule.LogPrefix != "" {
+			logExpr += fmt.Sprintf(" prefix \"%s\"", rule.LogPrefix)
+		}
+		if rule.LogLevel != "" {
+			logExpr += fmt.Sprintf(" level %s", rule.LogLevel)
+		}
+		return logExpr
+	case RuleActionMasquerade:
+		return "masquerade"
+	case RuleActionSnat:
+		if rule.NatToAddr != "" {
+			return fmt.Sprintf("snat to %s", rule.NatToAddr)
+		}
+		return "snat"
+	case RuleActionDnat:
+		target := ""
+		if rule.NatToAddr != "" {
+			target = rule.NatToAddr
+			if rule.NatToPort != "" {
+				target += ":" + natPortSpec(rule.NatToPort)
+			}
+			return fmt.Sprintf("dnat to %s", target)
+		}
+		return "dnat"
+	case RuleActionRedirect:
+		// A range spreads the redirected connections over its ports
+		if rule.NatToPort != "" {
+			return "redirect to :" + natPortSpec(rule.NatToPort)
+		}
+		return "redirect"
+	default:
+		return "accept"
+	}
+}
+
+// natPortSpec renders a NAT target port or port range; a range of one port is a single port
+func natPortSpec(port string) string {
+	port = strings.TrimSpace(port)
+	if low, high, ok := strings.Cut(port, "-"); ok {
+		low, high = strings.TrimSpace(low), strings.TrimSpace(high)
+		if low == high {
+			return low
+		}
+		return low + "-" + high
+	}
+	return port
+}
+
+func joinParts(parts []string) string {
+	return strings.Join(parts, " ")
+}
+
+// RollbackDeployment rolls back a deployment using nftables_rollback playbook
+func (s *Service) RollbackDeployment(ctx context.Context, token string, tenantID, userID, deploymentID uuid.UUID) (*FirewallDeployment, error) {
+	originalDeployment, err := s.repo.GetDeploymentByID(tenantID, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("deployment not found: %w", err)
+	}
+
+	// Create rollback deployment record
+	rollback := &FirewallDeployment{
+		TenantID:      tenantID,
+		ProfileID:     originalDeployment.ProfileID,
+		AgentID:       originalDeployment.AgentID,
+		AgentName:     originalDeployment.AgentName,
+		Action:        DeploymentActionRollback,
+		Status:        DeploymentStatusPending,
+		CreatedBy:     userID,
+	}
+
+	if err := s.repo.CreateDeployment(rollback); err != nil {
+		return nil, fmt.Errorf("failed to create rollback record: %w", err)
+	}
+
+	// Execute rollback asynchronously
+	go s.runRollback(rollback.ID, tenantID, token, originalDeployment.AgentID)
+
+	return rollback, nil
+}
+
+// PreviewRollback shows what rolling back a deployment would restore without touching the agent's rules.
+// The target is the backup of the agent's previous applied deployment; it is diffed against the live ruleset.
+func (s *Service) PreviewRollback(ctx context.Context, token string, tenantID, userID, deploymentID uuid.UUID) (*RollbackPreview, error) {
+	original, err := s.repo.GetDeploymentByID(tenantID, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("deployment not found: %w", err)
+	}
+
+	target, err := s.repo.GetPreviousBackupForAgent(tenantID, original.AgentID, original.CreatedAt)
+	if err != nil {
+		return nil, validation.NewValidationError("no earlier backup found for this agent; the rollback target cannot be previewed")
+	}
+
+	content, err := s.GetBackupContent(ctx, token, tenantID, original.AgentID, target.BackupKey)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &RollbackPreview{
+		DeploymentID:       original.ID,
+		AgentID:            original.AgentID,
+		AgentName:          original.AgentName,
+		TargetDeploymentID: target.ID,
+		BackupKey:          target.BackupKey,
+		ProfileID:          content.ProfileID,
+		ProfileName:        content.ProfileName,
+		Config:             content.Config,
+		Diff:               []ComplianceDeviation{},
+	}
+
+	agent := csdcore.Agent{ID: original.AgentID, Name: original.AgentName}
+	audited, err := s.captureLiveRuleset(tenantID, userID, token, agent)
+	if audited != nil {
+		preview.AuditID = &audited.ID
+	}
+	if err != nil {
+		preview.Message = "live ruleset unavailable, diff skipped: " + err.Error()
+	} else {
+		preview.Diff, _ = diffStatements(rulesetStatements(content.Config), rulesetStatements(audited.Output))
+		preview.Message = fmt.Sprintf("rollback would change %d statement(s)", len(preview.Diff))
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rollback.previewed",
+		ResourceType: "firewall_deployment",
+		ResourceID:   original.ID.String(),
+		Details: map[string]interface{}{
+			"agentId":   original.AgentID.String(),
+			"backupKey": target.BackupKey,
+			"changes":   len(preview.Diff),
+		},
+	})
+
+	return preview, nil
+}
+
+// runRollback executes the rollback in background
+func (s *Service) runRollback(rollbackID, tenantID uuid.UUID, token string, agentID uuid.UUID) {
+	// Use timeout to prevent goroutine leaks (2 minutes max for rollback)
+	ctx, cancel := s.startOperation(rollbackID, 2*time.Minute)
+	defer cancel()
+
+	s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusDeploying, "Rolling back firewall rules...", "")
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRollbackStarted,
+		tenantID,
+		rollbackID.String(),
+		map[string]interface{}{"agentId": agentID.String()},
+	))
+
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-rollback",
+			Config: map[string]interface{}{
+				"action": "rollback",
+			},
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+	if err != nil {
+		s.repo.UpdateDeploymentStatus(rollbackID, classifyTaskFailure(err.Error()), "Failed to execute rollback: "+err.Error(), "")
+		return
+	}
+
+	output := ""
+	if execution.Output != nil {
+		if s, ok := execution.Output.(string); ok {
+			output = s
+		}
+	}
+
+	if execution.Status != "SUCCESS" {
+		s.repo.UpdateDeploymentStatus(rollbackID, classifyTaskFailure(execution.Error), "Rollback failed: "+execution.Error, output)
+		return
+	}
+
+	s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusRolledBack, "Firewall rules rolled back successfully", output)
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRollbackCompleted,
+		tenantID,
+		rollbackID.String(),
+		map[string]interface{}{"agentId": agentID.String()},
+	))
+}
+
+// AuditDeployment audits the current firewall state on an agent
+func (s *Service) AuditDeployment(ctx context.Context, token string, tenantID, userID uuid.UUID, agentID uuid.UUID) (*FirewallDeployment, error) {
+	// Get agent name
+	agentName := "Unknown"
+	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
+		agentName = agent.Name
+	}
+
+	audit := &FirewallDeployment{
+		TenantID:  tenantID,
+		AgentID:   agentID,
+		AgentName: agentName,
+		Action:    DeploymentActionAudit,
+		Status:    DeploymentStatusPending,
+		CreatedBy: userID,
+	}
+
+	if err := s.repo.CreateDeployment(audit); err != nil {
+		return nil, fmt.Errorf("failed to create audit record: %w", err)
+	}
+
+	// Execute audit asynchronously
+	go s.runAudit(audit.ID, tenantID, token, agentID)
+
+	return audit, nil
+}
+
+// runAudit executes the audit in background
+func (s *Service) runAudit(auditID, tenantID uuid.UUID, token string, agentID uuid.UUID) {
+	// Use timeout to prevent goroutine leaks (2 minutes max for audit)
+	ctx, cancel := s.startOperation(auditID, 2*time.Minute)
+	defer cancel()
+
+	s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusDeploying, "Auditing firewall rules...", "")
+
+	execution, err := s.executeAuditTask(ctx, token, agentID)
+	if err != nil {
+		s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusError, "Failed to execute audit: "+err.Error(), "")
+		return
+	}
+
+	output := auditOutput(execution)
+
+	if execution.Status != "SUCCESS" {
+		s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusError, "Audit failed: "+execution.Error, output)
+		return
+	}
+
+	s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusApplied, "Audit completed successfully", output)
+}
+
+// executeAuditTask runs the nftables audit task on an agent and waits for it
+func (s *Service) executeAuditTask(ctx context.Context, token string, agentID uuid.UUID) (*csdcore.TaskExecution, error) {
+	return s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-audit",
+			Config: map[string]interface{}{
+				"action": "audit",
+			},
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+}
+
+// auditOutput returns the ruleset listing of an audit task
+func auditOutput(execution *csdcore.TaskExecution) string {
+	if output, ok := execution.Output.(string); ok {
+		return output
+	}
+	return ""
+}
+
+// readLiveRuleset runs an audit task on an agent and returns its live ruleset
+// without recording an AUDIT deployment
+func (s *Service) readLiveRuleset(ctx context.Context, token string, agentID uuid.UUID) (string, error) {
+	execution, err := s.executeAuditTask(ctx, token, agentID)
+	if err != nil {
+		return "", err
+	}
+	if execution.Status != "SUCCESS" {
+		return "", fmt.Errorf("audit failed: %s", execution.Error)
+	}
+	return auditOutput(execution), nil
+}
+
+// namedCounterRegex matches a counter block in "nft list counters" output
+var namedCounterRegex = regexp.MustCompile(`(?s)table (\w+) (\S+) \{|counter (\S+) \{\s*packets (\d+) bytes (\d+)`)
+
+// SyncCounters reads the named counters of an agent and links them to the rules that increment them
+func (s *Service) SyncCounters(ctx context.Context, token string, tenantID, agentID uuid.UUID) ([]NamedCounter, error) {
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent unavailable: %w", err)
+	}
+
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-list-counters",
+			Config: map[string]interface{}{
+				"action": "list_counters",
+			},
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read counters: %w", err)
+	}
+	if execution.Status != "SUCCESS" {
+		return nil, fmt.Errorf("failed to read counters: %s", execution.Error)
+	}
+
+	counters := parseNamedCounters(taskOutputString(execution))
+
+	rules, err := s.repo.GetRulesWithCounters(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	for i := range counters {
+		counters[i].Rules = []string{}
+		for _, rule := range rules {
+			if rule.CounterName == counters[i].Name {
+				counters[i].Rules = append(counters[i].Rules, rule.Name)
+				counters[i].RuleIDs = append(counters[i].RuleIDs, rule.ID)
+			}
+		}
+	}
+	return counters, nil
+}
+
+// GetRuleCounters reads the per-rule hit counters of the rules last applied to an agent.
+// The agent dumps its ruleset as JSON ("nft -j list ruleset") and each counter is matched to its rule
+// by name; rules deployed without the counter flag are returned with nil counts.
+func (s *Service) GetRuleCounters(ctx context.Context, token string, tenantID, agentID uuid.UUID) ([]RuleCounter, error) {
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent unavailable: %w", err)
+	}
+
+	deployment, err := s.repo.GetLatestApplyForAgent(tenantID, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("no deployment found for agent: %w", err)
+	}
+	var rules []FirewallRule
+	if deployment.RulesSnapshot != "" {
+		if err := json.Unmarshal([]byte(deployment.RulesSnapshot), &rules); err != nil {
+			return nil, fmt.Errorf("failed to decode rules snapshot: %w", err)
+		}
+	}
+
+	profile := &FirewallProfile{}
+	if deployment.ProfileID != nil {
+		if deployed, err := s.repo.GetProfileByID(tenantID, *deployment.ProfileID); err == nil {
+			profile = deployed
+		}
+	}
+
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-list-ruleset-json",
+			Config: map[string]interface{}{
+				"action":       "list_ruleset",
+				"format":       "json",
+				"nft_binary":   nftBinaryPath(profile),
+				"list_command": nftBinaryPath(profile) + " -j list ruleset",
+			},
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ruleset: %w", err)
+	}
+	if execution.Status != "SUCCESS" {
+		return nil, fmt.Errorf("failed to read ruleset: %s", execution.Error)
+	}
+	live, err := parseJSONRulesetCounters(taskOutputString(execution))
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]RuleCounter, 0, len(rules))
+	for _, rule := range rules {
+		counter := RuleCounter{RuleID: rule.ID, RuleName: rule.Name, Chain: rule.Chain}
+		if rule.Counter && rule.CounterName == "" {
+			if value, ok := live[ruleCounterName(rule)]; ok {
+				counter.Packets = &value.Packets
+				counter.Bytes = &value.Bytes
+			}
+		}
+		counters = append(counters, counter)
+	}
+	return counters, nil
+}
+
+// jsonRuleset is the subset of "nft -j list ruleset" output holding named counter objects
+type jsonRuleset struct {
+	Nftables []struct {
+		Counter *struct {
+			Name    string `json:"name"`
+			Packets int64  `json:"packets"`
+			Bytes   int64  `json:"bytes"`
+		} `json:"counter"`
+	} `json:"nftables"`
+}
+
+// parseJSONRulesetCounters returns the named counters of a JSON ruleset dump keyed by name
+func parseJSONRulesetCounters(output string) (map[string]NamedCounter, error) {
+	var ruleset jsonRuleset
+	if err := json.Unmarshal([]byte(output), &ruleset); err != nil {
+		return nil, fmt.Errorf("failed to parse ruleset: %w", err)
+	}
+	counters := make(map[string]NamedCounter)
+	for _, object := range ruleset.Nftables {
+		if object.Counter != nil {
+			counters[object.Counter.Name] = NamedCounter{
+				Name:    object.Counter.Name,
+				Packets: object.Counter.Packets,
+				Bytes:   object.Counter.Bytes,
+			}
+		}
+	}
+	return counters, nil
+}
+
+// counterSnapshotCheckInterval is how often the counter-sync job checks whether a snapshot is due
+const counterSnapshotCheckInterval = time.Minute
+
+// maxCounterHistoryPoints caps the snapshots returned by a counter history query
+const maxCounterHistoryPoints = 10000
+
+// runCounterSnapshotJob periodically snapshots the named counters of every agent running an applied profile.
+// The interval is read from the configuration on each check, so the job idles until the config is loaded.
+func (s *Service) runCounterSnapshotJob() {
+	defer logger.RecoverPanic("[Security] Counter snapshot job")
+
+	ticker := time.NewTicker(counterSnapshotCheckInterval)
+	defer ticker.Stop()
+
+	var lastRun time.Time
+	for range ticker.C {
+		cfg := config.GetConfig()
+		if cfg == nil || cfg.Limits.FirewallCounterSnapshotMinutes <= 0 {
+			continue
+		}
+		if time.Since(lastRun) < time.Duration(cfg.Limits.FirewallCounterSnapshotMinutes)*time.Minute {
+			continue
+		}
+		lastRun = time.Now()
+		s.snapshotAndPruneCounters(cfg)
+	}
+}
+
+// snapshotAndPruneCounters runs one pass of the counter snapshot job; a panic is logged and
+// the job carries on at the next tick
+func (s *Service) snapshotAndPruneCounters(cfg *config.Config) {
+	defer logger.RecoverPanic("[Security] Counter snapshot pass")
+
+	s.SnapshotAllCounters(context.Background(), cfg.CSDCore.ServiceToken)
+
+	if cfg.Limits.FirewallCounterRetentionDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -cfg.Limits.FirewallCounterRetentionDays)
+		if _, err := s.repo.DeleteCounterSnapshotsBefore(cutoff); err != nil {
+			logger.Error("[Security] Failed to prune counter snapshots: %s", err.Error())
+		}
+	}
+}
+
+// SnapshotAllCounters stores a counter snapshot for every agent running an applied profile.
+// Unreachable agents are logged and skipped so one failure does not stop the sync.
+func (s *Service) SnapshotAllCounters(ctx context.Context, token string) {
+	targets, err := s.repo.ListCounterSnapshotTargets()
+	if err != nil {
+		logger.Error("[Security] Failed to list counter snapshot targets: %s", err.Error())
+		return
+	}
+
+	for _, target := range targets {
+		if _, err := s.SnapshotCounters(ctx, token, target.TenantID, target.AgentID); err != nil {
+			logger.Warn("[Security] Counter snapshot skipped for agent %s: %s", target.AgentID, err.Error())
+		}
+	}
+}
+
+// SnapshotCounters reads the named counters of an agent and stores one snapshot per rule incrementing them
+func (s *Service) SnapshotCounters(ctx context.Context, token string, tenantID, agentID uuid.UUID) (int, error) {
+	counters, err := s.SyncCounters(ctx, token, tenantID, agentID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	snapshots := []FirewallCounterSnapshot{}
+	for _, counter := range counters {
+		for _, ruleID := range counter.RuleIDs {
+			snapshots = append(snapshots, FirewallCounterSnapshot{
+				TenantID:    tenantID,
+				RuleID:      ruleID,
+				AgentID:     agentID,
+				CounterName: counter.Name,
+				Packets:     counter.Packets,
+				Bytes:       counter.Bytes,
+				Timestamp:   now,
+			})
+		}
+	}
+
+	if err := s.repo.CreateCounterSnapshots(snapshots); err != nil {
+		return 0, fmt.Errorf("failed to store counter snapshots: %w", err)
+	}
+	return len(snapshots), nil
+}
+
+// GetRuleCounterHistory returns the counter snapshots of a rule over a period, optionally for one agent
+func (s *Service) GetRuleCounterHistory(ctx context.Context, tenantID, ruleID uuid.UUID, agentID *uuid.UUID, from, to time.Time) ([]FirewallCounterSnapshot, error) {
+	if _, err := s.repo.GetRuleByID(tenantID, ruleID); err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, validation.NewValidationError("to must be after from")
+	}
+	return s.repo.GetRuleCounterHistory(tenantID, ruleID, agentID, from, to, maxCounterHistoryPoints)
+}
+
+// parseNamedCounters extracts named counters from "nft list counters" output
+func parseNamedCounters(output string) []NamedCounter {
+	counters := []NamedCounter{}
+	family, table := "", ""
+	for _, m := range namedCounterRegex.FindAllStringSubmatch(output, -1) {
+		if m[1] != "" {
+			family, table = m[1], m[2]
+			continue
+		}
+		packets, _ := strconv.ParseInt(m[4], 10, 64)
+		bytes, _ := strconv.ParseInt(m[5], 10, 64)
+		counters = append(counters, NamedCounter{Name: m[3], Family: family, Table: table, Packets: packets, Bytes: bytes})
+	}
+	return counters
+}
+
+// fleetAuditConcurrency bounds how many agents are audited at the same time
+const fleetAuditConcurrency = 5
+
+// AuditAllAgents audits every online nftables-capable agent with bounded concurrency.
+// Each audit is stored as an AUDIT deployment; the summary classifies every agent.
+func (s *Service) AuditAllAgents(ctx context.Context, token string, tenantID, userID uuid.UUID) (*FleetAuditSummary, error) {
+	agents, err := s.client.ListAgentsByCapability(ctx, token, "nftables")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	var online []csdcore.Agent
+	for _, agent := range agents {
+		if agent.Status == "ONLINE" {
+			online = append(online, agent)
+		}
+	}
+
+	results := make([]AgentAuditResult, len(online))
+	sem := make(chan struct{}, fleetAuditConcurrency)
+	var wg sync.WaitGroup
+	for i, agent := range online {
+		// Agents with enforcement paused are left alone
+		if pause := s.activeEnforcementPause(tenantID, agent.ID); pause != nil {
+			results[i] = AgentAuditResult{AgentID: agent.ID, AgentName: agent.Name, Status: AgentAuditPaused, Message: pauseMessage(pause)}
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, agent csdcore.Agent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.auditAgent(tenantID, userID, token, agent)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	summary := &FleetAuditSummary{Total: len(results), Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case AgentAuditInSync:
+			summary.InSync++
+		case AgentAuditDrifted:
+			summary.Drifted++
+		case AgentAuditPaused:
+			summary.Paused++
+		default:
+			summary.Failed++
+		}
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.fleet.audited",
+		ResourceType: "firewall_deployment",
+		Details: map[string]interface{}{
+			"total":   summary.Total,
+			"inSync":  summary.InSync,
+			"drifted": summary.Drifted,
+			"failed":  summary.Failed,
+			"paused":  summary.Paused,
+		},
+	})
+
+	return summary, nil
+}
+
+// auditAgent runs an audit on one agent and classifies its ruleset.
+// An agent has drifted when its last deployment did not apply or the managed
+// filter table is missing from its live ruleset.
+func (s *Service) auditAgent(tenantID, userID uuid.UUID, token string, agent csdcore.Agent) AgentAuditResult {
+	result := AgentAuditResult{AgentID: agent.ID, AgentName: agent.Name}
+
+	audited, err := s.captureLiveRuleset(tenantID, userID, token, agent)
+	if audited != nil {
+		result.AuditID = &audited.ID
+	}
+	if err != nil {
+		result.Status = AgentAuditFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	lastApply, err := s.repo.GetLatestApplyForAgent(tenantID, agent.ID)
+	switch {
+	case err != nil:
+		result.Status = AgentAuditDrifted
+		result.Message = "no profile has been deployed to this agent"
+	case lastApply.Status != DeploymentStatusApplied:
+		result.Status = AgentAuditDrifted
+		result.Message = fmt.Sprintf("last deployment is %s: %s", lastApply.Status, lastApply.StatusMessage)
+	case !hasManagedFilterTable(audited.Output):
+		result.Status = AgentAuditDrifted
+		result.Message = "managed filter table is missing from the live ruleset"
+	default:
+		result.Status = AgentAuditInSync
+		result.Message = "ruleset matches the last deployment"
+	}
+	return result
+}
+
+// GetAgentStatus returns the latest deployment of an agent, whether the rules it applied still
+// match its profile, and when the agent was last audited
+func (s *Service) GetAgentStatus(ctx context.Context, tenantID, agentID uuid.UUID) (*AgentSecurityStatus, error) {
+	status := &AgentSecurityStatus{AgentID: agentID}
+
+	latest, err := s.repo.GetLatestDeploymentForAgent(tenantID, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest deployment: %w", err)
+	}
+	if latest != nil {
+		status.LatestDeployment = latest
+		status.Status = latest.Status
+		if latest.Profile != nil {
+			status.ProfileName = latest.Profile.Name
+		}
+
+		// Only a successful APPLY leaves the profile's rules on the agent
+		if latest.Action == DeploymentActionApply && latest.Status == DeploymentStatusApplied && latest.ProfileID != nil {
+			status.InSync = s.deploymentMatchesProfile(tenantID, latest)
+		}
+	}
+
+	audit, err := s.repo.GetLatestAuditForAgent(tenantID, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest audit: %w", err)
+	}
+	if audit != nil {
+		lastAuditAt := audit.CreatedAt
+		if audit.CompletedAt != nil {
+			lastAuditAt = *audit.CompletedAt
+		}
+		status.LastAuditAt = &lastAuditAt
+	}
+
+	return status, nil
+}
+
+// deploymentMatchesProfile reports whether a deployment would still be generated identically from
+// its profile. Deployments recorded before config hashes were stored fall back to comparing rules.
+func (s *Service) deploymentMatchesProfile(tenantID uuid.UUID, deployment *FirewallDeployment) bool {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, *deployment.ProfileID)
+	if err != nil {
+		return false
+	}
+	if deployment.ConfigHash == "" {
+		return rulesFingerprint(deployment.SnapshotRules) == rulesFingerprint(profile.Rules)
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return false
+	}
+	return nftConfigHash(s.generateNftablesConfigForProfile(profile)) == deployment.ConfigHash
+}
+
+// GetDeploymentsByConfigHash returns the latest deployment of every agent currently running the
+// nftables config with the given fingerprint
+func (s *Service) GetDeploymentsByConfigHash(ctx context.Context, tenantID uuid.UUID, hash string) ([]FirewallDeployment, error) {
+	return s.repo.GetDeploymentsByConfigHash(tenantID, hash)
+}
+
+// nftConfigHash returns the SHA-256 fingerprint of a generated nftables config. The generation
+// timestamp is left out so the same profile always yields the same fingerprint.
+func nftConfigHash(config string) string {
+	lines := strings.Split(config, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "# Generated at:") {
+			kept = append(kept, line)
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(kept, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// rulesFingerprint hashes rules independently of their load order and bookkeeping timestamps,
+// so a deployment snapshot and the live profile match when their rules are the same
+func rulesFingerprint(rules []FirewallRule) string {
+	normalized := make([]FirewallRule, len(rules))
+	copy(normalized, rules)
+	for i := range normalized {
+		normalized[i].CreatedAt = time.Time{}
+		normalized[i].UpdatedAt = time.Time{}
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].ID.String() < normalized[j].ID.String() })
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// captureLiveRuleset runs a synchronous audit on an agent and returns the completed AUDIT
+// deployment holding its live ruleset. The record is returned with the error when it exists.
+func (s *Service) captureLiveRuleset(tenantID, userID uuid.UUID, token string, agent csdcore.Agent) (*FirewallDeployment, error) {
+	audit := &FirewallDeployment{
+		TenantID:  tenantID,
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+		Action:    DeploymentActionAudit,
+		Status:    DeploymentStatusPending,
+		CreatedBy: userID,
+	}
+	if err := s.repo.CreateDeployment(audit); err != nil {
+		return nil, fmt.Errorf("failed to create audit record: %w", err)
+	}
+
+	s.runAudit(audit.ID, tenantID, token, agent.ID)
+
+	audited, err := s.repo.GetDeploymentByID(tenantID, audit.ID)
+	if err != nil {
+		return audit, fmt.Errorf("failed to read audit result: %w", err)
+	}
+	if audited.Status != DeploymentStatusApplied {
+		return audited, errors.New(audited.StatusMessage)
+	}
+	return audited, nil
+}
+
+// ComplianceReport audits each agent and compares its live ruleset with the configuration
+// generated from a baseline profile, scoring every agent by the share of matching statements
+func (s *Service) ComplianceReport(ctx context.Context, token string, tenantID, userID, baselineProfileID uuid.UUID, agentIDs []uuid.UUID) (*ComplianceReport, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, baselineProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("baseline profile not found: %w", err)
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+	baseline := rulesetStatements(s.generateNftablesConfigForProfile(profile))
+
+	agents, err := s.client.ListAgentsByCapability(ctx, token, "nftables")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	agentsByID := make(map[uuid.UUID]csdcore.Agent, len(agents))
+	for _, agent := range agents {
+		agentsByID[agent.ID] = agent
+	}
+
+	results := make([]AgentComplianceResult, len(agentIDs))
+	sem := make(chan struct{}, fleetAuditConcurrency)
+	var wg sync.WaitGroup
+	for i, agentID := range agentIDs {
+		agent, ok := agentsByID[agentID]
+		if !ok {
+			results[i] = AgentComplianceResult{AgentID: agentID, Status: ComplianceFailed, Message: "agent not found or does not support nftables"}
+			continue
+		}
+		if agent.Status != "ONLINE" {
+			results[i] = AgentComplianceResult{AgentID: agentID, AgentName: agent.Name, Status: ComplianceFailed, Message: "agent is " + agent.Status}
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, agent csdcore.Agent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.checkAgentCompliance(tenantID, userID, token, agent, baseline)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	report := &ComplianceReport{
+		BaselineProfileID:   profile.ID,
+		BaselineProfileName: profile.Name,
+		GeneratedAt:         time.Now(),
+		Results:             results,
+	}
+	var scoreTotal float64
+	for _, result := range results {
+		switch result.Status {
+		case ComplianceCompliant:
+			report.Compliant++
+		case ComplianceNonCompliant:
+			report.NonCompliant++
+		default:
+			report.Failed++
+			continue
+		}
+		scoreTotal += result.Score
+	}
+	if audited := report.Compliant + report.NonCompliant; audited > 0 {
+		report.AverageScore = math.Round(scoreTotal/float64(audited)*10) / 10
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.compliance.reported",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
+		Details: map[string]interface{}{
+			"name":         profile.Name,
+			"agents":       len(agentIDs),
+			"compliant":    report.Compliant,
+			"nonCompliant": report.NonCompliant,
+			"failed":       report.Failed,
+			"averageScore": report.AverageScore,
+		},
+	})
+
+	return report, nil
+}
+
+// checkAgentCompliance audits one agent and diffs its live ruleset against the baseline statements
+func (s *Service) checkAgentCompliance(tenantID, userID uuid.UUID, token string, agent csdcore.Agent, baseline []string) AgentComplianceResult {
+	result := AgentComplianceResult{AgentID: agent.ID, AgentName: agent.Name, Deviations: []ComplianceDeviation{}}
+
+	audited, err := s.captureLiveRuleset(tenantID, userID, token, agent)
+	if audited != nil {
+		result.AuditID = &audited.ID
+	}
+	if err != nil {
+		result.Status = ComplianceFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	deviations, matched := diffStatements(baseline, rulesetStatements(audited.Output))
+	result.Deviations = deviations
+	unexpected := len(deviations) - (len(baseline) - matched)
+
+	result.Score = 100
+	if total := len(baseline) + unexpected; total > 0 {
+		result.Score = math.Round(float64(matched)/float64(total)*1000) / 10
+	}
+	if len(result.Deviations) == 0 {
+		result.Status = ComplianceCompliant
+		result.Message = "live ruleset matches the baseline"
+	} else {
+		result.Status = ComplianceNonCompliant
+		result.Message = fmt.Sprintf("%d statement(s) deviate from the baseline", len(result.Deviations))
+	}
+	return result
+}
+
+// DiffDeployment compares the config a profile generates with the live ruleset of an agent,
+// read through the audit task, and returns an ordered line diff of the normalized statements
+func (s *Service) DiffDeployment(ctx context.Context, token string, tenantID, profileID, agentID uuid.UUID) (*ProfileDiff, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent unavailable: %w", err)
+	}
+
+	live, err := s.readLiveRuleset(ctx, token, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read live ruleset: %w", err)
+	}
+
+	diff := &ProfileDiff{
+		ProfileID:   profile.ID,
+		ProfileName: profile.Name,
+		AgentID:     agentID,
+		Lines:       diffLines(rulesetStatements(s.generateNftablesConfigForProfile(profile)), rulesetStatements(live)),
+	}
+	for _, line := range diff.Lines {
+		switch line.Type {
+		case DiffLineAdded:
+			diff.Added++
+		case DiffLineRemoved:
+			diff.Removed++
+		default:
+			diff.Unchanged++
+		}
+	}
+	diff.InSync = diff.Added == 0 && diff.Removed == 0
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.profile.diffed",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
+		Details: map[string]interface{}{
+			"agentId": agentID.String(),
+			"inSync":  diff.InSync,
+			"added":   diff.Added,
+			"removed": diff.Removed,
+		},
+	})
+
+	return diff, nil
+}
+
+// maxDiffCells bounds the LCS table of diffLines; larger inputs fall back to an unordered diff
+const maxDiffCells = 4000000
+
+// diffLines computes an ordered diff from expected to actual lines using their longest common
+// subsequence. Common leading and trailing lines are matched first to keep the table small.
+func diffLines(expected, actual []string) []ProfileDiffLine {
+	lines := []ProfileDiffLine{}
+	prefix := 0
+	for prefix < len(expected) && prefix < len(actual) && expected[prefix] == actual[prefix] {
+		lines = append(lines, ProfileDiffLine{Type: DiffLineUnchanged, Line: expected[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(expected)-prefix && suffix < len(actual)-prefix &&
+		expected[len(expected)-1-suffix] == actual[len(actual)-1-suffix] {
+		suffix++
+	}
+	a, b := expected[prefix:len(expected)-suffix], actual[prefix:len(actual)-suffix]
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		deviations, _ := diffStatements(a, b)
+		changed := make(map[string]bool, len(deviations))
+		for _, deviation := range deviations {
+			changed[deviation.Statement] = true
+		}
+		for _, line := range a {
+			if changed[line] {
+				lines = append(lines, ProfileDiffLine{Type: DiffLineRemoved, Line: line})
+			} else {
+				lines = append(lines, ProfileDiffLine{Type: DiffLineUnchanged, Line: line})
+			}
+		}
+		for _, line := range b {
+			if changed[line] {
+				lines = append(lines, ProfileDiffLine{Type: DiffLineAdded, Line: line})
+			}
+		}
+	} else {
+		// lcs[i][j] is the LCS length of a[i:] and b[j:]
+		lcs := make([][]int32, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				lines = append(lines, ProfileDiffLine{Type: DiffLineUnchanged, Line: a[i]})
+				i++
+				j++
+			case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+				lines = append(lines, ProfileDiffLine{Type: DiffLineRemoved, Line: a[i]})
+				i++
+			default:
+				lines = append(lines, ProfileDiffLine{Type: DiffLineAdded, Line: b[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range expected[len(expected)-suffix:] {
+		lines = append(lines, ProfileDiffLine{Type: DiffLineUnchanged, Line: line})
+	}
+	return lines
+}
+
+// diffStatements compares expected statements with actual ones. It returns the expected statements
+// absent from actual (MISSING), the actual statements not expected (UNEXPECTED) and the number matched.
+func diffStatements(expected, actual []string) ([]ComplianceDeviation, int) {
+	present := make(map[string]bool, len(actual))
+	for _, statement := range actual {
+		present[statement] = true
+	}
+	wanted := make(map[string]bool, len(expected))
+	deviations := []ComplianceDeviation{}
+	matched := 0
+	for _, statement := range expected {
+		wanted[statement] = true
+		if present[statement] {
+			matched++
+		} else {
+			deviations = append(deviations, ComplianceDeviation{Type: DeviationMissing, Statement: statement})
+		}
+	}
+	for _, statement := range actual {
+		if !wanted[statement] {
+			deviations = append(deviations, ComplianceDeviation{Type: DeviationUnexpected, Statement: statement})
+		}
+	}
+	return deviations, matched
+}
+
+// rulesetStatements extracts the distinct statements of an nftables config or listing in order,
+// ignoring comments, block delimiters and whitespace differences
+func rulesetStatements(config string) []string {
+	seen := make(map[string]bool)
+	var statements []string
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		// Drop the trailing "# rule name" marker added by ruleToNft
+		if i := strings.LastIndex(line, " # "); i >= 0 && !strings.Contains(line[i:], "\"") {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || line == "}" || line == "flush ruleset" || strings.HasPrefix(line, "#") || strings.HasSuffix(line, "{") ||
+			strings.HasPrefix(line, "table ") || strings.HasPrefix(line, "delete table ") {
+			continue
+		}
+		line = strings.Join(strings.Fields(line), " ")
+		if !seen[line] {
+			seen[line] = true
+			statements = append(statements, line)
+		}
+	}
+	return statements
+}
+
+// hasManagedFilterTable reports whether an audit output lists the generated filter table
+// (the pilote table in managed mode, the filter table otherwise)
+func hasManagedFilterTable(output string) bool {
+	for _, family := range managedTableFamilies {
+		for _, name := range []string{managedTableName, "filter"} {
+			if strings.Contains(output, "table "+family+" "+name+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// managedTableName is the single table used by profiles in MANAGED table mode
+const managedTableName = "pilote"
+
+// managedTableNames are the tables created by the nftables generator
+var managedTableNames = []string{managedTableName, "filter", "nat"}
+
+// managedTableFamilies are the families a managed table may have been created in
+var managedTableFamilies = []string{"ip", "inet"}
+
+// nftTableNameRegex matches a valid nftables table identifier
+var nftTableNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,62}$`)
+
+// FlushRules flushes firewall rules on an agent, limited to the managed tables unless
+// a confirmed full flush is requested
+func (s *Service) FlushRules(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FlushInput) (*FirewallDeployment, error) {
+	if input.Scope == "" {
+		input.Scope = FlushScopeManaged
+	}
+	if input.Scope == FlushScopeAll && !input.ConfirmFullFlush {
+		return nil, validation.NewValidationError("flushing the entire ruleset requires confirmFullFlush")
+	}
+	if input.Scope == FlushScopeAll && input.TableName != "" {
+		return nil, validation.NewValidationError("tableName cannot be combined with the ALL scope")
+	}
+
+	agentID := input.AgentID
+
+	// Get agent name
+	agentName := "Unknown"
+	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
+		agentName = agent.Name
+	}
+
+	flush := &FirewallDeployment{
+		TenantID:  tenantID,
+		AgentID:   agentID,
+		AgentName: agentName,
+		Action:    DeploymentActionFlush,
+		Status:    DeploymentStatusPending,
+		CreatedBy: userID,
+	}
+
+	if err := s.repo.CreateDeployment(flush); err != nil {
+		return nil, fmt.Errorf("failed to create flush record: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rules.flushed",
+		ResourceType: "firewall_deployment",
+		ResourceID:   flush.ID.String(),
+		Details: map[string]interface{}{
+			"agentId":   agentID.String(),
+			"scope":     input.Scope,
+			"tableName": input.TableName,
+		},
+	})
+
+	// Execute flush asynchronously
+	go s.runFlush(flush.ID, tenantID, token, agentID, flushTaskConfig(input))
+
+	return flush, nil
+}
+
+// flushTaskConfig builds the nftables task configuration for a flush
+func flushTaskConfig(input *FlushInput) map[string]interface{} {
+	if input.Scope == FlushScopeAll {
+		return map[string]interface{}{
+			"action":        "flush",
+			"scope":         "ruleset",
+			"confirm_flush": true,
+		}
+	}
+
+	names := managedTableNames
+	if input.TableName != "" {
+		names = []string{input.TableName}
+	}
+	tables := make([]string, 0, len(names)*len(managedTableFamilies))
+	for _, family := range managedTableFamilies {
+		for _, name := range names {
+			tables = append(tables, family+" "+name)
+		}
+	}
+
+	return map[string]interface{}{
+		"action":         "flush",
+		"scope":          "table",
+		"tables":         tables,
+		"ignore_missing": true, // A table only exists in the family it was deployed with
+	}
+}
+
+// runFlush executes the flush in background
+func (s *Service) runFlush(flushID, tenantID uuid.UUID, token string, agentID uuid.UUID, taskConfig map[string]interface{}) {
+	// Use timeout to prevent goroutine leaks (2 minutes max for flush)
+	ctx, cancel := s.startOperation(flushID, 2*time.Minute)
+	defer cancel()
+
+	s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusDeploying, "Flushing firewall rules...", "")
+
+	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
+		AgentID: agentID,
+		Task: csdcore.TaskInput{
+			Type: "nftables",
+			Name: "nftables-flush",
+			Config: taskConfig,
+		},
+		Wait:    true,
+		Timeout: 60,
+	})
+	if err != nil {
+		s.repo.UpdateDeploymentStatus(flushID, classifyTaskFailure(err.Error()), "Failed to execute flush: "+err.Error(), "")
+		return
+	}
+
+	output := ""
+	if execution.Output != nil {
+		if s, ok := execution.Output.(string); ok {
+			output = s
+		}
+	}
+
+	if execution.Status != "SUCCESS" {
+		s.repo.UpdateDeploymentStatus(flushID, classifyTaskFailure(execution.Error), "Flush failed: "+execution.Error, output)
+		return
+	}
+
+	s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusApplied, "Firewall rules flushed successfully", output)
+}
+
+// GetDeployment retrieves a deployment by ID
+func (s *Service) GetDeployment(ctx context.Context, tenantID, id uuid.UUID) (*FirewallDeployment, error) {
+	return s.repo.GetDeploymentByID(tenantID, id)
+}
+
+// ListDeployments retrieves all deployments for a tenant
+func (s *Service) ListDeployments(ctx context.Context, tenantID uuid.UUID, filter *FirewallDeploymentFilter, limit, offset int) ([]FirewallDeployment, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallDeployments, limit, offset)
+	return s.repo.ListDeployments(tenantID, filter, p.Limit, p.Offset)
+}
+
+// ListDeploymentsAfter retrieves the page of deployments after an opaque cursor ("" for the first
+// page), newest first, with the cursor of the next page, empty on the last one
+func (s *Service) ListDeploymentsAfter(ctx context.Context, tenantID uuid.UUID, filter *FirewallDeploymentFilter, after string, limit int) ([]FirewallDeployment, int64, string, error) {
+	cursor, err := decodeAfterCursor(after, false)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	p := pagination.NormalizeFor(pagination.ResourceFirewallDeployments, limit, 0)
+	deployments, count, err := s.repo.ListDeploymentsAfter(tenantID, filter, cursor, p.Limit)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	next := ""
+	if len(deployments) == p.Limit {
+		last := deployments[len(deployments)-1]
+		next = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return deployments, count, next, nil
+}
+
+// GetDeploymentStats returns the average and median duration of the successful APPLY deployments
+// of the last 30 days, overall and per agent, so slow-converging agents stand out
+func (s *Service) GetDeploymentStats(ctx context.Context, tenantID uuid.UUID) (*DeploymentStats, error) {
+	since := time.Now().Add(-deploymentStatsWindow)
+	deployments, err := s.repo.ListTimedApplies(tenantID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	var all []float64
+	byAgent := make(map[uuid.UUID][]float64)
+	agentNames := make(map[uuid.UUID]string)
+	for _, deployment := range deployments {
+		seconds := deployment.CompletedAt.Sub(*deployment.StartedAt).Seconds()
+		all = append(all, seconds)
+		byAgent[deployment.AgentID] = append(byAgent[deployment.AgentID], seconds)
+		agentNames[deployment.AgentID] = deployment.AgentName
+	}
+
+	stats := &DeploymentStats{
+		Since:       since,
+		Deployments: len(all),
+		Agents:      make([]AgentDeploymentStats, 0, len(byAgent)),
+	}
+	stats.AverageSeconds, stats.MedianSeconds = durationSummary(all)
+	for agentID, durations := range byAgent {
+		agent := AgentDeploymentStats{AgentID: agentID, AgentName: agentNames[agentID], Deployments: len(durations)}
+		agent.AverageSeconds, agent.MedianSeconds = durationSummary(durations)
+		stats.Agents = append(stats.Agents, agent)
+	}
+	sort.Slice(stats.Agents, func(i, j int) bool {
+		if stats.Agents[i].AverageSeconds != stats.Agents[j].AverageSeconds {
+			return stats.Agents[i].AverageSeconds > stats.Agents[j].AverageSeconds
+		}
+		return stats.Agents[i].AgentName < stats.Agents[j].AgentName
+	})
+	return stats, nil
+}
+
+// durationSummary returns the average and median of durations in seconds, rounded to 0.1s
+func durationSummary(durations []float64) (float64, float64) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+	sorted := append([]float64(nil), durations...)
+	sort.Float64s(sorted)
+	var total float64
+	for _, d := range sorted {
+		total += d
+	}
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	return math.Round(total/float64(len(sorted))*10) / 10, math.Round(median*10) / 10
+}
+
+// ProfileDeploymentMatrix returns, for every agent that ever received the profile, its latest deployment.
+// Current reports whether the profile is still the last one successfully applied on the agent.
+func (s *Service) ProfileDeploymentMatrix(ctx context.Context, tenantID, profileID uuid.UUID) ([]ProfileAgentDeployment, error) {
+	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+
+	deployments, err := s.repo.GetLatestProfileDeploymentPerAgent(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deployments: %w", err)
+	}
+
+	matrix := make([]ProfileAgentDeployment, 0, len(deployments))
+	if len(deployments) == 0 {
+		return matrix, nil
+	}
+
+	agentIDs := make([]uuid.UUID, len(deployments))
+	for i, d := range deployments {
+		agentIDs[i] = d.AgentID
+	}
+	applied, err := s.repo.GetAppliedProfilePerAgent(tenantID, agentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied profiles: %w", err)
+	}
+
+	for _, d := range deployments {
+		matrix = append(matrix, ProfileAgentDeployment{
+			AgentID:       d.AgentID,
+			AgentName:     d.AgentName,
+			DeploymentID:  d.ID,
+			Status:        d.Status,
+			StatusMessage: d.StatusMessage,
+			DeployedAt:    d.CreatedAt,
+			CompletedAt:   d.CompletedAt,
+			Current:       applied[d.AgentID] == profileID,
+		})
+	}
+	sort.Slice(matrix, func(i, j int) bool {
+		return matrix[i].AgentName < matrix[j].AgentName
+	})
+	return matrix, nil
+}
+
+// CountDeployments returns the total count of deployments
+func (s *Service) CountDeployments(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	return s.repo.CountDeployments(tenantID)
+}
+
+// ========================================
+// Enforcement Pauses
+// ========================================
+
+// PauseEnforcement suspends drift enforcement on an agent, optionally until resumeAt.
+// Pausing an already paused agent replaces its reason and resume time.
+func (s *Service) PauseEnforcement(ctx context.Context, token string, tenantID, userID, agentID uuid.UUID, reason string, resumeAt *time.Time) (*FirewallEnforcementPause, error) {
+	if resumeAt != nil && !resumeAt.After(time.Now()) {
+		return nil, validation.NewValidationError("resumeAt must be in the future")
+	}
+
+	agent, err := s.client.GetAgent(ctx, token, agentID)
+	if err != nil || agent == nil {
+		return nil, validation.NewValidationError("agent not found")
+	}
+
+	pause := &FirewallEnforcementPause{
+		TenantID:  tenantID,
+		AgentID:   agentID,
+		AgentName: agent.Name,
+		Reason:    reason,
+		ResumeAt:  resumeAt,
+		PausedBy:  userID,
+	}
+	if err := s.repo.SaveEnforcementPause(pause); err != nil {
+		return nil, fmt.Errorf("failed to pause enforcement: %w", err)
+	}
+
+	details := map[string]interface{}{
+		"agentName": agent.Name,
+		"reason":    reason,
+	}
+	if resumeAt != nil {
+		details["resumeAt"] = resumeAt.Format(time.RFC3339)
+	}
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.enforcement.paused",
+		ResourceType: "firewall_agent",
+		ResourceID:   agentID.String(),
+		Details:      details,
+	})
+
+	return pause, nil
+}
+
+// ResumeEnforcement lifts the enforcement pause of an agent
+func (s *Service) ResumeEnforcement(ctx context.Context, token string, tenantID, agentID uuid.UUID) error {
+	deleted, err := s.repo.DeleteEnforcementPause(tenantID, agentID)
+	if err != nil {
+		return fmt.Errorf("failed to resume enforcement: %w", err)
+	}
+	if deleted == 0 {
+		return validation.NewValidationError("enforcement is not paused on this agent")
+	}
+
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.enforcement.resumed",
+		ResourceType: "firewall_agent",
+		ResourceID:   agentID.String(),
+	})
+
+	return nil
+}
+
+// ListEnforcementPauses lists agents with enforcement paused, dropping pauses past their resume time
+func (s *Service) ListEnforcementPauses(ctx context.Context, tenantID uuid.UUID) ([]FirewallEnforcementPause, error) {
+	now := time.Now()
+	if err := s.repo.DeleteExpiredEnforcementPauses(tenantID, now); err != nil {
+		logger.Warn("[Security] Failed to delete expired enforcement pauses: %s", err.Error())
+	}
+	return s.repo.ListActiveEnforcementPauses(tenantID, now)
+}
+
+// activeEnforcementPause returns the pause in effect on an agent, or nil when it is enforced
+func (s *Service) activeEnforcementPause(tenantID, agentID uuid.UUID) *FirewallEnforcementPause {
+	pause, err := s.repo.GetEnforcementPause(tenantID, agentID)
+	if err != nil || !pause.Active(time.Now()) {
+		return nil
+	}
+	return pause
+}
+
+// pauseMessage describes an enforcement pause for audit results
+func pauseMessage(pause *FirewallEnforcementPause) string {
+	msg := "enforcement paused"
+	if pause.Reason != "" {
+		msg += ": " + pause.Reason
+	}
+	if pause.ResumeAt != nil {
+		msg += fmt.Sprintf(" (until %s)", pause.ResumeAt.Format(time.RFC3339))
+	}
+	return msg
+}
+
+// ========================================
+// In-flight Operations
+// ========================================
+
+// startOperation derives a cancellable context for a background deployment operation
+// from the service lifetime and registers it so it can be cancelled on request.
+// A zero timeout leaves the operation bounded only by cancellation.
+func (s *Service) startOperation(id uuid.UUID, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(s.lifetime, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(s.lifetime)
+	}
+
+	s.opsMu.Lock()
+	s.operations[id] = cancel
+	s.opsMu.Unlock()
+
+	return ctx, func() {
+		s.opsMu.Lock()
+		delete(s.operations, id)
+		s.opsMu.Unlock()
+		cancel()
+	}
+}
+
+// isOperationRunning reports whether a background operation is registered for the deployment
+func (s *Service) isOperationRunning(id uuid.UUID) bool {
+	s.opsMu.Lock()
+	defer s.opsMu.Unlock()
+	_, ok := s.operations[id]
+	return ok
+}
+
+// Shutdown cancels all background operations
+func (s *Service) Shutdown() {
+	s.shutdown()
+}
+
+// ListInFlightOperations lists pending and running deployments, rollbacks, audits and flushes
+func (s *Service) ListInFlightOperations(ctx context.Context, tenantID uuid.UUID) ([]InFlightOperation, error) {
+	deployments, err := s.repo.ListInFlightDeployments(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	operations := make([]InFlightOperation, 0, len(deployments))
+	for _, d := range deployments {
+		since := d.CreatedAt
+		if d.StartedAt != nil {
+			since = *d.StartedAt
+		}
+		operations = append(operations, InFlightOperation{
+			ID:            d.ID,
+			Action:        d.Action,
+			Status:        d.Status,
+			StatusMessage: d.StatusMessage,
+			ProfileID:     d.ProfileID,
+			AgentID:       d.AgentID,
+			AgentName:     d.AgentName,
+			RolloutID:     d.RolloutID,
+			CreatedAt:     d.CreatedAt,
+			StartedAt:     d.StartedAt,
+			AgeSeconds:    int64(now.Sub(since).Seconds()),
+			Running:       s.isOperationRunning(d.ID),
+		})
+	}
+	return operations, nil
+}
+
+// CancelOperation cancels a pending or running operation. A running operation is
+// signalled through its context and records the failure itself; an operation with
+// no running worker (e.g. left over from a restart) is marked as failed directly.
+func (s *Service) CancelOperation(ctx context.Context, token string, tenantID, id uuid.UUID) (*FirewallDeployment, error) {
+	deployment, err := s.repo.GetDeploymentByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if deployment.Status != DeploymentStatusPending && deployment.Status != DeploymentStatusDeploying {
+		return nil, validation.NewValidationError("only pending or running operations can be cancelled")
+	}
+
+	s.opsMu.Lock()
+	cancel, running := s.operations[id]
+	s.opsMu.Unlock()
+
+	if running {
+		cancel()
+	} else if err := s.repo.UpdateDeploymentStatus(id, DeploymentStatusError, "Operation cancelled", ""); err != nil {
+		return nil, err
+	}
+
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.operation.cancelled",
+		ResourceType: "firewall_deployment",
+		ResourceID:   id.String(),
+		Details: map[string]interface{}{
+			"action":  string(deployment.Action),
+			"agentId": deployment.AgentID.String(),
+			"running": running,
+		},
+	})
+
+	return s.repo.GetDeploymentByID(tenantID, id)
+}
+
+// ========================================
+// Packet Simulation
+// ========================================
+
+// SimulatePacket walks a profile's chain in evaluation order (base rules, user rules,
+// then policy) and returns the first rule that decides the packet's verdict
+func (s *Service) SimulatePacket(ctx context.Context, tenantID, profileID uuid.UUID, packet *SimulatedPacket) (*PacketSimulationResult, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+	return simulatePacket(profile, packet), nil
+}
+
+// SimulateTemplate runs the packet simulator over a template's rules before any profile is created
+// from it. The rules are placed in a transient profile built from the optional settings
+// (profile defaults with the template's own settings otherwise); NAT is enabled when the template has NAT rules.
+func (s *Service) SimulateTemplate(ctx context.Context, tenantID, userID, templateID uuid.UUID, settings *FirewallProfileInput, packet *SimulatedPacket) (*PacketSimulationResult, error) {
+	template, err := s.repo.GetTemplateByID(tenantID, userID, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+	defs, err := s.repo.GetTemplateRules(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template rules: %w", err)
+	}
+
+	templateSettings, err := s.repo.GetTemplateSettings(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template settings: %w", err)
+	}
+
+	explicitSettings := settings != nil
+	if settings == nil {
+		settings = &FirewallProfileInput{}
+	}
+	profile := newProfileFromInput(settings)
+	profile.TenantID = tenantID
+	profile.Name = template.Name
+	if !explicitSettings && templateSettings != nil {
+		applyTemplateSettings(profile, templateSettings)
+		settings.EnableNAT = templateSettings.EnableNAT
+	}
+
+	for _, def := range defs {
+		rule := newRuleFromDefinition(tenantID, userID, def)
+		// Matches that decide the verdict even though rule creation does not copy them
+		rule.InInterface = def.InInterface
+		rule.OutInterface = def.OutInterface
+		rule.CTState = def.CTState
+		rule.LogPrefix = def.LogPrefix
+		rule.ProfileSortOrder = len(profile.Rules)
+		profile.Rules = append(profile.Rules, *rule)
+		if settings.EnableNAT == nil && (rule.Chain == RuleChainPrerouting || rule.Chain == RuleChainPostrouting) {
+			profile.EnableNAT = true
+		}
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+	return simulatePacket(profile, packet), nil
+}
+
+// simulatePacket evaluates a packet against a profile without touching the database
+func simulatePacket(profile *FirewallProfile, packet *SimulatedPacket) *PacketSimulationResult {
+	if packet.Chain == "" {
+		packet.Chain = RuleChainInput
+	}
+	if packet.CTState == "" {
+		packet.CTState = string(CTStateNew)
+	}
+	if packet.Protocol == RuleProtocolICMP && packet.ICMPType == "" {
+		packet.ICMPType = "echo-request"
+	}
+
+	result := &PacketSimulationResult{
+		LoggedBy:     []string{},
+		SkippedRules: []string{},
+	}
+
+	// Base rules generated from profile feature flags come first
+	if verdict, baseRule := matchBaseRules(profile, packet); verdict != "" {
+		result.Verdict = verdict
+		result.MatchedBaseRule = baseRule
+		return result
+	}
+
+	// User rules only exist in the NAT chains when NAT is enabled
+	isNatChain := packet.Chain == RuleChainPrerouting || packet.Chain == RuleChainPostrouting
+	if !isNatChain || profile.EnableNAT {
+		for _, rule := range orderedRules(profile) {
+			if !rule.Enabled || rule.Chain != packet.Chain {
+				continue
+			}
+			if rule.RuleExpr != "" {
+				result.SkippedRules = append(result.SkippedRules, rule.Name)
+				continue
+			}
+			rule.SourceIP = expandIPSetReference(profile, rule.SourceIP)
+			rule.DestIP = expandIPSetReference(profile, rule.DestIP)
+			if !ruleMatchesPacket(&rule, packet) {
+				continue
+			}
+			// log is a non-terminal statement: evaluation continues
+			if rule.Action == RuleActionLog {
+				result.LoggedBy = append(result.LoggedBy, rule.Name)
+				continue
+			}
+			result.Verdict = strings.ToLower(string(rule.Action))
+			result.MatchedRule = &rule
+			return result
+		}
+	}
+
+	result.DecidedByPolicy = true
+	result.Verdict = chainPolicy(profile, packet.Chain)
+	return result
+}
+
+// matchBaseRules evaluates the loopback, conntrack and ICMP rules a profile generates
+func matchBaseRules(profile *FirewallProfile, packet *SimulatedPacket) (string, string) {
+	ctState := strings.ToUpper(packet.CTState)
+	established := ctState == string(CTStateEstablished) || ctState == string(CTStateRelated)
+
+	switch packet.Chain {
+	case RuleChainInput:
+		if profile.EnableManagementAccess && packet.Protocol == RuleProtocolTCP {
+			port := profile.ManagementPort
+			if port == "" {
+				port = defaultManagementPort
+			}
+			source := expandIPSetReference(profile, profile.ManagementSource)
+			if portMatches(port, packet.DestPort) && addressMatches(source, packet.SourceIP) {
+				return "accept", managementAccessExpr(profile)
+			}
+		}
+		if profile.AllowLoopback && packet.InInterface == "lo" {
+			return "accept", "iif lo accept"
+		}
+		if profile.AllowEstablished {
+			if established {
+				return "accept", "ct state established,related accept"
+			}
+			if ctState == string(CTStateInvalid) {
+				return "drop", "ct state invalid drop"
+			}
+		}
+		if profile.AllowICMPPing && packet.Protocol == RuleProtocolICMP && packet.ICMPType == "echo-request" {
+			return "accept", "ip protocol icmp icmp type echo-request accept"
+		}
+	case RuleChainOutput:
+		if profile.AllowLoopback && packet.OutInterface == "lo" {
+			return "accept", "oif lo accept"
+		}
+		if profile.AllowEstablished && established {
+			return "accept", "ct state established,related accept"
+		}
+	case RuleChainForward:
+		if profile.AllowEstablished {
+			if established {
+				return "accept", "ct state established,related accept"
+			}
+			if ctState == string(CTStateInvalid) {
+				return "drop", "ct state invalid drop"
+			}
+		}
+	}
+	return "", ""
+}
+
+// chainPolicy returns the policy applied when no rule matches in a chain
+func chainPolicy(profile *FirewallProfile, chain RuleChain) string {
+	policy := ""
+	switch chain {
+	case RuleChainInput:
+		policy = profile.InputPolicy
+	case RuleChainOutput:
+		policy = profile.OutputPolicy
+	case RuleChainForward:
+		policy = profile.ForwardPolicy
+	default:
+		// NAT chains are generated without a policy, which defaults to accept
+		return "accept"
+	}
+	if policy == "" {
+		policy = "drop"
+	}
+	return policy
+}
+
+// ruleMatchesPacket checks every match field of a rule against a packet
+func ruleMatchesPacket(rule *FirewallRule, packet *SimulatedPacket) bool {
+	if rule.InInterface != "" && rule.InInterface != packet.InInterface {
+		return false
+	}
+	if rule.OutInterface != "" && rule.OutInterface != packet.OutInterface {
+		return false
+	}
+	if rule.CTState != "" && !ctStateMatches(rule.CTState, packet.CTState) {
+		return false
+	}
+	if rule.Protocol != "" && rule.Protocol != RuleProtocolAll && (rule.Protocol == packet.Protocol) == rule.NegateProtocol {
+		return false
+	}
+	if !addressMatches(rule.SourceIP, packet.SourceIP) || !addressMatches(rule.DestIP, packet.DestIP) {
+		return false
+	}
+	if rule.SourcePort != "" || rule.DestPort != "" {
+		// Port matches only apply to TCP and UDP, as generated by ruleToNft
+		if packet.Protocol != RuleProtocolTCP && packet.Protocol != RuleProtocolUDP {
+			return false
+		}
+	}
+	if rule.SourcePort != "" && portMatches(rule.SourcePort, packet.SourcePort) == rule.NegateSourcePort {
+		return false
+	}
+	if rule.DestPort != "" && portMatches(rule.DestPort, packet.DestPort) == rule.NegateDestPort {
+		return false
+	}
+	if rule.ICMPType != "" && !icmpTypeMatches(rule.ICMPType, packet.ICMPType) {
+		return false
+	}
+	if rule.DSCP != "" && dscpValue(rule.DSCP) != dscpValue(packet.DSCP) {
+		return false
+	}
+	// Length ranges use the same syntax as port ranges
+	if !portMatches(rule.PacketLength, packet.Length) {
+		return false
+	}
+	return true
+}
+
+// icmpTypeMatches checks a packet's ICMP type against a rule's comma-separated types
+func icmpTypeMatches(types, icmpType string) bool {
+	for _, t := range splitICMPTypes(types) {
+		if strings.EqualFold(t, icmpType) {
+			return true
+		}
+	}
+	return false
+}
+
+// dscpValue returns the codepoint of a DSCP class name or numeric value (empty is cs0)
+func dscpValue(dscp string) int {
+	if value, ok := validation.DSCPClasses[strings.ToLower(dscp)]; ok {
+		return value
+	}
+	value, _ := strconv.Atoi(dscp)
+	return value
+}
+
+// ctStateMatches checks a packet state against a comma-separated list of states
+func ctStateMatches(states, state string) bool {
+	for _, s := range strings.Split(states, ",") {
+		if strings.EqualFold(strings.TrimSpace(s), state) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressMatches checks an IP against a rule address (IP, CIDR or comma/brace list)
+func addressMatches(spec, ip string) bool {
+	if spec == "" || spec == "any" {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, element := range splitSetElements(spec) {
+		if _, network, err := net.ParseCIDR(element); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if candidate := net.ParseIP(element); candidate != nil && candidate.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// portMatches checks a port against a rule port (single, range or comma/brace list)
+func portMatches(spec string, port int) bool {
+	if spec == "" {
+		return true
+	}
+	if port == 0 {
+		return false
+	}
+	for _, element := range splitSetElements(spec) {
+		low, high := element, element
+		if idx := strings.Index(element, "-"); idx > 0 {
+			low, high = element[:idx], element[idx+1:]
+		}
+		lowPort, errLow := strconv.Atoi(strings.TrimSpace(low))
+		highPort, errHigh := strconv.Atoi(strings.TrimSpace(high))
+		if errLow == nil && errHigh == nil && port >= lowPort && port <= highPort {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSetElements splits "a,b" or "{ a, b }" into trimmed elements
+func splitSetElements(spec string) []string {
+	spec = strings.TrimSpace(spec)
+	spec = strings.TrimSuffix(strings.TrimPrefix(spec, "{"), "}")
+	parts := strings.Split(spec, ",")
+	elements := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			elements = append(elements, part)
+		}
+	}
+	return elements
+}
+
+// ========================================
+// Rule Match Search
+// ========================================
+
+// SearchRulesByMatch finds the enabled rules whose addresses, ports and protocol overlap the
+// query, with the profiles containing them and the agents where those profiles are applied.
+// Addresses overlap by CIDR containment in either direction; IP set references are expanded.
+func (s *Service) SearchRulesByMatch(ctx context.Context, tenantID uuid.UUID, query *RuleMatchQuery) (*RuleMatchSearchResult, error) {
+	var queryNet *net.IPNet
+	if query.IP != "" {
+		var err error
+		if queryNet, err = parseNetwork(query.IP); err != nil {
+			return nil, validation.NewValidationError("ip must be an IP address or CIDR")
+		}
+	}
+
+	rules, err := s.repo.ListEnabledRules(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	sets, err := s.repo.GetIPSetsByNames(tenantID, referencedIPSetNames(rules))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IP sets: %w", err)
+	}
+	setElements := make(map[string][]string, len(sets))
+	for _, set := range sets {
+		setElements[set.Name] = set.Elements
+	}
+
+	result := &RuleMatchSearchResult{Matches: []RuleMatch{}, AffectedAgents: []RuleMatchAgent{}, SkippedRules: []string{}}
+	var ruleIDs []uuid.UUID
+	for _, rule := range rules {
+		if rule.RuleExpr != "" {
+			result.SkippedRules = append(result.SkippedRules, rule.Name)
+			continue
+		}
+		if matchedOn, ok := ruleOverlapsQuery(&rule, query, queryNet, setElements); ok {
+			result.Matches = append(result.Matches, RuleMatch{Rule: rule, MatchedOn: matchedOn, Profiles: []RuleMatchProfile{}})
+			ruleIDs = append(ruleIDs, rule.ID)
+		}
+	}
+
+	profilesByRule, err := s.repo.GetProfilesForRules(tenantID, ruleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profiles: %w", err)
+	}
+
+	// Applied agents are resolved once per profile
+	agentsByProfile := make(map[uuid.UUID][]RuleMatchAgent)
+	seenAgents := make(map[uuid.UUID]bool)
+	for i := range result.Matches {
+		for _, profile := range profilesByRule[result.Matches[i].Rule.ID] {
+			agents, ok := agentsByProfile[profile.ID]
+			if !ok {
+				matrix, err := s.ProfileDeploymentMatrix(ctx, tenantID, profile.ID)
+				if err != nil {
+					return nil, err
+				}
+				agents = []RuleMatchAgent{}
+				for _, entry := range matrix {
+					if entry.Current && entry.Status == DeploymentStatusApplied {
+						agents = append(agents, RuleMatchAgent{AgentID: entry.AgentID, AgentName: entry.AgentName})
+					}
+				}
+				agentsByProfile[profile.ID] = agents
+			}
+			for _, agent := range agents {
+				if !seenAgents[agent.AgentID] {
+					seenAgents[agent.AgentID] = true
+					result.AffectedAgents = append(result.AffectedAgents, agent)
+				}
+			}
+			result.Matches[i].Profiles = append(result.Matches[i].Profiles, RuleMatchProfile{
+				ProfileID:   profile.ID,
+				ProfileName: profile.Name,
+				Enabled:     profile.Enabled,
+				Agents:      agents,
+			})
+		}
+	}
+	sort.Slice(result.AffectedAgents, func(i, j int) bool {
+		return result.AffectedAgents[i].AgentName < result.AffectedAgents[j].AgentName
+	})
+
+	return result, nil
+}
+
+// ruleOverlapsQuery checks every criterion set in the query against a rule and returns the
+// rule fields that overlap it. Unset rule fields match anything and are reported as "(any)".
+func ruleOverlapsQuery(rule *FirewallRule, query *RuleMatchQuery, queryNet *net.IPNet, setElements map[string][]string) ([]string, bool) {
+	var matchedOn []string
+
+	if query.Protocol != "" {
+		switch {
+		case rule.Protocol == "" || rule.Protocol == RuleProtocolAll:
+			if query.SpecificOnly {
+				return nil, false
+			}
+			matchedOn = append(matchedOn, "protocol (any)")
+		case (rule.Protocol == query.Protocol) != rule.NegateProtocol:
+			matchedOn = append(matchedOn, "protocol")
+		default:
+			return nil, false
+		}
+	}
+
+	if queryNet != nil {
+		fields, ok := overlappingFields(query.SpecificOnly,
+			fieldOverlap{"sourceIp", rule.SourceIP == "" || rule.SourceIP == "any", addressOverlaps(rule.SourceIP, queryNet, setElements)},
+			fieldOverlap{"destIp", rule.DestIP == "" || rule.DestIP == "any", addressOverlaps(rule.DestIP, queryNet, setElements)})
+		if !ok {
+			return nil, false
+		}
+		matchedOn = append(matchedOn, fields...)
+	}
+
+	if query.Port > 0 {
+		// Ports only exist for TCP and UDP, as generated by ruleToNft
+		if rule.Protocol == RuleProtocolICMP && !rule.NegateProtocol {
+			return nil, false
+		}
+		fields, ok := overlappingFields(query.SpecificOnly,
+			fieldOverlap{"sourcePort", rule.SourcePort == "", rule.SourcePort == "" || portMatches(rule.SourcePort, query.Port) != rule.NegateSourcePort},
+			fieldOverlap{"destPort", rule.DestPort == "", rule.DestPort == "" || portMatches(rule.DestPort, query.Port) != rule.NegateDestPort})
+		if !ok {
+			return nil, false
+		}
+		matchedOn = append(matchedOn, fields...)
+	}
+
+	return matchedOn, true
+}
+
+// fieldOverlap is the outcome of comparing one rule field with a query criterion
+type fieldOverlap struct {
+	name     string
+	wildcard bool // The field is unset and matches anything
+	overlaps bool
+}
+
+// overlappingFields returns the source/destination fields overlapping a criterion; the criterion
+// is met when either field overlaps (a specific match is required when specificOnly is set)
+func overlappingFields(specificOnly bool, fields ...fieldOverlap) ([]string, bool) {
+	var specific, wildcard []string
+	for _, field := range fields {
+		switch {
+		case field.wildcard:
+			wildcard = append(wildcard, field.name+" (any)")
+		case field.overlaps:
+			specific = append(specific, field.name)
+		}
+	}
+	if len(specific) > 0 {
+		return specific, true
+	}
+	// Both fields unset: the rule matches any value
+	if !specificOnly && len(wildcard) == len(fields) {
+		return wildcard, true
+	}
+	return nil, false
+}
+
+// addressOverlaps checks whether a rule address (IP, CIDR, list or @set) shares any address with a network
+func addressOverlaps(spec string, network *net.IPNet, setElements map[string][]string) bool {
+	if spec == "" || spec == "any" {
+		return true
+	}
+	elements := splitSetElements(spec)
+	if name, ok := ipSetReference(spec); ok {
+		elements = setElements[name]
+	}
+	for _, element := range elements {
+		candidate, err := parseNetwork(element)
+		if err != nil {
+			continue
+		}
+		if candidate.Contains(network.IP) || network.Contains(candidate.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR, or a single IP as a host network
+func parseNetwork(value string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", value)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// ========================================
+// IP Sets
+// ========================================
+
+// ipSetReferencePrefix marks a rule address as a reference to a named IP set
+const ipSetReferencePrefix = "@"
+
+var ipSetNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,62}$`)
+
+// CreateIPSet creates a new tenant-wide IP set
+func (s *Service) CreateIPSet(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallIPSetInput) (*FirewallIPSet, error) {
+	if err := validateIPSetInput(input, true); err != nil {
+		return nil, err
+	}
+
+	set := &FirewallIPSet{
+		TenantID:    tenantID,
+		Name:        input.Name,
+		Description: input.Description,
+		Elements:    normalizeIPSetElements(input.Elements),
+		CreatedBy:   userID,
+	}
+
+	if err := s.repo.CreateIPSet(set); err != nil {
+		return nil, fmt.Errorf("failed to create IP set: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.ip_set.created",
+		ResourceType: "firewall_ip_set",
+		ResourceID:   set.ID.String(),
+		Details: map[string]interface{}{
+			"name":         set.Name,
+			"elementCount": len(set.Elements),
+		},
+	})
+
+	return set, nil
+}
+
+// GetIPSet retrieves an IP set by ID
+func (s *Service) GetIPSet(ctx context.Context, tenantID, id uuid.UUID) (*FirewallIPSet, error) {
+	return s.repo.GetIPSetByID(tenantID, id)
+}
+
+// ListIPSets retrieves all IP sets for a tenant
+func (s *Service) ListIPSets(ctx context.Context, tenantID uuid.UUID, search string, limit, offset int) ([]FirewallIPSet, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallIPSets, limit, offset)
+	return s.repo.ListIPSets(tenantID, search, p.Limit, p.Offset)
+}
+
+// UpdateIPSet updates an IP set. The new contents are picked up by the next deployment
+// of every profile referencing the set.
+func (s *Service) UpdateIPSet(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallIPSetInput) (*FirewallIPSet, error) {
+	if err := validateIPSetInput(input, false); err != nil {
+		return nil, err
+	}
+
+	set, err := s.repo.GetIPSetByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" && input.Name != set.Name {
+		// Rules reference sets by name, so renaming a set in use would break them
+		rules, err := s.repo.GetRulesReferencingIPSet(tenantID, set.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) > 0 {
+			return nil, validation.NewValidationError(fmt.Sprintf("IP set %s is referenced by %d rule(s) and cannot be renamed", set.Name, len(rules)))
+		}
+		set.Name = input.Name
+	}
+	if input.Description != "" {
+		set.Description = input.Description
+	}
+	if input.Elements != nil {
+		set.Elements = normalizeIPSetElements(input.Elements)
+	}
+
+	if err := s.repo.UpdateIPSet(set); err != nil {
+		return nil, fmt.Errorf("failed to update IP set: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.ip_set.updated",
+		ResourceType: "firewall_ip_set",
+		ResourceID:   set.ID.String(),
+		Details: map[string]interface{}{
+			"name":         set.Name,
+			"elementCount": len(set.Elements),
+		},
+	})
+
+	return set, nil
+}
+
+// DeleteIPSet deletes an IP set that is not referenced by any rule
+func (s *Service) DeleteIPSet(ctx context.Context, token string, tenantID, id uuid.UUID) error {
+	set, err := s.repo.GetIPSetByID(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	rules, err := s.repo.GetRulesReferencingIPSet(tenantID, set.Name)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		return validation.NewValidationError(fmt.Sprintf("IP set %s is referenced by %d rule(s)", set.Name, len(rules)))
+	}
+
+	if err := s.repo.DeleteIPSet(tenantID, id); err != nil {
+		return err
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.ip_set.deleted",
+		ResourceType: "firewall_ip_set",
+		ResourceID:   id.String(),
+		Details: map[string]interface{}{
+			"name": set.Name,
+		},
+	})
+
+	return nil
+}
+
+// GetIPSetUsage lists the rules and profiles that reference an IP set
+func (s *Service) GetIPSetUsage(ctx context.Context, tenantID, id uuid.UUID) (*IPSetUsage, error) {
+	set, err := s.repo.GetIPSetByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.repo.GetRulesReferencingIPSet(tenantID, set.Name)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := s.repo.GetProfilesReferencingIPSet(tenantID, set.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IPSetUsage{IPSet: set, Rules: rules, Profiles: profiles}, nil
+}
+
+// resolveProfileIPSets loads the current contents of every IP set referenced by a profile's
+// enabled rules into profile.IPSets, failing if a referenced set does not exist
+func (s *Service) resolveProfileIPSets(tenantID uuid.UUID, profile *FirewallProfile) error {
+	names := referencedIPSetNames(profile.Rules)
+	if name, ok := ipSetReference(profile.ManagementSource); ok && profile.EnableManagementAccess {
+		if i := sort.SearchStrings(names, name); i == len(names) || names[i] != name {
+			names = append(names, name)
+			sort.Strings(names)
+		}
+	}
+	sets, err := s.repo.GetIPSetsByNames(tenantID, names)
+	if err != nil {
+		return fmt.Errorf("failed to load IP sets: %w", err)
+	}
+
+	if len(sets) != len(names) {
+		found := make(map[string]bool, len(sets))
+		for _, set := range sets {
+			found[set.Name] = true
+		}
+		for _, name := range names {
+			if !found[name] {
+				return validation.NewValidationError(fmt.Sprintf("rules reference unknown IP set %s", name))
+			}
+		}
+	}
+
+	profile.IPSets = sets
+	return nil
+}
+
+// referencedIPSetNames returns the sorted names of IP sets referenced by enabled rules
+func referencedIPSetNames(rules []FirewallRule) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		for _, addr := range []string{rule.SourceIP, rule.DestIP} {
+			if name, ok := ipSetReference(addr); ok && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ipSetReference returns the set name when an address is an "@name" set reference
+func ipSetReference(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, ipSetReferencePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, ipSetReferencePrefix), true
+}
+
+// validateIPSetInput validates an IP set name and its IPv4 address/network elements
+func validateIPSetInput(input *FirewallIPSetInput, requireName bool) error {
+	errs := &validation.ValidationErrors{}
+
+	if input.Name == "" && requireName {
+		errs.Add("name", "name is required", "REQUIRED")
+	} else if input.Name != "" && !ipSetNameRegex.MatchString(input.Name) {
+		errs.Add("name", "name must start with a letter and contain only letters, digits and underscores (max 63 chars)", "INVALID_IP_SET_NAME")
+	}
+
+	for _, element := range input.Elements {
+		element = strings.TrimSpace(element)
+		if _, network, err := net.ParseCIDR(element); err == nil {
+			if network.IP.To4() == nil {
+				errs.Add("elements", fmt.Sprintf("%s is not an IPv4 network", element), "INVALID_IP_SET_ELEMENT")
+			}
+			continue
+		}
+		if ip := net.ParseIP(element); ip == nil || ip.To4() == nil {
+			errs.Add("elements", fmt.Sprintf("%s is not an IPv4 address or network", element), "INVALID_IP_SET_ELEMENT")
+		}
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// normalizeIPSetElements trims elements and drops blanks and duplicates
+func normalizeIPSetElements(elements []string) []string {
+	seen := make(map[string]bool, len(elements))
+	result := make([]string, 0, len(elements))
+	for _, element := range elements {
+		element = strings.TrimSpace(element)
+		if element == "" || seen[element] {
+			continue
+		}
+		seen[element] = true
+		result = append(result, element)
+	}
+	return result
+}
+
+// sameIPSetElements reports whether two element lists hold the same addresses, ignoring order and duplicates
+func sameIPSetElements(a, b []string) bool {
+	a, b = normalizeIPSetElements(a), normalizeIPSetElements(b)
+	if len(a) != len(b) {
+		return false
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeIPSets writes the nftables set definitions for a profile's IP sets
+func writeIPSets(config *strings.Builder, sets []FirewallIPSet) {
+	for _, set := range sets {
+		fmt.Fprintf(config, "    set %s {\n", set.Name)
+		config.WriteString("        type ipv4_addr\n")
+		config.WriteString("        flags interval\n")
+		if len(set.Elements) > 0 {
+			fmt.Fprintf(config, "        elements = { %s }\n", strings.Join(set.Elements, ", "))
+		}
+		config.WriteString("    }\n\n")
+	}
+}
+
+// logDropChain is the regular chain dropped packets go to when drop logging is enabled
+const logDropChain = "log_drop"
+
+// defaultLogDropPrefix is the log prefix of the log_drop chain when the profile does not set one
+const defaultLogDropPrefix = "pilote-drop: "
+
+// writeLogDropChain writes the shared chain that logs, counts and drops packets
+func writeLogDropChain(config *strings.Builder, profile *FirewallProfile) {
+	prefix := profile.LogDropPrefix
+	if prefix == "" {
+		prefix = defaultLogDropPrefix
+	}
+	fmt.Fprintf(config, "    chain %s {\n", logDropChain)
+	fmt.Fprintf(config, "        log prefix \"%s\" counter drop\n", strings.ReplaceAll(prefix, "\"", "\\\""))
+	config.WriteString("    }\n\n")
+}
+
+// writeCounters writes the named counter objects referenced by the enabled rules of the given chains
+func writeCounters(config *strings.Builder, rules []FirewallRule, chains ...RuleChain) {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		name := ruleCounterName(rule)
+		if !rule.Enabled || name == "" || seen[name] {
+			continue
+		}
+		for _, chain := range chains {
+			if rule.Chain == chain {
+				seen[name] = true
+				fmt.Fprintf(config, "    counter %s {\n    }\n\n", name)
+				break
+			}
+		}
+	}
+}
+
+// perRuleCounterPrefix starts the names of the counters generated for rules with the counter flag
+const perRuleCounterPrefix = "rule_"
+
+// ruleCounterName returns the named counter a rule increments: its counterName, or a per-rule counter
+// derived from its ID when the counter flag is set. Rule names are only kept in "#" comments, which nft
+// discards, so the ID in the counter name is what links a live counter back to its rule.
+func ruleCounterName(rule FirewallRule) string {
+	switch {
+	case rule.CounterName != "":
+		return rule.CounterName
+	case rule.Counter && rule.RuleExpr == "":
+		return perRuleCounterPrefix + strings.ReplaceAll(rule.ID.String(), "-", "")
+	}
+	return ""
+}
+
+// expandIPSetReference replaces an "@name" set reference with the set's elements
+func expandIPSetReference(profile *FirewallProfile, addr string) string {
+	name, ok := ipSetReference(addr)
+	if !ok {
+		return addr
+	}
+	for _, set := range profile.IPSets {
+		if set.Name == name {
+			return strings.Join(set.Elements, ",")
+		}
+	}
+	return addr
+}
+
+// ========================================
+// Agent Groups
+// ========================================
+
+// CreateAgentGroup creates a new agent group
+func (s *Service) CreateAgentGroup(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallAgentGroupInput) (*FirewallAgentGroup, error) {
+	agentIDs, err := s.groupInputAgentIDs(ctx, token, input)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &FirewallAgentGroup{
+		TenantID:    tenantID,
+		Name:        input.Name,
+		Description: input.Description,
+		CreatedBy:   userID,
+	}
+	for i, agentID := range agentIDs {
+		group.Members = append(group.Members, FirewallAgentGroupMember{AgentID: agentID, SortOrder: i})
+	}
+
+	if err := s.repo.CreateAgentGroup(group); err != nil {
+		return nil, fmt.Errorf("failed to create agent group: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.agent_group.created",
+		ResourceType: "firewall_agent_group",
+		ResourceID:   group.ID.String(),
+		Details: map[string]interface{}{
+			"name":       group.Name,
+			"agentCount": len(agentIDs),
+		},
+	})
+
+	return group, nil
+}
+
+// GetAgentGroup retrieves an agent group by ID
+func (s *Service) GetAgentGroup(ctx context.Context, tenantID, id uuid.UUID) (*FirewallAgentGroup, error) {
+	return s.repo.GetAgentGroupByID(tenantID, id)
+}
+
+// ListAgentGroups retrieves all agent groups for a tenant
+func (s *Service) ListAgentGroups(ctx context.Context, tenantID uuid.UUID, search string, limit, offset int) ([]FirewallAgentGroup, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallAgentGroups, limit, offset)
+	return s.repo.ListAgentGroups(tenantID, search, p.Limit, p.Offset)
+}
+
+// UpdateAgentGroup updates an agent group and, when provided, replaces its members
+func (s *Service) UpdateAgentGroup(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallAgentGroupInput) (*FirewallAgentGroup, error) {
+	group, err := s.repo.GetAgentGroupByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" {
+		group.Name = input.Name
+	}
+	if input.Description != "" {
+		group.Description = input.Description
+	}
+
+	// Resolve the members before writing, so the group and its members are saved together
+	var agentIDs []uuid.UUID
+	if input.AgentIDs != nil || input.AgentHostnames != nil {
+		if agentIDs, err = s.groupInputAgentIDs(ctx, token, input); err != nil {
+			return nil, err
+		}
+		if agentIDs == nil {
+			agentIDs = []uuid.UUID{}
+		}
+	}
+
+	if err := s.repo.UpdateAgentGroup(group, agentIDs); err != nil {
+		return nil, fmt.Errorf("failed to update agent group: %w", err)
+	}
+
+	group, err = s.repo.GetAgentGroupByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.agent_group.updated",
+		ResourceType: "firewall_agent_group",
+		ResourceID:   group.ID.String(),
+		Details: map[string]interface{}{
+			"name":       group.Name,
+			"agentCount": len(group.Members),
+		},
+	})
+
+	return group, nil
+}
+
+// DeleteAgentGroup deletes an agent group
+func (s *Service) DeleteAgentGroup(ctx context.Context, token string, tenantID, id uuid.UUID) error {
+	if err := s.repo.DeleteAgentGroup(tenantID, id); err != nil {
+		return err
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.agent_group.deleted",
+		ResourceType: "firewall_agent_group",
+		ResourceID:   id.String(),
+	})
+
+	return nil
+}
+
+// parseAgentIDs parses agent IDs, dropping duplicates while keeping order
+func parseAgentIDs(ids []string) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	agentIDs := make([]uuid.UUID, 0, len(ids))
+	for _, idStr := range ids {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agentId: %s", idStr)
+		}
+		if !seen[id] {
+			seen[id] = true
+			agentIDs = append(agentIDs, id)
+		}
+	}
+	return agentIDs, nil
+}
+
+// groupInputAgentIDs returns the group members from agent IDs followed by resolved hostnames
+func (s *Service) groupInputAgentIDs(ctx context.Context, token string, input *FirewallAgentGroupInput) ([]uuid.UUID, error) {
+	ids := append([]string{}, input.AgentIDs...)
+	if len(input.AgentHostnames) > 0 {
+		resolved, err := s.resolveAgentHostnames(ctx, token, input.AgentHostnames)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range resolved {
+			ids = append(ids, id.String())
+		}
+	}
+	return parseAgentIDs(ids)
+}
+
+// resolveAgentHostnames maps each hostname to the single agent registered with it.
+// Hostnames are matched case-insensitively; missing or ambiguous hostnames are rejected.
+func (s *Service) resolveAgentHostnames(ctx context.Context, token string, hostnames []string) ([]uuid.UUID, error) {
+	agents, err := s.client.ListAgents(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	agentIDs := make([]uuid.UUID, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		var matches []uuid.UUID
+		for _, agent := range agents {
+			if strings.EqualFold(agent.Hostname, hostname) {
+				matches = append(matches, agent.ID)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return nil, validation.NewValidationError(fmt.Sprintf("no agent found with hostname %q", hostname))
+		case 1:
+			agentIDs = append(agentIDs, matches[0])
+		default:
+			ids := make([]string, len(matches))
+			for i, id := range matches {
+				ids[i] = id.String()
+			}
+			return nil, validation.NewValidationError(fmt.Sprintf("hostname %q matches %d agents (%s); use agentId instead",
+				hostname, len(matches), strings.Join(ids, ", ")))
+		}
+	}
+	return agentIDs, nil
+}
+
+// ========================================
+// Rolling Deployments
+// ========================================
+
+// DeployProfileToGroup starts a rolling deployment of a profile to an agent group.
+// Agents are deployed in batches; each batch is verified against the live ruleset before
+// the next one starts, and the rollout aborts on the first failed batch.
+func (s *Service) DeployProfileToGroup(ctx context.Context, token string, tenantID, userID uuid.UUID, input *RolloutInput) (*FirewallRollout, error) {
+	profileID, err := uuid.Parse(input.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profileId: %w", err)
+	}
+
+	groupID, err := uuid.Parse(input.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid groupId: %w", err)
+	}
+
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+
+	group, err := s.repo.GetAgentGroupByID(tenantID, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("agent group not found: %w", err)
+	}
+	if len(group.Members) == 0 {
+		return nil, fmt.Errorf("agent group %s has no agents", group.Name)
+	}
+
+	if err := checkProfileEnabled(profile, input.Force); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	agentIDs := make([]uuid.UUID, 0, len(group.Members))
+	for _, member := range group.Members {
+		agentIDs = append(agentIDs, member.AgentID)
+	}
+
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if batchSize > len(agentIDs) {
+		batchSize = len(agentIDs)
+	}
+
+	rollout := &FirewallRollout{
+		TenantID:     tenantID,
+		ProfileID:    profile.ID,
+		GroupID:      group.ID,
+		BatchSize:    batchSize,
+		PauseSeconds: input.PauseSeconds,
+		TotalAgents:  len(agentIDs),
+		TotalBatches: (len(agentIDs) + batchSize - 1) / batchSize,
+		Status:       RolloutStatusPending,
+		ChangeRef:    input.ChangeRef,
+		AgentIDs:     agentIDs,
+		CreatedBy:    userID,
+	}
+
+	if err := s.repo.CreateRollout(rollout); err != nil {
+		return nil, fmt.Errorf("failed to create rollout: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rollout.initiated",
+		ResourceType: "firewall_rollout",
+		ResourceID:   rollout.ID.String(),
+		Details: map[string]interface{}{
+			"profileId":    profile.ID.String(),
+			"profileName":  profile.Name,
+			"groupId":      group.ID.String(),
+			"groupName":    group.Name,
+			"agentCount":   rollout.TotalAgents,
+			"batchSize":    rollout.BatchSize,
+			"pauseSeconds": rollout.PauseSeconds,
+			"changeRef":    rollout.ChangeRef,
+		},
+	})
+
+	// Start async rollout
+	go s.runRollout(rollout, tenantID, userID, token, profile, agentIDs)
+
+	return rollout, nil
+}
+
+// runRollout executes the rolling deployment in background, one batch at a time.
+// Cancelling the rollout or shutting down aborts it before the next batch.
+func (s *Service) runRollout(rollout *FirewallRollout, tenantID, userID uuid.UUID, token string, profile *FirewallProfile, agentIDs []uuid.UUID) {
+	defer logger.RecoverPanic("[Security] Rollout " + rollout.ID.String())
+
+	ctx, cancel := s.startOperation(rollout.ID, 0)
+	defer cancel()
+
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRolloutStarted,
+		tenantID,
+		rollout.ID.String(),
+		map[string]interface{}{
+			"profileId":    profile.ID.String(),
+			"groupId":      rollout.GroupID.String(),
+			"totalBatches": rollout.TotalBatches,
+		},
+	).WithActor(userID))
+
+	// A resumed rollout starts from the agents its earlier runs applied
+	completed := rollout.CompletedAgents
+	for batch := 1; batch <= rollout.TotalBatches; batch++ {
+		start := (batch - 1) * rollout.BatchSize
+		end := start + rollout.BatchSize
+		if end > len(agentIDs) {
+			end = len(agentIDs)
+		}
+		batchAgents := agentIDs[start:end]
+
+		if ctx.Err() != nil {
+			s.abortCancelledRollout(rollout, tenantID, token, profile, batch, completed)
+			return
+		}
+		s.repo.UpdateRolloutProgress(rollout.ID, RolloutStatusRunning, batch, completed,
+			fmt.Sprintf("Deploying batch %d/%d (%d agents)", batch, rollout.TotalBatches, len(batchAgents)))
+
+		failures := s.deployRolloutBatch(rollout, tenantID, userID, token, profile, batchAgents)
+		if len(failures) > 0 {
+			message := fmt.Sprintf("Batch %d/%d failed, rollout aborted: %s", batch, rollout.TotalBatches, strings.Join(failures, "; "))
+			s.repo.UpdateRolloutProgress(rollout.ID, RolloutStatusAborted, batch, completed, message)
+			events.GetEventBus().PublishAsync(events.NewEvent(
+				events.EventFirewallRolloutFailed,
+				tenantID,
+				rollout.ID.String(),
+				map[string]interface{}{
+					"batch":    batch,
+					"failures": failures,
+				},
+			))
+
+			// Audit log for failure
+			s.client.LogAuditAsync(context.Background(), token, csdcore.AuditEntry{
+				Action:       "firewall.rollout.aborted",
+				ResourceType: "firewall_rollout",
+				ResourceID:   rollout.ID.String(),
+				Details: map[string]interface{}{
+					"profileId":       profile.ID.String(),
+					"batch":           batch,
+					"completedAgents": completed,
+					"failures":        failures,
+				},
+			})
+			return
+		}
+		completed += len(batchAgents)
+
+		if batch < rollout.TotalBatches {
+			s.repo.UpdateRolloutProgress(rollout.ID, RolloutStatusRunning, batch, completed,
+				fmt.Sprintf("Batch %d/%d verified, next batch in %ds", batch, rollout.TotalBatches, rollout.PauseSeconds))
+			if rollout.PauseSeconds > 0 {
+				pause := time.NewTimer(time.Duration(rollout.PauseSeconds) * time.Second)
+				select {
+				case <-ctx.Done():
+					pause.Stop()
+				case <-pause.C:
+				}
+			}
+		}
+	}
+
+	s.repo.UpdateRolloutProgress(rollout.ID, RolloutStatusCompleted, rollout.TotalBatches, completed,
+		fmt.Sprintf("All %d agents deployed and verified", completed))
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRolloutCompleted,
+		tenantID,
+		rollout.ID.String(),
+		map[string]interface{}{
+			"profileId":  profile.ID.String(),
+			"agentCount": completed,
+		},
+	))
+
+	// Audit log for success
+	s.client.LogAuditAsync(context.Background(), token, csdcore.AuditEntry{
+		Action:       "firewall.rollout.completed",
+		ResourceType: "firewall_rollout",
+		ResourceID:   rollout.ID.String(),
+		Details: map[string]interface{}{
+			"profileId":  profile.ID.String(),
+			"agentCount": completed,
+		},
+	})
+}
+
+// abortCancelledRollout records a rollout stopped by cancellation before the given batch
+func (s *Service) abortCancelledRollout(rollout *FirewallRollout, tenantID uuid.UUID, token string, profile *FirewallProfile, batch, completed int) {
+	message := fmt.Sprintf("Rollout cancelled before batch %d/%d", batch, rollout.TotalBatches)
+	s.repo.UpdateRolloutProgress(rollout.ID, RolloutStatusAborted, batch-1, completed, message)
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallRolloutFailed,
+		tenantID,
+		rollout.ID.String(),
+		map[string]interface{}{
+			"batch":     batch,
+			"cancelled": true,
+		},
+	))
+
+	s.client.LogAuditAsync(context.Background(), token, csdcore.AuditEntry{
+		Action:       "firewall.rollout.cancelled",
+		ResourceType: "firewall_rollout",
+		ResourceID:   rollout.ID.String(),
+		Details: map[string]interface{}{
+			"profileId":       profile.ID.String(),
+			"batch":           batch,
+			"completedAgents": completed,
+		},
+	})
+}
+
+// deployRolloutBatch deploys a profile to a batch of agents in parallel.
+// Returns one message per agent that failed to deploy or verify.
+func (s *Service) deployRolloutBatch(rollout *FirewallRollout, tenantID, userID uuid.UUID, token string, profile *FirewallProfile, agentIDs []uuid.UUID) []string {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures []string
+	)
+
+	for _, agentID := range agentIDs {
+		wg.Add(1)
+		go func(agentID uuid.UUID) {
+			defer wg.Done()
+			if err := s.deployAndVerify(rollout, tenantID, userID, token, profile, agentID); err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Sprintf("agent %s: %s", agentID, err.Error()))
+				mu.Unlock()
+			}
+		}(agentID)
+	}
+
+	wg.Wait()
+	return failures
+}
+
+// deployAndVerify applies a profile to one agent, then reads back its live ruleset and
+// checks that every deployed statement is present. A cancelled rollout lets its current
+// batch finish; only a shutdown interrupts it.
+func (s *Service) deployAndVerify(rollout *FirewallRollout, tenantID, userID uuid.UUID, token string, profile *FirewallProfile, agentID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(s.lifetime, time.Minute)
+	defer cancel()
+
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return fmt.Errorf("agent capability validation failed: %w", err)
+	}
+
+	deployment := s.newApplyDeployment(ctx, token, tenantID, userID, profile, agentID)
+	deployment.RolloutID = &rollout.ID
+	deployment.ChangeRef = rollout.ChangeRef
+	if err := s.repo.CreateDeployment(deployment); err != nil {
+		return fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	s.runDeployment(deployment.ID, tenantID, token, profile, agentID, true)
+
+	result, err := s.repo.GetDeploymentByID(tenantID, deployment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read deployment result: %w", err)
+	}
+	if result.Status == DeploymentStatusUnknown {
+		return fmt.Errorf("deployment outcome unknown, agent should be re-audited: %s", result.StatusMessage)
+	}
+	if result.Status != DeploymentStatusApplied {
+		return fmt.Errorf("deployment failed: %s", result.StatusMessage)
+	}
+	// The live ruleset must hold every deployed statement before the rollout moves on
+	if !result.Verified {
+		return fmt.Errorf("verification failed: %s", result.StatusMessage)
+	}
+
+	return nil
+}
+
+// CancelRollout aborts a pending or running rollout. A running rollout stops before its
+// next batch (the current batch completes); one with no running worker is aborted directly.
+// Cancelled rollouts can be resumed like any aborted rollout.
+func (s *Service) CancelRollout(ctx context.Context, token string, tenantID, id uuid.UUID) (*FirewallRollout, error) {
+	rollout, err := s.repo.GetRolloutByID(tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("rollout not found: %w", err)
+	}
+	if rollout.Status != RolloutStatusPending && rollout.Status != RolloutStatusRunning {
+		return nil, validation.NewValidationError(fmt.Sprintf("only pending or running rollouts can be cancelled (rollout is %s)", rollout.Status))
+	}
+
+	s.opsMu.Lock()
+	cancel, running := s.operations[id]
+	s.opsMu.Unlock()
+
+	if running {
+		cancel()
+	} else if err := s.repo.UpdateRolloutProgress(id, RolloutStatusAborted, rollout.CurrentBatch, rollout.CompletedAgents, "Rollout cancelled"); err != nil {
+		return nil, err
+	}
+
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rollout.cancel_requested",
+		ResourceType: "firewall_rollout",
+		ResourceID:   id.String(),
+		Details: map[string]interface{}{
+			"running": running,
+		},
+	})
+
+	return s.repo.GetRolloutByID(tenantID, id)
+}
+
+// ResumeRollout re-runs an aborted rollout for the members it has not applied yet.
+// Members are those recorded when the rollout started, so later group changes are ignored.
+func (s *Service) ResumeRollout(ctx context.Context, token string, tenantID, userID, rolloutID uuid.UUID) (*FirewallRollout, error) {
+	rollout, err := s.repo.GetRolloutByID(tenantID, rolloutID)
+	if err != nil {
+		return nil, fmt.Errorf("rollout not found: %w", err)
+	}
+	if rollout.Status != RolloutStatusAborted {
+		return nil, validation.NewValidationError(fmt.Sprintf("only aborted rollouts can be resumed (rollout is %s)", rollout.Status))
+	}
+
+	members := rollout.AgentIDs
+	if len(members) == 0 {
+		// Rollouts created before membership was recorded fall back to the current group
+		group, err := s.repo.GetAgentGroupByID(tenantID, rollout.GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("agent group not found: %w", err)
+		}
+		for _, member := range group.Members {
+			members = append(members, member.AgentID)
+		}
+	}
+
+	applied, err := s.repo.GetAppliedRolloutAgents(tenantID, rollout.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rollout deployments: %w", err)
+	}
+	appliedSet := make(map[uuid.UUID]bool, len(applied))
+	for _, agentID := range applied {
+		appliedSet[agentID] = true
+	}
+	var remaining []uuid.UUID
+	for _, agentID := range members {
+		if !appliedSet[agentID] {
+			remaining = append(remaining, agentID)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil, validation.NewValidationError("every agent of the rollout has already been applied")
+	}
+
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, rollout.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := checkProfileEnabled(profile, false); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	rollout.AgentIDs = members
+	rollout.TotalAgents = len(members)
+	rollout.TotalBatches = (len(remaining) + rollout.BatchSize - 1) / rollout.BatchSize
+	rollout.CurrentBatch = 0
+	rollout.CompletedAgents = len(members) - len(remaining)
+	rollout.Status = RolloutStatusPending
+	rollout.StatusMessage = fmt.Sprintf("Resuming %d of %d agents", len(remaining), len(members))
+	rollout.CompletedAt = nil
+	rollout.ResumeCount++
+	if err := s.repo.UpdateRollout(rollout); err != nil {
+		return nil, fmt.Errorf("failed to update rollout: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.rollout.resumed",
+		ResourceType: "firewall_rollout",
+		ResourceID:   rollout.ID.String(),
+		Details: map[string]interface{}{
+			"profileId":      profile.ID.String(),
+			"remainingCount": len(remaining),
+			"appliedCount":   rollout.CompletedAgents,
+			"resumeCount":    rollout.ResumeCount,
+			"changeRef":      rollout.ChangeRef,
+		},
+	})
+
+	go s.runRollout(rollout, tenantID, userID, token, profile, remaining)
+
+	return rollout, nil
+}
+
+// RetryDeployment starts a fresh attempt of a failed APPLY deployment with the profile's
+// current rules; the new deployment is linked to the original through RetryOfID
+func (s *Service) RetryDeployment(ctx context.Context, token string, tenantID, userID, deploymentID uuid.UUID) (*FirewallDeployment, error) {
+	original, err := s.repo.GetDeploymentByID(tenantID, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("deployment not found: %w", err)
+	}
+	if original.Action != DeploymentActionApply || original.ProfileID == nil {
+		return nil, validation.NewValidationError("only APPLY deployments can be retried")
+	}
+	if original.Status != DeploymentStatusError && original.Status != DeploymentStatusUnknown {
+		return nil, validation.NewValidationError(fmt.Sprintf("only failed deployments can be retried (deployment is %s)", original.Status))
+	}
+
+	if err := s.client.ValidateAgentCapability(ctx, token, original.AgentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent capability validation failed: %w", err)
+	}
+
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, *original.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if err := checkProfileEnabled(profile, false); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	deployment := s.newApplyDeployment(ctx, token, tenantID, userID, profile, original.AgentID)
+	deployment.RetryOfID = &original.ID
+	deployment.ChangeRef = original.ChangeRef
+	deployment.Verify = original.Verify
+	if err := s.repo.CreateDeployment(deployment); err != nil {
+		return nil, fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.deployment.retried",
+		ResourceType: "firewall_deployment",
+		ResourceID:   deployment.ID.String(),
+		Details: map[string]interface{}{
+			"retryOfId":   original.ID.String(),
+			"profileId":   profile.ID.String(),
+			"profileName": profile.Name,
+			"agentId":     original.AgentID.String(),
+			"agentName":   deployment.AgentName,
+			"changeRef":   deployment.ChangeRef,
+		},
+	})
+
+	go s.runDeployment(deployment.ID, tenantID, token, profile, original.AgentID, deployment.Verify)
+
+	return deployment, nil
+}
+
+// RestoreDeployment re-applies the rules snapshot of a past successful APPLY deployment to its agent,
+// using the profile's current settings. The new deployment is linked to the source through RestoredFrom.
+func (s *Service) RestoreDeployment(ctx context.Context, token string, tenantID, userID, deploymentID uuid.UUID) (*FirewallDeployment, error) {
+	source, err := s.repo.GetDeploymentByID(tenantID, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("deployment not found: %w", err)
+	}
+	if source.Action != DeploymentActionApply || source.ProfileID == nil {
+		return nil, validation.NewValidationError("only APPLY deployments can be restored")
+	}
+	if source.Status != DeploymentStatusApplied {
+		return nil, validation.NewValidationError(fmt.Sprintf("only applied deployments can be restored (deployment is %s)", source.Status))
+	}
+	if source.RulesSnapshot == "" {
+		return nil, validation.NewValidationError("deployment has no rules snapshot to restore")
+	}
+	var rules []FirewallRule
+	if err := json.Unmarshal([]byte(source.RulesSnapshot), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode rules snapshot: %w", err)
+	}
+
+	if err := s.client.ValidateAgentCapability(ctx, token, source.AgentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent capability validation failed: %w", err)
+	}
+
+	profile, err := s.repo.GetProfileByID(tenantID, *source.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	profile.Rules = rules
+	if err := checkProfileEnabled(profile, false); err != nil {
+		return nil, err
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		return nil, err
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		return nil, err
+	}
+
+	deployment := s.newApplyDeployment(ctx, token, tenantID, userID, profile, source.AgentID)
+	deployment.RestoredFrom = &source.ID
+	deployment.ChangeRef = source.ChangeRef
+	deployment.Verify = source.Verify
+	if err := s.repo.CreateDeployment(deployment); err != nil {
+		return nil, fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.deployment.restored",
+		ResourceType: "firewall_deployment",
+		ResourceID:   deployment.ID.String(),
+		Details: map[string]interface{}{
+			"restoredFrom": source.ID.String(),
+			"profileId":    profile.ID.String(),
+			"profileName":  profile.Name,
+			"agentId":      source.AgentID.String(),
+			"agentName":    deployment.AgentName,
+			"ruleCount":    len(rules),
+			"changeRef":    deployment.ChangeRef,
+		},
+	})
+
+	go s.runDeployment(deployment.ID, tenantID, token, profile, source.AgentID, deployment.Verify)
+
+	return deployment, nil
+}
+
+// GetRollout retrieves a rollout by ID
+func (s *Service) GetRollout(ctx context.Context, tenantID, id uuid.UUID) (*FirewallRollout, error) {
+	return s.repo.GetRolloutByID(tenantID, id)
+}
+
+// ListRollouts retrieves rollouts for a tenant
+func (s *Service) ListRollouts(ctx context.Context, tenantID uuid.UUID, profileID *uuid.UUID, limit, offset int) ([]FirewallRollout, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallRollouts, limit, offset)
+	return s.repo.ListRollouts(tenantID, profileID, p.Limit, p.Offset)
+}
+
+// ========================================
+// Import/Export Functionality
+// ========================================
+
+// profileExportSchemaVersion is the export format written by ExportProfile
+const profileExportSchemaVersion = "1.0"
+
+// profileExportMigration upgrades an import payload from one export format version to the next
+type profileExportMigration struct {
+	from    string
+	to      string
+	migrate func(payload map[string]interface{})
+}
+
+// profileExportMigrations upgrade older payloads step by step to profileExportSchemaVersion
+var profileExportMigrations = []profileExportMigration{
+	// Exports written before versioning already have the 1.0 shape
+	{from: "", to: "1.0", migrate: func(map[string]interface{}) {}},
+}
+
+// compareSchemaVersions compares two MAJOR.MINOR export format versions
+func compareSchemaVersions(a, b string) (int, error) {
+	var aMajor, aMinor, bMajor, bMinor int
+	if _, err := fmt.Sscanf(a, "%d.%d", &aMajor, &aMinor); err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", a)
+	}
+	if _, err := fmt.Sscanf(b, "%d.%d", &bMajor, &bMinor); err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", b)
+	}
+	if aMajor != bMajor {
+		return aMajor - bMajor, nil
+	}
+	return aMinor - bMinor, nil
+}
+
+// migrateProfileImport upgrades an import payload in place to the current export format.
+// Payloads written by a newer server are rejected rather than imported lossily.
+func migrateProfileImport(payload map[string]interface{}) error {
+	version := ""
+	if raw, ok := payload["schemaVersion"]; ok {
+		if version, ok = raw.(string); !ok {
+			return validation.NewValidationError("schemaVersion must be a string")
+		}
+	}
+
+	if version != "" {
+		cmp, err := compareSchemaVersions(version, profileExportSchemaVersion)
+		if err != nil {
+			return validation.NewValidationError(err.Error())
+		}
+		if cmp > 0 {
+			return validation.NewValidationError(fmt.Sprintf(
+				"export format %s is newer than the supported format %s; upgrade csd-pilote to import it",
+				version, profileExportSchemaVersion))
+		}
+	}
+
+	for _, m := range profileExportMigrations {
+		if m.from == version {
+			m.migrate(payload)
+			version = m.to
+		}
+	}
+	if version != profileExportSchemaVersion {
+		return validation.NewValidationError(fmt.Sprintf("export format %s is not supported", version))
+	}
+	payload["schemaVersion"] = version
+	return nil
+}
+
+// ExportProfile exports a profile with its rules to JSON format
+func (s *Service) ExportProfile(ctx context.Context, token string, tenantID, profileID uuid.UUID, groupIDs []uuid.UUID, exportedBy string) (*ProfileExport, error) {
+	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+
+	// Convert rules to template rule definitions
+	rules := make([]TemplateRuleDefinition, 0, len(profile.Rules))
+	for _, rule := range profile.Rules {
+		rules = append(rules, TemplateRuleDefinition{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Chain:       rule.Chain,
+			Priority:    rule.Priority,
+			Protocol:    rule.Protocol,
+			SourceIP:    rule.SourceIP,
+			SourcePort:  rule.SourcePort,
+			DestIP:      rule.DestIP,
+			DestPort:    rule.DestPort,
+			Action:      rule.Action,
+			Comment:     rule.Comment,
+
+			NegateProtocol:   rule.NegateProtocol,
+			NegateSourcePort: rule.NegateSourcePort,
+			NegateDestPort:   rule.NegateDestPort,
+
+			ConnLimitCount: rule.ConnLimitCount,
+			ConnLimitMask:  rule.ConnLimitMask,
+		})
+	}
+
+	// Bundle the referenced IP sets and requested target groups so the export is portable
+	sets, err := s.repo.GetIPSetsByNames(tenantID, definitionIPSetNames(rules))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IP sets: %w", err)
+	}
+	ipSets := make([]IPSetExport, 0, len(sets))
+	for _, set := range sets {
+		ipSets = append(ipSets, IPSetExport{Name: set.Name, Description: set.Description, Elements: set.Elements})
+	}
+	agentGroups, err := s.exportAgentGroups(ctx, token, tenantID, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &ProfileExport{
+		SchemaVersion: profileExportSchemaVersion,
+		Name:          profile.Name,
+		Description:   profile.Description,
+		Rules:         rules,
+		IPSets:        ipSets,
+		AgentGroups:   agentGroups,
+		ExportedAt:    time.Now().Format(time.RFC3339),
+		ExportedBy:    exportedBy,
+		AppVersion:    config.AppVersion,
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.profile.exported",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
+		Details: map[string]interface{}{
+			"name":            profile.Name,
+			"ruleCount":       len(rules),
+			"ipSetCount":      len(ipSets),
+			"agentGroupCount": len(agentGroups),
+		},
+	})
+
+	return export, nil
 }
 
-// ruleToNft converts a FirewallRule to nftables syntax
-func (s *Service) ruleToNft(rule FirewallRule) string {
-	// If raw expression is provided, use it directly
-	if rule.RuleExpr != "" {
-		return fmt.Sprintf("%s # %s", rule.RuleExpr, rule.Name)
+// exportAgentGroups converts agent groups for an export, listing members by hostname.
+// Members no longer registered in csd-core are left out.
+func (s *Service) exportAgentGroups(ctx context.Context, token string, tenantID uuid.UUID, groupIDs []uuid.UUID) ([]AgentGroupExport, error) {
+	exports := make([]AgentGroupExport, 0, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return exports, nil
 	}
 
-	var parts []string
-
-	// Interface matching
-	if rule.InInterface != "" {
-		parts = append(parts, fmt.Sprintf("iif %s", rule.InInterface))
-	}
-	if rule.OutInterface != "" {
-		parts = append(parts, fmt.Sprintf("oif %s", rule.OutInterface))
+	agents, err := s.client.ListAgents(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
 	}
-
-	// Connection tracking state
-	if rule.CTState != "" {
-		parts = append(parts, fmt.Sprintf("ct state %s", strings.ToLower(rule.CTState)))
+	hostnames := make(map[uuid.UUID]string, len(agents))
+	for _, agent := range agents {
+		hostnames[agent.ID] = agent.Hostname
 	}
 
-	// Protocol
-	if rule.Protocol != "" && rule.Protocol != RuleProtocolAll {
-		proto := strings.ToLower(string(rule.Protocol))
-		parts = append(parts, fmt.Sprintf("ip protocol %s", proto))
+	for _, groupID := range groupIDs {
+		group, err := s.repo.GetAgentGroupByID(tenantID, groupID)
+		if err != nil {
+			return nil, fmt.Errorf("agent group not found: %w", err)
+		}
+		export := AgentGroupExport{Name: group.Name, Description: group.Description, AgentHostnames: []string{}}
+		for _, member := range group.Members {
+			if hostname := hostnames[member.AgentID]; hostname != "" {
+				export.AgentHostnames = append(export.AgentHostnames, hostname)
+			}
+		}
+		exports = append(exports, export)
 	}
+	return exports, nil
+}
 
-	// Source IP
-	if rule.SourceIP != "" {
-		parts = append(parts, fmt.Sprintf("ip saddr %s", rule.SourceIP))
+// definitionIPSetNames returns the sorted names of the IP sets referenced by rule definitions
+func definitionIPSetNames(defs []TemplateRuleDefinition) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, def := range defs {
+		for _, addr := range []string{def.SourceIP, def.DestIP} {
+			if name, ok := ipSetReference(addr); ok && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Destination IP
-	if rule.DestIP != "" {
-		parts = append(parts, fmt.Sprintf("ip daddr %s", rule.DestIP))
-	}
+// validateImportBundle checks the IP sets and agent groups of an import and that every
+// IP set referenced by its rules is part of the bundle
+func validateImportBundle(input *ProfileImportInput) *validation.ValidationErrors {
+	errs := &validation.ValidationErrors{}
 
-	// Source port (requires TCP or UDP)
-	if rule.SourcePort != "" {
-		proto := strings.ToLower(string(rule.Protocol))
-		if proto == "tcp" || proto == "udp" {
-			parts = append(parts, fmt.Sprintf("%s sport %s", proto, rule.SourcePort))
-		} else {
-			parts = append(parts, fmt.Sprintf("th sport %s", rule.SourcePort))
+	bundled := make(map[string]bool, len(input.IPSets))
+	for _, set := range input.IPSets {
+		if bundled[set.Name] {
+			errs.Add("ipSets.name", fmt.Sprintf("IP set %s appears more than once", set.Name), "DUPLICATE_IP_SET")
+			continue
+		}
+		bundled[set.Name] = true
+		err := validateIPSetInput(&FirewallIPSetInput{Name: set.Name, Elements: set.Elements}, true)
+		var setErrs *validation.ValidationErrors
+		if errors.As(err, &setErrs) {
+			for _, e := range setErrs.Errors {
+				errs.Add("ipSets."+e.Field, fmt.Sprintf("IP set %s: %s", set.Name, e.Message), e.Code)
+			}
 		}
 	}
 
-	// Destination port (requires TCP or UDP)
-	if rule.DestPort != "" {
-		proto := strings.ToLower(string(rule.Protocol))
-		if proto == "tcp" || proto == "udp" {
-			parts = append(parts, fmt.Sprintf("%s dport %s", proto, rule.DestPort))
-		} else {
-			parts = append(parts, fmt.Sprintf("th dport %s", rule.DestPort))
+	for _, name := range definitionIPSetNames(input.Rules) {
+		if !bundled[name] {
+			errs.Add("ipSets", fmt.Sprintf("rules reference IP set %s, which is not in the bundle", name), "MISSING_IP_SET")
 		}
 	}
 
-	// Rate limiting
-	if rule.RateLimit != "" {
-		limitExpr := fmt.Sprintf("limit rate %s", rule.RateLimit)
-		if rule.RateBurst > 0 {
-			limitExpr += fmt.Sprintf(" burst %d packets", rule.RateBurst)
+	groupNames := make(map[string]bool, len(input.AgentGroups))
+	for _, group := range input.AgentGroups {
+		if group.Name == "" {
+			errs.Add("agentGroups.name", "agent group name is required", "REQUIRED")
+		} else if groupNames[group.Name] {
+			errs.Add("agentGroups.name", fmt.Sprintf("agent group %s appears more than once", group.Name), "DUPLICATE_AGENT_GROUP")
 		}
-		parts = append(parts, limitExpr)
+		groupNames[group.Name] = true
 	}
 
-	// Action
-	action := s.actionToNft(rule)
-	parts = append(parts, action)
-
-	// Comment
-	if rule.Comment != "" {
-		// Escape quotes in comment
-		comment := strings.ReplaceAll(rule.Comment, "\"", "\\\"")
-		parts = append(parts, fmt.Sprintf("comment \"%s\"", comment))
+	if errs.HasErrors() {
+		return errs
 	}
-
-	return fmt.Sprintf("%s # %s", joinParts(parts), rule.Name)
+	return nil
 }
 
-// actionToNft converts a rule action to nftables syntax
-func (s *Service) actionToNft(rule FirewallRule) string {
-	switch rule.Action {
-	case RuleActionAccept:
-		return "accept"
-	case RuleActionDrop:
-		return "drop"
-	case RuleActionReject:
-		return "reject"
-	case RuleActionLog:
-		logExpr := "log"
-		if rule.LogPrefix != "" {
-			logExpr += fmt.Sprintf(" prefix \"%s\"", rule.LogPrefix)
+// importIPSetPlan splits the bundled IP sets into sets to create and sets already present
+// with the same elements. A same-named set with different elements is a conflict.
+func (s *Service) importIPSetPlan(tenantID uuid.UUID, sets []IPSetExport) (create []IPSetExport, reused []string, err error) {
+	names := make([]string, 0, len(sets))
+	for _, set := range sets {
+		names = append(names, set.Name)
+	}
+	existing, err := s.repo.GetIPSetsByNames(tenantID, names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load IP sets: %w", err)
+	}
+	byName := make(map[string]FirewallIPSet, len(existing))
+	for _, set := range existing {
+		byName[set.Name] = set
+	}
+
+	for _, set := range sets {
+		current, ok := byName[set.Name]
+		if !ok {
+			create = append(create, set)
+			continue
 		}
-		if rule.LogLevel != "" {
-			logExpr += fmt.Sprintf(" level %s", rule.LogLevel)
+		if !sameIPSetElements(current.Elements, set.Elements) {
+			return nil, nil, validation.NewValidationError(fmt.Sprintf("IP set %s already exists with different elements", set.Name))
 		}
-		return logExpr
-	case RuleActionMasquerade:
-		return "masquerade"
-	case RuleActionSnat:
-		if rule.NatToAddr != "" {
-			return fmt.Sprintf("snat to %s", rule.NatToAddr)
+		reused = append(reused, set.Name)
+	}
+	return create, reused, nil
+}
+
+// validateImportRules checks every imported rule, listing each problem under the rule's index
+func validateImportRules(tenantID, userID uuid.UUID, defs []TemplateRuleDefinition) *validation.ValidationErrors {
+	errs := &validation.ValidationErrors{}
+	for i, def := range defs {
+		err := validateRuleSemantics(newRuleFromDefinition(tenantID, userID, def))
+		if err == nil {
+			continue
 		}
-		return "snat"
-	case RuleActionDnat:
-		target := ""
-		if rule.NatToAddr != "" {
-			target = rule.NatToAddr
-			if rule.NatToPort != "" {
-				target += ":" + rule.NatToPort
+		for _, e := range importRuleErrors(i, def.Name, err) {
+			field := fmt.Sprintf("rules[%d]", i)
+			if e.Field != "" {
+				field += "." + strings.TrimPrefix(e.Field, "rules.")
 			}
-			return fmt.Sprintf("dnat to %s", target)
-		}
-		return "dnat"
-	case RuleActionRedirect:
-		if rule.NatToPort != "" {
-			return fmt.Sprintf("redirect to :%s", rule.NatToPort)
+			errs.Add(field, e.Message, e.Code)
 		}
-		return "redirect"
-	default:
-		return "accept"
 	}
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
 }
 
-func joinParts(parts []string) string {
-	return strings.Join(parts, " ")
-}
-
-// RollbackDeployment rolls back a deployment using nftables_rollback playbook
-func (s *Service) RollbackDeployment(ctx context.Context, token string, tenantID, userID, deploymentID uuid.UUID) (*FirewallDeployment, error) {
-	originalDeployment, err := s.repo.GetDeploymentByID(tenantID, deploymentID)
-	if err != nil {
-		return nil, fmt.Errorf("deployment not found: %w", err)
+// ImportProfile imports a profile from JSON format. The import is all-or-nothing: every rule
+// is validated up front, and if a rule still cannot be persisted everything created is removed.
+func (s *Service) ImportProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *ProfileImportInput) (*FirewallProfile, error) {
+	if input.Name == "" {
+		return nil, fmt.Errorf("profile name is required")
 	}
-
-	// Create rollback deployment record
-	rollback := &FirewallDeployment{
-		TenantID:      tenantID,
-		ProfileID:     originalDeployment.ProfileID,
-		AgentID:       originalDeployment.AgentID,
-		AgentName:     originalDeployment.AgentName,
-		Action:        DeploymentActionRollback,
-		Status:        DeploymentStatusPending,
-		CreatedBy:     userID,
+	if errs := validateImportRules(tenantID, userID, input.Rules); errs != nil {
+		return nil, errs
 	}
-
-	if err := s.repo.CreateDeployment(rollback); err != nil {
-		return nil, fmt.Errorf("failed to create rollback record: %w", err)
+	if errs := validateImportBundle(input); errs != nil {
+		return nil, errs
 	}
 
-	// Execute rollback asynchronously
-	go s.runRollback(rollback.ID, tenantID, token, originalDeployment.AgentID)
-
-	return rollback, nil
-}
-
-// runRollback executes the rollback in background
-func (s *Service) runRollback(rollbackID, tenantID uuid.UUID, token string, agentID uuid.UUID) {
-	// Use timeout to prevent goroutine leaks (2 minutes max for rollback)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusDeploying, "Rolling back firewall rules...", "")
-
-	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallRollbackStarted,
-		tenantID,
-		rollbackID.String(),
-		map[string]interface{}{"agentId": agentID.String()},
-	))
-
-	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
-		AgentID: agentID,
-		Task: csdcore.TaskInput{
-			Type: "nftables",
-			Name: "nftables-rollback",
-			Config: map[string]interface{}{
-				"action": "rollback",
-			},
-		},
-		Wait:    true,
-		Timeout: 60,
-	})
+	// Resolve everything that can fail before persisting anything
+	createSets, reusedSets, err := s.importIPSetPlan(tenantID, input.IPSets)
 	if err != nil {
-		s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusError, "Failed to execute rollback: "+err.Error(), "")
-		return
+		return nil, err
 	}
-
-	output := ""
-	if execution.Output != nil {
-		if s, ok := execution.Output.(string); ok {
-			output = s
+	groupNames := make([]string, 0, len(input.AgentGroups))
+	for _, group := range input.AgentGroups {
+		groupNames = append(groupNames, group.Name)
+	}
+	existingGroups, err := s.repo.GetAgentGroupsByNames(tenantID, groupNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent groups: %w", err)
+	}
+	existingGroupNames := make(map[string]bool, len(existingGroups))
+	for _, group := range existingGroups {
+		existingGroupNames[group.Name] = true
+	}
+	groupAgents := make(map[string][]uuid.UUID)
+	for _, group := range input.AgentGroups {
+		if existingGroupNames[group.Name] || len(group.AgentHostnames) == 0 {
+			continue
+		}
+		agentIDs, err := s.resolveAgentHostnames(ctx, token, group.AgentHostnames)
+		if err != nil {
+			return nil, fmt.Errorf("agent group %s: %w", group.Name, err)
 		}
+		groupAgents[group.Name] = agentIDs
 	}
 
-	if execution.Status != "SUCCESS" {
-		s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusError, "Rollback failed: "+execution.Error, output)
-		return
+	createdSetIDs := make([]uuid.UUID, 0, len(createSets))
+	var createdGroupIDs []uuid.UUID
+	rollback := func(profileID uuid.UUID, ruleIDs []uuid.UUID) {
+		for _, id := range createdGroupIDs {
+			s.repo.DeleteAgentGroup(tenantID, id)
+		}
+		if len(ruleIDs) > 0 {
+			s.repo.BulkDeleteRules(tenantID, ruleIDs)
+		}
+		if profileID != uuid.Nil {
+			s.repo.DeleteProfile(tenantID, profileID)
+		}
+		for _, id := range createdSetIDs {
+			s.repo.DeleteIPSet(tenantID, id)
+		}
 	}
 
-	s.repo.UpdateDeploymentStatus(rollbackID, DeploymentStatusRolledBack, "Firewall rules rolled back successfully", output)
-	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallRollbackCompleted,
-		tenantID,
-		rollbackID.String(),
-		map[string]interface{}{"agentId": agentID.String()},
-	))
-}
+	for _, set := range createSets {
+		created, err := s.CreateIPSet(ctx, token, tenantID, userID, &FirewallIPSetInput{
+			Name:        set.Name,
+			Description: set.Description,
+			Elements:    set.Elements,
+		})
+		if err != nil {
+			rollback(uuid.Nil, nil)
+			return nil, err
+		}
+		createdSetIDs = append(createdSetIDs, created.ID)
+	}
 
-// AuditDeployment audits the current firewall state on an agent
-func (s *Service) AuditDeployment(ctx context.Context, token string, tenantID, userID uuid.UUID, agentID uuid.UUID) (*FirewallDeployment, error) {
-	// Get agent name
-	agentName := "Unknown"
-	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
-		agentName = agent.Name
+	// Create the profile
+	profile := &FirewallProfile{
+		TenantID:    tenantID,
+		Name:        input.Name,
+		Description: input.Description,
+		IsDefault:   false,
+		Enabled:     true,
+		CreatedBy:   userID,
 	}
 
-	audit := &FirewallDeployment{
-		TenantID:  tenantID,
-		AgentID:   agentID,
-		AgentName: agentName,
-		Action:    DeploymentActionAudit,
-		Status:    DeploymentStatusPending,
-		CreatedBy: userID,
+	if err := s.repo.CreateProfile(profile); err != nil {
+		rollback(uuid.Nil, nil)
+		return nil, fmt.Errorf("failed to create profile: %w", err)
 	}
 
-	if err := s.repo.CreateDeployment(audit); err != nil {
-		return nil, fmt.Errorf("failed to create audit record: %w", err)
+	// Create rules from import and add to profile; one rule that cannot be stored undoes the import
+	ruleIDs, failures := s.createRulesFromDefinitions(tenantID, userID, input.Rules)
+	if len(failures) > 0 {
+		rollback(profile.ID, ruleIDs)
+		return nil, fmt.Errorf("rule %d (%s) could not be created: %s", failures[0].Index, failures[0].Name, failures[0].Error)
 	}
 
-	// Execute audit asynchronously
-	go s.runAudit(audit.ID, tenantID, token, agentID)
+	// Add rules to profile (tenantID for validation)
+	if len(ruleIDs) > 0 {
+		if err := s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs); err != nil {
+			rollback(profile.ID, ruleIDs)
+			return nil, fmt.Errorf("failed to add rules to profile: %w", err)
+		}
+	}
 
-	return audit, nil
-}
+	// Target groups that do not exist yet in this tenant
+	for _, group := range input.AgentGroups {
+		if existingGroupNames[group.Name] {
+			continue
+		}
+		agentIDs := make([]string, 0, len(groupAgents[group.Name]))
+		for _, agentID := range groupAgents[group.Name] {
+			agentIDs = append(agentIDs, agentID.String())
+		}
+		created, err := s.CreateAgentGroup(ctx, token, tenantID, userID, &FirewallAgentGroupInput{
+			Name:        group.Name,
+			Description: group.Description,
+			AgentIDs:    agentIDs,
+		})
+		if err != nil {
+			rollback(profile.ID, ruleIDs)
+			return nil, err
+		}
+		createdGroupIDs = append(createdGroupIDs, created.ID)
+	}
 
-// runAudit executes the audit in background
-func (s *Service) runAudit(auditID, tenantID uuid.UUID, token string, agentID uuid.UUID) {
-	// Use timeout to prevent goroutine leaks (2 minutes max for audit)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
+	// Reload profile with rules
+	profile, _ = s.repo.GetProfileByIDWithRules(tenantID, profile.ID)
 
-	s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusDeploying, "Auditing firewall rules...", "")
+	events.GetEventBus().PublishAsync(events.NewEvent(
+		events.EventFirewallProfileCreated,
+		tenantID,
+		profile.ID.String(),
+		map[string]interface{}{
+			"name":     profile.Name,
+			"imported": true,
+		},
+	).WithActor(userID))
 
-	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
-		AgentID: agentID,
-		Task: csdcore.TaskInput{
-			Type: "nftables",
-			Name: "nftables-audit",
-			Config: map[string]interface{}{
-				"action": "audit",
-			},
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.profile.imported",
+		ResourceType: "firewall_profile",
+		ResourceID:   profile.ID.String(),
+		Details: map[string]interface{}{
+			"name":               profile.Name,
+			"rulesCreated":       len(ruleIDs),
+			"ipSetsCreated":      len(createSets),
+			"ipSetsReused":       reusedSets,
+			"agentGroupsCreated": len(createdGroupIDs),
 		},
-		Wait:    true,
-		Timeout: 60,
 	})
-	if err != nil {
-		s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusError, "Failed to execute audit: "+err.Error(), "")
-		return
-	}
 
-	output := ""
-	if execution.Output != nil {
-		if s, ok := execution.Output.(string); ok {
-			output = s
+	return profile, nil
+}
+
+// ruleCreateAttempts bounds retries of a rule insert during bulk creation
+const ruleCreateAttempts = 3
+
+// createRulesFromDefinitions creates one rule per definition, retrying transient failures.
+// Returns the created rule IDs in order and the definitions that still failed.
+func (s *Service) createRulesFromDefinitions(tenantID, userID uuid.UUID, defs []TemplateRuleDefinition) ([]uuid.UUID, []RuleCreationFailure) {
+	ruleIDs := make([]uuid.UUID, 0, len(defs))
+	var failures []RuleCreationFailure
+
+	for i, def := range defs {
+		// Semantic errors are permanent; only persistence is retried
+		if err := validateRuleSemantics(newRuleFromDefinition(tenantID, userID, def)); err != nil {
+			failures = append(failures, RuleCreationFailure{Index: i, Name: def.Name, Error: err.Error()})
+			continue
 		}
-	}
 
-	if execution.Status != "SUCCESS" {
-		s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusError, "Audit failed: "+execution.Error, output)
-		return
+		var err error
+		for attempt := 1; attempt <= ruleCreateAttempts; attempt++ {
+			rule := newRuleFromDefinition(tenantID, userID, def)
+			if err = s.repo.CreateRule(rule); err == nil {
+				ruleIDs = append(ruleIDs, rule.ID)
+				break
+			}
+			if attempt < ruleCreateAttempts {
+				time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+			}
+		}
+		if err != nil {
+			failures = append(failures, RuleCreationFailure{Index: i, Name: def.Name, Error: err.Error()})
+		}
 	}
 
-	s.repo.UpdateDeploymentStatus(auditID, DeploymentStatusApplied, "Audit completed successfully", output)
+	return ruleIDs, failures
 }
 
-// FlushRules flushes all firewall rules on an agent
-func (s *Service) FlushRules(ctx context.Context, token string, tenantID, userID uuid.UUID, agentID uuid.UUID) (*FirewallDeployment, error) {
-	// Get agent name
-	agentName := "Unknown"
-	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
-		agentName = agent.Name
+// newRuleFromDefinition builds an unsaved, enabled rule from an imported rule definition
+func newRuleFromDefinition(tenantID, userID uuid.UUID, def TemplateRuleDefinition) *FirewallRule {
+	return &FirewallRule{
+		TenantID:    tenantID,
+		Name:        def.Name,
+		Description: def.Description,
+		Chain:       def.Chain,
+		Priority:    def.Priority,
+		Protocol:    normalizeRuleProtocol(string(def.Protocol)),
+		SourceIP:    def.SourceIP,
+		SourcePort:  def.SourcePort,
+		DestIP:      def.DestIP,
+		DestPort:    def.DestPort,
+		Action:      def.Action,
+		Comment:     def.Comment,
+		Enabled:     true,
+		CreatedBy:   userID,
+
+		NegateProtocol:   def.NegateProtocol,
+		NegateSourcePort: def.NegateSourcePort,
+		NegateDestPort:   def.NegateDestPort,
+
+		ConnLimitCount: def.ConnLimitCount,
+		ConnLimitMask:  def.ConnLimitMask,
 	}
+}
 
-	flush := &FirewallDeployment{
-		TenantID:  tenantID,
-		AgentID:   agentID,
-		AgentName: agentName,
-		Action:    DeploymentActionFlush,
-		Status:    DeploymentStatusPending,
-		CreatedBy: userID,
+// ValidateProfileImport checks an import the same way ImportProfile would build it,
+// without persisting anything, and summarizes the rules it contains
+func (s *Service) ValidateProfileImport(ctx context.Context, tenantID uuid.UUID, input *ProfileImportInput) *ProfileImportValidation {
+	result := &ProfileImportValidation{
+		RuleCount: len(input.Rules),
+		Chains:    make(map[string]int),
+		Actions:   make(map[string]int),
+		Errors:    []ImportRuleError{},
 	}
 
-	if err := s.repo.CreateDeployment(flush); err != nil {
-		return nil, fmt.Errorf("failed to create flush record: %w", err)
+	if input.Name == "" {
+		result.Errors = append(result.Errors, ImportRuleError{
+			Index: -1, Field: "name", Message: "profile name is required", Code: "REQUIRED",
+		})
 	}
 
-	// Execute flush asynchronously
-	go s.runFlush(flush.ID, tenantID, token, agentID)
+	for i, def := range input.Rules {
+		result.Chains[string(def.Chain)]++
+		result.Actions[string(def.Action)]++
 
-	return flush, nil
-}
+		rule := newRuleFromDefinition(tenantID, uuid.Nil, def)
+		if err := validateRuleSemantics(rule); err != nil {
+			result.Errors = append(result.Errors, importRuleErrors(i, def.Name, err)...)
+		}
+	}
 
-// runFlush executes the flush in background
-func (s *Service) runFlush(flushID, tenantID uuid.UUID, token string, agentID uuid.UUID) {
-	// Use timeout to prevent goroutine leaks (2 minutes max for flush)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
+	if errs := validateImportBundle(input); errs != nil {
+		for _, e := range errs.Errors {
+			result.Errors = append(result.Errors, ImportRuleError{
+				Index: -1, Field: e.Field, Message: e.Message, Code: e.Code,
+			})
+		}
+	}
 
-	s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusDeploying, "Flushing firewall rules...", "")
+	result.Valid = len(result.Errors) == 0
+	return result
+}
 
-	execution, err := s.client.ExecuteTask(ctx, token, &csdcore.ExecuteTaskInput{
-		AgentID: agentID,
-		Task: csdcore.TaskInput{
-			Type: "nftables",
-			Name: "nftables-flush",
-			Config: map[string]interface{}{
-				"action":        "flush",
-				"confirm_flush": true,
-			},
-		},
-		Wait:    true,
-		Timeout: 60,
-	})
-	if err != nil {
-		s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusError, "Failed to execute flush: "+err.Error(), "")
-		return
+// importRuleErrors converts a rule validation error into import errors for the rule at index i
+func importRuleErrors(i int, name string, err error) []ImportRuleError {
+	var errs *validation.ValidationErrors
+	if !errors.As(err, &errs) {
+		return []ImportRuleError{{Index: i, Name: name, Message: err.Error()}}
 	}
+	result := make([]ImportRuleError, 0, len(errs.Errors))
+	for _, e := range errs.Errors {
+		result = append(result, ImportRuleError{
+			Index: i, Name: name, Field: "rules." + e.Field, Message: e.Message, Code: e.Code,
+		})
+	}
+	return result
+}
 
-	output := ""
-	if execution.Output != nil {
-		if s, ok := execution.Output.(string); ok {
-			output = s
+// CheckPolicy validates a profile definition in the export/import format, generates its nftables config and,
+// when an agent is designated, checks the config there with "nft -c". Nothing is persisted, so CI pipelines
+// can use it as a policy linter. profileInput optionally carries the profile settings (policies, features).
+func (s *Service) CheckPolicy(ctx context.Context, token string, tenantID uuid.UUID, input *ProfileImportInput, profileInput *FirewallProfileInput, agentID *uuid.UUID) (*PolicyCheckResult, error) {
+	// Running a task on an agent needs the same permission as deploying to it
+	if agentID != nil {
+		allowed, err := s.client.CheckPermission(ctx, token, "csd-pilote.security.deploy")
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permission: %w", err)
+		}
+		if !allowed {
+			return nil, validation.NewForbiddenError("csd-pilote.security.deploy")
 		}
 	}
 
-	if execution.Status != "SUCCESS" {
-		s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusError, "Flush failed: "+execution.Error, output)
-		return
+	result := &PolicyCheckResult{
+		Validation: s.ValidateProfileImport(ctx, tenantID, input),
+		Warnings:   []LintFinding{},
 	}
+	errs := &result.Validation.Errors
 
-	s.repo.UpdateDeploymentStatus(flushID, DeploymentStatusApplied, "Firewall rules flushed successfully", output)
-}
+	if profileInput == nil {
+		profileInput = &FirewallProfileInput{}
+	}
+	if profileInput.Name == "" {
+		profileInput.Name = input.Name
+	}
+	profile := newProfileFromInput(profileInput)
+	for i, def := range input.Rules {
+		rule := newRuleFromDefinition(tenantID, uuid.Nil, def)
+		if err := normalizeRuleRateLimit(rule); err != nil {
+			*errs = append(*errs, importRuleErrors(i, def.Name, err)...)
+		}
+		profile.Rules = append(profile.Rules, *rule)
+		result.Warnings = append(result.Warnings, lintRule(*rule)...)
+	}
+	if err := validateProfileForDeploy(profile); err != nil {
+		*errs = append(*errs, ImportRuleError{Index: -1, Message: err.Error()})
+	}
+	if err := s.resolveProfileIPSets(tenantID, profile); err != nil {
+		*errs = append(*errs, ImportRuleError{Index: -1, Message: err.Error()})
+	}
+	result.Validation.Valid = len(*errs) == 0
+	if !result.Validation.Valid {
+		return result, nil
+	}
 
-// GetDeployment retrieves a deployment by ID
-func (s *Service) GetDeployment(ctx context.Context, tenantID, id uuid.UUID) (*FirewallDeployment, error) {
-	return s.repo.GetDeploymentByID(tenantID, id)
+	result.Config = s.generateNftablesConfigForProfile(profile)
+	result.Passed = true
+	if agentID != nil {
+		result.AgentCheck = s.checkConfigOnAgent(ctx, token, *agentID, profile, result.Config)
+		result.Passed = result.AgentCheck.Passed
+	}
+	return result, nil
 }
 
-// ListDeployments retrieves all deployments for a tenant
-func (s *Service) ListDeployments(ctx context.Context, tenantID uuid.UUID, filter *FirewallDeploymentFilter, limit, offset int) ([]FirewallDeployment, int64, error) {
-	p := pagination.Normalize(limit, offset)
-	return s.repo.ListDeployments(tenantID, filter, p.Limit, p.Offset)
-}
+// checkConfigOnAgent runs "nft -c" on an agent against a generated config without applying it
+func (s *Service) checkConfigOnAgent(ctx context.Context, token string, agentID uuid.UUID, profile *FirewallProfile, nftConfig string) *AgentConfigCheck {
+	check := &AgentConfigCheck{AgentID: agentID}
 
-// CountDeployments returns the total count of deployments
-func (s *Service) CountDeployments(ctx context.Context, tenantID uuid.UUID) (int64, error) {
-	return s.repo.CountDeployments(tenantID)
+	execution, err := s.checkNftConfig(ctx, token, agentID, profile, nftConfig)
+	if errors.Is(err, errNftValidateUnsupported) {
+		check.Error = err.Error() + "; the configuration was not checked"
+		return check
+	}
+	if err != nil {
+		check.Error = "failed to execute check: " + err.Error()
+		return check
+	}
+
+	check.Output = taskOutputString(execution)
+	if execution.Status != "SUCCESS" {
+		check.Error = execution.Error
+		return check
+	}
+	check.Passed = true
+	return check
 }
 
 // ========================================
-// Import/Export Functionality
+// Deployment Schedules
 // ========================================
 
-// ExportProfile exports a profile with its rules to JSON format
-func (s *Service) ExportProfile(ctx context.Context, token string, tenantID, profileID uuid.UUID) (*ProfileExport, error) {
-	profile, err := s.repo.GetProfileByIDWithRules(tenantID, profileID)
+// scheduleCheckInterval is how often the schedule job looks for due schedules
+const scheduleCheckInterval = time.Minute
+
+// nextScheduleRun returns the first run time of a cron expression after from, in UTC
+func nextScheduleRun(cronExpr string, from time.Time) (time.Time, error) {
+	schedule, err := cron.ParseStandard(cronExpr)
+	if err != nil {
+		return time.Time{}, validation.NewValidationError("invalid cronExpr: " + err.Error())
+	}
+	next := schedule.Next(from.UTC())
+	if next.IsZero() {
+		return time.Time{}, validation.NewValidationError("cronExpr never fires")
+	}
+	return next, nil
+}
+
+// CreateSchedule creates a schedule that re-applies a profile to an agent
+func (s *Service) CreateSchedule(ctx context.Context, token string, tenantID, userID uuid.UUID, input *FirewallScheduleInput) (*FirewallSchedule, error) {
+	profileID, err := uuid.Parse(input.ProfileID)
+	if err != nil {
+		return nil, validation.NewValidationError("invalid profileId")
+	}
+	agentID, err := uuid.Parse(input.AgentID)
 	if err != nil {
+		return nil, validation.NewValidationError("invalid agentId")
+	}
+	if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
 		return nil, fmt.Errorf("profile not found: %w", err)
 	}
+	if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+		return nil, fmt.Errorf("agent capability validation failed: %w", err)
+	}
 
-	// Convert rules to template rule definitions
-	rules := make([]TemplateRuleDefinition, 0, len(profile.Rules))
-	for _, rule := range profile.Rules {
-		rules = append(rules, TemplateRuleDefinition{
-			Name:        rule.Name,
-			Description: rule.Description,
-			Chain:       rule.Chain,
-			Priority:    rule.Priority,
-			Protocol:    rule.Protocol,
-			SourceIP:    rule.SourceIP,
-			SourcePort:  rule.SourcePort,
-			DestIP:      rule.DestIP,
-			DestPort:    rule.DestPort,
-			Action:      rule.Action,
-			Comment:     rule.Comment,
-		})
+	schedule := &FirewallSchedule{
+		TenantID:  tenantID,
+		ProfileID: profileID,
+		AgentID:   agentID,
+		AgentName: "Unknown",
+		CronExpr:  input.CronExpr,
+		Enabled:   input.Enabled == nil || *input.Enabled,
+		CreatedBy: userID,
+	}
+	if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
+		schedule.AgentName = agent.Name
+	}
+	if err := s.scheduleNextRun(schedule); err != nil {
+		return nil, err
 	}
 
-	export := &ProfileExport{
-		Name:        profile.Name,
-		Description: profile.Description,
-		Rules:       rules,
-		ExportedAt:  time.Now().Format(time.RFC3339),
+	if err := s.repo.CreateSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
 	}
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.profile.exported",
-		ResourceType: "firewall_profile",
-		ResourceID:   profile.ID.String(),
+		Action:       "firewall.schedule.created",
+		ResourceType: "firewall_schedule",
+		ResourceID:   schedule.ID.String(),
 		Details: map[string]interface{}{
-			"name":      profile.Name,
-			"ruleCount": len(rules),
+			"profileId": profileID.String(),
+			"agentId":   agentID.String(),
+			"cronExpr":  schedule.CronExpr,
+			"enabled":   schedule.Enabled,
 		},
 	})
 
-	return export, nil
+	return schedule, nil
 }
 
-// ImportProfile imports a profile from JSON format
-func (s *Service) ImportProfile(ctx context.Context, token string, tenantID, userID uuid.UUID, input *ProfileImportInput) (*FirewallProfile, error) {
-	if input.Name == "" {
-		return nil, fmt.Errorf("profile name is required")
+// scheduleNextRun sets the next run time of a schedule from now, or clears it while disabled
+func (s *Service) scheduleNextRun(schedule *FirewallSchedule) error {
+	next, err := nextScheduleRun(schedule.CronExpr, time.Now())
+	if err != nil {
+		return err
 	}
-
-	// Create the profile
-	profile := &FirewallProfile{
-		TenantID:    tenantID,
-		Name:        input.Name,
-		Description: input.Description,
-		IsDefault:   false,
-		Enabled:     true,
-		CreatedBy:   userID,
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		schedule.NextRunAt = &next
 	}
+	return nil
+}
 
-	if err := s.repo.CreateProfile(profile); err != nil {
-		return nil, fmt.Errorf("failed to create profile: %w", err)
+// GetSchedule retrieves a deployment schedule by ID
+func (s *Service) GetSchedule(ctx context.Context, tenantID, id uuid.UUID) (*FirewallSchedule, error) {
+	return s.repo.GetScheduleByID(tenantID, id)
+}
+
+// ListSchedules retrieves deployment schedules for a tenant
+func (s *Service) ListSchedules(ctx context.Context, tenantID uuid.UUID, profileID *uuid.UUID, limit, offset int) ([]FirewallSchedule, int64, error) {
+	p := pagination.NormalizeFor(pagination.ResourceFirewallSchedules, limit, offset)
+	return s.repo.ListSchedules(tenantID, profileID, p.Limit, p.Offset)
+}
+
+// UpdateSchedule updates a deployment schedule. Changing the expression or enabling the
+// schedule recomputes its next run from now.
+func (s *Service) UpdateSchedule(ctx context.Context, token string, tenantID, id uuid.UUID, input *FirewallScheduleInput) (*FirewallSchedule, error) {
+	schedule, err := s.repo.GetScheduleByID(tenantID, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create rules from import and add to profile
-	ruleIDs := make([]uuid.UUID, 0, len(input.Rules))
-	for _, ruleDef := range input.Rules {
-		rule := &FirewallRule{
-			TenantID:    tenantID,
-			Name:        ruleDef.Name,
-			Description: ruleDef.Description,
-			Chain:       ruleDef.Chain,
-			Priority:    ruleDef.Priority,
-			Protocol:    ruleDef.Protocol,
-			SourceIP:    ruleDef.SourceIP,
-			SourcePort:  ruleDef.SourcePort,
-			DestIP:      ruleDef.DestIP,
-			DestPort:    ruleDef.DestPort,
-			Action:      ruleDef.Action,
-			Comment:     ruleDef.Comment,
-			Enabled:     true,
-			CreatedBy:   userID,
+	if input.ProfileID != "" {
+		profileID, err := uuid.Parse(input.ProfileID)
+		if err != nil {
+			return nil, validation.NewValidationError("invalid profileId")
 		}
-		if err := s.repo.CreateRule(rule); err != nil {
-			continue // Skip failed rules
+		if _, err := s.repo.GetProfileByID(tenantID, profileID); err != nil {
+			return nil, fmt.Errorf("profile not found: %w", err)
 		}
-		ruleIDs = append(ruleIDs, rule.ID)
+		schedule.ProfileID = profileID
 	}
-
-	// Add rules to profile (tenantID for validation)
-	if len(ruleIDs) > 0 {
-		if err := s.repo.AddRulesToProfile(tenantID, profile.ID, ruleIDs); err != nil {
-			return nil, fmt.Errorf("failed to add rules to profile: %w", err)
+	if input.AgentID != "" {
+		agentID, err := uuid.Parse(input.AgentID)
+		if err != nil {
+			return nil, validation.NewValidationError("invalid agentId")
+		}
+		if agentID != schedule.AgentID {
+			if err := s.client.ValidateAgentCapability(ctx, token, agentID, "nftables"); err != nil {
+				return nil, fmt.Errorf("agent capability validation failed: %w", err)
+			}
+			schedule.AgentID = agentID
+			schedule.AgentName = "Unknown"
+			if agent, err := s.client.GetAgent(ctx, token, agentID); err == nil && agent != nil {
+				schedule.AgentName = agent.Name
+			}
 		}
 	}
 
-	// Reload profile with rules
-	profile, _ = s.repo.GetProfileByIDWithRules(tenantID, profile.ID)
+	reschedule := false
+	if input.CronExpr != "" && input.CronExpr != schedule.CronExpr {
+		schedule.CronExpr = input.CronExpr
+		reschedule = true
+	}
+	if input.Enabled != nil && *input.Enabled != schedule.Enabled {
+		schedule.Enabled = *input.Enabled
+		reschedule = true
+	}
+	if reschedule {
+		if err := s.scheduleNextRun(schedule); err != nil {
+			return nil, err
+		}
+	}
 
-	events.GetEventBus().PublishAsync(events.NewEvent(
-		events.EventFirewallProfileCreated,
-		tenantID,
-		profile.ID.String(),
-		map[string]interface{}{
-			"name":     profile.Name,
-			"imported": true,
-		},
-	))
+	if err := s.repo.UpdateSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
 
 	// Audit logging
 	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
-		Action:       "firewall.profile.imported",
-		ResourceType: "firewall_profile",
-		ResourceID:   profile.ID.String(),
+		Action:       "firewall.schedule.updated",
+		ResourceType: "firewall_schedule",
+		ResourceID:   schedule.ID.String(),
 		Details: map[string]interface{}{
-			"name":         profile.Name,
-			"rulesCreated": len(ruleIDs),
+			"profileId": schedule.ProfileID.String(),
+			"agentId":   schedule.AgentID.String(),
+			"cronExpr":  schedule.CronExpr,
+			"enabled":   schedule.Enabled,
 		},
 	})
 
-	return profile, nil
+	return schedule, nil
+}
+
+// DeleteSchedule deletes a deployment schedule
+func (s *Service) DeleteSchedule(ctx context.Context, token string, tenantID, id uuid.UUID) error {
+	if err := s.repo.DeleteSchedule(tenantID, id); err != nil {
+		return err
+	}
+
+	// Audit logging
+	s.client.LogAuditAsync(ctx, token, csdcore.AuditEntry{
+		Action:       "firewall.schedule.deleted",
+		ResourceType: "firewall_schedule",
+		ResourceID:   id.String(),
+	})
+
+	return nil
+}
+
+// runScheduleJob periodically deploys the profiles of due schedules.
+// The job idles until the config is loaded, since scheduled runs use the service token.
+func (s *Service) runScheduleJob() {
+	defer logger.RecoverPanic("[Security] Schedule job")
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cfg := config.GetConfig()
+		if cfg == nil {
+			continue
+		}
+		func() {
+			defer logger.RecoverPanic("[Security] Schedule pass")
+			s.RunDueSchedules(context.Background(), cfg.CSDCore.ServiceToken)
+		}()
+	}
+}
+
+// RunDueSchedules claims every due schedule and deploys its profile through DeployProfile, so
+// scheduled runs publish the same deployment events as manual ones. Schedules claimed by another
+// replica are skipped.
+func (s *Service) RunDueSchedules(ctx context.Context, token string) {
+	now := time.Now()
+	schedules, err := s.repo.ListDueSchedules(now)
+	if err != nil {
+		logger.Error("[Security] Failed to list due schedules: %s", err.Error())
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		next, err := nextScheduleRun(schedule.CronExpr, now)
+		if err != nil {
+			logger.Warn("[Security] Schedule %s has an invalid cron expression: %s", schedule.ID, err.Error())
+			continue
+		}
+		claimed, err := s.repo.ClaimSchedule(schedule, now, next)
+		if err != nil {
+			logger.Error("[Security] Failed to claim schedule %s: %s", schedule.ID, err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.runSchedule(ctx, token, schedule)
+	}
+}
+
+// runSchedule starts the deployment of a claimed schedule and records the outcome on it.
+// Agents with enforcement paused are left alone.
+func (s *Service) runSchedule(ctx context.Context, token string, schedule *FirewallSchedule) {
+	if pause := s.activeEnforcementPause(schedule.TenantID, schedule.AgentID); pause != nil {
+		s.repo.SetScheduleResult(schedule.ID, nil, pauseMessage(pause))
+		return
+	}
+
+	deployment, err := s.DeployProfile(ctx, token, schedule.TenantID, schedule.CreatedBy, &DeploymentInput{
+		ProfileID: schedule.ProfileID.String(),
+		AgentID:   schedule.AgentID.String(),
+		Action:    DeploymentActionApply,
+	})
+	if err != nil {
+		logger.Warn("[Security] Scheduled deployment %s failed to start: %s", schedule.ID, err.Error())
+		s.repo.SetScheduleResult(schedule.ID, nil, err.Error())
+		return
+	}
+	s.repo.SetScheduleResult(schedule.ID, &deployment.ID, "")
 }
@@ -0,0 +1,386 @@
+package security
+
+import (
+	"testing"
+
+	"csd-pilote/backend/modules/platform/validation"
+)
+
+func TestNormalizeRuleProtocol(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RuleProtocol
+	}{
+		{"tcp", RuleProtocolTCP},
+		{"UDP", RuleProtocolUDP},
+		{"icmpv6", RuleProtocolICMPv6},
+		{"all", RuleProtocolAll},
+		{"any", RuleProtocolAll},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeRuleProtocol(tt.in); got != tt.want {
+			t.Errorf("normalizeRuleProtocol(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRuleToNftReject(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule FirewallRule
+		want string
+	}{
+		{
+			name: "tcp reset",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("tcp"), DestPort: "22", Action: RuleActionReject, RejectWith: "tcp", RejectType: "reset"},
+			want: "ip protocol tcp tcp dport 22 reject with tcp reset # r",
+		},
+		{
+			name: "icmp type",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("udp"), DestPort: "53", Action: RuleActionReject, RejectWith: "icmp", RejectType: "admin-prohibited"},
+			want: "ip protocol udp udp dport 53 reject with icmp type admin-prohibited # r",
+		},
+		{
+			name: "plain",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("all"), Action: RuleActionReject},
+			want: "reject # r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRuleSemantics(&tt.rule); err != nil {
+				t.Fatalf("validateRuleSemantics: %v", err)
+			}
+			if got := s.ruleToNft(tt.rule); got != tt.want {
+				t.Errorf("ruleToNft = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleToNftConnLimit(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule FirewallRule
+		want func(rule FirewallRule) string
+	}{
+		{
+			name: "global",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("tcp"), DestPort: "22", ConnLimitCount: 5, Action: RuleActionDrop},
+			want: func(FirewallRule) string { return "ip protocol tcp tcp dport 22 ct count over 5 drop # r" },
+		},
+		{
+			name: "per source network",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("tcp"), DestPort: "22", ConnLimitCount: 5, ConnLimitMask: 24, Action: RuleActionDrop},
+			want: func(rule FirewallRule) string {
+				return "ip protocol tcp tcp dport 22 meta nfproto ipv4 meter " + connLimitMeterName(rule) +
+					" { ip saddr and 255.255.255.0 ct count over 5 } drop # r"
+			},
+		},
+		{
+			name: "per ipv6 source",
+			rule: FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("tcp"), SourceIP: "2001:db8::/32", ConnLimitCount: 3, ConnLimitMask: 128, Action: RuleActionDrop},
+			want: func(rule FirewallRule) string {
+				return "meta l4proto tcp ip6 saddr 2001:db8::/32 meta nfproto ipv6 meter " + connLimitMeterName(rule) +
+					" { ip6 saddr ct count over 3 } drop # r"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRuleSemantics(&tt.rule); err != nil {
+				t.Fatalf("validateRuleSemantics: %v", err)
+			}
+			if got, want := s.ruleToNft(tt.rule), tt.want(tt.rule); got != want {
+				t.Errorf("ruleToNft = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateRuleSemanticsNormalizedProtocol(t *testing.T) {
+	// Lower-case API values used to miss the upper-case comparisons and slip through validation
+	rule := FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("udp"), Action: RuleActionReject, RejectWith: "tcp", RejectType: "reset"}
+	if err := validateRuleSemantics(&rule); err == nil {
+		t.Error("reject with tcp reset on a UDP rule was accepted")
+	}
+
+	rule = FirewallRule{Name: "r", Protocol: normalizeRuleProtocol("tcp"), Action: RuleActionReject, RejectWith: "tcp", RejectType: "reset"}
+	if err := validateRuleSemantics(&rule); err != nil {
+		t.Errorf("reject with tcp reset on a TCP rule was rejected: %v", err)
+	}
+}
+
+func TestValidateRuleMatchesPortProtocol(t *testing.T) {
+	tests := []struct {
+		protocol RuleProtocol
+		valid    bool
+	}{
+		{"", true},
+		{RuleProtocolAll, true},
+		{RuleProtocolTCP, true},
+		{RuleProtocolUDP, true},
+		{RuleProtocolICMP, false},
+		{RuleProtocolICMPv6, false},
+	}
+	for _, tt := range tests {
+		rule := FirewallRule{Name: "r", Chain: RuleChainInput, Protocol: tt.protocol, DestPort: "443", Action: RuleActionAccept}
+		err := validateRuleSemantics(&rule)
+		if (err == nil) != tt.valid {
+			t.Errorf("protocol %q with ports: err = %v, want valid %v", tt.protocol, err, tt.valid)
+		}
+	}
+}
+
+func TestRuleToNftPortsWithoutTransportProtocol(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule FirewallRule
+		want string
+	}{
+		{
+			name: "empty protocol",
+			rule: FirewallRule{Name: "r", DestPort: "443", Action: RuleActionAccept},
+			want: "meta l4proto { tcp, udp } th dport 443 accept # r",
+		},
+		{
+			name: "all with port list",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolAll, DestPort: "80,443", Action: RuleActionAccept},
+			want: "meta l4proto { tcp, udp } th dport { 80, 443 } accept # r",
+		},
+		{
+			name: "all with source and destination ports",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolAll, SourcePort: "1024-65535", DestPort: "53", Action: RuleActionAccept},
+			want: "meta l4proto { tcp, udp } th sport 1024-65535 th dport 53 accept # r",
+		},
+		{
+			name: "tcp keeps its own header",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolTCP, DestPort: "443", Action: RuleActionAccept},
+			want: "ip protocol tcp tcp dport 443 accept # r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.ruleToNft(tt.rule); got != tt.want {
+				t.Errorf("ruleToNft = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleToNftRateLimit(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name  string
+		limit string
+		burst int
+		want  string
+	}{
+		{"packet rate", "10/second", 0, "ip protocol tcp tcp dport 22 limit rate 10/second accept # r"},
+		{"packet rate with burst", " 100 / MINUTE ", 20, "ip protocol tcp tcp dport 22 limit rate 100/minute burst 20 packets accept # r"},
+		{"byte rate", "512 kbytes/second", 0, "ip protocol tcp tcp dport 22 limit rate 512 kbytes/second accept # r"},
+		{"byte rate with burst", "1mbytes/second", 65536, "ip protocol tcp tcp dport 22 limit rate 1 mbytes/second burst 65536 bytes accept # r"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := FirewallRule{Name: "r", Protocol: RuleProtocolTCP, DestPort: "22", RateLimit: tt.limit, RateBurst: tt.burst, Action: RuleActionAccept}
+			if err := normalizeRuleRateLimit(&rule); err != nil {
+				t.Fatalf("normalizeRuleRateLimit: %v", err)
+			}
+			if got := s.ruleToNft(rule); got != tt.want {
+				t.Errorf("ruleToNft = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRateLimitInvalid(t *testing.T) {
+	for _, limit := range []string{"0/second", "10", "10/week", "10 gbytes/second", "ten/second"} {
+		if _, err := normalizeRateLimit(limit); err == nil {
+			t.Errorf("normalizeRateLimit(%q) succeeded, want an error", limit)
+		}
+	}
+}
+
+func TestRuleToNftAddressFamily(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule FirewallRule
+		want string
+	}{
+		{
+			name: "ipv4",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolTCP, SourceIP: "10.0.0.0/8", DestIP: "192.168.1.10", DestPort: "22", Action: RuleActionAccept},
+			want: "ip protocol tcp ip saddr 10.0.0.0/8 ip daddr 192.168.1.10 tcp dport 22 accept # r",
+		},
+		{
+			name: "ipv6",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolTCP, SourceIP: "2001:db8::/32", DestIP: "2001:db8::10", DestPort: "22", Action: RuleActionAccept},
+			want: "meta l4proto tcp ip6 saddr 2001:db8::/32 ip6 daddr 2001:db8::10 tcp dport 22 accept # r",
+		},
+		{
+			name: "ipv6 icmp",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolICMP, SourceIP: "fe80::/10", ICMPType: "echo-request", Action: RuleActionAccept},
+			want: "meta l4proto ipv6-icmp ip6 saddr fe80::/10 icmpv6 type echo-request accept # r",
+		},
+		{
+			name: "icmpv6 without addresses",
+			rule: FirewallRule{Name: "r", Protocol: RuleProtocolICMPv6, ICMPType: "nd-neighbor-solicit", Action: RuleActionAccept},
+			want: "meta l4proto ipv6-icmp icmpv6 type nd-neighbor-solicit accept # r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRuleSemantics(&tt.rule); err != nil {
+				t.Fatalf("validateRuleSemantics: %v", err)
+			}
+			if got := s.ruleToNft(tt.rule); got != tt.want {
+				t.Errorf("ruleToNft = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRuleSemanticsMixedFamily(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		dest     string
+		wantFail bool
+	}{
+		{"both ipv4", "10.0.0.1", "10.0.0.0/24", false},
+		{"both ipv6", "2001:db8::1", "2001:db8::/64", false},
+		{"ipv4 to ipv6", "10.0.0.1", "2001:db8::1", true},
+		{"ipv6 to ipv4", "2001:db8::/64", "192.168.0.0/16", true},
+		{"any source", "any", "2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := FirewallRule{Name: "r", Protocol: RuleProtocolTCP, SourceIP: tt.source, DestIP: tt.dest, Action: RuleActionAccept}
+			err := validateRuleSemantics(&rule)
+			if (err != nil) != tt.wantFail {
+				t.Errorf("validateRuleSemantics() = %v, want failure %v", err, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestRuleToNftRedirect(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule FirewallRule
+		want string
+	}{
+		{
+			name: "without port",
+			rule: FirewallRule{Name: "r", Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, DestPort: "80", Action: RuleActionRedirect},
+			want: "ip protocol tcp tcp dport 80 redirect # r",
+		},
+		{
+			name: "single port",
+			rule: FirewallRule{Name: "r", Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, DestPort: "80", NatToPort: "3128", Action: RuleActionRedirect},
+			want: "ip protocol tcp tcp dport 80 redirect to :3128 # r",
+		},
+		{
+			name: "port range",
+			rule: FirewallRule{Name: "r", Chain: RuleChainPrerouting, Protocol: RuleProtocolUDP, DestPort: "53", NatToPort: "5300-5309", Action: RuleActionRedirect},
+			want: "ip protocol udp udp dport 53 redirect to :5300-5309 # r",
+		},
+		{
+			name: "range of one port",
+			rule: FirewallRule{Name: "r", Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, NatToPort: "8080-8080", Action: RuleActionRedirect},
+			want: "ip protocol tcp redirect to :8080 # r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRuleSemantics(&tt.rule); err != nil {
+				t.Fatalf("validateRuleSemantics: %v", err)
+			}
+			if got := s.ruleToNft(tt.rule); got != tt.want {
+				t.Errorf("ruleToNft = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRuleSemanticsRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		rule FirewallRule
+		code string
+	}{
+		{"no protocol", FirewallRule{Chain: RuleChainPrerouting}, "INVALID_REDIRECT_PROTOCOL"},
+		{"all protocols", FirewallRule{Chain: RuleChainPrerouting, Protocol: RuleProtocolAll, NatToPort: "3128"}, "INVALID_REDIRECT_PROTOCOL"},
+		{"icmp", FirewallRule{Chain: RuleChainPrerouting, Protocol: RuleProtocolICMP}, "INVALID_REDIRECT_PROTOCOL"},
+		{"negated protocol", FirewallRule{Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, NegateProtocol: true}, "INVALID_REDIRECT_PROTOCOL"},
+		{"input chain", FirewallRule{Chain: RuleChainInput, Protocol: RuleProtocolTCP, NatToPort: "3128"}, "INVALID_REDIRECT_CHAIN"},
+		{"reversed range", FirewallRule{Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, NatToPort: "3130-3128"}, "INVALID_PORT_RANGE"},
+		{"port out of range", FirewallRule{Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, NatToPort: "70000"}, "INVALID_PORT_RANGE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Name, tt.rule.Action = "r", RuleActionRedirect
+			err := validateRuleSemantics(&tt.rule)
+			errs, ok := err.(*validation.ValidationErrors)
+			if !ok {
+				t.Fatalf("validateRuleSemantics = %v, want validation errors", err)
+			}
+			found := false
+			for _, e := range errs.Errors {
+				found = found || e.Code == tt.code
+			}
+			if !found {
+				t.Errorf("errors %v do not include %s", errs.Errors, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateProfileForDeployRedirectNeedsNAT(t *testing.T) {
+	rule := FirewallRule{Name: "proxy", Enabled: true, Chain: RuleChainPrerouting, Protocol: RuleProtocolTCP, DestPort: "80", NatToPort: "3128", Action: RuleActionRedirect}
+	profile := &FirewallProfile{Name: "p", Rules: []FirewallRule{rule}}
+	if err := validateProfileForDeploy(profile); err == nil {
+		t.Error("REDIRECT was accepted on a profile without NAT")
+	}
+	profile.EnableNAT = true
+	if err := validateProfileForDeploy(profile); err != nil {
+		t.Errorf("REDIRECT was rejected on a profile with NAT: %v", err)
+	}
+}
+
+func TestManagementAccessExpr(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "tcp dport 2222 accept"},
+		{"10.0.0.0/8", "ip saddr 10.0.0.0/8 tcp dport 2222 accept"},
+		{"2001:db8::/32", "ip6 saddr 2001:db8::/32 tcp dport 2222 accept"},
+		{"2001:db8::1", "ip6 saddr 2001:db8::1 tcp dport 2222 accept"},
+		{"@admins", "ip saddr @admins tcp dport 2222 accept"},
+	}
+	for _, tt := range tests {
+		profile := &FirewallProfile{ManagementPort: "2222", ManagementSource: tt.source}
+		if got := managementAccessExpr(profile); got != tt.want {
+			t.Errorf("managementAccessExpr(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestValidateProfileForDeployIPv6ManagementSource(t *testing.T) {
+	profile := &FirewallProfile{Name: "p", EnableManagementAccess: true, ManagementSource: "2001:db8::/32"}
+	if err := validateProfileForDeploy(profile); err == nil {
+		t.Error("an IPv6 management source was accepted on a profile without IPv6")
+	}
+	profile.EnableIPv6 = true
+	if err := validateProfileForDeploy(profile); err != nil {
+		t.Errorf("an IPv6 management source was rejected on a profile with IPv6: %v", err)
+	}
+}
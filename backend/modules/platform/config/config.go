@@ -22,23 +22,44 @@ type Config struct {
 	CLI        CLIConfig        `yaml:"cli"`
 	Pagination PaginationConfig `yaml:"pagination"`
 	Limits     LimitsConfig     `yaml:"limits"`
+	Firewall   FirewallConfig   `yaml:"firewall"`
 }
 
 // PaginationConfig configures pagination and count strategies
 type PaginationConfig struct {
-	DefaultLimit           int   `yaml:"default_limit"`
-	MaxLimit               int   `yaml:"max_limit"`
-	ExactCountThreshold    int64 `yaml:"exact_count_threshold"`
-	EstimateCountThreshold int64 `yaml:"estimate_count_threshold"`
-	AlwaysExactWithFilters bool  `yaml:"always_exact_with_filters"`
+	DefaultLimit           int   `yaml:"default-limit"`
+	MaxLimit               int   `yaml:"max-limit"`
+	ExactCountThreshold    int64 `yaml:"exact-count-threshold"`
+	EstimateCountThreshold int64 `yaml:"estimate-count-threshold"`
+	AlwaysExactWithFilters *bool `yaml:"always-exact-with-filters"` // nil when unset, so an explicit false is kept
+	// Per-resource overrides keyed by resource name (see pagination.Resource*)
+	Resources map[string]ResourcePaginationConfig `yaml:"resources"`
+}
+
+// ResourcePaginationConfig overrides the default and max list limits of one resource type
+type ResourcePaginationConfig struct {
+	DefaultLimit int `yaml:"default-limit"`
+	MaxLimit     int `yaml:"max-limit"`
 }
 
 // LimitsConfig configures various resource limits
 type LimitsConfig struct {
-	MaxNodesPerCluster          int `yaml:"max_nodes_per_cluster"`
-	ClusterDeploymentTimeout    int `yaml:"cluster_deployment_timeout_minutes"`
-	HypervisorDeploymentTimeout int `yaml:"hypervisor_deployment_timeout_minutes"`
-	FirewallDeploymentTimeout   int `yaml:"firewall_deployment_timeout_minutes"`
+	MaxNodesPerCluster              int `yaml:"max_nodes_per_cluster"`
+	ClusterDeploymentTimeout        int `yaml:"cluster_deployment_timeout_minutes"`
+	HypervisorDeploymentTimeout     int `yaml:"hypervisor_deployment_timeout_minutes"`
+	FirewallDeploymentTimeout       int `yaml:"firewall_deployment_timeout_minutes"`
+	FirewallRulesetWarnBytes        int `yaml:"firewall_ruleset_warn_bytes"`
+	FirewallCounterSnapshotMinutes  int `yaml:"firewall_counter_snapshot_minutes"` // Negative disables the counter-sync job
+	FirewallCounterRetentionDays    int `yaml:"firewall_counter_retention_days"`
+	ContainerInventoryMinutes       int `yaml:"container_inventory_minutes"` // Negative disables the container inventory job
+	ContainerInventoryRetentionDays int `yaml:"container_inventory_retention_days"`
+	ContainerProbeMinutes           int `yaml:"container_probe_minutes"` // Negative disables the engine connectivity probe job
+}
+
+// FirewallConfig configures firewall deployments
+type FirewallConfig struct {
+	DeployRetries             int `yaml:"deploy-retries"`               // Negative disables retries
+	DeployRetryBackoffSeconds int `yaml:"deploy-retry-backoff-seconds"` // Doubled after every failed attempt
 }
 
 // RawConfig represents the YAML file structure with common/backend/frontend/cli sections
@@ -56,11 +77,14 @@ type CommonConfig struct {
 }
 
 type BackendConfig struct {
-	Server  ServerConfig  `yaml:"server"`
-	CSDCore CSDCoreConfig `yaml:"csd-core"`
-	JWT     JWTConfig     `yaml:"jwt"`
-	CORS    CORSConfig    `yaml:"cors"`
-	Logging LoggingConfig `yaml:"logging"`
+	Server   ServerConfig   `yaml:"server"`
+	CSDCore  CSDCoreConfig  `yaml:"csd-core"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Firewall FirewallConfig `yaml:"firewall"`
+
+	Pagination PaginationConfig `yaml:"pagination"`
 }
 
 type ServerConfig struct {
@@ -76,6 +100,25 @@ type CSDCoreConfig struct {
 	URL             string `yaml:"url"`
 	GraphQLEndpoint string `yaml:"graphql-endpoint"`
 	ServiceToken    string `yaml:"service-token"`
+
+	// Retry policy of idempotent queries; mutations only retry when the call opts in (see csdcore.WithRetry)
+	RetryMaxAttempts   int `yaml:"retry-max-attempts"`   // Attempts per call including the first, 1 disables retries
+	RetryBaseDelayMs   int `yaml:"retry-base-delay-ms"`  // Delay before the first retry, doubled after each attempt
+	RetryJitterPercent int `yaml:"retry-jitter-percent"` // Random spread of each delay, negative disables jitter
+
+	// Call tracing: one log line per call with operation, variable names, duration and status
+	Trace      bool   `yaml:"trace"`
+	TraceLevel string `yaml:"trace-level"` // Level of the trace lines (debug or info)
+
+	// How long the full agent list is reused per token, negative disables the cache
+	AgentCacheTTLSeconds int `yaml:"agent-cache-ttl-seconds"`
+
+	// HTTP connection pool; per-call deadlines still come from the request context
+	TimeoutSeconds         int `yaml:"timeout-seconds"`           // Overall limit of a single HTTP request
+	MaxIdleConns           int `yaml:"max-idle-conns"`            // Idle connections kept across all hosts
+	MaxIdleConnsPerHost    int `yaml:"max-idle-conns-per-host"`   // Idle connections kept to csd-core
+	IdleConnTimeoutSeconds int `yaml:"idle-conn-timeout-seconds"` // How long an idle connection is kept open
+	KeepAliveSeconds       int `yaml:"keep-alive-seconds"`        // TCP keep-alive period, negative disables keep-alives
 }
 
 // FrontendConfig holds frontend integration settings for Module Federation
@@ -119,6 +162,9 @@ type SeedsConfig struct {
 
 var globalConfig *Config
 
+// AppVersion is the running csd-pilote version, set by the main package at startup
+var AppVersion = "1.0.0"
+
 // Load loads configuration from a YAML file
 func Load(configPath string) (*Config, error) {
 	if configPath == "" {
@@ -166,6 +212,36 @@ func Load(configPath string) (*Config, error) {
 	if cfg.CSDCore.GraphQLEndpoint == "" {
 		cfg.CSDCore.GraphQLEndpoint = common.DefaultCSDCoreGraphQL
 	}
+	if cfg.CSDCore.RetryMaxAttempts == 0 {
+		cfg.CSDCore.RetryMaxAttempts = GetDefaultInt("backend.csd-core.retry-max-attempts", 4)
+	}
+	if cfg.CSDCore.RetryBaseDelayMs == 0 {
+		cfg.CSDCore.RetryBaseDelayMs = GetDefaultInt("backend.csd-core.retry-base-delay-ms", 100)
+	}
+	if cfg.CSDCore.RetryJitterPercent == 0 {
+		cfg.CSDCore.RetryJitterPercent = GetDefaultInt("backend.csd-core.retry-jitter-percent", 20)
+	}
+	if cfg.CSDCore.TraceLevel == "" {
+		cfg.CSDCore.TraceLevel = GetDefaultString("backend.csd-core.trace-level", "debug")
+	}
+	if cfg.CSDCore.AgentCacheTTLSeconds == 0 {
+		cfg.CSDCore.AgentCacheTTLSeconds = GetDefaultInt("backend.csd-core.agent-cache-ttl-seconds", 5)
+	}
+	if cfg.CSDCore.TimeoutSeconds == 0 {
+		cfg.CSDCore.TimeoutSeconds = GetDefaultInt("backend.csd-core.timeout-seconds", 30)
+	}
+	if cfg.CSDCore.MaxIdleConns == 0 {
+		cfg.CSDCore.MaxIdleConns = GetDefaultInt("backend.csd-core.max-idle-conns", 100)
+	}
+	if cfg.CSDCore.MaxIdleConnsPerHost == 0 {
+		cfg.CSDCore.MaxIdleConnsPerHost = GetDefaultInt("backend.csd-core.max-idle-conns-per-host", 32)
+	}
+	if cfg.CSDCore.IdleConnTimeoutSeconds == 0 {
+		cfg.CSDCore.IdleConnTimeoutSeconds = GetDefaultInt("backend.csd-core.idle-conn-timeout-seconds", 90)
+	}
+	if cfg.CSDCore.KeepAliveSeconds == 0 {
+		cfg.CSDCore.KeepAliveSeconds = GetDefaultInt("backend.csd-core.keep-alive-seconds", 30)
+	}
 	if cfg.JWT.Issuer == "" {
 		cfg.JWT.Issuer = common.DefaultJWTIssuer
 	}
@@ -195,6 +271,10 @@ func Load(configPath string) (*Config, error) {
 	if cfg.Pagination.EstimateCountThreshold == 0 {
 		cfg.Pagination.EstimateCountThreshold = GetDefaultInt64("backend.pagination.estimate-count-threshold", 100000)
 	}
+	if cfg.Pagination.AlwaysExactWithFilters == nil {
+		alwaysExact := GetDefaultBool("backend.pagination.always-exact-with-filters", true)
+		cfg.Pagination.AlwaysExactWithFilters = &alwaysExact
+	}
 
 	// Limits defaults
 	if cfg.Limits.MaxNodesPerCluster == 0 {
@@ -209,6 +289,32 @@ func Load(configPath string) (*Config, error) {
 	if cfg.Limits.FirewallDeploymentTimeout == 0 {
 		cfg.Limits.FirewallDeploymentTimeout = 5 // minutes
 	}
+	if cfg.Limits.FirewallRulesetWarnBytes == 0 {
+		cfg.Limits.FirewallRulesetWarnBytes = 64 * 1024
+	}
+	if cfg.Limits.FirewallCounterSnapshotMinutes == 0 {
+		cfg.Limits.FirewallCounterSnapshotMinutes = 15 // minutes
+	}
+	if cfg.Limits.FirewallCounterRetentionDays == 0 {
+		cfg.Limits.FirewallCounterRetentionDays = 30
+	}
+	if cfg.Limits.ContainerInventoryMinutes == 0 {
+		cfg.Limits.ContainerInventoryMinutes = 5 // minutes
+	}
+	if cfg.Limits.ContainerInventoryRetentionDays == 0 {
+		cfg.Limits.ContainerInventoryRetentionDays = 7
+	}
+	if cfg.Limits.ContainerProbeMinutes == 0 {
+		cfg.Limits.ContainerProbeMinutes = 10 // minutes
+	}
+
+	// Firewall defaults
+	if cfg.Firewall.DeployRetries == 0 {
+		cfg.Firewall.DeployRetries = GetDefaultInt("backend.firewall.deploy-retries", 3)
+	}
+	if cfg.Firewall.DeployRetryBackoffSeconds == 0 {
+		cfg.Firewall.DeployRetryBackoffSeconds = GetDefaultInt("backend.firewall.deploy-retry-backoff-seconds", 10)
+	}
 
 	globalConfig = &cfg
 	return &cfg, nil
@@ -226,8 +332,11 @@ func mergeConfig(raw RawConfig) Config {
 		Server:   raw.Backend.Server,
 		JWT:      raw.Backend.JWT,
 		CORS:     raw.Backend.CORS,
+		Firewall: raw.Backend.Firewall,
 		Frontend: raw.Frontend,
 		CLI:      raw.CLI,
+
+		Pagination: raw.Backend.Pagination,
 	}
 
 	// Override common with backend-specific if set
@@ -240,6 +349,39 @@ func mergeConfig(raw RawConfig) Config {
 	if raw.Backend.CSDCore.GraphQLEndpoint != "" {
 		cfg.CSDCore.GraphQLEndpoint = raw.Backend.CSDCore.GraphQLEndpoint
 	}
+	if raw.Backend.CSDCore.RetryMaxAttempts != 0 {
+		cfg.CSDCore.RetryMaxAttempts = raw.Backend.CSDCore.RetryMaxAttempts
+	}
+	if raw.Backend.CSDCore.RetryBaseDelayMs != 0 {
+		cfg.CSDCore.RetryBaseDelayMs = raw.Backend.CSDCore.RetryBaseDelayMs
+	}
+	if raw.Backend.CSDCore.RetryJitterPercent != 0 {
+		cfg.CSDCore.RetryJitterPercent = raw.Backend.CSDCore.RetryJitterPercent
+	}
+	if raw.Backend.CSDCore.Trace {
+		cfg.CSDCore.Trace = true
+	}
+	if raw.Backend.CSDCore.TraceLevel != "" {
+		cfg.CSDCore.TraceLevel = raw.Backend.CSDCore.TraceLevel
+	}
+	if raw.Backend.CSDCore.AgentCacheTTLSeconds != 0 {
+		cfg.CSDCore.AgentCacheTTLSeconds = raw.Backend.CSDCore.AgentCacheTTLSeconds
+	}
+	if raw.Backend.CSDCore.TimeoutSeconds != 0 {
+		cfg.CSDCore.TimeoutSeconds = raw.Backend.CSDCore.TimeoutSeconds
+	}
+	if raw.Backend.CSDCore.MaxIdleConns != 0 {
+		cfg.CSDCore.MaxIdleConns = raw.Backend.CSDCore.MaxIdleConns
+	}
+	if raw.Backend.CSDCore.MaxIdleConnsPerHost != 0 {
+		cfg.CSDCore.MaxIdleConnsPerHost = raw.Backend.CSDCore.MaxIdleConnsPerHost
+	}
+	if raw.Backend.CSDCore.IdleConnTimeoutSeconds != 0 {
+		cfg.CSDCore.IdleConnTimeoutSeconds = raw.Backend.CSDCore.IdleConnTimeoutSeconds
+	}
+	if raw.Backend.CSDCore.KeepAliveSeconds != 0 {
+		cfg.CSDCore.KeepAliveSeconds = raw.Backend.CSDCore.KeepAliveSeconds
+	}
 
 	// Override logging if backend-specific is set
 	if raw.Backend.Logging.Level != "" {
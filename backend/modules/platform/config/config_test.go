@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestConfig(t *testing.T, yaml string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "csd-pilote.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadAlwaysExactWithFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want bool
+	}{
+		{"unset", "backend:\n  server:\n    port: \"8080\"\n", true},
+		{"explicit false", "backend:\n  pagination:\n    always-exact-with-filters: false\n", false},
+		{"explicit true", "backend:\n  pagination:\n    always-exact-with-filters: true\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, tt.yaml)
+			got := cfg.Pagination.AlwaysExactWithFilters
+			if got == nil || *got != tt.want {
+				t.Errorf("AlwaysExactWithFilters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCountThresholds(t *testing.T) {
+	cfg := loadTestConfig(t, "backend:\n  pagination:\n    exact-count-threshold: 50\n    estimate-count-threshold: 500\n")
+	if cfg.Pagination.ExactCountThreshold != 50 || cfg.Pagination.EstimateCountThreshold != 500 {
+		t.Errorf("thresholds = %d/%d, want 50/500", cfg.Pagination.ExactCountThreshold, cfg.Pagination.EstimateCountThreshold)
+	}
+}
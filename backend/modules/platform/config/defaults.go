@@ -27,6 +27,17 @@ var ConfigDefaults = []ConfigKeyInfo{
 	{Key: "backend.csd-core.url", Type: "string", Default: common.DefaultCSDCoreURL, Description: "CSD-Core backend URL override", Essential: false},
 	{Key: "backend.csd-core.graphql-endpoint", Type: "string", Default: common.DefaultCSDCoreGraphQL, Description: "CSD-Core GraphQL endpoint override", Essential: false},
 	{Key: "backend.csd-core.service-token", Type: "string", Default: "", Description: "Service token for CSD-Core (backend-specific)", Essential: true},
+	{Key: "backend.csd-core.retry-max-attempts", Type: "int", Default: 4, Description: "Attempts of an idempotent CSD-Core query on connection errors and 502/503/504 (1 disables retries)", Essential: false},
+	{Key: "backend.csd-core.retry-base-delay-ms", Type: "int", Default: 100, Description: "Delay before the first CSD-Core retry in milliseconds, doubled each attempt", Essential: false},
+	{Key: "backend.csd-core.retry-jitter-percent", Type: "int", Default: 20, Description: "Random spread applied to CSD-Core retry delays (negative disables)", Essential: false},
+	{Key: "backend.csd-core.trace", Type: "bool", Default: false, Description: "Log every CSD-Core call with operation, variable names, duration and HTTP status", Essential: false},
+	{Key: "backend.csd-core.trace-level", Type: "string", Default: "debug", Description: "Log level of CSD-Core call traces (debug, info)", Essential: false},
+	{Key: "backend.csd-core.agent-cache-ttl-seconds", Type: "int", Default: 5, Description: "Seconds the CSD-Core agent list is reused per token (negative disables the cache)", Essential: false},
+	{Key: "backend.csd-core.timeout-seconds", Type: "int", Default: 30, Description: "Overall timeout of a single CSD-Core HTTP request in seconds", Essential: false},
+	{Key: "backend.csd-core.max-idle-conns", Type: "int", Default: 100, Description: "Idle HTTP connections kept by the CSD-Core client across all hosts", Essential: false},
+	{Key: "backend.csd-core.max-idle-conns-per-host", Type: "int", Default: 32, Description: "Idle HTTP connections kept open to CSD-Core", Essential: false},
+	{Key: "backend.csd-core.idle-conn-timeout-seconds", Type: "int", Default: 90, Description: "Seconds an idle CSD-Core connection is kept before closing", Essential: false},
+	{Key: "backend.csd-core.keep-alive-seconds", Type: "int", Default: 30, Description: "TCP keep-alive period of CSD-Core connections in seconds (negative disables keep-alives)", Essential: false},
 
 	// Backend CORS
 	{Key: "backend.cors.allowed-origins", Type: "[]string", Default: []string{}, Description: "Additional CORS origins (frontend.url is auto-added)", Essential: false},
@@ -44,6 +55,10 @@ var ConfigDefaults = []ConfigKeyInfo{
 	{Key: "backend.pagination.estimate-count-threshold", Type: "int64", Default: int64(100000), Description: "Use pg_class estimate when estimated rows > this", Essential: false},
 	{Key: "backend.pagination.always-exact-with-filters", Type: "bool", Default: true, Description: "Always use exact count when filters are applied", Essential: false},
 
+	// Backend Firewall
+	{Key: "backend.firewall.deploy-retries", Type: "int", Default: 3, Description: "Retries of a firewall deployment lost to an offline agent (negative disables)", Essential: false},
+	{Key: "backend.firewall.deploy-retry-backoff-seconds", Type: "int", Default: 10, Description: "Initial delay between firewall deployment retries, doubled each attempt", Essential: false},
+
 	// Frontend (Module Federation integration)
 	{Key: "frontend.url", Type: "string", Default: common.DefaultFrontendURL, Description: "Frontend URL for CORS", Essential: true},
 	{Key: "frontend.remote-entry-path", Type: "string", Default: "/assets/remoteEntry.js", Description: "Module Federation remote entry path", Essential: false},
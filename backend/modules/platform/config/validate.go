@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	common "csd-pilote/backend/modules/common/config"
+)
+
+// ConfigProblem describes a missing or invalid configuration key
+type ConfigProblem struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// ValidationError aggregates all configuration problems found by Validate
+type ValidationError struct {
+	Problems []ConfigProblem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = fmt.Sprintf("%s: %s", p.Key, p.Message)
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// essentialResolvers maps essential keys to their value in the merged config.
+// Keys that can be set in several sections share a resolver and are reported once.
+var essentialResolvers = map[string]func(*Config) string{
+	"common.database.url":            func(c *Config) string { return c.Database.URL },
+	"common.csd-core.url":            func(c *Config) string { return c.CSDCore.URL },
+	"common.csd-core.service-token":  func(c *Config) string { return c.CSDCore.ServiceToken },
+	"backend.csd-core.service-token": func(c *Config) string { return c.CSDCore.ServiceToken },
+	"backend.server.host":            func(c *Config) string { return c.Server.Host },
+	"backend.server.port":            func(c *Config) string { return c.Server.Port },
+	"backend.jwt.secret":             func(c *Config) string { return c.JWT.Secret },
+	"frontend.url":                   func(c *Config) string { return c.Frontend.URL },
+}
+
+// Validate checks that every essential key is set and well-formed
+// and returns a *ValidationError listing all problems
+func (c *Config) Validate() error {
+	var problems []ConfigProblem
+	reported := make(map[string]bool)
+
+	essential := append([]ConfigKeyInfo{}, common.CommonConfigDefaults...)
+	essential = append(essential, ConfigDefaults...)
+
+	for _, info := range essential {
+		if !info.Essential {
+			continue
+		}
+		resolve, ok := essentialResolvers[info.Key]
+		if !ok {
+			continue
+		}
+
+		// Report keys that share a setting (common.* vs backend.*) only once
+		name := info.Key[strings.Index(info.Key, ".")+1:]
+		if reported[name] {
+			continue
+		}
+
+		if msg := checkEssentialValue(info.Key, resolve(c)); msg != "" {
+			reported[name] = true
+			problems = append(problems, ConfigProblem{Key: info.Key, Message: msg})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkEssentialValue returns a problem description for a key's value, or "" when valid.
+// Messages never include the value: URLs and DSNs can carry credentials and are logged
+// at startup and served by the config health check.
+func checkEssentialValue(key, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "is required but not set"
+	}
+
+	switch {
+	case key == "common.database.url" && isKeywordDSN(value):
+		return ""
+	case strings.HasSuffix(key, ".url"):
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute URL"
+		}
+	case strings.HasSuffix(key, ".port"):
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return "must be a port number between 1 and 65535"
+		}
+	}
+	return ""
+}
+
+// isKeywordDSN reports whether value is a libpq keyword/value connection string
+// (host=db dbname=pilote password='s3 cret'), the other form the database accepts besides URLs
+func isKeywordDSN(value string) bool {
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 || !dsnKeywordRegex.MatchString(strings.TrimSpace(rest[:eq])) {
+			return false
+		}
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+		if strings.HasPrefix(rest, "'") {
+			// Quoted value: runs to the next unescaped quote
+			end := 1
+			for end < len(rest) && rest[end] != '\'' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return false
+			}
+			rest = rest[end+1:]
+		} else if sp := strings.IndexAny(rest, " \t"); sp >= 0 {
+			rest = rest[sp:]
+		} else {
+			rest = ""
+		}
+		rest = strings.TrimSpace(rest)
+	}
+	return true
+}
+
+// dsnKeywordRegex matches a libpq connection parameter name
+var dsnKeywordRegex = regexp.MustCompile(`^[a-z_]+$`)
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckEssentialValue(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{"common.database.url", "postgres://pilote:s3cret@db:5432/pilote?sslmode=disable", true},
+		{"common.database.url", "host=db port=5432 dbname=pilote user=pilote password=s3cret", true},
+		{"common.database.url", "host=db password='s3 cret\\'s' sslmode=disable", true},
+		{"common.database.url", "host=db password='s3cret", false},
+		{"common.database.url", "pilote:s3cret@db", false},
+		{"common.csd-core.url", "host=core", false},
+		{"common.csd-core.url", "https://core.example.com", true},
+		{"backend.server.port", "8080", true},
+		{"backend.server.port", "70000", false},
+		{"backend.jwt.secret", " ", false},
+	}
+	for _, tt := range tests {
+		msg := checkEssentialValue(tt.key, tt.value)
+		if (msg == "") != tt.valid {
+			t.Errorf("checkEssentialValue(%s, %q) = %q, want valid %v", tt.key, tt.value, msg, tt.valid)
+		}
+		if strings.Contains(msg, "s3cret") {
+			t.Errorf("checkEssentialValue(%s) message %q leaks the value", tt.key, msg)
+		}
+	}
+}
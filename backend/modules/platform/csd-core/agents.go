@@ -0,0 +1,154 @@
+package csdcore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentCache keeps the full agent list of each token for a short time, so the agent lookups
+// made by several modules while serving one request share a single csd-core call
+type agentCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*agentCacheEntry
+}
+
+// agentCacheEntry is the agent list of one token; done is closed once the fetch has finished
+type agentCacheEntry struct {
+	done    chan struct{}
+	agents  []Agent
+	err     error
+	expires time.Time
+}
+
+// newAgentCache creates a cache, disabled when ttl is not positive
+func newAgentCache(ttl time.Duration) *agentCache {
+	return &agentCache{ttl: ttl, entries: make(map[string]*agentCacheEntry)}
+}
+
+// peek returns the cached agents of a token without fetching, or false when there is no fresh list
+func (ac *agentCache) peek(token string) ([]Agent, bool) {
+	if ac.ttl <= 0 {
+		return nil, false
+	}
+	ac.mu.Lock()
+	entry, ok := ac.entries[token]
+	ac.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	select {
+	case <-entry.done:
+	default:
+		return nil, false
+	}
+	if entry.err != nil || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return copyAgents(entry.agents), true
+}
+
+// get returns the cached agents of a token, calling fetch when there is no fresh list.
+// Concurrent callers of the same token wait for the fetch in flight; failures are not cached.
+func (ac *agentCache) get(ctx context.Context, token string, fetch func() ([]Agent, error)) ([]Agent, error) {
+	if ac.ttl <= 0 {
+		return fetch()
+	}
+
+	ac.mu.Lock()
+	entry, ok := ac.entries[token]
+	if ok {
+		select {
+		case <-entry.done:
+			if entry.err != nil || time.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		ac.pruneLocked()
+		entry = &agentCacheEntry{done: make(chan struct{})}
+		ac.entries[token] = entry
+		ac.mu.Unlock()
+
+		entry.agents, entry.err = fetch()
+		entry.expires = time.Now().Add(ac.ttl)
+		close(entry.done)
+		if entry.err != nil {
+			ac.mu.Lock()
+			if ac.entries[token] == entry {
+				delete(ac.entries, token)
+			}
+			ac.mu.Unlock()
+			return nil, entry.err
+		}
+		return copyAgents(entry.agents), nil
+	}
+	ac.mu.Unlock()
+
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return copyAgents(entry.agents), nil
+}
+
+// pruneLocked drops expired lists so tokens that are no longer used do not accumulate
+func (ac *agentCache) pruneLocked() {
+	now := time.Now()
+	for token, entry := range ac.entries {
+		select {
+		case <-entry.done:
+			if now.After(entry.expires) {
+				delete(ac.entries, token)
+			}
+		default:
+		}
+	}
+}
+
+// copyAgents returns a copy of agents so callers cannot alter the cached list
+func copyAgents(agents []Agent) []Agent {
+	result := make([]Agent, len(agents))
+	copy(result, agents)
+	return result
+}
+
+// filterAgents returns the agents for which keep returns true
+func filterAgents(agents []Agent, keep func(*Agent) bool) []Agent {
+	filtered := make([]Agent, 0)
+	for i := range agents {
+		if keep(&agents[i]) {
+			filtered = append(filtered, agents[i])
+		}
+	}
+	return filtered
+}
+
+// unsupportedQueryMarkers identify GraphQL validation errors of a csd-core that lacks a field or argument
+var unsupportedQueryMarkers = []string{"unknown argument", "cannot query field"}
+
+// isUnsupportedQueryError reports whether csd-core rejected a query it does not know
+func isUnsupportedQueryError(err error) bool {
+	var coreErr *CSDCoreError
+	if !errors.As(err, &coreErr) {
+		return false
+	}
+	for _, message := range coreErr.Messages {
+		lower := strings.ToLower(message)
+		for _, marker := range unsupportedQueryMarkers {
+			if strings.Contains(lower, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -3,32 +3,114 @@ package csdcore
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"csd-pilote/backend/modules/platform/config"
+	"csd-pilote/backend/modules/platform/logger"
+	"csd-pilote/backend/modules/platform/metrics"
 	"github.com/google/uuid"
 )
 
 // Retry configuration
 const (
-	defaultMaxRetries     = 3
+	defaultMaxAttempts    = 4
 	defaultInitialBackoff = 100 * time.Millisecond
 	defaultMaxBackoff     = 5 * time.Second
 	defaultBackoffFactor  = 2.0
+	defaultJitter         = 0.2
 )
 
+// RetryPolicy controls how a call is retried on connection errors and 502/503/504 responses.
+// 4xx responses and GraphQL errors are never retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // Delay before the first retry, multiplied by defaultBackoffFactor after each attempt
+	MaxDelay    time.Duration // Upper bound of a single delay
+	Jitter      float64       // Random spread of each delay as a fraction (0.2 = ±20%)
+}
+
+// DefaultRetryPolicy returns the retry policy used when the configuration sets none
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultInitialBackoff,
+		MaxDelay:    defaultMaxBackoff,
+		Jitter:      defaultJitter,
+	}
+}
+
+// retryPolicyFromConfig builds a retry policy from the csd-core configuration, keeping defaults for unset values
+func retryPolicyFromConfig(cfg *config.CSDCoreConfig) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	if cfg.RetryMaxAttempts > 0 {
+		policy.MaxAttempts = cfg.RetryMaxAttempts
+	}
+	if cfg.RetryBaseDelayMs > 0 {
+		policy.BaseDelay = time.Duration(cfg.RetryBaseDelayMs) * time.Millisecond
+	}
+	if cfg.RetryJitterPercent > 0 {
+		policy.Jitter = float64(cfg.RetryJitterPercent) / 100
+	} else if cfg.RetryJitterPercent < 0 {
+		policy.Jitter = 0
+	}
+	return policy
+}
+
+// delay returns the jittered wait before the given retry (1 for the first retry)
+func (p RetryPolicy) delay(retry int) time.Duration {
+	backoff := p.BaseDelay
+	for i := 1; i < retry && backoff < p.MaxDelay; i++ {
+		backoff = time.Duration(float64(backoff) * defaultBackoffFactor)
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		backoff = time.Duration(float64(backoff) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return backoff
+}
+
+// retryContextKey marks a context with an explicit retry decision
+type retryContextKey struct{}
+
+// WithRetry returns a context that enables or disables retries for the csd-core calls made with it.
+// Without it, queries are retried and mutations are not, since replaying a mutation may apply it twice.
+func WithRetry(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, enabled)
+}
+
+// retryEnabled reports whether a call may be retried
+func retryEnabled(ctx context.Context, query string) bool {
+	if enabled, ok := ctx.Value(retryContextKey{}).(bool); ok {
+		return enabled
+	}
+	return !strings.HasPrefix(strings.TrimSpace(query), "mutation")
+}
+
 // Client is a GraphQL client for csd-core
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	endpoint   string
+	httpClient  *http.Client
+	baseURL     string
+	endpoint    string
+	retryPolicy RetryPolicy
+	trace       bool   // Log every call (see recordCall)
+	traceLevel  string // Level of the trace lines: debug or info
+	agents      *agentCache
+	// Set once csd-core rejects the capability argument of the agents query; filtering then happens here
+	agentFilterUnsupported atomic.Bool
 }
 
 // GraphQLRequest represents a GraphQL request
@@ -67,16 +149,77 @@ var globalClient *Client
 // NewClient creates a new csd-core client
 func NewClient(cfg *config.CSDCoreConfig) *Client {
 	client := &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL:  cfg.URL,
-		endpoint: cfg.GraphQLEndpoint,
+		httpClient:  newHTTPClient(cfg),
+		baseURL:     cfg.URL,
+		endpoint:    cfg.GraphQLEndpoint,
+		retryPolicy: retryPolicyFromConfig(cfg),
+		trace:       cfg.Trace,
+		traceLevel:  cfg.TraceLevel,
+		agents:      newAgentCache(time.Duration(cfg.AgentCacheTTLSeconds) * time.Second),
 	}
 	globalClient = client
 	return client
 }
 
+// HTTP client defaults, used when the configuration leaves a value unset
+const (
+	defaultHTTPTimeout         = 30 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// newHTTPClient builds the pooled HTTP client used to reach csd-core. Every resolver makes
+// csd-core calls, so idle connections are kept open and reused instead of redialed.
+func newHTTPClient(cfg *config.CSDCoreConfig) *http.Client {
+	timeout := defaultHTTPTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	maxIdle := defaultMaxIdleConns
+	if cfg.MaxIdleConns > 0 {
+		maxIdle = cfg.MaxIdleConns
+	}
+	maxIdlePerHost := defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		maxIdlePerHost = cfg.MaxIdleConnsPerHost
+	}
+	idleTimeout := defaultIdleConnTimeout
+	if cfg.IdleConnTimeoutSeconds > 0 {
+		idleTimeout = time.Duration(cfg.IdleConnTimeoutSeconds) * time.Second
+	}
+	keepAlive := defaultKeepAlive
+	if cfg.KeepAliveSeconds > 0 {
+		keepAlive = time.Duration(cfg.KeepAliveSeconds) * time.Second
+	} else if cfg.KeepAliveSeconds < 0 {
+		keepAlive = -1
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: keepAlive,
+	}).DialContext
+	transport.MaxIdleConns = maxIdle
+	transport.MaxIdleConnsPerHost = maxIdlePerHost
+	transport.IdleConnTimeout = idleTimeout
+	transport.DisableKeepAlives = cfg.KeepAliveSeconds < 0
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// SetRetryPolicy replaces the retry policy of the client
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	c.retryPolicy = policy
+}
+
 // GetClient returns the global client
 func GetClient() *Client {
 	return globalClient
@@ -87,9 +230,19 @@ func (c *Client) Execute(ctx context.Context, token string, query string, variab
 	return c.ExecuteWithName(ctx, token, "", query, variables)
 }
 
-// ExecuteWithName executes a GraphQL query/mutation with an explicit operation name
-// Includes automatic retry with exponential backoff for transient errors
+// ExecuteWithName executes a GraphQL query/mutation with an explicit operation name.
+// Transient failures are retried with jittered exponential backoff per the client's retry policy
+// when the call is retryable (see WithRetry). Every call is timed in the metrics, and logged when tracing is enabled.
 func (c *Client) ExecuteWithName(ctx context.Context, token string, operationName string, query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+	start := time.Now()
+	resp, status, err := c.executeWithRetry(ctx, token, operationName, query, variables)
+	c.recordCall(operationLabel(operationName, query), variables, time.Since(start), status, resp, err)
+	return resp, err
+}
+
+// executeWithRetry runs ExecuteWithName's request and retry loop, also returning the last HTTP status (0 when none was received)
+func (c *Client) executeWithRetry(ctx context.Context, token string, operationName string, query string, variables map[string]interface{}) (*GraphQLResponse, int, error) {
+	status := 0
 	reqBody := GraphQLRequest{
 		Query:         query,
 		OperationName: operationName,
@@ -98,36 +251,35 @@ func (c *Client) ExecuteWithName(ctx context.Context, token string, operationNam
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, status, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	var lastErr error
-	backoff := defaultInitialBackoff
+	policy := c.retryPolicy
+	if policy.MaxAttempts < 1 || !retryEnabled(ctx, query) {
+		policy.MaxAttempts = 1
+	}
 
-	for attempt := 0; attempt <= defaultMaxRetries; attempt++ {
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
 		// Check context before each attempt
 		if ctx.Err() != nil {
-			return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
+			return nil, status, fmt.Errorf("context cancelled: %w", ctx.Err())
 		}
 
 		// Wait before retry (skip on first attempt)
 		if attempt > 0 {
-			log.Printf("[csd-core] Retry attempt %d/%d for operation %s after %v", attempt, defaultMaxRetries, operationName, backoff)
+			backoff := policy.delay(attempt)
+			log.Printf("[csd-core] Retry attempt %d/%d for operation %s after %v", attempt, policy.MaxAttempts-1, operationLabel(operationName, query), backoff)
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("context cancelled during retry: %w", ctx.Err())
+				return nil, status, fmt.Errorf("context cancelled during retry: %w", ctx.Err())
 			case <-time.After(backoff):
 			}
-			// Increase backoff with factor, cap at max
-			backoff = time.Duration(float64(backoff) * defaultBackoffFactor)
-			if backoff > defaultMaxBackoff {
-				backoff = defaultMaxBackoff
-			}
 		}
 
 		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+c.endpoint, bytes.NewBuffer(jsonBody))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+			return nil, status, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -137,51 +289,95 @@ func (c *Client) ExecuteWithName(ctx context.Context, token string, operationNam
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			lastErr = fmt.Errorf("failed to execute request: %w", err)
+			lastErr = newTransportError("failed to execute request", err, isRetryableError(err))
 			if isRetryableError(err) {
 				continue
 			}
-			return nil, lastErr
+			return nil, status, lastErr
 		}
+		status = resp.StatusCode
 
 		body, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		if err != nil {
-			lastErr = fmt.Errorf("failed to read response: %w", err)
+			lastErr = newTransportError("failed to read response", err, true)
 			continue // I/O errors are retryable
 		}
 
 		if len(body) == 0 {
-			lastErr = fmt.Errorf("empty response from server (status: %d)", resp.StatusCode)
+			lastErr = newStatusError(resp.StatusCode, fmt.Sprintf("empty response from server (status: %d)", resp.StatusCode))
 			if isRetryableStatusCode(resp.StatusCode) {
 				continue
 			}
-			return nil, lastErr
-		}
-
-		// Only retry on 5xx server errors, not 4xx client errors
-		if resp.StatusCode >= 500 {
-			lastErr = fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
-			continue
+			return nil, status, lastErr
 		}
 
+		// Only retry gateway and availability errors, never 4xx client errors
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+			lastErr = newStatusError(resp.StatusCode, fmt.Sprintf("server returned status %d: %s", resp.StatusCode, string(body)))
+			if isRetryableStatusCode(resp.StatusCode) {
+				continue
+			}
+			return nil, status, lastErr
 		}
 
 		var graphqlResp GraphQLResponse
 		if err := json.Unmarshal(body, &graphqlResp); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %w", err)
+			return nil, status, newTransportError("failed to parse response", err, false)
 		}
 
 		if len(graphqlResp.Errors) > 0 {
-			return &graphqlResp, fmt.Errorf("graphql error: %s", graphqlResp.Errors[0].Message)
+			return &graphqlResp, status, newGraphQLError(status, graphqlResp.Errors)
 		}
 
-		return &graphqlResp, nil
+		return &graphqlResp, status, nil
 	}
 
-	return nil, fmt.Errorf("max retries (%d) exceeded: %w", defaultMaxRetries, lastErr)
+	if policy.MaxAttempts == 1 {
+		return nil, status, lastErr
+	}
+	return nil, status, fmt.Errorf("max attempts (%d) exceeded: %w", policy.MaxAttempts, lastErr)
+}
+
+// operationNameRegex extracts the operation name declared in a GraphQL document
+var operationNameRegex = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+(\w+)`)
+
+// operationLabel names a call in traces and metrics: the explicit operation name, else the one declared in the query
+func operationLabel(operationName, query string) string {
+	if operationName != "" {
+		return operationName
+	}
+	if match := operationNameRegex.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+	return "anonymous"
+}
+
+// recordCall adds a call to the csd-core latency metrics and, when tracing is enabled, logs it.
+// Only variable names are logged since values may hold secrets.
+func (c *Client) recordCall(operation string, variables map[string]interface{}, duration time.Duration, status int, resp *GraphQLResponse, err error) {
+	metrics.GetMetrics().RecordCSDCoreCall(operation, duration, err != nil)
+	if !c.trace {
+		return
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	graphqlErrors := resp != nil && len(resp.Errors) > 0
+	message := fmt.Sprintf("[csd-core] operation=%s variables=[%s] duration=%s status=%d graphqlErrors=%t",
+		operation, strings.Join(keys, ","), duration.Round(time.Millisecond), status, graphqlErrors)
+	if err != nil && !graphqlErrors {
+		message += " error=" + err.Error()
+	}
+	if c.traceLevel == "info" {
+		logger.Info("%s", message)
+	} else {
+		logger.Debug("%s", message)
+	}
 }
 
 // isRetryableError determines if an error is transient and should be retried
@@ -227,10 +423,9 @@ func isNetError(err error, target *net.Error) bool {
 // isRetryableStatusCode determines if an HTTP status code indicates a retryable error
 func isRetryableStatusCode(code int) bool {
 	switch code {
-	case http.StatusBadGateway,         // 502
-		http.StatusServiceUnavailable,   // 503
-		http.StatusGatewayTimeout,       // 504
-		http.StatusInternalServerError:  // 500
+	case http.StatusBadGateway, // 502
+		http.StatusServiceUnavailable, // 503
+		http.StatusGatewayTimeout:     // 504
 		return true
 	default:
 		return false
@@ -296,8 +491,12 @@ func (c *Client) CheckPermission(ctx context.Context, token string, permission s
 	return result.HasPermission, nil
 }
 
-// GetArtifactContent gets artifact content from csd-core by key
-func (c *Client) GetArtifactContent(ctx context.Context, token string, key string) ([]byte, error) {
+// GetArtifactContent gets artifact content from csd-core by key.
+// csd-core returns the content base64 encoded, but older artifacts hold plaintext, and plaintext
+// made only of base64 characters would be corrupted by a blind decode. isPlain, when not nil,
+// recognises the caller's plaintext format (e.g. json.Valid) so such content is returned as is;
+// otherwise content that is not valid base64 is returned as is and the rest is decoded.
+func (c *Client) GetArtifactContent(ctx context.Context, token string, key string, isPlain func([]byte) bool) ([]byte, error) {
 	query := `
 		query GetArtifactByKey($key: String!) {
 			artifactByKey(key: $key) {
@@ -328,8 +527,15 @@ func (c *Client) GetArtifactContent(ctx context.Context, token string, key strin
 		return nil, fmt.Errorf("artifact not found: %s", key)
 	}
 
-	// Content is base64 encoded
-	return []byte(result.ArtifactByKey.Content), nil
+	content := []byte(result.ArtifactByKey.Content)
+	if isPlain != nil && isPlain(content) {
+		return content, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(result.ArtifactByKey.Content)
+	if err != nil {
+		return content, nil
+	}
+	return decoded, nil
 }
 
 // ExecutePlaybook executes a playbook via csd-core
@@ -390,6 +596,11 @@ type TaskExecution struct {
 	CompletedAt *string     `json:"completedAt"`
 }
 
+// IsTerminal reports whether the task has finished, successfully or not
+func (t *TaskExecution) IsTerminal() bool {
+	return t.Status == "SUCCESS" || t.Status == "FAILED"
+}
+
 // TaskInput defines a task to execute
 type TaskInput struct {
 	Type   string                 `json:"type"`
@@ -491,6 +702,47 @@ func (c *Client) GetTaskExecution(ctx context.Context, token string, executionID
 	return result.TaskExecution, nil
 }
 
+// defaultTaskPollInterval is used by WaitForTask when no poll interval is given
+const defaultTaskPollInterval = 2 * time.Second
+
+// WaitForTask polls a task execution until it reaches SUCCESS or FAILED and returns it.
+// onPoll, when not nil, receives every execution read, so callers can follow the task output.
+// Transient polling failures are retried; the wait ends early with the last known execution
+// and an error when ctx is cancelled or its deadline passes.
+func (c *Client) WaitForTask(ctx context.Context, token string, executionID uuid.UUID, pollInterval time.Duration, onPoll func(*TaskExecution)) (*TaskExecution, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultTaskPollInterval
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var execution *TaskExecution
+	for {
+		current, err := c.GetTaskExecution(ctx, token, executionID)
+		switch {
+		case err != nil && ctx.Err() == nil && KindOf(err) != ErrorKindTransient:
+			return execution, err
+		case err == nil && current == nil:
+			return execution, &CSDCoreError{Kind: ErrorKindNotFound, message: fmt.Sprintf("task execution not found: %s", executionID)}
+		case err == nil:
+			execution = current
+			if onPoll != nil {
+				onPoll(execution)
+			}
+			if execution.IsTerminal() {
+				return execution, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return execution, fmt.Errorf("task %s did not complete: %w", executionID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
 // ValidateAgentCapability checks if an agent supports a specific capability
 func (c *Client) ValidateAgentCapability(ctx context.Context, token string, agentID uuid.UUID, capability string) error {
 	agent, err := c.GetAgent(ctx, token, agentID)
@@ -499,11 +751,11 @@ func (c *Client) ValidateAgentCapability(ctx context.Context, token string, agen
 	}
 
 	if agent == nil {
-		return fmt.Errorf("agent not found: %s", agentID)
+		return &CSDCoreError{Kind: ErrorKindNotFound, message: fmt.Sprintf("agent not found: %s", agentID)}
 	}
 
 	if agent.Status != "ONLINE" {
-		return fmt.Errorf("agent is not online (status: %s)", agent.Status)
+		return &CSDCoreError{Kind: ErrorKindTransient, message: fmt.Sprintf("agent is not online (status: %s)", agent.Status)}
 	}
 
 	if !agent.HasCapability(capability) {
@@ -722,19 +974,57 @@ func (a *Agent) HasCapabilityPrefix(prefix string) bool {
 
 // ListAgentsByCapability lists agents that support a specific capability
 func (c *Client) ListAgentsByCapability(ctx context.Context, token string, capability string) ([]Agent, error) {
-	agents, err := c.ListAgents(ctx, token)
-	if err != nil {
-		return nil, err
-	}
+	return c.ListAgentsFiltered(ctx, token, capability)
+}
 
-	filtered := make([]Agent, 0)
-	for _, agent := range agents {
-		if agent.HasCapability(capability) {
-			filtered = append(filtered, agent)
+// ListAgentsFiltered lists agents that support a capability, letting csd-core do the filtering.
+// A cached full agent list is filtered locally instead, and so is a fresh one when csd-core
+// does not support the capability argument.
+func (c *Client) ListAgentsFiltered(ctx context.Context, token string, capability string) ([]Agent, error) {
+	hasCapability := func(a *Agent) bool { return a.HasCapability(capability) }
+
+	if agents, ok := c.agents.peek(token); ok {
+		return filterAgents(agents, hasCapability), nil
+	}
+
+	if !c.agentFilterUnsupported.Load() {
+		query := `
+			query ListAgentsByCapability($capability: String!) {
+				agents(capability: $capability) {
+					id
+					name
+					status
+					hostname
+					lastSeen
+					capabilities
+				}
+			}
+		`
+
+		resp, err := c.ExecuteWithName(ctx, token, "ListAgentsByCapability", query, map[string]interface{}{
+			"capability": capability,
+		})
+		if err == nil {
+			var result struct {
+				Agents []Agent `json:"agents"`
+			}
+			if err := json.Unmarshal(resp.Data, &result); err != nil {
+				return nil, fmt.Errorf("failed to parse agents: %w", err)
+			}
+			return result.Agents, nil
+		}
+		if !isUnsupportedQueryError(err) {
+			return nil, err
 		}
+		c.agentFilterUnsupported.Store(true)
+		log.Printf("[csd-core] Agents query does not accept a capability filter, filtering agents locally: %v", err)
 	}
 
-	return filtered, nil
+	agents, err := c.ListAgents(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return filterAgents(agents, hasCapability), nil
 }
 
 // ListAgentsByCapabilityPrefix lists agents that have any capability starting with prefix
@@ -744,14 +1034,7 @@ func (c *Client) ListAgentsByCapabilityPrefix(ctx context.Context, token string,
 		return nil, err
 	}
 
-	filtered := make([]Agent, 0)
-	for _, agent := range agents {
-		if agent.HasCapabilityPrefix(prefix) {
-			filtered = append(filtered, agent)
-		}
-	}
-
-	return filtered, nil
+	return filterAgents(agents, func(a *Agent) bool { return a.HasCapabilityPrefix(prefix) }), nil
 }
 
 // GetAgentCapabilitiesByPrefix returns capabilities matching a prefix for an agent
@@ -765,8 +1048,15 @@ func (a *Agent) GetCapabilitiesByPrefix(prefix string) []string {
 	return result
 }
 
-// ListAgents lists available agents
+// ListAgents lists available agents. The list is cached per token for the configured agent cache TTL
 func (c *Client) ListAgents(ctx context.Context, token string) ([]Agent, error) {
+	return c.agents.get(ctx, token, func() ([]Agent, error) {
+		return c.fetchAgents(ctx, token)
+	})
+}
+
+// fetchAgents queries csd-core for every agent visible to the token
+func (c *Client) fetchAgents(ctx context.Context, token string) ([]Agent, error) {
 	query := `
 		query ListAgents {
 			agents {
@@ -880,7 +1170,8 @@ func (c *Client) EncryptData(ctx context.Context, token string, data []byte, key
 		input["keyId"] = keyID
 	}
 
-	resp, err := c.ExecuteWithName(ctx, token, "EncryptData", mutation, map[string]interface{}{
+	// Encryption has no side effects, so it is safe to retry
+	resp, err := c.ExecuteWithName(WithRetry(ctx, true), token, "EncryptData", mutation, map[string]interface{}{
 		"input": input,
 	})
 	if err != nil {
@@ -915,7 +1206,8 @@ func (c *Client) DecryptData(ctx context.Context, token string, encryptedData st
 		input["keyId"] = keyID
 	}
 
-	resp, err := c.ExecuteWithName(ctx, token, "DecryptData", mutation, map[string]interface{}{
+	// Decryption has no side effects, so it is safe to retry
+	resp, err := c.ExecuteWithName(WithRetry(ctx, true), token, "DecryptData", mutation, map[string]interface{}{
 		"input": input,
 	})
 	if err != nil {
@@ -947,9 +1239,9 @@ func (c *Client) RegisterService(ctx context.Context, serviceToken string, reg *
 	}
 
 	var lastErr error
-	backoff := defaultInitialBackoff
+	policy := c.retryPolicy
 
-	for attempt := 0; attempt <= defaultMaxRetries; attempt++ {
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
 		// Check context before each attempt
 		if ctx.Err() != nil {
 			return fmt.Errorf("context cancelled: %w", ctx.Err())
@@ -957,17 +1249,13 @@ func (c *Client) RegisterService(ctx context.Context, serviceToken string, reg *
 
 		// Wait before retry (skip on first attempt)
 		if attempt > 0 {
-			log.Printf("[csd-core] Retry attempt %d/%d for service registration after %v", attempt, defaultMaxRetries, backoff)
+			backoff := policy.delay(attempt)
+			log.Printf("[csd-core] Retry attempt %d/%d for service registration after %v", attempt, policy.MaxAttempts-1, backoff)
 			select {
 			case <-ctx.Done():
 				return fmt.Errorf("context cancelled during retry: %w", ctx.Err())
 			case <-time.After(backoff):
 			}
-			// Increase backoff with factor, cap at max
-			backoff = time.Duration(float64(backoff) * defaultBackoffFactor)
-			if backoff > defaultMaxBackoff {
-				backoff = defaultMaxBackoff
-			}
 		}
 
 		req, err := http.NewRequestWithContext(ctx, "POST", regURL, bytes.NewBuffer(jsonBody))
@@ -1007,5 +1295,5 @@ func (c *Client) RegisterService(ctx context.Context, serviceToken string, reg *
 		return nil
 	}
 
-	return fmt.Errorf("max retries (%d) exceeded for service registration: %w", defaultMaxRetries, lastErr)
+	return fmt.Errorf("max attempts (%d) exceeded for service registration: %w", policy.MaxAttempts, lastErr)
 }
@@ -0,0 +1,298 @@
+package csdcore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"csd-pilote/backend/modules/platform/config"
+)
+
+// newTestClient returns a client talking to a test csd-core served by handler, retrying without delay
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.CSDCoreConfig{URL: server.URL, GraphQLEndpoint: "/graphql"})
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	return client
+}
+
+// writeData writes a successful GraphQL response with data
+func writeData(t *testing.T, w http.ResponseWriter, data interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GraphQLResponse{Data: raw})
+}
+
+func TestWaitForTask(t *testing.T) {
+	id := uuid.New()
+	statuses := []string{"RUNNING", "RUNNING", "SUCCESS"}
+	var polls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := int(polls.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		writeData(t, w, map[string]interface{}{"taskExecution": TaskExecution{ID: id, Status: statuses[n], Output: statuses[n]}})
+	})
+
+	var seen []string
+	execution, err := client.WaitForTask(context.Background(), "token", id, time.Millisecond, func(current *TaskExecution) {
+		seen = append(seen, current.Status)
+	})
+	if err != nil {
+		t.Fatalf("WaitForTask: %v", err)
+	}
+	if execution.Status != "SUCCESS" {
+		t.Errorf("status = %s, want SUCCESS", execution.Status)
+	}
+	if len(seen) != len(statuses) {
+		t.Errorf("onPoll saw %v, want %v", seen, statuses)
+	}
+}
+
+func TestWaitForTaskContextDone(t *testing.T) {
+	id := uuid.New()
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeData(t, w, map[string]interface{}{"taskExecution": TaskExecution{ID: id, Status: "RUNNING"}})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	execution, err := client.WaitForTask(ctx, "token", id, time.Millisecond, nil)
+	if err == nil {
+		t.Fatal("WaitForTask returned no error after its context expired")
+	}
+	if execution == nil || execution.Status != "RUNNING" {
+		t.Errorf("execution = %+v, want the last RUNNING execution", execution)
+	}
+}
+
+// flakyHandler fails the first failures requests with status, then answers with data
+func flakyHandler(t *testing.T, calls *atomic.Int32, failures int32, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte("unavailable"))
+			return
+		}
+		writeData(t, w, map[string]interface{}{"ok": true})
+	}
+}
+
+func TestExecuteRetry(t *testing.T) {
+	const query = `query Ping { ok }`
+	const mutation = `mutation Apply { ok }`
+	tests := []struct {
+		name      string
+		query     string
+		retry     *bool // WithRetry value, nil when the context carries none
+		failures  int32
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "query retried on 503", query: query, failures: 2, status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "query retried on 502", query: query, failures: 1, status: http.StatusBadGateway, wantCalls: 2},
+		{name: "query retried on 504", query: query, failures: 1, status: http.StatusGatewayTimeout, wantCalls: 2},
+		{name: "query gives up after max attempts", query: query, failures: 5, status: http.StatusServiceUnavailable, wantCalls: 3, wantErr: true},
+		{name: "4xx never retried", query: query, failures: 1, status: http.StatusBadRequest, wantCalls: 1, wantErr: true},
+		{name: "500 never retried", query: query, failures: 1, status: http.StatusInternalServerError, wantCalls: 1, wantErr: true},
+		{name: "mutation not retried by default", query: mutation, failures: 1, status: http.StatusServiceUnavailable, wantCalls: 1, wantErr: true},
+		{name: "mutation retried when opted in", query: mutation, retry: boolPtr(true), failures: 1, status: http.StatusServiceUnavailable, wantCalls: 2},
+		{name: "query not retried when opted out", query: query, retry: boolPtr(false), failures: 1, status: http.StatusServiceUnavailable, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			client := newTestClient(t, flakyHandler(t, &calls, tt.failures, tt.status))
+
+			ctx := context.Background()
+			if tt.retry != nil {
+				ctx = WithRetry(ctx, *tt.retry)
+			}
+			_, err := client.Execute(ctx, "token", tt.query, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("csd-core received %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphQLErrorNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(GraphQLResponse{Errors: []GraphQLError{{Message: "agent not found"}}})
+	})
+
+	_, err := client.Execute(context.Background(), "token", `query Agent { agent { id } }`, nil)
+	if err == nil {
+		t.Fatal("Execute returned no error for a GraphQL error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("csd-core received %d calls, want 1", got)
+	}
+}
+
+func TestExecuteTaskNotRetried(t *testing.T) {
+	// Replaying ExecuteTask could run the task twice on the agent
+	var calls atomic.Int32
+	client := newTestClient(t, flakyHandler(t, &calls, 1, http.StatusServiceUnavailable))
+
+	if _, err := client.ExecuteTask(context.Background(), "token", &ExecuteTaskInput{AgentID: uuid.New()}); err == nil {
+		t.Fatal("ExecuteTask succeeded on a 503")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("csd-core received %d calls, want 1", got)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestGetArtifactContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isPlain func([]byte) bool
+		want    string
+	}{
+		{"base64", base64.StdEncoding.EncodeToString([]byte("apiVersion: v1\nkind: Config\n")), nil, "apiVersion: v1\nkind: Config\n"},
+		{"base64 json", base64.StdEncoding.EncodeToString([]byte(`{"rules":[]}`)), json.Valid, `{"rules":[]}`},
+		{"plaintext", "apiVersion: v1\nkind: Config\n", nil, "apiVersion: v1\nkind: Config\n"},
+		{"plaintext json", `{"rules":[]}`, json.Valid, `{"rules":[]}`},
+		// "1234" and "true" are valid base64 and valid JSON: isPlain keeps them from being decoded
+		{"plaintext that is valid base64", "1234", json.Valid, "1234"},
+		{"plaintext word that is valid base64", "true", json.Valid, "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeData(t, w, map[string]interface{}{"artifactByKey": map[string]string{"id": "1", "content": tt.content}})
+			})
+			got, err := client.GetArtifactContent(context.Background(), "token", "key", tt.isPlain)
+			if err != nil {
+				t.Fatalf("GetArtifactContent: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetArtifactContent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetArtifactContentNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeData(t, w, map[string]interface{}{"artifactByKey": nil})
+	})
+	if _, err := client.GetArtifactContent(context.Background(), "token", "missing", nil); err == nil {
+		t.Error("GetArtifactContent returned no error for a missing artifact")
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              config.CSDCoreConfig
+		timeout          time.Duration
+		maxIdle          int
+		maxIdlePerHost   int
+		idleTimeout      time.Duration
+		disableKeepAlive bool
+	}{
+		{
+			name:           "defaults",
+			timeout:        defaultHTTPTimeout,
+			maxIdle:        defaultMaxIdleConns,
+			maxIdlePerHost: defaultMaxIdleConnsPerHost,
+			idleTimeout:    defaultIdleConnTimeout,
+		},
+		{
+			name:           "configured",
+			cfg:            config.CSDCoreConfig{TimeoutSeconds: 5, MaxIdleConns: 10, MaxIdleConnsPerHost: 4, IdleConnTimeoutSeconds: 15, KeepAliveSeconds: 20},
+			timeout:        5 * time.Second,
+			maxIdle:        10,
+			maxIdlePerHost: 4,
+			idleTimeout:    15 * time.Second,
+		},
+		{
+			name:             "keep-alives disabled",
+			cfg:              config.CSDCoreConfig{KeepAliveSeconds: -1},
+			timeout:          defaultHTTPTimeout,
+			maxIdle:          defaultMaxIdleConns,
+			maxIdlePerHost:   defaultMaxIdleConnsPerHost,
+			idleTimeout:      defaultIdleConnTimeout,
+			disableKeepAlive: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newHTTPClient(&tt.cfg)
+			if client.Timeout != tt.timeout {
+				t.Errorf("Timeout = %v, want %v", client.Timeout, tt.timeout)
+			}
+			transport, ok := client.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("Transport is %T, want *http.Transport", client.Transport)
+			}
+			if transport.MaxIdleConns != tt.maxIdle {
+				t.Errorf("MaxIdleConns = %d, want %d", transport.MaxIdleConns, tt.maxIdle)
+			}
+			if transport.MaxIdleConnsPerHost != tt.maxIdlePerHost {
+				t.Errorf("MaxIdleConnsPerHost = %d, want %d", transport.MaxIdleConnsPerHost, tt.maxIdlePerHost)
+			}
+			if transport.IdleConnTimeout != tt.idleTimeout {
+				t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, tt.idleTimeout)
+			}
+			if transport.DisableKeepAlives != tt.disableKeepAlive {
+				t.Errorf("DisableKeepAlives = %v, want %v", transport.DisableKeepAlives, tt.disableKeepAlive)
+			}
+			if transport.DialContext == nil {
+				t.Error("DialContext is not set")
+			}
+		})
+	}
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeData(t, w, map[string]interface{}{"ok": true})
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.CSDCoreConfig{URL: server.URL, GraphQLEndpoint: "/graphql"})
+	for i := 0; i < 5; i++ {
+		if _, err := client.Execute(context.Background(), "token", `query Ping { ok }`, nil); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("opened %d connections for 5 sequential calls, want 1", got)
+	}
+}
@@ -0,0 +1,117 @@
+package csdcore
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind classifies a csd-core failure so callers can react without matching messages
+type ErrorKind string
+
+const (
+	ErrorKindNotFound     ErrorKind = "NOT_FOUND"    // The requested resource does not exist
+	ErrorKindUnauthorized ErrorKind = "UNAUTHORIZED" // Missing or invalid token, or permission denied
+	ErrorKindTransient    ErrorKind = "TRANSIENT"    // Connectivity, gateway or availability problem; worth retrying
+	ErrorKindServer       ErrorKind = "SERVER"       // Any other failure reported by csd-core
+)
+
+// CSDCoreError is returned by the client when a csd-core call fails
+type CSDCoreError struct {
+	Kind       ErrorKind
+	StatusCode int      // HTTP status, 0 when no response was received
+	Messages   []string // GraphQL error messages, empty for transport and HTTP errors
+	message    string
+	err        error // Underlying transport error, if any
+}
+
+func (e *CSDCoreError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the underlying transport error
+func (e *CSDCoreError) Unwrap() error {
+	return e.err
+}
+
+// Retryable reports whether the call may succeed if repeated
+func (e *CSDCoreError) Retryable() bool {
+	return e.Kind == ErrorKindTransient
+}
+
+// notFoundMarkers, unauthorizedMarkers and transientMarkers classify GraphQL error messages
+var (
+	notFoundMarkers     = []string{"not found", "no rows", "does not exist"}
+	unauthorizedMarkers = []string{"unauthorized", "unauthenticated", "permission denied", "forbidden", "invalid token", "token expired"}
+	transientMarkers    = []string{"offline", "unavailable", "timeout", "timed out", "connection refused", "connection reset"}
+)
+
+// classifyMessage returns the kind matching a csd-core error message, defaulting to ErrorKindServer
+func classifyMessage(message string) ErrorKind {
+	lower := strings.ToLower(message)
+	for _, group := range []struct {
+		kind    ErrorKind
+		markers []string
+	}{
+		{ErrorKindNotFound, notFoundMarkers},
+		{ErrorKindUnauthorized, unauthorizedMarkers},
+		{ErrorKindTransient, transientMarkers},
+	} {
+		for _, marker := range group.markers {
+			if strings.Contains(lower, marker) {
+				return group.kind
+			}
+		}
+	}
+	return ErrorKindServer
+}
+
+// classifyStatus returns the kind of a non-200 HTTP status
+func classifyStatus(code int) ErrorKind {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrorKindUnauthorized
+	case code == http.StatusNotFound:
+		return ErrorKindNotFound
+	case isRetryableStatusCode(code):
+		return ErrorKindTransient
+	}
+	return ErrorKindServer
+}
+
+// newGraphQLError builds the error of a response carrying GraphQL errors
+func newGraphQLError(status int, graphqlErrors []GraphQLError) *CSDCoreError {
+	messages := make([]string, 0, len(graphqlErrors))
+	for _, e := range graphqlErrors {
+		messages = append(messages, e.Message)
+	}
+	return &CSDCoreError{
+		Kind:       classifyMessage(messages[0]),
+		StatusCode: status,
+		Messages:   messages,
+		message:    "graphql error: " + messages[0],
+	}
+}
+
+// newStatusError builds the error of a non-200 HTTP response
+func newStatusError(status int, message string) *CSDCoreError {
+	return &CSDCoreError{Kind: classifyStatus(status), StatusCode: status, message: message}
+}
+
+// newTransportError builds the error of a request that got no usable response
+func newTransportError(message string, err error, transient bool) *CSDCoreError {
+	kind := ErrorKindServer
+	if transient {
+		kind = ErrorKindTransient
+	}
+	return &CSDCoreError{Kind: kind, message: message + ": " + err.Error(), err: err}
+}
+
+// KindOf returns the kind of a csd-core error anywhere in err's chain, or "" when there is none
+func KindOf(err error) ErrorKind {
+	var coreErr *CSDCoreError
+	if errors.As(err, &coreErr) {
+		return coreErr.Kind
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	"csd-pilote/backend/modules/pilot/activity"
 	"csd-pilote/backend/modules/pilot/clusters"
 	"csd-pilote/backend/modules/pilot/containers"
 	"csd-pilote/backend/modules/pilot/hypervisors"
@@ -180,6 +181,38 @@ func migrateGroup(db *gorm.DB, groupName string, models []interface{}) (GroupRes
 	return result, nil
 }
 
+// normalizeRuleProtocols rewrites rule protocols stored in lower case by older versions to the
+// upper-case values the rule generator compares against; "any" becomes ALL
+func normalizeRuleProtocols(db *gorm.DB) error {
+	table := SchemaName + ".firewall_rules"
+	if err := db.Exec("UPDATE " + table + " SET protocol = UPPER(protocol) WHERE protocol <> UPPER(protocol)").Error; err != nil {
+		return fmt.Errorf("failed to normalize firewall rule protocols: %w", err)
+	}
+	if err := db.Exec("UPDATE " + table + " SET protocol = 'ALL' WHERE protocol = 'ANY'").Error; err != nil {
+		return fmt.Errorf("failed to normalize firewall rule protocols: %w", err)
+	}
+	return nil
+}
+
+// normalizeTemplateCategories rewrites the WEBSERVER template category accepted by older
+// versions to WEB_SERVER, the spelling of the built-in templates
+func normalizeTemplateCategories(db *gorm.DB) error {
+	table := SchemaName + ".firewall_templates"
+	if err := db.Exec("UPDATE " + table + " SET category = 'WEB_SERVER' WHERE category = 'WEBSERVER'").Error; err != nil {
+		return fmt.Errorf("failed to normalize firewall template categories: %w", err)
+	}
+	return nil
+}
+
+// dropRuleCreatedIndex drops the (tenant_id, created_at, id) rule index that rule pages, now
+// ordered by priority first, no longer use; idx_rule_tenant_priority_created replaces it
+func dropRuleCreatedIndex(db *gorm.DB) error {
+	if err := db.Exec("DROP INDEX IF EXISTS " + SchemaName + ".idx_rule_tenant_created").Error; err != nil {
+		return fmt.Errorf("failed to drop idx_rule_tenant_created: %w", err)
+	}
+	return nil
+}
+
 // logGroupResult logs the result of a group migration (only in verbose mode)
 func logGroupResult(group GroupResult) {
 	if !Verbose {
@@ -253,6 +286,7 @@ func AutoMigrateWithResult() (*MigrationResult, error) {
 	// Container Engines
 	containerModels := []interface{}{
 		&containers.ContainerEngine{},
+		&containers.ContainerSnapshot{},
 	}
 	group, err = migrateGroup(DB, "Container Engines", containerModels)
 	if err != nil {
@@ -268,6 +302,13 @@ func AutoMigrateWithResult() (*MigrationResult, error) {
 		&security.FirewallProfileRule{},
 		&security.FirewallTemplate{},
 		&security.FirewallDeployment{},
+		&security.FirewallIPSet{},
+		&security.FirewallAgentGroup{},
+		&security.FirewallAgentGroupMember{},
+		&security.FirewallRollout{},
+		&security.FirewallCounterSnapshot{},
+		&security.FirewallEnforcementPause{},
+		&security.FirewallSchedule{},
 	}
 	group, err = migrateGroup(DB, "Firewall Security", securityModels)
 	if err != nil {
@@ -275,6 +316,26 @@ func AutoMigrateWithResult() (*MigrationResult, error) {
 	}
 	result.AddGroup(group)
 	logGroupResult(group)
+	if err := normalizeRuleProtocols(DB); err != nil {
+		return nil, err
+	}
+	if err := dropRuleCreatedIndex(DB); err != nil {
+		return nil, err
+	}
+	if err := normalizeTemplateCategories(DB); err != nil {
+		return nil, err
+	}
+
+	// Activity Feed
+	activityModels := []interface{}{
+		&activity.ActivityEvent{},
+	}
+	group, err = migrateGroup(DB, "Activity Feed", activityModels)
+	if err != nil {
+		return nil, err
+	}
+	result.AddGroup(group)
+	logGroupResult(group)
 
 	// Create indexes
 	if Verbose {
@@ -364,6 +425,12 @@ func createIndexes(db *gorm.DB) (int, error) {
 		{"idx_firewall_deployments_agent", SchemaName + ".firewall_deployments", "agent_id"},
 		{"idx_firewall_deployments_status", SchemaName + ".firewall_deployments", "status"},
 		{"idx_firewall_deployments_action", SchemaName + ".firewall_deployments", "action"},
+		{"idx_firewall_deployments_rollout", SchemaName + ".firewall_deployments", "rollout_id"},
+
+		// Firewall Agent Groups & Rollouts
+		{"idx_firewall_agent_groups_tenant", SchemaName + ".firewall_agent_groups", "tenant_id"},
+		{"idx_firewall_rollouts_tenant", SchemaName + ".firewall_rollouts", "tenant_id"},
+		{"idx_firewall_rollouts_profile", SchemaName + ".firewall_rollouts", "profile_id"},
 	}
 
 	for _, idx := range indexes {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,6 +34,11 @@ const (
 	EventContainerEngineDeleted   EventType = "container_engine.deleted"
 	EventContainerEngineConnected EventType = "container_engine.connected"
 	EventContainerEngineError     EventType = "container_engine.error"
+	EventContainerStopped         EventType = "container.stopped"          // A running container was found exited or dead
+	EventContainerMissing         EventType = "container.missing"          // A running container is no longer listed by its engine
+	EventContainerActionPerformed EventType = "container.action_performed" // A lifecycle action (start, stop, kill...) was run on a container
+	EventContainerStackDeployed   EventType = "container_stack.deployed"   // Every service of a stack was deployed
+	EventContainerStackFailed     EventType = "container_stack.failed"     // At least one service of a stack failed
 
 	// Firewall Security Events
 	EventFirewallRuleCreated      EventType = "firewall_rule.created"
@@ -49,6 +55,9 @@ const (
 	EventFirewallDeployFailed     EventType = "firewall_deploy.failed"
 	EventFirewallRollbackStarted  EventType = "firewall_rollback.started"
 	EventFirewallRollbackCompleted EventType = "firewall_rollback.completed"
+	EventFirewallRolloutStarted   EventType = "firewall_rollout.started"
+	EventFirewallRolloutCompleted EventType = "firewall_rollout.completed"
+	EventFirewallRolloutFailed    EventType = "firewall_rollout.failed"
 )
 
 // Event represents a domain event
@@ -57,6 +66,7 @@ type Event struct {
 	Type       EventType              `json:"type"`
 	TenantID   uuid.UUID              `json:"tenantId"`
 	ResourceID string                 `json:"resourceId"`
+	ActorID    *uuid.UUID             `json:"actorId,omitempty"` // User who triggered the event, when known
 	Payload    map[string]interface{} `json:"payload"`
 	Timestamp  time.Time              `json:"timestamp"`
 }
@@ -100,11 +110,14 @@ func (b *EventBus) SubscribeAll(handler Handler) {
 		EventHypervisorDeploying, EventHypervisorConnected, EventHypervisorError,
 		EventContainerEngineCreated, EventContainerEngineUpdated, EventContainerEngineDeleted,
 		EventContainerEngineConnected, EventContainerEngineError,
+		EventContainerStopped, EventContainerMissing, EventContainerActionPerformed,
+		EventContainerStackDeployed, EventContainerStackFailed,
 		EventFirewallRuleCreated, EventFirewallRuleUpdated, EventFirewallRuleDeleted,
 		EventFirewallProfileCreated, EventFirewallProfileUpdated, EventFirewallProfileDeleted,
 		EventFirewallTemplateCreated, EventFirewallTemplateUpdated, EventFirewallTemplateDeleted,
 		EventFirewallDeployStarted, EventFirewallDeployCompleted, EventFirewallDeployFailed,
 		EventFirewallRollbackStarted, EventFirewallRollbackCompleted,
+		EventFirewallRolloutStarted, EventFirewallRolloutCompleted, EventFirewallRolloutFailed,
 	}
 
 	for _, t := range eventTypes {
@@ -158,6 +171,20 @@ func NewEvent(eventType EventType, tenantID uuid.UUID, resourceID string, payloa
 	}
 }
 
+// WithActor returns a copy of the event attributed to the given user
+func (e Event) WithActor(userID uuid.UUID) Event {
+	e.ActorID = &userID
+	return e
+}
+
+// ResourceType returns the resource part of the event type (e.g. "firewall_rule")
+func (e Event) ResourceType() string {
+	if i := strings.Index(string(e.Type), "."); i >= 0 {
+		return string(e.Type)[:i]
+	}
+	return string(e.Type)
+}
+
 // ToJSON converts the event to JSON
 func (e Event) ToJSON() ([]byte, error) {
 	return json.Marshal(e)
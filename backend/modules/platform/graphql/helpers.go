@@ -3,6 +3,7 @@ package graphql
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -100,9 +101,11 @@ func ParseFilterSearch(filter map[string]interface{}) (string, error) {
 
 // ParsePagination extracts and validates pagination parameters
 func ParsePagination(variables map[string]interface{}) (limit, offset int) {
-	limit = pagination.DefaultLimit()
-	offset = 0
+	return ParsePaginationFor(variables, "")
+}
 
+// ParsePaginationFor extracts pagination parameters and applies the limits of a resource type
+func ParsePaginationFor(variables map[string]interface{}, resource string) (limit, offset int) {
 	if l, ok := variables["limit"].(float64); ok {
 		limit = int(l)
 	}
@@ -110,9 +113,8 @@ func ParsePagination(variables map[string]interface{}) (limit, offset int) {
 		offset = int(o)
 	}
 
-	// Apply validation limits
-	limit, offset, _ = validation.ValidatePagination(limit, offset)
-	return limit, offset
+	p := pagination.NormalizeFor(resource, limit, offset)
+	return p.Limit, p.Offset
 }
 
 // ParseUUID extracts and validates a UUID from variables
@@ -196,6 +198,29 @@ func WriteValidationError(w http.ResponseWriter, message string) {
 	json.NewEncoder(w).Encode(NewErrorResponse(message))
 }
 
+// WriteValidationErrors writes one error per invalid field, with the field and code in the extensions
+func WriteValidationErrors(w http.ResponseWriter, errs *validation.ValidationErrors) {
+	json.NewEncoder(w).Encode(NewValidationErrorResponse(errs))
+}
+
+// WriteFieldErrors writes err per field when it is or wraps *validation.ValidationErrors,
+// and reports whether it did
+func WriteFieldErrors(w http.ResponseWriter, err error) bool {
+	var errs *validation.ValidationErrors
+	if !errors.As(err, &errs) || !errs.HasErrors() {
+		return false
+	}
+	WriteValidationErrors(w, errs)
+	return true
+}
+
+// WriteInputError writes an input parsing error, per field when it carries validation errors
+func WriteInputError(w http.ResponseWriter, err error) {
+	if !WriteFieldErrors(w, err) {
+		WriteValidationError(w, err.Error())
+	}
+}
+
 // WriteUnauthorized writes an unauthorized error response
 func WriteUnauthorized(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
@@ -237,10 +262,11 @@ var (
 	ContainerEngineTypeValues   = []string{"DOCKER", "PODMAN"}
 	ContainerEngineStatusValues = []string{"PENDING", "CONNECTED", "DISCONNECTED", "ERROR"}
 	ContainerActionValues     = []string{"start", "stop", "restart", "pause", "unpause", "kill", "remove"}
+	ContainerStateValues      = []string{"created", "running", "paused", "restarting", "removing", "exited", "dead"}
 	RuleChainValues           = []string{"INPUT", "OUTPUT", "FORWARD", "PREROUTING", "POSTROUTING"}
 	RuleProtocolValues        = []string{"tcp", "udp", "icmp", "icmpv6", "all", "any"}
 	RuleActionValues          = []string{"ACCEPT", "DROP", "REJECT", "LOG", "MASQUERADE", "SNAT", "DNAT", "RETURN", "JUMP"}
 	DeploymentStatusValues    = []string{"PENDING", "RUNNING", "COMPLETED", "FAILED", "ROLLED_BACK"}
-	TemplateCategoryValues    = []string{"BASIC", "WEBSERVER", "DATABASE", "MAIL", "DNS", "MONITORING", "SECURITY", "CUSTOM"}
+	TemplateCategoryValues    = []string{"BASIC", "WEB_SERVER", "DATABASE", "BASTION", "GATEWAY", "MAIL", "DNS", "MONITORING", "SECURITY", "CUSTOM"}
 	KubernetesDistroValues    = []string{"K3S", "RKE2", "KUBEADM", "K0S", "MICROK8S", "EKS", "GKE", "AKS", "OPENSHIFT", "RANCHER", "OTHER"}
 )
@@ -1,5 +1,7 @@
 package graphql
 
+import "csd-pilote/backend/modules/platform/validation"
+
 // GraphQLRequest represents an incoming GraphQL request
 type GraphQLRequest struct {
 	Query         string                 `json:"query"`
@@ -33,6 +35,22 @@ func NewDataResponse(data interface{}) GraphQLResponse {
 	}
 }
 
+// NewValidationErrorResponse creates an error response with one error per invalid field,
+// carrying the field and code in the extensions
+func NewValidationErrorResponse(errs *validation.ValidationErrors) GraphQLResponse {
+	response := GraphQLResponse{Errors: make([]GraphQLError, 0, len(errs.Errors))}
+	for _, e := range errs.Errors {
+		response.Errors = append(response.Errors, GraphQLError{
+			Message: e.Message,
+			Extensions: map[string]interface{}{
+				"code":  e.Code,
+				"field": e.Field,
+			},
+		})
+	}
+	return response
+}
+
 // NewErrorResponseWithCode creates an error response with an error code
 func NewErrorResponseWithCode(code, message string) GraphQLResponse {
 	return GraphQLResponse{
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"time"
 
@@ -222,3 +223,11 @@ func Error(format string, args ...interface{}) {
 func Debug(format string, args ...interface{}) {
 	GetGlobalLogger().Debug(format, args...)
 }
+
+// RecoverPanic logs a panic with its stack instead of letting it crash the process.
+// It must be deferred directly: defer logger.RecoverPanic("[Security] schedule job")
+func RecoverPanic(scope string) {
+	if r := recover(); r != nil {
+		GetGlobalLogger().Error("%s panicked: %v\n%s", scope, r, debug.Stack())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,6 +35,9 @@ type Metrics struct {
 	// Error tracking by type
 	ErrorCounts map[string]*uint64
 
+	// csd-core call latencies by GraphQL operation name
+	CSDCoreCalls map[string]*LatencyHistogram
+
 	// Start time for uptime calculation
 	StartTime time.Time
 }
@@ -49,6 +53,7 @@ func GetMetrics() *Metrics {
 		globalMetrics = &Metrics{
 			OperationCounts: make(map[string]*uint64),
 			ErrorCounts:     make(map[string]*uint64),
+			CSDCoreCalls:    make(map[string]*LatencyHistogram),
 			StartTime:       time.Now(),
 			MinLatency:      ^uint64(0), // Max uint64 value initially
 		}
@@ -128,6 +133,101 @@ func (m *Metrics) RecordLatency(latencyMs uint64) {
 	}
 }
 
+// latencyBucketsMs are the upper bounds of the latency histogram buckets; slower calls go to a final +Inf bucket
+var latencyBucketsMs = []uint64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// LatencyHistogram counts calls per latency bucket
+type LatencyHistogram struct {
+	mu      sync.Mutex
+	count   uint64
+	errors  uint64
+	totalMs uint64
+	maxMs   uint64
+	buckets []uint64 // One per latencyBucketsMs entry plus +Inf
+}
+
+// LatencyBucket is the number of calls at or below an upper bound ("+Inf" for the last bucket)
+type LatencyBucket struct {
+	Le    string `json:"le"`
+	Count uint64 `json:"count"`
+}
+
+// LatencyStats is a snapshot of a latency histogram
+type LatencyStats struct {
+	Count            uint64          `json:"count"`
+	Errors           uint64          `json:"errors"`
+	AverageLatencyMs float64         `json:"average_latency_ms"`
+	MaxLatencyMs     uint64          `json:"max_latency_ms"`
+	Buckets          []LatencyBucket `json:"buckets"`
+}
+
+// observe adds one call to the histogram
+func (h *LatencyHistogram) observe(latencyMs uint64, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(latencyBucketsMs)+1)
+	}
+	h.count++
+	if failed {
+		h.errors++
+	}
+	h.totalMs += latencyMs
+	if latencyMs > h.maxMs {
+		h.maxMs = latencyMs
+	}
+	bucket := len(latencyBucketsMs)
+	for i, bound := range latencyBucketsMs {
+		if latencyMs <= bound {
+			bucket = i
+			break
+		}
+	}
+	h.buckets[bucket]++
+}
+
+// stats returns a snapshot of the histogram
+func (h *LatencyHistogram) stats() LatencyStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := LatencyStats{
+		Count:        h.count,
+		Errors:       h.errors,
+		MaxLatencyMs: h.maxMs,
+		Buckets:      make([]LatencyBucket, 0, len(latencyBucketsMs)+1),
+	}
+	if h.count > 0 {
+		stats.AverageLatencyMs = float64(h.totalMs) / float64(h.count)
+	}
+	for i := 0; i <= len(latencyBucketsMs); i++ {
+		le := "+Inf"
+		if i < len(latencyBucketsMs) {
+			le = strconv.FormatUint(latencyBucketsMs[i], 10) + "ms"
+		}
+		var count uint64
+		if h.buckets != nil {
+			count = h.buckets[i]
+		}
+		stats.Buckets = append(stats.Buckets, LatencyBucket{Le: le, Count: count})
+	}
+	return stats
+}
+
+// RecordCSDCoreCall records the duration of a csd-core GraphQL call by operation name
+func (m *Metrics) RecordCSDCoreCall(operation string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	histogram := m.CSDCoreCalls[operation]
+	if histogram == nil {
+		histogram = &LatencyHistogram{}
+		m.CSDCoreCalls[operation] = histogram
+	}
+	m.mu.Unlock()
+
+	histogram.observe(uint64(duration.Milliseconds()), failed)
+}
+
 // GetAverageLatency returns average latency in milliseconds
 func (m *Metrics) GetAverageLatency() float64 {
 	count := atomic.LoadUint64(&m.RequestCount)
@@ -164,6 +264,9 @@ type Snapshot struct {
 	// Errors
 	ErrorCounts map[string]uint64 `json:"error_counts"`
 
+	// csd-core call latencies by operation name
+	CSDCoreCalls map[string]LatencyStats `json:"csd_core_calls"`
+
 	// Runtime metrics
 	Runtime RuntimeMetrics `json:"runtime"`
 }
@@ -200,6 +303,10 @@ func (m *Metrics) GetSnapshot() Snapshot {
 	for k, v := range m.ErrorCounts {
 		errCounts[k] = atomic.LoadUint64(v)
 	}
+	csdCoreCalls := make(map[string]LatencyStats, len(m.CSDCoreCalls))
+	for k, v := range m.CSDCoreCalls {
+		csdCoreCalls[k] = v.stats()
+	}
 	m.mu.RUnlock()
 
 	// Get runtime stats
@@ -225,6 +332,7 @@ func (m *Metrics) GetSnapshot() Snapshot {
 		MinLatencyMs:      minLatency,
 		OperationCounts:   opCounts,
 		ErrorCounts:       errCounts,
+		CSDCoreCalls:      csdCoreCalls,
 		Runtime: RuntimeMetrics{
 			Goroutines:   runtime.NumGoroutine(),
 			HeapAllocMB:  float64(memStats.HeapAlloc) / 1024 / 1024,
@@ -251,6 +359,7 @@ func (m *Metrics) Reset() {
 	m.mu.Lock()
 	m.OperationCounts = make(map[string]*uint64)
 	m.ErrorCounts = make(map[string]*uint64)
+	m.CSDCoreCalls = make(map[string]*LatencyHistogram)
 	m.StartTime = time.Now()
 	m.mu.Unlock()
 }
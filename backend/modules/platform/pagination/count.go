@@ -0,0 +1,104 @@
+package pagination
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"csd-pilote/backend/modules/platform/config"
+)
+
+// Built-in count thresholds used when the config does not set them
+const (
+	fallbackExactCountThreshold    = 10000
+	fallbackEstimateCountThreshold = 100000
+)
+
+// CountStrategy chooses between an exact COUNT(*) and the planner's row estimate for the total
+// of a list query. Estimates come from the statistics in pg_class and pg_stats, so they cost a
+// planning round trip instead of a scan of every matching row.
+type CountStrategy struct {
+	ExactThreshold         int64 // Unfiltered lists estimated above this use the estimate
+	EstimateThreshold      int64 // Filtered lists estimated above this use the estimate
+	AlwaysExactWithFilters bool  // Filtered lists are always counted exactly
+}
+
+// CurrentCountStrategy returns the count strategy of the pagination config
+func CurrentCountStrategy() CountStrategy {
+	strategy := CountStrategy{
+		ExactThreshold:         fallbackExactCountThreshold,
+		EstimateThreshold:      fallbackEstimateCountThreshold,
+		AlwaysExactWithFilters: true,
+	}
+
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return strategy
+	}
+	if cfg.Pagination.ExactCountThreshold > 0 {
+		strategy.ExactThreshold = cfg.Pagination.ExactCountThreshold
+	}
+	if cfg.Pagination.EstimateCountThreshold > 0 {
+		strategy.EstimateThreshold = cfg.Pagination.EstimateCountThreshold
+	}
+	if cfg.Pagination.AlwaysExactWithFilters != nil {
+		strategy.AlwaysExactWithFilters = *cfg.Pagination.AlwaysExactWithFilters
+	}
+	return strategy
+}
+
+// Count returns the number of rows matched by query, which must have its model and conditions
+// set. filtered tells whether the query carries conditions beyond its tenant scope. Lists below
+// the threshold that applies, or whose estimate cannot be read, are counted exactly.
+func (s CountStrategy) Count(query *gorm.DB, filtered bool) (int64, error) {
+	threshold := s.ExactThreshold
+	if filtered {
+		if s.AlwaysExactWithFilters {
+			return exactCount(query)
+		}
+		threshold = s.EstimateThreshold
+	}
+
+	if estimate, err := estimateCount(query); err == nil && estimate > threshold {
+		return estimate, nil
+	}
+	return exactCount(query)
+}
+
+// exactCount runs COUNT(*) on query
+func exactCount(query *gorm.DB) (int64, error) {
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// estimateCount returns the planner's estimate of the rows matched by query, read with EXPLAIN
+func estimateCount(query *gorm.DB) (int64, error) {
+	var rows []map[string]interface{}
+	dryRun := query.Session(&gorm.Session{DryRun: true}).Select("1").Find(&rows)
+	if dryRun.Error != nil {
+		return 0, dryRun.Error
+	}
+	stmt := dryRun.Statement
+
+	ctx := stmt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var raw string
+	if err := stmt.ConnPool.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&raw); err != nil {
+		return 0, err
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil || len(plans) == 0 {
+		return 0, err
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
@@ -0,0 +1,28 @@
+package pagination
+
+import (
+	"testing"
+
+	"csd-pilote/backend/modules/platform/config"
+)
+
+func TestCurrentCountStrategy(t *testing.T) {
+	previous := config.GetConfig()
+	defer config.SetConfig(previous)
+
+	config.SetConfig(nil)
+	if s := CurrentCountStrategy(); !s.AlwaysExactWithFilters || s.ExactThreshold != fallbackExactCountThreshold {
+		t.Errorf("without config: %+v, want the fallbacks", s)
+	}
+
+	alwaysExact := false
+	config.SetConfig(&config.Config{Pagination: config.PaginationConfig{
+		ExactCountThreshold:    50,
+		EstimateCountThreshold: 500,
+		AlwaysExactWithFilters: &alwaysExact,
+	}})
+	want := CountStrategy{ExactThreshold: 50, EstimateThreshold: 500, AlwaysExactWithFilters: false}
+	if s := CurrentCountStrategy(); s != want {
+		t.Errorf("CurrentCountStrategy() = %+v, want %+v", s, want)
+	}
+}
@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor was not produced by EncodeCursor
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Cursor is the position after which a keyset page starts: the sort key of the last row of the
+// previous page. Lists in created_at DESC, id DESC order use (created_at, id); lists ordered by
+// priority first (priority ASC, created_at DESC, id DESC) also carry the priority.
+type Cursor struct {
+	Priority  *int // Set by EncodePriorityCursor
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// EncodeCursor returns the opaque cursor of a row
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// EncodePriorityCursor returns the opaque cursor of a row of a list ordered by priority first
+func EncodePriorityCursor(priority int, createdAt time.Time, id uuid.UUID) string {
+	raw := strconv.Itoa(priority) + "|" + createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor or EncodePriorityCursor
+func DecodeCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var result Cursor
+	if len(parts) == 3 {
+		priority, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+		result.Priority = &priority
+		parts = parts[1:]
+	}
+	if result.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[0]); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if result.ID, err = uuid.Parse(parts[1]); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	id := uuid.New()
+
+	cursor, err := DecodeCursor(EncodeCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if cursor.Priority != nil || !cursor.CreatedAt.Equal(createdAt) || cursor.ID != id {
+		t.Errorf("DecodeCursor(EncodeCursor) = %+v", cursor)
+	}
+
+	cursor, err = DecodeCursor(EncodePriorityCursor(-5, createdAt, id))
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if cursor.Priority == nil || *cursor.Priority != -5 || !cursor.CreatedAt.Equal(createdAt) || cursor.ID != id {
+		t.Errorf("DecodeCursor(EncodePriorityCursor) = %+v", cursor)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	id := uuid.New().String()
+	for _, raw := range []string{
+		"",
+		"2024-03-01T12:30:00Z",
+		"not-a-time|" + id,
+		"2024-03-01T12:30:00Z|not-a-uuid",
+		"high|2024-03-01T12:30:00Z|" + id,
+		"1|2|2024-03-01T12:30:00Z|" + id,
+	} {
+		if _, err := DecodeCursor(encodeRaw(raw)); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", raw, err)
+		}
+	}
+	if _, err := DecodeCursor("%%%"); err != ErrInvalidCursor {
+		t.Errorf("DecodeCursor of bad base64 error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func encodeRaw(raw string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
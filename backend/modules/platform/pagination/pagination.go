@@ -4,28 +4,74 @@ import (
 	"csd-pilote/backend/modules/platform/config"
 )
 
+// Resource names used as keys of pagination.resources in the config
+const (
+	ResourceActivity            = "activity"
+	ResourceClusters            = "clusters"
+	ResourceContainerEngines    = "container_engines"
+	ResourceContainerInventory  = "container_inventory"
+	ResourceHypervisors         = "hypervisors"
+	ResourceFirewallRules       = "firewall_rules"
+	ResourceFirewallProfiles    = "firewall_profiles"
+	ResourceFirewallTemplates   = "firewall_templates"
+	ResourceFirewallDeployments = "firewall_deployments"
+	ResourceFirewallIPSets      = "firewall_ip_sets"
+	ResourceFirewallAgentGroups = "firewall_agent_groups"
+	ResourceFirewallRollouts    = "firewall_rollouts"
+	ResourceFirewallSchedules   = "firewall_schedules"
+)
+
+// Built-in limits used when the config does not set them
+const (
+	fallbackDefaultLimit = 20
+	fallbackMaxLimit     = 100
+)
+
 // Params represents validated pagination parameters
 type Params struct {
 	Limit  int
 	Offset int
 }
 
-// Normalize validates and normalizes pagination parameters using config values
-// Returns normalized limit and offset values
-func Normalize(limit, offset int) Params {
-	cfg := config.GetConfig()
-
-	defaultLimit := 20
-	maxLimit := 100
+// Limits returns the default and max limits for a resource type.
+// Resource overrides take precedence over the global values; an empty resource uses the global ones.
+func Limits(resource string) (defaultLimit, maxLimit int) {
+	defaultLimit = fallbackDefaultLimit
+	maxLimit = fallbackMaxLimit
 
-	if cfg != nil {
-		if cfg.Pagination.DefaultLimit > 0 {
-			defaultLimit = cfg.Pagination.DefaultLimit
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return defaultLimit, maxLimit
+	}
+	if cfg.Pagination.DefaultLimit > 0 {
+		defaultLimit = cfg.Pagination.DefaultLimit
+	}
+	if cfg.Pagination.MaxLimit > 0 {
+		maxLimit = cfg.Pagination.MaxLimit
+	}
+	if override, ok := cfg.Pagination.Resources[resource]; ok && resource != "" {
+		if override.DefaultLimit > 0 {
+			defaultLimit = override.DefaultLimit
 		}
-		if cfg.Pagination.MaxLimit > 0 {
-			maxLimit = cfg.Pagination.MaxLimit
+		if override.MaxLimit > 0 {
+			maxLimit = override.MaxLimit
 		}
 	}
+	if defaultLimit > maxLimit {
+		defaultLimit = maxLimit
+	}
+	return defaultLimit, maxLimit
+}
+
+// Normalize validates and normalizes pagination parameters using config values
+// Returns normalized limit and offset values
+func Normalize(limit, offset int) Params {
+	return NormalizeFor("", limit, offset)
+}
+
+// NormalizeFor validates and normalizes pagination parameters using the limits of a resource type
+func NormalizeFor(resource string, limit, offset int) Params {
+	defaultLimit, maxLimit := Limits(resource)
 
 	// Apply default if not specified
 	if limit <= 0 {
@@ -55,18 +101,12 @@ func NormalizeLimit(limit int) int {
 
 // DefaultLimit returns the configured default limit
 func DefaultLimit() int {
-	cfg := config.GetConfig()
-	if cfg != nil && cfg.Pagination.DefaultLimit > 0 {
-		return cfg.Pagination.DefaultLimit
-	}
-	return 20
+	defaultLimit, _ := Limits("")
+	return defaultLimit
 }
 
 // MaxLimit returns the configured max limit
 func MaxLimit() int {
-	cfg := config.GetConfig()
-	if cfg != nil && cfg.Pagination.MaxLimit > 0 {
-		return cfg.Pagination.MaxLimit
-	}
-	return 100
+	_, maxLimit := Limits("")
+	return maxLimit
 }
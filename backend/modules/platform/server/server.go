@@ -22,6 +22,23 @@ import (
 	"csd-pilote/backend/modules/platform/websocket"
 )
 
+// startHooks run once the database and the csd-core client are ready (see OnStart)
+var startHooks []func()
+
+// OnStart registers fn to run when the server starts, after the database connected and the
+// csd-core client was created. Modules use it to start background jobs from init().
+func OnStart(fn func()) {
+	startHooks = append(startHooks, fn)
+}
+
+// stopHooks run on shutdown, after the HTTP server stopped and before the database closes (see OnStop)
+var stopHooks []func()
+
+// OnStop registers fn to run when the server shuts down. Modules use it to cancel background work.
+func OnStop(fn func()) {
+	stopHooks = append(stopHooks, fn)
+}
+
 // Server represents the csd-pilote server
 type Server struct {
 	cfg           *config.Config
@@ -62,6 +79,25 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		})
 	})
 
+	// Configuration health check - requires the admin permission (never exposes values)
+	mux.Handle(apiBasePath+"/health/config", middleware.RequireAuth(requireAdmin(csdCoreClient, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := "healthy"
+		problems := []config.ConfigProblem{}
+		if err := cfg.Validate(); err != nil {
+			status = "unhealthy"
+			if verr, ok := err.(*config.ValidationError); ok {
+				problems = verr.Problems
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    status,
+			"problems":  problems,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+	}))))
+
 	// Metrics endpoint - requires authentication
 	mux.Handle(apiBasePath+"/metrics", middleware.RequireAuth(metrics.MetricsHandler()))
 
@@ -118,6 +154,10 @@ func (s *Server) Start() error {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	for _, hook := range startHooks {
+		hook()
+	}
+
 	go func() {
 		log.Printf("Server starting on %s", s.httpServer.Addr)
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -136,6 +176,9 @@ func (s *Server) Start() error {
 	}
 
 	// Stop background services
+	for _, hook := range stopHooks {
+		hook()
+	}
 	websocket.GetHub().Stop()
 	ratelimit.GetRateLimiter().Stop()
 
@@ -144,6 +187,25 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// adminPermission is the csd-core permission required by administrative endpoints
+const adminPermission = "system.admin"
+
+// requireAdmin lets only users holding adminPermission in csd-core through
+func requireAdmin(client *csdcore.Client, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := middleware.GetTokenFromContext(r.Context())
+		if !ok {
+			http.Error(w, `{"errors":[{"message":"Unauthorized"}]}`, http.StatusUnauthorized)
+			return
+		}
+		if isAdmin, err := client.CheckPermission(r.Context(), token, adminPermission); err != nil || !isAdmin {
+			http.Error(w, `{"errors":[{"message":"Forbidden"}]}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // securityHeaders adds security headers to all responses
 func securityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
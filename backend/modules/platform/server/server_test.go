@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"csd-pilote/backend/modules/platform/config"
+	csdcore "csd-pilote/backend/modules/platform/csd-core"
+	"csd-pilote/backend/modules/platform/middleware"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	core := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		isAdmin := r.Header.Get("Authorization") == "Bearer admin-token" && req.Variables["permission"] == adminPermission
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]bool{"hasPermission": isAdmin}})
+	}))
+	defer core.Close()
+	client := csdcore.NewClient(&config.CSDCoreConfig{URL: core.URL, GraphQLEndpoint: "/graphql"})
+
+	handler := requireAdmin(client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"tenant user", "user-token", http.StatusForbidden},
+		{"admin", "admin-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health/config", nil)
+			if tt.token != "" {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.TokenContextKey, tt.token))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
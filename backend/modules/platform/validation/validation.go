@@ -3,13 +3,15 @@ package validation
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
 
-	"csd-pilote/backend/modules/platform/config"
+	"csd-pilote/backend/modules/platform/pagination"
 )
 
 // Limits defines validation limits
@@ -31,8 +33,19 @@ var (
 	portRangeRegex    = regexp.MustCompile(`^(\d+)(-(\d+))?$`)
 	k8sNameRegex      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 	dockerImageRegex  = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]*[a-z0-9])?(:[a-zA-Z0-9._-]+)?(@sha256:[a-f0-9]{64})?$`)
+	hostLabelRegex    = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	macAddressRegex   = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}$|^[0-9a-fA-F]{2}(-[0-9a-fA-F]{2}){5}$`)
+	nftPriorityRegex  = regexp.MustCompile(`^(-?\d+|(raw|mangle|dstnat|filter|security|srcnat)(\s*[+-]\s*\d+)?)$`)
 )
 
+// DSCPClasses maps the DSCP class names understood by nftables to their codepoint
+var DSCPClasses = map[string]int{
+	"cs0": 0, "cs1": 8, "cs2": 16, "cs3": 24, "cs4": 32, "cs5": 40, "cs6": 48, "cs7": 56,
+	"af11": 10, "af12": 12, "af13": 14, "af21": 18, "af22": 20, "af23": 22,
+	"af31": 26, "af32": 28, "af33": 30, "af41": 34, "af42": 36, "af43": 38,
+	"ef": 46, "va": 44, "le": 1,
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string
@@ -205,6 +218,83 @@ func (v *Validator) CIDR(field, value string) *Validator {
 	return v
 }
 
+// MAC validates a MAC address written as aa:bb:cc:dd:ee:ff or aa-bb-cc-dd-ee-ff
+func (v *Validator) MAC(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	if !macAddressRegex.MatchString(value) {
+		v.errors.Add(field, fmt.Sprintf("%s must be a valid MAC address (e.g. aa:bb:cc:dd:ee:ff)", field), "INVALID_MAC")
+	}
+	return v
+}
+
+// Hostname validates an RFC 1123 hostname or fully qualified domain name
+func (v *Validator) Hostname(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	if !isHostname(value) {
+		v.errors.Add(field, fmt.Sprintf("%s must be a valid hostname", field), "INVALID_HOSTNAME")
+	}
+	return v
+}
+
+// isHostname reports whether value is an RFC 1123 hostname, optionally with a trailing dot
+func isHostname(value string) bool {
+	value = strings.TrimSuffix(value, ".")
+	if value == "" || len(value) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(value, ".") {
+		if !hostLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// URL validates a URL whose scheme is one of allowedSchemes and whose host, when present, is a
+// hostname or IP address with an optional valid port. A "+transport" suffix on the scheme
+// (qemu+ssh) is checked by its base scheme. URLs without a host must carry a path (unix:///run/docker.sock).
+func (v *Validator) URL(field, value string, allowedSchemes ...string) *Validator {
+	if value == "" {
+		return v
+	}
+	schemes := strings.Join(allowedSchemes, ", ")
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		v.errors.Add(field, fmt.Sprintf("%s must be a URL such as scheme://host[:port] (schemes: %s)", field, schemes), "INVALID_URL")
+		return v
+	}
+
+	base := strings.ToLower(strings.SplitN(u.Scheme, "+", 2)[0])
+	allowed := false
+	for _, scheme := range allowedSchemes {
+		if base == scheme {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		v.errors.Add(field, fmt.Sprintf("%s must use one of the schemes: %s", field, schemes), "INVALID_URL_SCHEME")
+		return v
+	}
+
+	host := u.Hostname()
+	switch {
+	case host == "" && u.Path == "":
+		v.errors.Add(field, fmt.Sprintf("%s must include a host or a path", field), "INVALID_URL")
+	case host != "" && net.ParseIP(host) == nil && !isHostname(host):
+		v.errors.Add(field, fmt.Sprintf("%s has an invalid host %q", field, host), "INVALID_HOSTNAME")
+	case u.Port() != "":
+		if port, err := strconv.Atoi(u.Port()); err != nil || port < MinPortNumber || port > MaxPortNumber {
+			v.errors.Add(field, fmt.Sprintf("%s must use a valid port (1-65535)", field), "INVALID_PORT")
+		}
+	}
+	return v
+}
+
 // Port validates that a number is a valid port
 func (v *Validator) Port(field string, value int) *Validator {
 	if value < MinPortNumber || value > MaxPortNumber {
@@ -213,17 +303,92 @@ func (v *Validator) Port(field string, value int) *Validator {
 	return v
 }
 
-// PortRange validates a port range string (e.g., "80", "80-443")
+// PortRange validates a port range string (e.g., "80", "80-443"); ports must be 1-65535 and
+// a range must not end before it starts
 func (v *Validator) PortRange(field, value string) *Validator {
 	if value == "" {
 		return v
 	}
-	if !portRangeRegex.MatchString(value) {
+	m := portRangeRegex.FindStringSubmatch(value)
+	if m == nil {
 		v.errors.Add(field, fmt.Sprintf("%s must be a valid port or port range", field), "INVALID_PORT_RANGE")
+		return v
+	}
+	start, err := strconv.Atoi(m[1])
+	end := start
+	if err == nil && m[3] != "" {
+		end, err = strconv.Atoi(m[3])
+	}
+	if err != nil || start < MinPortNumber || end > MaxPortNumber || end < start {
+		v.errors.Add(field, fmt.Sprintf("%s must be a valid port or port range (1-65535, low-high)", field), "INVALID_PORT_RANGE")
 	}
 	return v
 }
 
+// PortSet validates a list of ports and port ranges, bare ("80,443") or as an nftables
+// anonymous set ("{ 22, 80, 8000-8080 }")
+func (v *Validator) PortSet(field, value string) *Validator {
+	if strings.TrimSpace(value) == "" {
+		return v
+	}
+	elements, ok := setElements(value)
+	if !ok {
+		v.errors.Add(field, fmt.Sprintf("%s must be a port, a port range or a list of them (e.g. { 22, 80, 8000-8080 })", field), "INVALID_PORT_SET")
+		return v
+	}
+	for _, element := range elements {
+		v.PortRange(field, element)
+	}
+	return v
+}
+
+// IPSet validates a list of IP addresses and CIDR networks, bare ("10.0.0.1,10.1.0.0/16") or
+// as an nftables anonymous set ("{ 10.0.0.1, 10.1.0.0/16 }")
+func (v *Validator) IPSet(field, value string) *Validator {
+	if strings.TrimSpace(value) == "" {
+		return v
+	}
+	elements, ok := setElements(value)
+	if !ok {
+		v.errors.Add(field, fmt.Sprintf("%s must be an address, a network or a list of them (e.g. { 10.0.0.1, 10.1.0.0/16 })", field), "INVALID_IP_SET")
+		return v
+	}
+	for _, element := range elements {
+		if strings.Contains(element, "/") {
+			v.CIDR(field, element)
+		} else {
+			v.IP(field, element)
+		}
+	}
+	return v
+}
+
+// setElements splits a comma-separated list, optionally wrapped in braces, into trimmed elements.
+// It fails on unbalanced braces and on empty elements, which nftables rejects.
+func setElements(value string) ([]string, bool) {
+	value = strings.TrimSpace(value)
+	opened, closed := strings.HasPrefix(value, "{"), strings.HasSuffix(value, "}")
+	if opened != closed {
+		return nil, false
+	}
+	if opened {
+		value = value[1 : len(value)-1]
+	}
+	if strings.ContainsAny(value, "{}") {
+		return nil, false
+	}
+	parts := strings.Split(value, ",")
+	elements := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, false
+		}
+		elements = append(elements, part)
+	}
+	return elements, true
+}
+
 // SafeString validates that a string doesn't contain dangerous characters
 func (v *Validator) SafeString(field, value string) *Validator {
 	if value == "" {
@@ -295,6 +460,53 @@ func (v *Validator) NftablesExpression(field, value string) *Validator {
 	return v
 }
 
+// DSCP validates a DSCP class name (e.g. "ef", "af21") or codepoint (0-63)
+func (v *Validator) DSCP(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	if _, ok := DSCPClasses[strings.ToLower(value)]; ok {
+		return v
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 63 {
+		return v
+	}
+	v.errors.Add(field, fmt.Sprintf("%s must be a DSCP class name (e.g. ef, af21, cs1) or a value between 0 and 63", field), "INVALID_DSCP")
+	return v
+}
+
+// PacketLength validates a packet length or length range (e.g. "1500", "0-128")
+func (v *Validator) PacketLength(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	m := portRangeRegex.FindStringSubmatch(value)
+	if m == nil {
+		v.errors.Add(field, fmt.Sprintf("%s must be a length or length range (e.g. 1500, 0-128)", field), "INVALID_LENGTH")
+		return v
+	}
+	low, _ := strconv.Atoi(m[1])
+	high := low
+	if m[3] != "" {
+		high, _ = strconv.Atoi(m[3])
+	}
+	if high > MaxPortNumber || low > high {
+		v.errors.Add(field, fmt.Sprintf("%s must be between 0 and 65535 with the lower bound first", field), "INVALID_LENGTH")
+	}
+	return v
+}
+
+// NftablesPriority validates a chain priority (integer or named priority with optional offset)
+func (v *Validator) NftablesPriority(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	if !nftPriorityRegex.MatchString(strings.TrimSpace(value)) {
+		v.errors.Add(field, fmt.Sprintf("%s must be an integer or a named nftables priority (e.g. filter, dstnat + 10)", field), "INVALID_NFTABLES_PRIORITY")
+	}
+	return v
+}
+
 // ValidateName validates a name field with standard rules
 func ValidateName(name string) error {
 	v := NewValidator()
@@ -318,31 +530,20 @@ func ValidateDescription(description string) error {
 	return nil
 }
 
-// ValidatePagination validates limit and offset parameters
-func ValidatePagination(limit, offset int) (int, int, error) {
-	defaultLimit := 20
-	maxLimit := MaxPaginationLimit
-
-	// Use config values if available
-	if cfg := config.GetConfig(); cfg != nil {
-		if cfg.Pagination.DefaultLimit > 0 {
-			defaultLimit = cfg.Pagination.DefaultLimit
-		}
-		if cfg.Pagination.MaxLimit > 0 {
-			maxLimit = cfg.Pagination.MaxLimit
-		}
+// ValidateMAC validates an optional MAC address field
+func ValidateMAC(mac string) error {
+	v := NewValidator()
+	v.MAC("mac", mac)
+	if v.HasErrors() {
+		return v.errors
 	}
+	return nil
+}
 
-	if limit <= 0 {
-		limit = defaultLimit
-	}
-	if limit > maxLimit {
-		limit = maxLimit
-	}
-	if offset < 0 {
-		offset = 0
-	}
-	return limit, offset, nil
+// ValidatePagination validates limit and offset parameters
+func ValidatePagination(limit, offset int) (int, int, error) {
+	p := pagination.Normalize(limit, offset)
+	return p.Limit, p.Offset, nil
 }
 
 // ValidateBulkIDs validates a list of IDs for bulk operations
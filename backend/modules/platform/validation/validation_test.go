@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPortSet(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"", true},
+		{"22", true},
+		{"8000-8080", true},
+		{"80,443", true},
+		{"{ 22, 80, 8000-8080 }", true},
+		{"{22,80}", true},
+		{" { 53 } ", true},
+		{"1-65535", true},
+		{"0", false},
+		{"65536", false},
+		{"443-80", false},
+		{"80-70000", false},
+		{"http", false},
+		{"80,", false},
+		{",80", false},
+		{"80,,443", false},
+		{"{ 80, 443", false},
+		{"80, 443 }", false},
+		{"{ }", false},
+		{"{ 80, { 443 } }", false},
+		{"80 443", false},
+		{"-80", false},
+	}
+	for _, tt := range tests {
+		v := NewValidator()
+		v.PortSet("port", tt.value)
+		if v.HasErrors() == tt.valid {
+			t.Errorf("PortSet(%q) valid = %v, want %v (%s)", tt.value, !v.HasErrors(), tt.valid, v.FirstError())
+		}
+	}
+}
+
+func TestIPSet(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"", true},
+		{"10.0.0.1", true},
+		{"10.1.0.0/16", true},
+		{"10.0.0.1,10.1.0.0/16", true},
+		{"{ 10.0.0.1, 10.1.0.0/16 }", true},
+		{"{ 2001:db8::1, 2001:db8::/32 }", true},
+		{"{ 10.0.0.1, 2001:db8::1 }", true},
+		{"10.0.0.256", false},
+		{"10.0.0.0/33", false},
+		{"2001:db8::/129", false},
+		{"example.com", false},
+		{"10.0.0.1,", false},
+		{"10.0.0.1,,10.0.0.2", false},
+		{"{ 10.0.0.1", false},
+		{"10.0.0.1 }", false},
+		{"{ }", false},
+		{"{ 10.0.0.1, { 10.0.0.2 } }", false},
+		{"10.0.0.1 10.0.0.2", false},
+	}
+	for _, tt := range tests {
+		v := NewValidator()
+		v.IPSet("addr", tt.value)
+		if v.HasErrors() == tt.valid {
+			t.Errorf("IPSet(%q) valid = %v, want %v (%s)", tt.value, !v.HasErrors(), tt.valid, v.FirstError())
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		schemes []string
+		code    string // expected error code, empty when valid
+	}{
+		{"empty", "", []string{"tcp"}, ""},
+		{"host and port", "tcp://10.0.0.5:2376", []string{"tcp"}, ""},
+		{"hostname", "tcp://docker.example.com", []string{"tcp"}, ""},
+		{"trailing dot", "tcp://docker.example.com.", []string{"tcp"}, ""},
+		{"ipv6 host", "tcp://[2001:db8::1]:2375", []string{"tcp"}, ""},
+		{"path only", "unix:///var/run/docker.sock", []string{"unix"}, ""},
+		{"transport suffix", "qemu+ssh://root@kvm01/system", []string{"qemu"}, ""},
+		{"scheme case", "QEMU:///system", []string{"qemu"}, ""},
+		{"no scheme", "docker.example.com", []string{"tcp"}, "INVALID_URL"},
+		{"opaque", "tcp:docker.example.com", []string{"tcp"}, "INVALID_URL"},
+		{"parse error", "tcp://bad host", []string{"tcp"}, "INVALID_URL"},
+		{"no host or path", "tcp://", []string{"tcp"}, "INVALID_URL"},
+		{"scheme not allowed", "http://docker.example.com", []string{"tcp", "unix"}, "INVALID_URL_SCHEME"},
+		{"transport of other scheme", "xen+ssh://root@host/", []string{"qemu"}, "INVALID_URL_SCHEME"},
+		{"bad hostname", "tcp://docker_host", []string{"tcp"}, "INVALID_HOSTNAME"},
+		{"long label", "tcp://" + strings.Repeat("a", 64) + ".example.com", []string{"tcp"}, "INVALID_HOSTNAME"},
+		{"port zero", "tcp://docker.example.com:0", []string{"tcp"}, "INVALID_PORT"},
+		{"port too high", "tcp://docker.example.com:65536", []string{"tcp"}, "INVALID_PORT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator()
+			v.URL("url", tt.value, tt.schemes...)
+			code := ""
+			if v.HasErrors() {
+				code = v.Errors().Errors[0].Code
+			}
+			if code != tt.code {
+				t.Errorf("URL(%q) error code = %q, want %q (%s)", tt.value, code, tt.code, v.FirstError())
+			}
+		})
+	}
+}
+
+func TestMAC(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"", true},
+		{"aa:bb:cc:dd:ee:ff", true},
+		{"AA:BB:CC:00:11:22", true},
+		{"aa-bb-cc-dd-ee-ff", true},
+		{"aa:bb-cc:dd:ee:ff", false},
+		{"aa:bb:cc:dd:ee", false},
+		{"aa:bb:cc:dd:ee:ff:00", false},
+		{"aabb.ccdd.eeff", false},
+		{"gg:bb:cc:dd:ee:ff", false},
+		{"a:bb:cc:dd:ee:ff", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateMAC(tt.value); (err == nil) != tt.valid {
+			t.Errorf("ValidateMAC(%q) = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}